@@ -3,6 +3,7 @@ package cmd
 import (
 	"github.com/bestruirui/octopus/internal/conf"
 	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/helper"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/server"
 	"github.com/bestruirui/octopus/internal/task"
@@ -36,6 +37,10 @@ var startCmd = &cobra.Command{
 		}
 		shutdown.Register(op.SaveCache)
 
+		if err := helper.ApplyTokenizerRules(); err != nil {
+			log.Warnf("invalid tokenizer rules: %v", err)
+		}
+
 		if err := op.UserInit(); err != nil {
 			log.Errorf("user init error: %v", err)
 			return
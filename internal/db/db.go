@@ -64,9 +64,20 @@ func InitDB(dbType, dsn string, debug bool) error {
 		&model.StatsDaily{},
 		&model.StatsHourly{},
 		&model.StatsModel{},
+		&model.StatsModelHourly{},
+		&model.StatsAPIKeyModel{},
 		&model.StatsChannel{},
 		&model.StatsAPIKey{},
+		&model.APIKeyLimitUsage{},
+		&model.APIKeyTransaction{},
+		&model.NotifyTarget{},
+		&model.NotifyRule{},
+		&model.NotifyDedup{},
 		&model.RelayLog{},
+		&model.ResponseObject{},
+		&model.File{},
+		&model.Batch{},
+		&model.BatchResult{},
 		&migrate.MigrationRecord{},
 	); err != nil {
 		return err
@@ -0,0 +1,44 @@
+package migrate
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterBeforeAutoMigration(Migration{
+		Version: 4,
+		Up:      dropLegacyStatsModels,
+	})
+}
+
+// 004: stats_models 由自增 id 改为 (date, name, channel_id) 主键，旧表从未写入数据，直接删除后由 AutoMigrate 重建
+func dropLegacyStatsModels(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+	migrator := db.Migrator()
+	if !migrator.HasTable("stats_models") {
+		return nil
+	}
+	// sqlite 下 HasColumn 按建表语句模糊匹配，channel_id 也会被当作 id，需要精确检查
+	hasID := false
+	if db.Dialector.Name() == "sqlite" {
+		var name string
+		if err := db.Raw("SELECT name FROM pragma_table_info(?) WHERE name = ? LIMIT 1", "stats_models", "id").
+			Scan(&name).Error; err != nil {
+			return fmt.Errorf("failed to check sqlite column stats_models.id: %w", err)
+		}
+		hasID = name == "id"
+	} else {
+		hasID = migrator.HasColumn("stats_models", "id")
+	}
+	if !hasID {
+		return nil
+	}
+	if err := migrator.DropTable("stats_models"); err != nil {
+		return fmt.Errorf("failed to drop legacy stats_models: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package migrate
+
+import "testing"
+
+func TestDropLegacyStatsModels(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		exists bool
+	}{
+		{"legacy table with id", "CREATE TABLE stats_models (id integer PRIMARY KEY, name text, channel_id integer)", false},
+		{"current table", "CREATE TABLE stats_models (date text, name text, channel_id integer, PRIMARY KEY (date, name, channel_id))", true},
+		{"fresh database", "", false},
+	}
+	for _, tt := range tests {
+		db := newTestDB(t)
+		if tt.schema != "" {
+			if err := db.Exec(tt.schema).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := dropLegacyStatsModels(db); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if exists := db.Migrator().HasTable("stats_models"); exists != tt.exists {
+			t.Errorf("%s: table exists = %t, want %t", tt.name, exists, tt.exists)
+		}
+	}
+}
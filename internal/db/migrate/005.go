@@ -0,0 +1,25 @@
+package migrate
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterAfterAutoMigration(Migration{
+		Version: 5,
+		Up:      migrateStatsAPIKeyQuotaCost,
+	})
+}
+
+// 005: 额度重置不再清空 API Key 统计，改为清零 quota_cost；此前两者始终相等，按已有费用初始化
+func migrateStatsAPIKeyQuotaCost(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+	if err := db.Exec("UPDATE stats_api_keys SET quota_cost = input_cost + output_cost").Error; err != nil {
+		return fmt.Errorf("failed to init stats_api_keys quota_cost: %w", err)
+	}
+	return nil
+}
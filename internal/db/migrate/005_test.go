@@ -0,0 +1,20 @@
+package migrate
+
+import "testing"
+
+func TestMigrateStatsAPIKeyQuotaCost(t *testing.T) {
+	db := newTestDB(t,
+		"CREATE TABLE stats_api_keys (api_key_id integer PRIMARY KEY, input_cost real, output_cost real, quota_cost real DEFAULT 0)",
+		"INSERT INTO stats_api_keys (api_key_id, input_cost, output_cost) VALUES (1, 1.5, 0.5), (2, 0, 0)",
+	)
+	if err := migrateStatsAPIKeyQuotaCost(db); err != nil {
+		t.Fatal(err)
+	}
+	var costs []float64
+	if err := db.Raw("SELECT quota_cost FROM stats_api_keys ORDER BY api_key_id").Scan(&costs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(costs) != 2 || costs[0] != 2 || costs[1] != 0 {
+		t.Fatalf("quota_cost = %v, want [2 0]", costs)
+	}
+}
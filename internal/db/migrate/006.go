@@ -0,0 +1,122 @@
+package migrate
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	RegisterAfterAutoMigration(Migration{
+		Version: 6,
+		Up:      backfillStatsAPIKeyModels,
+	})
+}
+
+type relayLogUsage006 struct {
+	Time             int64
+	APIKeyID         int
+	RequestModelName string
+	ActualModelName  string
+	InputTokens      int64
+	OutputTokens     int64
+	CacheReadTokens  int64
+	CacheWriteTokens int64
+	UseTime          int64
+	Cost             float64
+	UpstreamCost     float64
+	Error            string
+}
+
+type statsAPIKeyModel006 struct {
+	Date            string `gorm:"primaryKey"`
+	APIKeyID        int    `gorm:"primaryKey"`
+	Name            string `gorm:"primaryKey"`
+	InputToken      int64
+	OutputToken     int64
+	InputCost       float64
+	OutputCost      float64
+	UpstreamCost    float64
+	WaitTime        int64
+	RequestSuccess  int64
+	RequestFailed   int64
+	CacheReadToken  int64
+	CacheWriteToken int64
+}
+
+func (statsAPIKeyModel006) TableName() string { return "stats_api_key_models" }
+
+// 006: 用量导出改为读取 stats_api_key_models，用已保存的请求日志补齐此前的数据
+// 日志只记录总费用，补齐的数据全部计入 input_cost
+func backfillStatsAPIKeyModels(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+	var count int64
+	if err := db.Model(&statsAPIKeyModel006{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count stats_api_key_models: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	rows, err := db.Table("relay_logs").
+		Select("time, api_key_id, request_model_name, actual_model_name, input_tokens, output_tokens, cache_read_tokens, cache_write_tokens, use_time, cost, upstream_cost, error").
+		Rows()
+	if err != nil {
+		return fmt.Errorf("failed to query relay_logs: %w", err)
+	}
+	defer rows.Close()
+
+	type key struct {
+		Date     string
+		APIKeyID int
+		Name     string
+	}
+	stats := make(map[key]*statsAPIKeyModel006)
+	for rows.Next() {
+		var l relayLogUsage006
+		if err := db.ScanRows(rows, &l); err != nil {
+			return fmt.Errorf("failed to scan relay_logs: %w", err)
+		}
+		name := l.ActualModelName
+		if name == "" {
+			name = l.RequestModelName
+		}
+		k := key{Date: time.Unix(l.Time, 0).Format("20060102"), APIKeyID: l.APIKeyID, Name: name}
+		s, ok := stats[k]
+		if !ok {
+			s = &statsAPIKeyModel006{Date: k.Date, APIKeyID: k.APIKeyID, Name: k.Name}
+			stats[k] = s
+		}
+		if l.Error == "" {
+			s.RequestSuccess++
+		} else {
+			s.RequestFailed++
+		}
+		s.InputToken += l.InputTokens
+		s.OutputToken += l.OutputTokens
+		s.CacheReadToken += l.CacheReadTokens
+		s.CacheWriteToken += l.CacheWriteTokens
+		s.WaitTime += l.UseTime
+		s.InputCost += l.Cost
+		s.UpstreamCost += l.UpstreamCost
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read relay_logs: %w", err)
+	}
+	if len(stats) == 0 {
+		return nil
+	}
+
+	batch := make([]statsAPIKeyModel006, 0, len(stats))
+	for _, s := range stats {
+		batch = append(batch, *s)
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&batch, 500).Error; err != nil {
+		return fmt.Errorf("failed to backfill stats_api_key_models: %w", err)
+	}
+	return nil
+}
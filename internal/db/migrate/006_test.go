@@ -0,0 +1,67 @@
+package migrate
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const relayLogs006 = `CREATE TABLE relay_logs (id integer PRIMARY KEY, time integer, api_key_id integer, request_model_name text, actual_model_name text,
+	input_tokens integer, output_tokens integer, cache_read_tokens integer, cache_write_tokens integer, use_time integer, cost real, upstream_cost real, error text)`
+
+const statsAPIKeyModels006 = `CREATE TABLE stats_api_key_models (date text, api_key_id integer, name text, input_token integer, output_token integer,
+	input_cost real, output_cost real, upstream_cost real, wait_time integer, request_success integer, request_failed integer,
+	cache_read_token integer, cache_write_token integer, PRIMARY KEY (date, api_key_id, name))`
+
+func TestBackfillStatsAPIKeyModels(t *testing.T) {
+	day := time.Date(2026, 1, 2, 12, 0, 0, 0, time.Local)
+	db := newTestDB(t, relayLogs006, statsAPIKeyModels006)
+	logs := []relayLogUsage006{
+		{Time: day.Unix(), APIKeyID: 1, RequestModelName: "alias", ActualModelName: "gpt-4o", InputTokens: 10, OutputTokens: 5, CacheReadTokens: 2, UseTime: 100, Cost: 0.1, UpstreamCost: 0.05},
+		{Time: day.Add(time.Hour).Unix(), APIKeyID: 1, RequestModelName: "gpt-4o", InputTokens: 20, CacheWriteTokens: 3, UseTime: 50, Cost: 0.2, Error: "upstream error"},
+		// 没有实际模型时按请求模型计入
+		{Time: day.Unix(), APIKeyID: 1, RequestModelName: "claude", InputTokens: 1},
+		{Time: day.AddDate(0, 0, 1).Unix(), APIKeyID: 2, ActualModelName: "gpt-4o", Cost: 1},
+	}
+	if err := db.Table("relay_logs").Create(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := backfillStatsAPIKeyModels(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []statsAPIKeyModel006
+	if err := db.Order("date, api_key_id, name").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []statsAPIKeyModel006{
+		{Date: "20260102", APIKeyID: 1, Name: "claude", InputToken: 1, RequestSuccess: 1},
+		{Date: "20260102", APIKeyID: 1, Name: "gpt-4o", InputToken: 30, OutputToken: 5, CacheReadToken: 2, CacheWriteToken: 3, WaitTime: 150,
+			InputCost: 0.3, UpstreamCost: 0.05, RequestSuccess: 1, RequestFailed: 1},
+		{Date: "20260103", APIKeyID: 2, Name: "gpt-4o", InputCost: 1, RequestSuccess: 1},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %+v", rows)
+	}
+	for i := range want {
+		got := rows[i]
+		costMatch := math.Abs(got.InputCost-want[i].InputCost) < 1e-9
+		got.InputCost = want[i].InputCost
+		if got != want[i] || !costMatch {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	// 已有数据时不再补齐
+	if err := db.Table("relay_logs").Create(&relayLogUsage006{Time: day.Unix(), APIKeyID: 3, ActualModelName: "gpt-4o"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := backfillStatsAPIKeyModels(db); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&statsAPIKeyModel006{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("rows after second run = %d, want 3", count)
+	}
+}
@@ -0,0 +1,52 @@
+package migrate
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 创建临时 SQLite 数据库，并执行 schema 中的建表语句
+func newTestDB(t *testing.T, schema ...string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	for _, stmt := range schema {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestRunMigrationsWithRecord(t *testing.T) {
+	db := newTestDB(t)
+	var runs []int
+	migrations := []Migration{
+		{Version: 2, Up: func(*gorm.DB) error { runs = append(runs, 2); return nil }},
+		{Version: 1, Up: func(*gorm.DB) error { runs = append(runs, 1); return nil }},
+	}
+	if err := runMigrationsWithRecord(db, migrations); err != nil {
+		t.Fatal(err)
+	}
+	// 已成功的迁移不再执行
+	if err := runMigrationsWithRecord(db, migrations); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0] != 1 || runs[1] != 2 {
+		t.Fatalf("runs = %v, want [1 2]", runs)
+	}
+	if err := runMigrationsWithRecord(db, []Migration{{Version: 3, Up: func(*gorm.DB) error { return nil }}, {Version: 3, Up: func(*gorm.DB) error { return nil }}}); err == nil {
+		t.Fatal("duplicated versions should fail")
+	}
+}
@@ -0,0 +1,59 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrAPIKeyDisabled = errors.New("API key is disabled")
+	ErrAPIKeyExpired  = errors.New("API key has expired")
+)
+
+// APIKeyAuthorize 校验 API Key 是否可用并按周期自动重置额度，返回总额度是否已用完
+// /v1 接口的鉴权中间件和批处理共用，apiKey 会被更新为重置后的状态
+func APIKeyAuthorize(apiKey *model.APIKey, ctx context.Context) (bool, error) {
+	if !apiKey.Enabled {
+		return false, ErrAPIKeyDisabled
+	}
+	if apiKey.ExpireAt > 0 && apiKey.ExpireAt < time.Now().Unix() {
+		return false, ErrAPIKeyExpired
+	}
+
+	// Auto reset quota logic
+	if apiKey.AutoResetQuota && apiKey.ResetDuration > 0 {
+		now := time.Now()
+		nowUnix := now.Unix()
+		forceReset := apiKey.ResetUnit == "day" && apiKey.NextResetTime > 0 && !IsAlignedToMidnight(apiKey.NextResetTime)
+		if apiKey.NextResetTime == 0 {
+			apiKey.NextResetTime = CalculateNextResetTime(now, apiKey.ResetDuration, apiKey.ResetUnit)
+			op.APIKeyUpdate(apiKey, ctx)
+		} else if nowUnix >= apiKey.NextResetTime || forceReset {
+			if err := op.StatsAPIKeyReset(apiKey.ID); err == nil {
+				apiKey.NextResetTime = CalculateNextResetTime(now, apiKey.ResetDuration, apiKey.ResetUnit)
+				op.APIKeyUpdate(apiKey, ctx)
+			}
+		}
+	}
+
+	statsAPIKey := op.StatsAPIKeyGet(apiKey.ID)
+	return apiKey.MaxCost > 0 && apiKey.MaxCost < statsAPIKey.QuotaCost, nil
+}
+
+// SetAPIKeyContext 写入中继读取的 API Key 信息，requestType 为 openai 或 anthropic
+func SetAPIKeyContext(c *gin.Context, apiKey model.APIKey, requestType string, quotaExceeded bool) {
+	if quotaExceeded {
+		c.Set("quota_exceeded", true)
+	}
+	// 用量在中继中计入，这里只返回当前状态
+	limitStates, _ := op.APIKeyLimitCheck(apiKey)
+	SetRateLimitHeaders(c.Writer.Header(), limitStates)
+	c.Set("request_type", requestType)
+	c.Set("supported_models", apiKey.SupportedModels)
+	c.Set("api_key_id", apiKey.ID)
+}
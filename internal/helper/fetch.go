@@ -3,6 +3,7 @@ package helper
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -22,6 +23,11 @@ func FetchModels(ctx context.Context, request model.Channel) ([]string, error) {
 		fetchModel, err = fetchAnthropicModels(client, ctx, request)
 	case outbound.OutboundTypeGemini:
 		fetchModel, err = fetchGeminiModels(client, ctx, request)
+	case outbound.OutboundTypeOllama:
+		fetchModel, err = fetchOllamaModels(client, ctx, request)
+	case outbound.OutboundTypeVertex, outbound.OutboundTypeAzureChat, outbound.OutboundTypeAzureResponse, outbound.OutboundTypeAzureEmbedding:
+		// Vertex 与 Azure 没有可用的部署/模型列表接口，需要手动填写模型
+		return nil, fmt.Errorf("fetching models is not supported for channel type %d", request.Type)
 	default:
 		fetchModel, err = fetchOpenAIModels(client, ctx, request)
 	}
@@ -173,3 +179,37 @@ func fetchAnthropicModels(client *http.Client, ctx context.Context, request mode
 	}
 	return allModels, nil
 }
+
+// refer: https://github.com/ollama/ollama/blob/main/docs/api.md#list-local-models
+func fetchOllamaModels(client *http.Client, ctx context.Context, request model.Channel) ([]string, error) {
+	req, _ := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		strings.TrimSuffix(request.GetBaseUrl(), "/")+"/api/tags",
+		nil,
+	)
+	if key := request.GetChannelKey().ChannelKey; key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result model.OllamaModelList
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(result.Models))
+	for _, m := range result.Models {
+		models = append(models, m.Name)
+	}
+	if len(models) == 0 {
+		return fetchOpenAIModels(client, ctx, request)
+	}
+	return models, nil
+}
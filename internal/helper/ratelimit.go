@@ -0,0 +1,59 @@
+package helper
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+// SetRateLimitHeaders 写入 x-ratelimit-{limit,remaining,reset}-{requests,tokens,cost} 响应头
+// 同一维度配置了多个窗口时，取剩余比例最低的一条
+func SetRateLimitHeaders(header http.Header, states []model.APIKeyLimitState) {
+	tightest := make(map[model.APIKeyLimitMetric]model.APIKeyLimitState)
+	for _, state := range states {
+		current, ok := tightest[state.Metric]
+		if !ok || state.Remaining()/state.Max < current.Remaining()/current.Max {
+			tightest[state.Metric] = state
+		}
+	}
+	now := time.Now()
+	for metric, state := range tightest {
+		header.Set("x-ratelimit-limit-"+string(metric), formatLimitValue(metric, state.Max))
+		header.Set("x-ratelimit-remaining-"+string(metric), formatLimitValue(metric, state.Remaining()))
+		header.Set("x-ratelimit-reset-"+string(metric), resetIn(state, now).String())
+	}
+}
+
+// RetryAfter 达到上限的限额距离重置的秒数
+func RetryAfter(state model.APIKeyLimitState) string {
+	return strconv.FormatInt(int64(resetIn(state, time.Now())/time.Second), 10)
+}
+
+// RateLimitMessage 描述达到上限的限额，如 "rate limit exceeded: 2000000 tokens per day (used 2000150), resets in 5h3m0s"
+func RateLimitMessage(state model.APIKeyLimitState) string {
+	var limit, used string
+	switch state.Metric {
+	case model.APIKeyLimitMetricCost:
+		limit = "$" + formatLimitValue(state.Metric, state.Max)
+		used = "$" + formatLimitValue(state.Metric, state.Used)
+	default:
+		limit = formatLimitValue(state.Metric, state.Max) + " " + string(state.Metric)
+		used = formatLimitValue(state.Metric, state.Used)
+	}
+	return fmt.Sprintf("rate limit exceeded: %s per %s (used %s), resets in %s", limit, state.Window, used, resetIn(state, time.Now()))
+}
+
+func resetIn(state model.APIKeyLimitState, now time.Time) time.Duration {
+	return max(time.Unix(state.ResetAt, 0).Sub(now).Round(time.Second), time.Second)
+}
+
+func formatLimitValue(metric model.APIKeyLimitMetric, value float64) string {
+	if metric == model.APIKeyLimitMetricCost {
+		return strconv.FormatFloat(math.Round(value*1e6)/1e6, 'f', -1, 64)
+	}
+	return strconv.FormatInt(int64(value), 10)
+}
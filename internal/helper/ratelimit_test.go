@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+func TestSetRateLimitHeaders(t *testing.T) {
+	resetAt := time.Now().Add(90 * time.Second).Unix()
+	header := http.Header{}
+	SetRateLimitHeaders(header, []model.APIKeyLimitState{
+		// 同一维度取剩余比例最低的窗口
+		{APIKeyLimit: model.APIKeyLimit{Metric: model.APIKeyLimitMetricRequests, Window: model.APIKeyLimitWindowMinute, Max: 60}, Used: 10, ResetAt: resetAt},
+		{APIKeyLimit: model.APIKeyLimit{Metric: model.APIKeyLimitMetricRequests, Window: model.APIKeyLimitWindowDay, Max: 1000}, Used: 900, ResetAt: resetAt},
+		{APIKeyLimit: model.APIKeyLimit{Metric: model.APIKeyLimitMetricCost, Window: model.APIKeyLimitWindowMonth, Max: 5}, Used: 6.1234567, ResetAt: resetAt},
+	})
+
+	want := map[string]string{
+		"x-ratelimit-limit-requests":     "1000",
+		"x-ratelimit-remaining-requests": "100",
+		"x-ratelimit-limit-cost":         "5",
+		"x-ratelimit-remaining-cost":     "0",
+	}
+	for key, value := range want {
+		if got := header.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	if reset := header.Get("x-ratelimit-reset-requests"); reset != "1m30s" && reset != "1m29s" {
+		t.Errorf("x-ratelimit-reset-requests = %q", reset)
+	}
+	if header.Get("x-ratelimit-limit-tokens") != "" {
+		t.Error("tokens header set without a tokens limit")
+	}
+}
+
+func TestRateLimitMessage(t *testing.T) {
+	resetAt := time.Now().Add(-time.Second).Unix()
+	cost := model.APIKeyLimitState{APIKeyLimit: model.APIKeyLimit{Metric: model.APIKeyLimitMetricCost, Window: model.APIKeyLimitWindowDay, Max: 5}, Used: 5.0000004, ResetAt: resetAt}
+	if got := RateLimitMessage(cost); !strings.HasPrefix(got, "rate limit exceeded: $5 per day (used $5), resets in ") {
+		t.Errorf("cost message = %q", got)
+	}
+	tokens := model.APIKeyLimitState{APIKeyLimit: model.APIKeyLimit{Metric: model.APIKeyLimitMetricTokens, Window: model.APIKeyLimitWindowHour, Max: 2000000}, Used: 2000150, ResetAt: resetAt}
+	if got := RateLimitMessage(tokens); !strings.HasPrefix(got, "rate limit exceeded: 2000000 tokens per hour (used 2000150)") {
+		t.Errorf("tokens message = %q", got)
+	}
+	// 已过重置时间时至少返回 1 秒
+	if got := RetryAfter(tokens); got != "1" {
+		t.Errorf("retry after = %q, want 1", got)
+	}
+}
@@ -0,0 +1,20 @@
+package helper
+
+import (
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/utils/tokenizer"
+)
+
+// ApplyTokenizerRules 将设置中的自定义分词器规则应用到本地 token 计数
+func ApplyTokenizerRules() error {
+	value, err := op.SettingGetString(model.SettingKeyTokenizerRules)
+	if err != nil {
+		return err
+	}
+	rules, err := model.ParseTokenizerRules(value)
+	if err != nil {
+		return err
+	}
+	return tokenizer.SetRules(rules)
+}
@@ -0,0 +1,69 @@
+package helper
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+var usageCSVHeader = []string{
+	"date", "api_key_id", "api_key_name", "model",
+	"requests", "failed_requests",
+	"input_tokens", "output_tokens", "cache_read_tokens", "cache_write_tokens",
+	"cost",
+}
+
+// ParseUsageRange 解析 YYYY-MM-DD 格式的起止日期 (含结束当天，按服务器时区)，返回 [start, end) 区间
+// 未指定时默认从本月 1 日到今天
+func ParseUsageRange(startDate, endDate string) (time.Time, time.Time, error) {
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	var err error
+	if startDate != "" {
+		if start, err = time.ParseInLocation(time.DateOnly, startDate, time.Local); err != nil {
+			return start, end, fmt.Errorf("invalid start date %q, expected YYYY-MM-DD", startDate)
+		}
+	}
+	if endDate != "" {
+		if end, err = time.ParseInLocation(time.DateOnly, endDate, time.Local); err != nil {
+			return start, end, fmt.Errorf("invalid end date %q, expected YYYY-MM-DD", endDate)
+		}
+	}
+	if end.Before(start) {
+		return start, end, fmt.Errorf("end date must not be before start date")
+	}
+	return start, end.AddDate(0, 0, 1), nil
+}
+
+// WriteUsageCSV 将用量汇总写为带表头的 CSV
+func WriteUsageCSV(w io.Writer, rows []model.UsageRow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(usageCSVHeader); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := []string{
+			row.Date,
+			strconv.Itoa(row.APIKeyID),
+			row.APIKeyName,
+			row.Model,
+			strconv.FormatInt(row.Requests, 10),
+			strconv.FormatInt(row.FailedRequests, 10),
+			strconv.FormatInt(row.InputTokens, 10),
+			strconv.FormatInt(row.OutputTokens, 10),
+			strconv.FormatInt(row.CacheReadTokens, 10),
+			strconv.FormatInt(row.CacheWriteTokens, 10),
+			strconv.FormatFloat(row.Cost, 'f', 6, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
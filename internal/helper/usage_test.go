@@ -0,0 +1,79 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+func TestWriteUsageCSV(t *testing.T) {
+	var buf strings.Builder
+	err := WriteUsageCSV(&buf, []model.UsageRow{
+		{Date: "2026-09-01", APIKeyID: 1, APIKeyName: "team, a", Model: "gpt-4o", Requests: 3, FailedRequests: 1, InputTokens: 100, OutputTokens: 50, CacheReadTokens: 40, CacheWriteTokens: 7, Cost: 0.0123456789},
+		{Date: "2026-09-02", APIKeyID: 0, Model: "claude", Requests: 1, Cost: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 名称中的逗号按 CSV 规则加引号，费用保留 6 位小数
+	want := "date,api_key_id,api_key_name,model,requests,failed_requests,input_tokens,output_tokens,cache_read_tokens,cache_write_tokens,cost\n" +
+		"2026-09-01,1,\"team, a\",gpt-4o,3,1,100,50,40,7,0.012346\n" +
+		"2026-09-02,0,,claude,1,0,0,0,0,0,2.000000\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("csv =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteUsageCSVEmpty(t *testing.T) {
+	var buf strings.Builder
+	if err := WriteUsageCSV(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != strings.Join(usageCSVHeader, ",")+"\n" {
+		t.Fatalf("csv = %q, want header only", got)
+	}
+}
+
+func TestParseUsageRange(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.ParseInLocation(time.DateOnly, s, time.Local)
+		return d
+	}
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name       string
+		start, end string
+		wantStart  time.Time
+		wantEnd    time.Time
+		wantErr    bool
+	}{
+		{name: "default", wantStart: monthStart, wantEnd: tomorrow},
+		{name: "end inclusive", start: "2026-09-01", end: "2026-09-30", wantStart: day("2026-09-01"), wantEnd: day("2026-10-01")},
+		{name: "single day", start: "2026-09-15", end: "2026-09-15", wantStart: day("2026-09-15"), wantEnd: day("2026-09-16")},
+		{name: "invalid start", start: "2026/09/01", end: "2026-09-30", wantErr: true},
+		{name: "invalid end", start: "2026-09-01", end: "30", wantErr: true},
+		{name: "end before start", start: "2026-09-02", end: "2026-09-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := ParseUsageRange(tt.start, tt.end)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Fatalf("range = [%s, %s), want [%s, %s)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package model
+
+// TransactionType 余额流水类型
+type TransactionType string
+
+const (
+	TransactionTypeTopUp      TransactionType = "topup"      // 充值
+	TransactionTypeDebit      TransactionType = "debit"      // 请求扣费
+	TransactionTypeAdjustment TransactionType = "adjustment" // 人工调整，可正可负
+	TransactionTypeRefund     TransactionType = "refund"     // 退款，退回余额
+)
+
+func (t TransactionType) IsValid() bool {
+	switch t {
+	case TransactionTypeTopUp, TransactionTypeDebit, TransactionTypeAdjustment, TransactionTypeRefund:
+		return true
+	}
+	return false
+}
+
+// APIKeyTransaction 预付费 API Key 的余额流水，只追加不修改
+type APIKeyTransaction struct {
+	ID       int64           `json:"id" gorm:"primaryKey"`
+	APIKeyID int             `json:"api_key_id" gorm:"not null;index:idx_api_key_transaction,priority:1"`
+	Time     int64           `json:"time" gorm:"index:idx_api_key_transaction,priority:2"`
+	Type     TransactionType `json:"type" gorm:"size:16;not null"`
+	Amount   float64         `json:"amount"`  // 入账为正，扣减为负
+	Balance  float64         `json:"balance"` // 本条流水之后的余额
+	Model    string          `json:"model,omitempty"`
+	Note     string          `json:"note,omitempty"`
+}
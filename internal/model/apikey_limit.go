@@ -0,0 +1,132 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIKeyLimitMetric 限额的计量维度
+type APIKeyLimitMetric string
+
+const (
+	APIKeyLimitMetricCost     APIKeyLimitMetric = "cost"     // 费用 (美元)
+	APIKeyLimitMetricTokens   APIKeyLimitMetric = "tokens"   // 输入与输出 token 之和
+	APIKeyLimitMetricRequests APIKeyLimitMetric = "requests" // 请求次数
+)
+
+// APIKeyLimitWindow 限额窗口，按 UTC 自然周期对齐，窗口结束时用量清零
+type APIKeyLimitWindow string
+
+const (
+	APIKeyLimitWindowMinute APIKeyLimitWindow = "minute"
+	APIKeyLimitWindowHour   APIKeyLimitWindow = "hour"
+	APIKeyLimitWindowDay    APIKeyLimitWindow = "day"
+	APIKeyLimitWindowWeek   APIKeyLimitWindow = "week" // 周一开始
+	APIKeyLimitWindowMonth  APIKeyLimitWindow = "month"
+)
+
+var APIKeyLimitWindows = []APIKeyLimitWindow{APIKeyLimitWindowMinute, APIKeyLimitWindowHour, APIKeyLimitWindowDay, APIKeyLimitWindowWeek, APIKeyLimitWindowMonth}
+
+// APIKeyLimit API Key 的一条限额，如 {"metric":"cost","window":"day","max":5} 表示每天 5 美元
+type APIKeyLimit struct {
+	Metric APIKeyLimitMetric `json:"metric"`
+	Window APIKeyLimitWindow `json:"window"`
+	Max    float64           `json:"max"`
+}
+
+// APIKeyLimitUsage API Key 在当前窗口内的用量，每个维度与窗口的组合一条
+type APIKeyLimitUsage struct {
+	APIKeyID int               `json:"api_key_id" gorm:"primaryKey;autoIncrement:false"`
+	Metric   APIKeyLimitMetric `json:"metric" gorm:"primaryKey;size:16"`
+	Window   APIKeyLimitWindow `json:"window" gorm:"primaryKey;size:16;column:time_window"`
+	Start    int64             `json:"start"` // 窗口开始时间
+	Value    float64           `json:"value"`
+}
+
+// APIKeyLimitState 限额在当前窗口的状态
+type APIKeyLimitState struct {
+	APIKeyLimit
+	Used    float64 `json:"used"`
+	ResetAt int64   `json:"reset_at"`
+}
+
+func (s APIKeyLimitState) Remaining() float64 {
+	return max(s.Max-s.Used, 0)
+}
+
+func (s APIKeyLimitState) Exceeded() bool {
+	return s.Used >= s.Max
+}
+
+func (m APIKeyLimitMetric) IsValid() bool {
+	switch m {
+	case APIKeyLimitMetricCost, APIKeyLimitMetricTokens, APIKeyLimitMetricRequests:
+		return true
+	}
+	return false
+}
+
+func (w APIKeyLimitWindow) IsValid() bool {
+	switch w {
+	case APIKeyLimitWindowMinute, APIKeyLimitWindowHour, APIKeyLimitWindowDay, APIKeyLimitWindowWeek, APIKeyLimitWindowMonth:
+		return true
+	}
+	return false
+}
+
+// Start 返回 t 所在窗口的开始时间
+func (w APIKeyLimitWindow) Start(t time.Time) time.Time {
+	t = t.UTC()
+	switch w {
+	case APIKeyLimitWindowMinute:
+		return t.Truncate(time.Minute)
+	case APIKeyLimitWindowHour:
+		return t.Truncate(time.Hour)
+	case APIKeyLimitWindowDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case APIKeyLimitWindowWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// End 返回 t 所在窗口的结束时间，即下一次重置的时间
+func (w APIKeyLimitWindow) End(t time.Time) time.Time {
+	start := w.Start(t)
+	switch w {
+	case APIKeyLimitWindowMinute:
+		return start.Add(time.Minute)
+	case APIKeyLimitWindowHour:
+		return start.Add(time.Hour)
+	case APIKeyLimitWindowDay:
+		return start.AddDate(0, 0, 1)
+	case APIKeyLimitWindowWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// ValidateAPIKeyLimits 校验限额配置，同一维度与窗口只能配置一条
+func ValidateAPIKeyLimits(limits []APIKeyLimit) error {
+	seen := make(map[APIKeyLimit]struct{}, len(limits))
+	for _, limit := range limits {
+		if !limit.Metric.IsValid() {
+			return fmt.Errorf("invalid limit metric: %s", limit.Metric)
+		}
+		if !limit.Window.IsValid() {
+			return fmt.Errorf("invalid limit window: %s", limit.Window)
+		}
+		if limit.Max <= 0 {
+			return fmt.Errorf("limit max must be positive: %s/%s", limit.Metric, limit.Window)
+		}
+		key := APIKeyLimit{Metric: limit.Metric, Window: limit.Window}
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("duplicate limit: %s/%s", limit.Metric, limit.Window)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPIKeyLimitWindowBounds(t *testing.T) {
+	// 2026-10-21 为周三
+	now := time.Date(2026, 10, 21, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		window     APIKeyLimitWindow
+		start, end time.Time
+	}{
+		{APIKeyLimitWindowMinute, time.Date(2026, 10, 21, 15, 4, 0, 0, time.UTC), time.Date(2026, 10, 21, 15, 5, 0, 0, time.UTC)},
+		{APIKeyLimitWindowHour, time.Date(2026, 10, 21, 15, 0, 0, 0, time.UTC), time.Date(2026, 10, 21, 16, 0, 0, 0, time.UTC)},
+		{APIKeyLimitWindowDay, time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 22, 0, 0, 0, 0, time.UTC)},
+		{APIKeyLimitWindowWeek, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC)},
+		{APIKeyLimitWindowMonth, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := tt.window.Start(now); !got.Equal(tt.start) {
+			t.Errorf("%s start = %s, want %s", tt.window, got, tt.start)
+		}
+		if got := tt.window.End(now); !got.Equal(tt.end) {
+			t.Errorf("%s end = %s, want %s", tt.window, got, tt.end)
+		}
+	}
+
+	// 周日属于周一开始的那一周，非 UTC 时区按 UTC 对齐
+	sunday := time.Date(2026, 10, 25, 23, 0, 0, 0, time.UTC).In(time.FixedZone("UTC+8", 8*3600))
+	if got, want := APIKeyLimitWindowWeek.Start(sunday), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("sunday week start = %s, want %s", got, want)
+	}
+}
+
+func TestValidateAPIKeyLimits(t *testing.T) {
+	valid := []APIKeyLimit{
+		{Metric: APIKeyLimitMetricRequests, Window: APIKeyLimitWindowMinute, Max: 60},
+		{Metric: APIKeyLimitMetricRequests, Window: APIKeyLimitWindowDay, Max: 1000},
+		{Metric: APIKeyLimitMetricCost, Window: APIKeyLimitWindowMonth, Max: 20},
+	}
+	if err := ValidateAPIKeyLimits(valid); err != nil {
+		t.Fatalf("valid limits rejected: %v", err)
+	}
+	for _, limits := range [][]APIKeyLimit{
+		{{Metric: "bytes", Window: APIKeyLimitWindowDay, Max: 1}},
+		{{Metric: APIKeyLimitMetricCost, Window: "year", Max: 1}},
+		{{Metric: APIKeyLimitMetricCost, Window: APIKeyLimitWindowDay, Max: 0}},
+		{{Metric: APIKeyLimitMetricCost, Window: APIKeyLimitWindowDay, Max: 1}, {Metric: APIKeyLimitMetricCost, Window: APIKeyLimitWindowDay, Max: 2}},
+	} {
+		if err := ValidateAPIKeyLimits(limits); err == nil {
+			t.Errorf("invalid limits accepted: %+v", limits)
+		}
+	}
+}
@@ -13,17 +13,23 @@ type DBDump struct {
 	Channels    []Channel    `json:"channels,omitempty"`
 	ChannelKeys []ChannelKey `json:"channel_keys,omitempty"`
 	Groups      []Group      `json:"groups,omitempty"`
-	GroupItems []GroupItem `json:"group_items,omitempty"`
-	LLMInfos   []LLMInfo   `json:"llm_infos,omitempty"`
-	APIKeys    []APIKey    `json:"api_keys,omitempty"`
-	Settings   []Setting   `json:"settings,omitempty"`
+	GroupItems  []GroupItem  `json:"group_items,omitempty"`
+	LLMInfos    []LLMInfo    `json:"llm_infos,omitempty"`
+	APIKeys     []APIKey     `json:"api_keys,omitempty"`
+	Settings    []Setting    `json:"settings,omitempty"`
 
-	StatsTotal   []StatsTotal   `json:"stats_total,omitempty"`
-	StatsDaily   []StatsDaily   `json:"stats_daily,omitempty"`
-	StatsHourly  []StatsHourly  `json:"stats_hourly,omitempty"`
-	StatsModel   []StatsModel   `json:"stats_model,omitempty"`
-	StatsChannel []StatsChannel `json:"stats_channel,omitempty"`
-	StatsAPIKey  []StatsAPIKey  `json:"stats_api_key,omitempty"`
+	APIKeyTransactions []APIKeyTransaction `json:"api_key_transactions,omitempty"`
+	NotifyTargets      []NotifyTarget      `json:"notify_targets,omitempty"`
+	NotifyRules        []NotifyRule        `json:"notify_rules,omitempty"`
+
+	StatsTotal       []StatsTotal       `json:"stats_total,omitempty"`
+	StatsDaily       []StatsDaily       `json:"stats_daily,omitempty"`
+	StatsHourly      []StatsHourly      `json:"stats_hourly,omitempty"`
+	StatsModel       []StatsModel       `json:"stats_model,omitempty"`
+	StatsModelHourly []StatsModelHourly `json:"stats_model_hourly,omitempty"`
+	StatsAPIKeyModel []StatsAPIKeyModel `json:"stats_api_key_model,omitempty"`
+	StatsChannel     []StatsChannel     `json:"stats_channel,omitempty"`
+	StatsAPIKey      []StatsAPIKey      `json:"stats_api_key,omitempty"`
 
 	RelayLogs []RelayLog `json:"relay_logs,omitempty"`
 }
@@ -0,0 +1,113 @@
+package model
+
+import "encoding/json"
+
+type BatchStatus string
+
+const (
+	BatchStatusValidating BatchStatus = "validating"
+	BatchStatusFailed     BatchStatus = "failed"
+	BatchStatusInProgress BatchStatus = "in_progress"
+	BatchStatusFinalizing BatchStatus = "finalizing"
+	BatchStatusCompleted  BatchStatus = "completed"
+	BatchStatusExpired    BatchStatus = "expired"
+	BatchStatusCancelling BatchStatus = "cancelling"
+	BatchStatusCancelled  BatchStatus = "cancelled"
+)
+
+const (
+	FilePurposeBatch       = "batch"
+	FilePurposeBatchOutput = "batch_output"
+)
+
+// File 通过 /v1/files 上传或由批处理生成的文件，内容直接存储在数据库中
+type File struct {
+	ID        string `json:"id" gorm:"primaryKey;size:64"`
+	APIKeyID  int    `json:"-" gorm:"index"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Bytes     int64  `json:"bytes"`
+	Content   []byte `json:"-"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func (f File) MarshalJSON() ([]byte, error) {
+	type Alias File
+	return json.Marshal(struct {
+		Object string `json:"object"`
+		Alias
+	}{
+		Object: "file",
+		Alias:  Alias(f),
+	})
+}
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Line    *int   `json:"line,omitempty"`
+}
+
+type BatchErrors struct {
+	Object string       `json:"object"`
+	Data   []BatchError `json:"data"`
+}
+
+// Batch OpenAI 兼容的批处理任务，由 Octopus 逐行通过中继执行
+type Batch struct {
+	ID               string             `json:"id" gorm:"primaryKey;size:64"`
+	APIKeyID         int                `json:"-" gorm:"index"`
+	Endpoint         string             `json:"endpoint"`
+	InputFileID      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           BatchStatus        `json:"status" gorm:"index"`
+	OutputFileID     *string            `json:"output_file_id"`
+	ErrorFileID      *string            `json:"error_file_id"`
+	Errors           *BatchErrors       `json:"errors" gorm:"serializer:json"`
+	RequestCounts    BatchRequestCounts `json:"request_counts" gorm:"embedded;embeddedPrefix:request_"`
+	Metadata         map[string]string  `json:"metadata" gorm:"serializer:json"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     *int64             `json:"in_progress_at"`
+	ExpiresAt        *int64             `json:"expires_at"`
+	FinalizingAt     *int64             `json:"finalizing_at"`
+	CompletedAt      *int64             `json:"completed_at"`
+	FailedAt         *int64             `json:"failed_at"`
+	ExpiredAt        *int64             `json:"expired_at"`
+	CancellingAt     *int64             `json:"cancelling_at"`
+	CancelledAt      *int64             `json:"cancelled_at"`
+}
+
+func (b Batch) MarshalJSON() ([]byte, error) {
+	type Alias Batch
+	return json.Marshal(struct {
+		Object string `json:"object"`
+		Alias
+	}{
+		Object: "batch",
+		Alias:  Alias(b),
+	})
+}
+
+// IsFinished 判断批处理是否已处于终态
+func (b *Batch) IsFinished() bool {
+	switch b.Status {
+	case BatchStatusFailed, BatchStatusCompleted, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// BatchResult 批处理中单行请求的执行结果，用于服务重启后继续执行，生成结果文件后删除
+type BatchResult struct {
+	ID      int64  `gorm:"primaryKey"`
+	BatchID string `gorm:"uniqueIndex:idx_batch_result_line;size:64"`
+	Line    int    `gorm:"uniqueIndex:idx_batch_result_line"`
+	Success bool
+	Output  string // 写入 output / error 文件的 JSONL 行
+}
@@ -1,6 +1,9 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/bestruirui/octopus/internal/transformer/outbound"
@@ -15,23 +18,127 @@ const (
 	AutoGroupTypeRegex AutoGroupType = 3 //正则匹配
 )
 
+// MediaMode 远程图片/文件的处理方式
+type MediaMode string
+
+const (
+	MediaModeAuto   MediaMode = ""       // 上游只接受内联数据时抓取并内联
+	MediaModeInline MediaMode = "inline" // 始终抓取并内联
+	MediaModeURL    MediaMode = "url"    // 始终透传地址
+)
+
+// IsValid 判断媒体处理方式是否合法
+func (m MediaMode) IsValid() bool {
+	switch m {
+	case MediaModeAuto, MediaModeInline, MediaModeURL:
+		return true
+	}
+	return false
+}
+
+// StreamMode 上游请求的流式模式
+type StreamMode string
+
+const (
+	StreamModeAuto      StreamMode = ""           // 跟随客户端请求
+	StreamModeStream    StreamMode = "stream"     // 始终以流式请求上游
+	StreamModeNonStream StreamMode = "non_stream" // 始终以非流式请求上游
+)
+
+// IsValid 判断流式模式是否合法
+func (m StreamMode) IsValid() bool {
+	switch m {
+	case StreamModeAuto, StreamModeStream, StreamModeNonStream:
+		return true
+	}
+	return false
+}
+
+// PromptCacheMode Anthropic 渠道自动插入 cache_control 缓存断点的策略
+type PromptCacheMode string
+
+const (
+	PromptCacheOff    PromptCacheMode = ""        // 只保留客户端设置的断点
+	PromptCacheAuto   PromptCacheMode = "auto"    // 自动插入断点，缓存 5 分钟
+	PromptCacheAuto1h PromptCacheMode = "auto_1h" // 自动插入断点，缓存 1 小时
+)
+
+// IsValid 判断缓存策略是否合法
+func (m PromptCacheMode) IsValid() bool {
+	switch m {
+	case PromptCacheOff, PromptCacheAuto, PromptCacheAuto1h:
+		return true
+	}
+	return false
+}
+
 type Channel struct {
-	ID            int                   `json:"id" gorm:"primaryKey"`
-	Name          string                `json:"name" gorm:"unique;not null"`
-	Type          outbound.OutboundType `json:"type"`
-	Enabled       bool                  `json:"enabled" gorm:"default:true"`
-	BaseUrls      []BaseUrl             `json:"base_urls" gorm:"serializer:json"`
-	Keys          []ChannelKey          `json:"keys" gorm:"foreignKey:ChannelID"`
-	Model         string                `json:"model"`
-	CustomModel   string                `json:"custom_model"`
-	Proxy         bool                  `json:"proxy" gorm:"default:false"`
-	AutoSync      bool                  `json:"auto_sync" gorm:"default:false"`
-	AutoGroup     AutoGroupType         `json:"auto_group" gorm:"default:0"`
-	CustomHeader  []CustomHeader        `json:"custom_header" gorm:"serializer:json"`
-	ParamOverride *string               `json:"param_override"`
-	ChannelProxy  *string               `json:"channel_proxy"`
-	Stats         *StatsChannel         `json:"stats,omitempty" gorm:"foreignKey:ChannelID"`
-	MatchRegex    *string               `json:"match_regex"`
+	ID             int                   `json:"id" gorm:"primaryKey"`
+	Name           string                `json:"name" gorm:"unique;not null"`
+	Type           outbound.OutboundType `json:"type"`
+	Enabled        bool                  `json:"enabled" gorm:"default:true"`
+	BaseUrls       []BaseUrl             `json:"base_urls" gorm:"serializer:json"`
+	Keys           []ChannelKey          `json:"keys" gorm:"foreignKey:ChannelID"`
+	Model          string                `json:"model"`
+	CustomModel    string                `json:"custom_model"`
+	Proxy          bool                  `json:"proxy" gorm:"default:false"`
+	AutoSync       bool                  `json:"auto_sync" gorm:"default:false"`
+	AutoGroup      AutoGroupType         `json:"auto_group" gorm:"default:0"`
+	CustomHeader   []CustomHeader        `json:"custom_header" gorm:"serializer:json"`
+	ParamOverride  *string               `json:"param_override"`
+	ChannelProxy   *string               `json:"channel_proxy"`
+	Stats          *StatsChannel         `json:"stats,omitempty" gorm:"foreignKey:ChannelID"`
+	MatchRegex     *string               `json:"match_regex"`
+	ModelMapping   *string               `json:"model_mapping"`                         // 模型映射 JSON，如 Azure 部署名 {"gpt-4o":"my-gpt4o"}
+	PromptToolCall bool                  `json:"prompt_tool_call" gorm:"default:false"` // 上游不支持原生工具调用时，通过提示词模拟函数调用
+	MediaMode      MediaMode             `json:"media_mode"`                            // 远程图片/文件处理方式
+	StreamMode     StreamMode            `json:"stream_mode"`                           // 上游请求的流式模式
+	PromptCache    PromptCacheMode       `json:"prompt_cache"`                          // 自动缓存断点策略，仅 Anthropic 渠道生效
+	// PromptCacheMinTokens 前缀达到该 token 数才放置断点，0 表示使用默认值
+	PromptCacheMinTokens int `json:"prompt_cache_min_tokens"`
+	// PriceMultiplier 通过该渠道调用的实际成本相对模型价格的倍率，为空时为 1
+	PriceMultiplier *float64 `json:"price_multiplier"`
+	// PriceOverrides 按模型指定该渠道的实际价格，优先于倍率
+	PriceOverrides []ChannelPriceOverride `json:"price_overrides" gorm:"serializer:json"`
+}
+
+// ChannelPriceOverride 渠道上某个模型的实际价格，Model 不区分大小写
+type ChannelPriceOverride struct {
+	Model string `json:"model"`
+	LLMPrice
+}
+
+// PriceOverride 返回该渠道为模型指定的实际价格，未指定时返回 nil
+func (c *Channel) PriceOverride(modelName string) *LLMPrice {
+	for _, override := range c.PriceOverrides {
+		if strings.EqualFold(override.Model, modelName) {
+			price := override.LLMPrice
+			return &price
+		}
+	}
+	return nil
+}
+
+// ValidateChannelPricing 校验价格倍率和模型价格覆盖
+func ValidateChannelPricing(multiplier *float64, overrides []ChannelPriceOverride) error {
+	if multiplier != nil && *multiplier < 0 {
+		return fmt.Errorf("price multiplier must not be negative")
+	}
+	seen := make(map[string]struct{}, len(overrides))
+	for _, override := range overrides {
+		name := strings.ToLower(strings.TrimSpace(override.Model))
+		if name == "" {
+			return fmt.Errorf("price override model must not be empty")
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate price override: %s", override.Model)
+		}
+		seen[name] = struct{}{}
+		if err := override.Validate(); err != nil {
+			return fmt.Errorf("invalid price override %s: %w", override.Model, err)
+		}
+	}
+	return nil
 }
 
 type BaseUrl struct {
@@ -57,20 +164,29 @@ type ChannelKey struct {
 
 // ChannelUpdateRequest 渠道更新请求 - 仅包含变更的数据
 type ChannelUpdateRequest struct {
-	ID            int                    `json:"id" binding:"required"`
-	Name          *string                `json:"name,omitempty"`
-	Type          *outbound.OutboundType `json:"type,omitempty"`
-	Enabled       *bool                  `json:"enabled,omitempty"`
-	BaseUrls      *[]BaseUrl             `json:"base_urls,omitempty"`
-	Model         *string                `json:"model,omitempty"`
-	CustomModel   *string                `json:"custom_model,omitempty"`
-	Proxy         *bool                  `json:"proxy,omitempty"`
-	AutoSync      *bool                  `json:"auto_sync,omitempty"`
-	AutoGroup     *AutoGroupType         `json:"auto_group,omitempty"`
-	CustomHeader  *[]CustomHeader        `json:"custom_header,omitempty"`
-	ChannelProxy  *string                `json:"channel_proxy,omitempty"`
-	ParamOverride *string                `json:"param_override,omitempty"`
-	MatchRegex    *string                `json:"match_regex,omitempty"`
+	ID             int                    `json:"id" binding:"required"`
+	Name           *string                `json:"name,omitempty"`
+	Type           *outbound.OutboundType `json:"type,omitempty"`
+	Enabled        *bool                  `json:"enabled,omitempty"`
+	BaseUrls       *[]BaseUrl             `json:"base_urls,omitempty"`
+	Model          *string                `json:"model,omitempty"`
+	CustomModel    *string                `json:"custom_model,omitempty"`
+	Proxy          *bool                  `json:"proxy,omitempty"`
+	AutoSync       *bool                  `json:"auto_sync,omitempty"`
+	AutoGroup      *AutoGroupType         `json:"auto_group,omitempty"`
+	CustomHeader   *[]CustomHeader        `json:"custom_header,omitempty"`
+	ChannelProxy   *string                `json:"channel_proxy,omitempty"`
+	ParamOverride  *string                `json:"param_override,omitempty"`
+	MatchRegex     *string                `json:"match_regex,omitempty"`
+	ModelMapping   *string                `json:"model_mapping,omitempty"`
+	PromptToolCall *bool                  `json:"prompt_tool_call,omitempty"`
+	MediaMode      *MediaMode             `json:"media_mode,omitempty"`
+	StreamMode     *StreamMode            `json:"stream_mode,omitempty"`
+	PromptCache    *PromptCacheMode       `json:"prompt_cache,omitempty"`
+
+	PromptCacheMinTokens *int                    `json:"prompt_cache_min_tokens,omitempty"`
+	PriceMultiplier      *float64                `json:"price_multiplier,omitempty"`
+	PriceOverrides       *[]ChannelPriceOverride `json:"price_overrides,omitempty"`
 
 	KeysToAdd    []ChannelKeyAddRequest    `json:"keys_to_add,omitempty"`
 	KeysToUpdate []ChannelKeyUpdateRequest `json:"keys_to_update,omitempty"`
@@ -90,6 +206,39 @@ type ChannelKeyUpdateRequest struct {
 	Remark     *string `json:"remark,omitempty"`
 }
 
+// NeedsInlineMedia 判断发往该渠道的请求是否需要将远程图片/文件转为内联数据
+func (c *Channel) NeedsInlineMedia() bool {
+	switch c.MediaMode {
+	case MediaModeInline:
+		return true
+	case MediaModeURL:
+		return false
+	}
+	return outbound.NeedsInlineMedia(c.Type)
+}
+
+// UpstreamStream 根据渠道的流式模式决定上游请求是否使用流式
+func (c *Channel) UpstreamStream(clientStream bool) bool {
+	switch c.StreamMode {
+	case StreamModeStream:
+		return true
+	case StreamModeNonStream:
+		return false
+	}
+	return clientStream
+}
+
+// PromptCacheTTL 返回自动插入断点使用的缓存时长，空字符串为上游默认的 5 分钟，未启用时返回 false
+func (c *Channel) PromptCacheTTL() (string, bool) {
+	switch c.PromptCache {
+	case PromptCacheAuto:
+		return "", true
+	case PromptCacheAuto1h:
+		return "1h", true
+	}
+	return "", false
+}
+
 // ChannelFetchModelRequest is used by /channel/fetch-model (not persisted).
 type ChannelFetchModelRequest struct {
 	Type    outbound.OutboundType `json:"type" binding:"required"`
@@ -153,3 +302,30 @@ func (c *Channel) GetChannelKey() ChannelKey {
 	}
 	return best
 }
+
+// ParseModelMapping 解析模型映射配置，空值返回 nil
+func ParseModelMapping(raw *string) (map[string]string, error) {
+	if raw == nil || strings.TrimSpace(*raw) == "" {
+		return nil, nil
+	}
+	var mapping map[string]string
+	if err := json.Unmarshal([]byte(*raw), &mapping); err != nil {
+		return nil, fmt.Errorf("invalid model mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// GetMappedModel 返回模型在该渠道上游的实际名称（如 Azure 部署名），未配置映射时原样返回
+func (c *Channel) GetMappedModel(modelName string) string {
+	if c == nil {
+		return modelName
+	}
+	mapping, err := ParseModelMapping(c.ModelMapping)
+	if err != nil {
+		return modelName
+	}
+	if mapped, ok := mapping[modelName]; ok && mapped != "" {
+		return mapped
+	}
+	return modelName
+}
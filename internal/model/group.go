@@ -9,13 +9,37 @@ const (
 	GroupModeWeighted   GroupMode = 4 // 加权分配：按优权重分配流量
 )
 
+// StructuredOutputMode 结构化输出 (response_format json_schema/json_object) 的处理方式
+type StructuredOutputMode string
+
+const (
+	StructuredOutputOff    StructuredOutputMode = ""       // 不处理，直接透传
+	StructuredOutputNative StructuredOutputMode = "native" // 使用上游原生 response_format，仅校验输出
+	StructuredOutputPrompt StructuredOutputMode = "prompt" // 将 schema 写入系统提示词模拟
+	StructuredOutputTool   StructuredOutputMode = "tool"   // 强制调用以 schema 为参数的工具模拟
+)
+
+// MaxStructuredOutputRetries 结构化输出校验失败后的最大重试次数
+const MaxStructuredOutputRetries = 3
+
+func (m StructuredOutputMode) IsValid() bool {
+	switch m {
+	case StructuredOutputOff, StructuredOutputNative, StructuredOutputPrompt, StructuredOutputTool:
+		return true
+	}
+	return false
+}
+
 type Group struct {
-	ID                int         `json:"id" gorm:"primaryKey"`
-	Name              string      `json:"name" gorm:"unique;not null"`
-	Mode              GroupMode   `json:"mode" gorm:"not null"`
-	MatchRegex        string      `json:"match_regex"`
-	FirstTokenTimeOut int         `json:"first_token_time_out"` // 单个渠道首个Token响应超时时间(秒)
-	Items             []GroupItem `json:"items,omitempty" gorm:"foreignKey:GroupID"`
+	ID                      int                  `json:"id" gorm:"primaryKey"`
+	Name                    string               `json:"name" gorm:"unique;not null"`
+	Mode                    GroupMode            `json:"mode" gorm:"not null"`
+	MatchRegex              string               `json:"match_regex"`
+	FirstTokenTimeOut       int                  `json:"first_token_time_out"`      // 单个渠道首个Token响应超时时间(秒)
+	StructuredOutput        StructuredOutputMode `json:"structured_output"`         // 结构化输出处理方式
+	StructuredOutputRetries int                  `json:"structured_output_retries"` // 结构化输出校验失败后的重试次数
+	ReasoningPolicy         ReasoningPolicy      `json:"reasoning_policy"`          // 推理内容处理方式
+	Items                   []GroupItem          `json:"items,omitempty" gorm:"foreignKey:GroupID"`
 }
 
 type GroupItem struct {
@@ -29,14 +53,17 @@ type GroupItem struct {
 
 // GroupUpdateRequest 分组更新请求 - 仅包含变更的数据
 type GroupUpdateRequest struct {
-	ID                int                      `json:"id" binding:"required"`
-	Name              *string                  `json:"name,omitempty"`                 // 仅在名称变更时发送
-	Mode              *GroupMode               `json:"mode,omitempty"`                 // 仅在模式变更时发送
-	MatchRegex        *string                  `json:"match_regex,omitempty"`          // 仅在匹配正则变更时发送
-	FirstTokenTimeOut *int                     `json:"first_token_time_out,omitempty"` // 仅在超时变更时发送(秒)
-	ItemsToAdd        []GroupItemAddRequest    `json:"items_to_add,omitempty"`         // 新增的 items
-	ItemsToUpdate     []GroupItemUpdateRequest `json:"items_to_update,omitempty"`      // 更新的 items (priority 变更)
-	ItemsToDelete     []int                    `json:"items_to_delete,omitempty"`      // 删除的 item IDs
+	ID                      int                      `json:"id" binding:"required"`
+	Name                    *string                  `json:"name,omitempty"`                      // 仅在名称变更时发送
+	Mode                    *GroupMode               `json:"mode,omitempty"`                      // 仅在模式变更时发送
+	MatchRegex              *string                  `json:"match_regex,omitempty"`               // 仅在匹配正则变更时发送
+	FirstTokenTimeOut       *int                     `json:"first_token_time_out,omitempty"`      // 仅在超时变更时发送(秒)
+	StructuredOutput        *StructuredOutputMode    `json:"structured_output,omitempty"`         // 仅在结构化输出方式变更时发送
+	StructuredOutputRetries *int                     `json:"structured_output_retries,omitempty"` // 仅在重试次数变更时发送
+	ReasoningPolicy         *ReasoningPolicy         `json:"reasoning_policy,omitempty"`          // 仅在推理处理方式变更时发送
+	ItemsToAdd              []GroupItemAddRequest    `json:"items_to_add,omitempty"`              // 新增的 items
+	ItemsToUpdate           []GroupItemUpdateRequest `json:"items_to_update,omitempty"`           // 更新的 items (priority 变更)
+	ItemsToDelete           []int                    `json:"items_to_delete,omitempty"`           // 删除的 item IDs
 }
 
 // GroupItemAddRequest 新增 item 请求
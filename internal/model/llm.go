@@ -1,12 +1,118 @@
 package model
 
+import "fmt"
+
+// LLMPrice 模型价格，按 token 计费时单位为美元每百万 token
+// 细分价格为 0 时按对应的输入或输出价格计算
 type LLMPrice struct {
-	Type       string  `json:"type" gorm:"default:'token'"`
+	Type         string         `json:"type" gorm:"default:'token'"`
+	Input        float64        `json:"input"`
+	Output       float64        `json:"output"`
+	CacheRead    float64        `json:"cache_read"`
+	CacheWrite   float64        `json:"cache_write"`
+	CacheWrite1h float64        `json:"cache_write_1h,omitempty"` // 1 小时缓存写入，为 0 时按 CacheWrite 的 1.6 倍
+	Reasoning    float64        `json:"reasoning,omitempty"`      // 推理输出
+	InputAudio   float64        `json:"input_audio,omitempty"`
+	OutputAudio  float64        `json:"output_audio,omitempty"`
+	InputImage   float64        `json:"input_image,omitempty"`
+	OutputImage  float64        `json:"output_image,omitempty"`
+	Request      float64        `json:"request"`
+	ContextTiers []LLMPriceTier `json:"context_tiers,omitempty" gorm:"serializer:json"` // 按上下文长度分档的价格
+}
+
+// LLMPriceTier 上下文超过 Threshold 个 token 时使用的价格，为 0 的字段沿用基础价格
+type LLMPriceTier struct {
+	Threshold  int64   `json:"threshold"`
 	Input      float64 `json:"input"`
 	Output     float64 `json:"output"`
 	CacheRead  float64 `json:"cache_read"`
 	CacheWrite float64 `json:"cache_write"`
-	Request    float64 `json:"request"`
+}
+
+// ForContext 返回上下文为 contextTokens 个 token 时适用的价格，命中分档时覆盖基础价格
+// 细分价格未设置时随分档后的输入、输出价格变化
+func (p LLMPrice) ForContext(contextTokens int64) LLMPrice {
+	var tier *LLMPriceTier
+	for i := range p.ContextTiers {
+		if contextTokens > p.ContextTiers[i].Threshold && (tier == nil || p.ContextTiers[i].Threshold > tier.Threshold) {
+			tier = &p.ContextTiers[i]
+		}
+	}
+	if tier == nil {
+		return p
+	}
+	result := p
+	if tier.Input > 0 {
+		result.Input = tier.Input
+	}
+	if tier.Output > 0 {
+		result.Output = tier.Output
+	}
+	if tier.CacheRead > 0 {
+		result.CacheRead = tier.CacheRead
+	}
+	if tier.CacheWrite > 0 {
+		result.CacheWrite = tier.CacheWrite
+	}
+	result.ContextTiers = nil
+	return result
+}
+
+// Validate 校验价格类型，价格和分档阈值不能为负数
+func (p LLMPrice) Validate() error {
+	if p.Type != "" && p.Type != "token" && p.Type != "request" {
+		return fmt.Errorf("invalid price type: %s", p.Type)
+	}
+	for _, v := range []float64{p.Input, p.Output, p.CacheRead, p.CacheWrite, p.CacheWrite1h, p.Reasoning,
+		p.InputAudio, p.OutputAudio, p.InputImage, p.OutputImage, p.Request} {
+		if v < 0 {
+			return fmt.Errorf("price must not be negative")
+		}
+	}
+	for _, tier := range p.ContextTiers {
+		if tier.Threshold <= 0 {
+			return fmt.Errorf("context tier threshold must be positive")
+		}
+		if tier.Input < 0 || tier.Output < 0 || tier.CacheRead < 0 || tier.CacheWrite < 0 {
+			return fmt.Errorf("price must not be negative")
+		}
+	}
+	return nil
+}
+
+// CacheWrite1hRate 1 小时缓存写入价格，Anthropic 1 小时写入为输入价的 2 倍，5 分钟为 1.25 倍
+func (p LLMPrice) CacheWrite1hRate() float64 {
+	if p.CacheWrite1h > 0 {
+		return p.CacheWrite1h
+	}
+	return p.CacheWrite * 1.6
+}
+
+func (p LLMPrice) ReasoningRate() float64 {
+	return rateOr(p.Reasoning, p.Output)
+}
+
+func (p LLMPrice) InputAudioRate() float64 {
+	return rateOr(p.InputAudio, p.Input)
+}
+
+func (p LLMPrice) OutputAudioRate() float64 {
+	return rateOr(p.OutputAudio, p.Output)
+}
+
+func (p LLMPrice) InputImageRate() float64 {
+	return rateOr(p.InputImage, p.Input)
+}
+
+func (p LLMPrice) OutputImageRate() float64 {
+	return rateOr(p.OutputImage, p.Output)
+}
+
+func rateOr(rate, fallback float64) float64 {
+	if rate > 0 {
+		return rate
+	}
+	return fallback
 }
 
 type LLMInfo struct {
@@ -56,3 +162,12 @@ type AnthropicModelList struct {
 	HasMore bool             `json:"has_more"`
 	LastID  string           `json:"last_id"`
 }
+
+type OllamaModel struct {
+	Name  string `json:"name"`
+	Model string `json:"model"`
+}
+
+type OllamaModelList struct {
+	Models []OllamaModel `json:"models"`
+}
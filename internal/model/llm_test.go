@@ -0,0 +1,53 @@
+package model
+
+import "testing"
+
+func TestLLMPriceForContext(t *testing.T) {
+	price := LLMPrice{
+		Input: 1, Output: 2, CacheRead: 0.1, CacheWrite: 1.25,
+		// 分档无序，取超过的最高阈值
+		ContextTiers: []LLMPriceTier{
+			{Threshold: 500000, Input: 4},
+			{Threshold: 200000, Input: 2, Output: 4, CacheRead: 0.2},
+		},
+	}
+	tests := []struct {
+		name                             string
+		contextTokens                    int64
+		input, output, cacheRead, cacheW float64
+	}{
+		{"below the first tier", 100000, 1, 2, 0.1, 1.25},
+		{"at the threshold", 200000, 1, 2, 0.1, 1.25},
+		{"above the threshold", 200001, 2, 4, 0.2, 1.25},
+		{"highest tier, unset fields keep base price", 600000, 4, 2, 0.1, 1.25},
+	}
+	for _, tt := range tests {
+		got := price.ForContext(tt.contextTokens)
+		if got.Input != tt.input || got.Output != tt.output || got.CacheRead != tt.cacheRead || got.CacheWrite != tt.cacheW {
+			t.Errorf("%s: got %+v", tt.name, got)
+		}
+	}
+	if got := price.ForContext(300000); got.ContextTiers != nil || got.ReasoningRate() != 4 || got.OutputAudioRate() != 4 {
+		t.Errorf("tier price should drop tiers and derive unset rates from the tier output: %+v", got)
+	}
+	if got := price.ForContext(0); len(got.ContextTiers) != 2 {
+		t.Error("base price should keep its tiers")
+	}
+}
+
+func TestLLMPriceRates(t *testing.T) {
+	base := LLMPrice{Input: 1, Output: 2, CacheWrite: 1.25}
+	if got := base.CacheWrite1hRate(); got != 2 {
+		t.Errorf("default 1h cache write rate = %v, want 2", got)
+	}
+	if got := base.ReasoningRate(); got != 2 {
+		t.Errorf("default reasoning rate = %v, want 2", got)
+	}
+	if got := base.InputAudioRate(); got != 1 {
+		t.Errorf("default input audio rate = %v, want 1", got)
+	}
+	explicit := LLMPrice{Input: 1, Output: 2, CacheWrite: 1.25, CacheWrite1h: 3, Reasoning: 5, InputImage: 7}
+	if explicit.CacheWrite1hRate() != 3 || explicit.ReasoningRate() != 5 || explicit.InputImageRate() != 7 {
+		t.Errorf("explicit rates not used: %+v", explicit)
+	}
+}
@@ -12,22 +12,37 @@ type ChannelAttempt struct {
 	Duration    int    `json:"duration"` // 耗时(毫秒)
 }
 
+// UsageSource 日志中用量的计算方式
+type UsageSource string
+
+const (
+	UsageSourceUpstream  UsageSource = "upstream"  // 上游返回的用量
+	UsageSourceDrained   UsageSource = "drained"   // 客户端断开后继续读取上游得到的用量
+	UsageSourceEstimated UsageSource = "estimated" // 按已传输的内容本地估算
+)
+
 type RelayLog struct {
-	ID               int64             `json:"id" gorm:"primaryKey;autoIncrement:false"` // Snowflake ID
-	Time             int64             `json:"time"`                                     // 时间戳（秒）
-	RequestModelName string            `json:"request_model_name"`                       // 请求模型名称
-	ChannelId        int               `json:"channel"`                                  // 实际使用的渠道ID
-	ChannelName      string            `json:"channel_name"`                             // 渠道名称
-	ActualModelName  string            `json:"actual_model_name"`                        // 实际使用模型名称
-	InputTokens      int               `json:"input_tokens"`                             // 输入Token
-	OutputTokens     int               `json:"output_tokens"`                            // 输出 Token
-	Ftut             int               `json:"ftut"`                                     // 首字时间(毫秒)
-	UseTime          int               `json:"use_time"`                                 // 总用时(毫秒)
-	Cost             float64           `json:"cost"`                                     // 消耗费用
-	RequestContent   string            `json:"request_content"`                          // 请求内容
-	ResponseContent  string            `json:"response_content"`                         // 响应内容
-	Error            string            `json:"error"`                                    // 错误信息
-	Attempts         []ChannelAttempt  `json:"attempts" gorm:"serializer:json"`          // 所有尝试记录
-	TotalAttempts    int               `json:"total_attempts"`                           // 总尝试次数
-	SuccessfulRound  int               `json:"successful_round"`                         // 成功的轮次
+	ID               int64            `json:"id" gorm:"primaryKey;autoIncrement:false"`                      // Snowflake ID
+	Time             int64            `json:"time" gorm:"index;index:idx_relay_log_api_key_time,priority:2"` // 时间戳（秒）
+	APIKeyID         int              `json:"api_key_id" gorm:"index:idx_relay_log_api_key_time,priority:1"` // 发起请求的 API Key
+	RequestModelName string           `json:"request_model_name"`                                            // 请求模型名称
+	ChannelId        int              `json:"channel"`                                                       // 实际使用的渠道ID
+	ChannelName      string           `json:"channel_name"`                                                  // 渠道名称
+	ActualModelName  string           `json:"actual_model_name"`                                             // 实际使用模型名称
+	InputTokens      int              `json:"input_tokens"`                                                  // 输入Token
+	OutputTokens     int              `json:"output_tokens"`                                                 // 输出 Token
+	CacheReadTokens  int              `json:"cache_read_tokens"`                                             // 缓存命中 Token
+	CacheWriteTokens int              `json:"cache_write_tokens"`                                            // 缓存写入 Token
+	Ftut             int              `json:"ftut"`                                                          // 首字时间(毫秒)
+	UseTime          int              `json:"use_time"`                                                      // 总用时(毫秒)
+	Cost             float64          `json:"cost"`                                                          // 消耗费用
+	UpstreamCost     float64          `json:"upstream_cost"`                                                 // 按渠道实际价格计算的上游费用
+	RequestContent   string           `json:"request_content"`                                               // 请求内容
+	ResponseContent  string           `json:"response_content"`                                              // 响应内容
+	Error            string           `json:"error"`                                                         // 错误信息
+	Attempts         []ChannelAttempt `json:"attempts" gorm:"serializer:json"`                               // 所有尝试记录
+	TotalAttempts    int              `json:"total_attempts"`                                                // 总尝试次数
+	SuccessfulRound  int              `json:"successful_round"`                                              // 成功的轮次
+	StructuredOutput string           `json:"structured_output,omitempty"`                                   // 结构化输出校验结果
+	UsageSource      UsageSource      `json:"usage_source,omitempty"`                                        // 用量的计算方式
 }
@@ -0,0 +1,189 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// NotifyTargetType 通知渠道类型
+type NotifyTargetType string
+
+const (
+	NotifyTargetWebhook NotifyTargetType = "webhook" // POST 固定格式的事件 JSON
+	NotifyTargetJSON    NotifyTargetType = "json"    // POST 按模板渲染的 JSON，适配 Slack、飞书、钉钉等机器人
+	NotifyTargetSMTP    NotifyTargetType = "smtp"    // 邮件
+)
+
+// NotifyEvent 通知事件类型
+type NotifyEvent string
+
+const (
+	NotifyEventAPIKeyBudget       NotifyEvent = "apikey_budget"        // API Key 费用达到 MaxCost 的百分比阈值
+	NotifyEventDailySpend         NotifyEvent = "daily_spend"          // 当日总费用超过阈值（美元）
+	NotifyEventChannelKeyError    NotifyEvent = "channel_key_error"    // 渠道 Key 返回 401 或因 429 被暂停使用
+	NotifyEventChannelSuccessRate NotifyEvent = "channel_success_rate" // 渠道在窗口内的成功率低于阈值（百分比）
+	NotifyEventModelSyncRemoved   NotifyEvent = "model_sync_removed"   // 模型同步时上游删除了模型
+	NotifyEventTest               NotifyEvent = "test"                 // 手动发送的测试通知
+)
+
+func (e NotifyEvent) IsValid() bool {
+	switch e {
+	case NotifyEventAPIKeyBudget, NotifyEventDailySpend, NotifyEventChannelKeyError,
+		NotifyEventChannelSuccessRate, NotifyEventModelSyncRemoved:
+		return true
+	}
+	return false
+}
+
+// NotifySMTP 邮件服务器配置，端口 465 使用 TLS 直连，其余端口在服务器支持时使用 STARTTLS
+type NotifySMTP struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// NotifyTarget 通知渠道
+type NotifyTarget struct {
+	ID      int               `json:"id" gorm:"primaryKey"`
+	Name    string            `json:"name" gorm:"not null"`
+	Type    NotifyTargetType  `json:"type" gorm:"size:16;not null"`
+	Enabled bool              `json:"enabled" gorm:"default:true"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty" gorm:"serializer:json"`
+	// Template 仅 json 类型使用，{{title}}、{{message}}、{{event}}、{{time}} 会被替换为 JSON 转义后的内容
+	Template string      `json:"template,omitempty"`
+	SMTP     *NotifySMTP `json:"smtp,omitempty" gorm:"serializer:json"`
+}
+
+// NotifyTemplateFields 通知模板中可用的占位符
+var NotifyTemplateFields = []string{"title", "message", "event", "time"}
+
+// Validate 校验通知渠道配置
+func (t *NotifyTarget) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch t.Type {
+	case NotifyTargetWebhook, NotifyTargetJSON:
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid url: %s", t.URL)
+		}
+		if t.Type == NotifyTargetJSON {
+			if strings.TrimSpace(t.Template) == "" {
+				return fmt.Errorf("template is required")
+			}
+			// 用空字符串替换占位符后必须是合法的 JSON
+			rendered := t.Template
+			for _, field := range NotifyTemplateFields {
+				rendered = strings.ReplaceAll(rendered, "{{"+field+"}}", "")
+			}
+			if !json.Valid([]byte(rendered)) {
+				return fmt.Errorf("template is not valid json")
+			}
+		}
+	case NotifyTargetSMTP:
+		if t.SMTP == nil || t.SMTP.Host == "" || t.SMTP.Port <= 0 {
+			return fmt.Errorf("smtp host and port are required")
+		}
+		if _, err := mail.ParseAddress(t.SMTP.From); err != nil {
+			return fmt.Errorf("invalid smtp from: %s", t.SMTP.From)
+		}
+		if len(t.SMTP.To) == 0 {
+			return fmt.Errorf("smtp recipients are required")
+		}
+		for _, to := range t.SMTP.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("invalid smtp recipient: %s", to)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid target type: %s", t.Type)
+	}
+	return nil
+}
+
+// NotifyRule 通知规则，事件满足条件时发送到指定的通知渠道
+type NotifyRule struct {
+	ID      int         `json:"id" gorm:"primaryKey"`
+	Name    string      `json:"name" gorm:"not null"`
+	Event   NotifyEvent `json:"event" gorm:"size:32;not null"`
+	Enabled bool        `json:"enabled" gorm:"default:true"`
+	// Threshold 含义随事件而定：apikey_budget 为 MaxCost 的百分比，daily_spend 为美元，channel_success_rate 为成功率百分比
+	Threshold   float64 `json:"threshold"`
+	Window      int     `json:"window,omitempty"`       // channel_success_rate 的统计窗口（分钟），默认 15
+	MinRequests int     `json:"min_requests,omitempty"` // channel_success_rate 窗口内请求数不足时不判断，默认 10
+	Cooldown    int     `json:"cooldown,omitempty"`     // 同一对象重复通知的最小间隔（分钟），默认 60
+	TargetIDs   []int   `json:"target_ids" gorm:"serializer:json"`
+}
+
+const (
+	DefaultNotifyWindow      = 15
+	DefaultNotifyMinRequests = 10
+	DefaultNotifyCooldown    = 60
+)
+
+// Validate 校验通知规则
+func (r *NotifyRule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !r.Event.IsValid() {
+		return fmt.Errorf("invalid event: %s", r.Event)
+	}
+	switch r.Event {
+	case NotifyEventAPIKeyBudget:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("threshold must be a positive percentage")
+		}
+	case NotifyEventDailySpend:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("threshold must be positive")
+		}
+	case NotifyEventChannelSuccessRate:
+		if r.Threshold <= 0 || r.Threshold > 100 {
+			return fmt.Errorf("threshold must be a percentage between 0 and 100")
+		}
+	}
+	if r.Window < 0 || r.MinRequests < 0 || r.Cooldown < 0 {
+		return fmt.Errorf("window, min_requests and cooldown must not be negative")
+	}
+	if len(r.TargetIDs) == 0 {
+		return fmt.Errorf("at least one target is required")
+	}
+	return nil
+}
+
+func (r *NotifyRule) WindowMinutes() int {
+	if r.Window > 0 {
+		return r.Window
+	}
+	return DefaultNotifyWindow
+}
+
+func (r *NotifyRule) MinRequestCount() int {
+	if r.MinRequests > 0 {
+		return r.MinRequests
+	}
+	return DefaultNotifyMinRequests
+}
+
+func (r *NotifyRule) CooldownMinutes() int {
+	if r.Cooldown > 0 {
+		return r.Cooldown
+	}
+	return DefaultNotifyCooldown
+}
+
+// NotifyDedup 通知去重记录，重启后仍在有效期内的事件不会重复发送
+type NotifyDedup struct {
+	Key      string `gorm:"primaryKey;size:255"`
+	RuleID   int    `gorm:"index"`
+	ExpireAt int64  `gorm:"index"`
+}
@@ -0,0 +1,31 @@
+package model
+
+// ReasoningPolicy 推理内容 (reasoning_content、<think> 标签、thinking 块等) 的处理方式
+type ReasoningPolicy string
+
+const (
+	ReasoningPolicyDefault     ReasoningPolicy = ""            // 未设置：API Key 继承分组配置，分组等同透传
+	ReasoningPolicyPassthrough ReasoningPolicy = "passthrough" // 原样透传
+	ReasoningPolicyStrip       ReasoningPolicy = "strip"       // 移除推理内容及正文中的 <think> 标签
+	ReasoningPolicyThinkTags   ReasoningPolicy = "think_tags"  // 将正文中的 <think> 标签转为结构化推理内容
+	ReasoningPolicyText        ReasoningPolicy = "text"        // 将推理内容以 <think> 标签写入正文
+)
+
+func (p ReasoningPolicy) IsValid() bool {
+	switch p {
+	case ReasoningPolicyDefault, ReasoningPolicyPassthrough, ReasoningPolicyStrip, ReasoningPolicyThinkTags, ReasoningPolicyText:
+		return true
+	}
+	return false
+}
+
+// ResolveReasoningPolicy API Key 配置优先于分组配置
+func ResolveReasoningPolicy(group, apiKey ReasoningPolicy) ReasoningPolicy {
+	if apiKey != ReasoningPolicyDefault {
+		return apiKey
+	}
+	if group != ReasoningPolicyDefault {
+		return group
+	}
+	return ReasoningPolicyPassthrough
+}
@@ -0,0 +1,13 @@
+package model
+
+// ResponseObject 持久化的 Responses API 响应，按 API Key 隔离
+type ResponseObject struct {
+	ID                 string `json:"id" gorm:"primaryKey;size:64"`
+	APIKeyID           int    `json:"api_key_id" gorm:"index"`
+	PreviousResponseID string `json:"previous_response_id"`
+	Model              string `json:"model"`
+	Response           string `json:"response"`    // 返回给客户端的 response 对象 JSON
+	InputItems         string `json:"input_items"` // 本轮请求的输入项 JSON
+	Messages           string `json:"messages"`    // 截止本轮的完整对话（内部格式）JSON，用于展开 previous_response_id
+	CreatedAt          int64  `json:"created_at" gorm:"index"`
+}
@@ -1,9 +1,14 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/utils/tokenizer"
 )
 
 type SettingKey string
@@ -16,8 +21,27 @@ const (
 	SettingKeyRelayLogKeepPeriod      SettingKey = "relay_log_keep_period"      // 日志保存时间范围(天)
 	SettingKeyRelayLogKeepEnabled     SettingKey = "relay_log_keep_enabled"     // 是否保留历史日志
 	SettingKeyCORSAllowOrigins        SettingKey = "cors_allow_origins"         // 跨域白名单(逗号分隔, 如 "example.com,example2.com"). 为空不允许跨域, "*"允许所有
+	SettingKeyResponseKeepPeriod      SettingKey = "response_keep_period"       // Responses API 存储的响应保存时间(天), 0 为永久保存
+	SettingKeyBatchConcurrency        SettingKey = "batch_concurrency"          // 单个批处理任务的并发请求数
+	SettingKeyMediaMaxSize            SettingKey = "media_max_size"             // 抓取远程图片/文件的大小上限(MB)
+	SettingKeyMediaMaxDimension       SettingKey = "media_max_dimension"        // 内联图片最长边上限(像素), 超过时缩小, 0 为不缩放
+	SettingKeyStreamDrainGrace        SettingKey = "stream_drain_grace"         // 客户端中断流式请求后继续读取上游以获取用量的时长(秒), 0 为立即断开
+	SettingKeyStatsModelHourlyKeep    SettingKey = "stats_model_hourly_keep"    // 按模型统计的小时数据保存时间(天)
+	SettingKeyPriceSources            SettingKey = "price_sources"              // 价格来源, 每行一个 URL 或本地文件路径, 支持 models.dev 和 LiteLLM 格式
+	SettingKeyPriceProviders          SettingKey = "price_providers"            // 采用价格的供应商(逗号分隔), "*" 为全部
+	SettingKeyPriceAliases            SettingKey = "price_aliases"              // 模型名改写规则(JSON), 未找到价格时依次尝试
+	SettingKeyNotifyRateLimit         SettingKey = "notify_rate_limit"          // 每个通知渠道每分钟最多发送的通知数, 0 为不限制
+	SettingKeyIdempotencyTTL          SettingKey = "idempotency_ttl"            // Idempotency-Key 的有效期(分钟), 0 为不启用
+	SettingKeyIdempotencyMaxSize      SettingKey = "idempotency_max_size"       // 为 Idempotency-Key 保存的响应总大小上限(MB)
+	SettingKeyTokenizerRules          SettingKey = "tokenizer_rules"            // 自定义分词器规则(JSON), 优先于内置规则
 )
 
+// DefaultPriceProviders 默认采用价格的供应商
+const DefaultPriceProviders = "openai,anthropic,google,deepseek,xai,alibaba,zhipuai,minimax,moonshotai,v0"
+
+// DefaultPriceAliases 默认去掉 "anthropic/claude-3.5-sonnet" 这类转售商名称中的供应商前缀
+const DefaultPriceAliases = `[{"match":"^[^/]+/(.+)$","replace":"$1"}]`
+
 type Setting struct {
 	Key   SettingKey `json:"key" gorm:"primaryKey"`
 	Value string     `json:"value" gorm:"not null"`
@@ -32,17 +56,94 @@ func DefaultSettings() []Setting {
 		{Key: SettingKeySyncLLMInterval, Value: "24"},         // 默认24小时同步一次LLM
 		{Key: SettingKeyRelayLogKeepPeriod, Value: "7"},       // 默认日志保存7天
 		{Key: SettingKeyRelayLogKeepEnabled, Value: "true"},   // 默认保留历史日志
+		{Key: SettingKeyResponseKeepPeriod, Value: "30"},      // 默认响应保存30天
+		{Key: SettingKeyBatchConcurrency, Value: "4"},         // 默认批处理并发4
+		{Key: SettingKeyMediaMaxSize, Value: "20"},            // 默认远程媒体上限20MB
+		{Key: SettingKeyMediaMaxDimension, Value: "0"},        // 默认不缩放图片
+		{Key: SettingKeyStreamDrainGrace, Value: "30"},        // 默认客户端断开后继续读取30秒
+		{Key: SettingKeyStatsModelHourlyKeep, Value: "7"},     // 默认保留7天的小时数据
+		{Key: SettingKeyPriceSources, Value: "https://models.dev/api.json"},
+		{Key: SettingKeyPriceProviders, Value: DefaultPriceProviders},
+		{Key: SettingKeyPriceAliases, Value: DefaultPriceAliases},
+		{Key: SettingKeyNotifyRateLimit, Value: "10"},    // 默认每个通知渠道每分钟最多10条
+		{Key: SettingKeyIdempotencyTTL, Value: "60"},     // 默认保留60分钟
+		{Key: SettingKeyIdempotencyMaxSize, Value: "64"}, // 默认最多保存64MB响应
+		{Key: SettingKeyTokenizerRules, Value: ""},
 	}
 }
 
 func (s *Setting) Validate() error {
 	switch s.Key {
-	case SettingKeyModelInfoUpdateInterval, SettingKeySyncLLMInterval, SettingKeyRelayLogKeepPeriod:
+	case SettingKeyModelInfoUpdateInterval, SettingKeySyncLLMInterval, SettingKeyRelayLogKeepPeriod, SettingKeyResponseKeepPeriod:
 		_, err := strconv.Atoi(s.Value)
 		if err != nil {
-			return fmt.Errorf("model info update interval must be an integer")
+			return fmt.Errorf("%s must be an integer", s.Key)
+		}
+		return nil
+	case SettingKeyBatchConcurrency:
+		v, err := strconv.Atoi(s.Value)
+		if err != nil || v < 1 {
+			return fmt.Errorf("batch concurrency must be a positive integer")
+		}
+		return nil
+	case SettingKeyMediaMaxSize:
+		v, err := strconv.Atoi(s.Value)
+		if err != nil || v < 1 {
+			return fmt.Errorf("media max size must be a positive integer")
+		}
+		return nil
+	case SettingKeyMediaMaxDimension:
+		v, err := strconv.Atoi(s.Value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("media max dimension must be a non-negative integer")
+		}
+		return nil
+	case SettingKeyStatsModelHourlyKeep:
+		v, err := strconv.Atoi(s.Value)
+		if err != nil || v < 1 {
+			return fmt.Errorf("stats model hourly keep must be a positive integer")
+		}
+		return nil
+	case SettingKeyStreamDrainGrace:
+		v, err := strconv.Atoi(s.Value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("stream drain grace must be a non-negative integer")
+		}
+		return nil
+	case SettingKeyNotifyRateLimit:
+		v, err := strconv.Atoi(s.Value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("notify rate limit must be a non-negative integer")
 		}
 		return nil
+	case SettingKeyIdempotencyTTL:
+		v, err := strconv.Atoi(s.Value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("idempotency ttl must be a non-negative integer")
+		}
+		return nil
+	case SettingKeyIdempotencyMaxSize:
+		v, err := strconv.Atoi(s.Value)
+		if err != nil || v < 1 {
+			return fmt.Errorf("idempotency max size must be a positive integer")
+		}
+		return nil
+	case SettingKeyPriceSources:
+		for _, source := range ParsePriceSources(s.Value) {
+			if strings.Contains(source, "://") {
+				u, err := url.Parse(source)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("price source must be an http(s) URL or a local file path: %s", source)
+				}
+			}
+		}
+		return nil
+	case SettingKeyPriceAliases:
+		_, err := ParsePriceAliases(s.Value)
+		return err
+	case SettingKeyTokenizerRules:
+		_, err := ParseTokenizerRules(s.Value)
+		return err
 	case SettingKeyRelayLogKeepEnabled:
 		if s.Value != "true" && s.Value != "false" {
 			return fmt.Errorf("relay log keep enabled must be true or false")
@@ -72,3 +173,56 @@ func (s *Setting) Validate() error {
 
 	return nil
 }
+
+// PriceAlias 模型名改写规则, 模型名匹配 Match 正则时改写为 Replace, Replace 可引用分组如 $1
+type PriceAlias struct {
+	Match   string `json:"match"`
+	Replace string `json:"replace"`
+}
+
+// ParsePriceSources 按行或逗号拆分价格来源
+func ParsePriceSources(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == '\n' || r == ',' })
+	sources := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			sources = append(sources, field)
+		}
+	}
+	return sources
+}
+
+// ParsePriceAliases 解析并校验模型名改写规则, 空值表示没有规则
+func ParsePriceAliases(value string) ([]PriceAlias, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var aliases []PriceAlias
+	if err := json.Unmarshal([]byte(value), &aliases); err != nil {
+		return nil, fmt.Errorf("price aliases must be a JSON array of {match, replace}: %w", err)
+	}
+	for _, alias := range aliases {
+		if alias.Match == "" {
+			return nil, fmt.Errorf("price alias pattern must not be empty")
+		}
+		if _, err := regexp.Compile(alias.Match); err != nil {
+			return nil, fmt.Errorf("invalid price alias pattern %q: %w", alias.Match, err)
+		}
+	}
+	return aliases, nil
+}
+
+// ParseTokenizerRules 解析并校验自定义分词器规则, 空值表示没有规则
+func ParseTokenizerRules(value string) ([]tokenizer.Rule, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var rules []tokenizer.Rule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("tokenizer rules must be a JSON array of {match, encoding, factor}: %w", err)
+	}
+	if err := tokenizer.ValidateRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
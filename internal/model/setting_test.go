@@ -0,0 +1,15 @@
+package model
+
+import "testing"
+
+func TestSettingValidateIntegerKeys(t *testing.T) {
+	for _, key := range []SettingKey{SettingKeyModelInfoUpdateInterval, SettingKeySyncLLMInterval, SettingKeyRelayLogKeepPeriod, SettingKeyResponseKeepPeriod} {
+		if err := (&Setting{Key: key, Value: "7"}).Validate(); err != nil {
+			t.Errorf("%s: unexpected error: %v", key, err)
+		}
+		err := (&Setting{Key: key, Value: "x"}).Validate()
+		if want := string(key) + " must be an integer"; err == nil || err.Error() != want {
+			t.Errorf("%s: error = %v, want %q", key, err, want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ type StatsMetrics struct {
 	OutputToken    int64   `json:"output_token" gorm:"bigint"`
 	InputCost      float64 `json:"input_cost" gorm:"type:real"`
 	OutputCost     float64 `json:"output_cost" gorm:"type:real"`
+	UpstreamCost   float64 `json:"upstream_cost" gorm:"type:real"` // 按渠道实际价格计算的上游费用
 	WaitTime       int64   `json:"wait_time" gorm:"bigint"`
 	RequestSuccess int64   `json:"request_success" gorm:"bigint"`
 	RequestFailed  int64   `json:"request_failed" gorm:"bigint"`
@@ -26,10 +27,45 @@ type StatsDaily struct {
 	StatsMetrics
 }
 
+// StatsModel 按 模型 × 渠道 × 天 统计
 type StatsModel struct {
-	ID        int    `json:"id" gorm:"primaryKey"`
-	Name      string `json:"name" gorm:"not null"`
-	ChannelID int    `json:"channel_id" gorm:"not null"`
+	Date      string `json:"date" gorm:"primaryKey;size:8"` // 格式：20060102
+	Name      string `json:"name" gorm:"primaryKey;size:191"`
+	ChannelID int    `json:"channel_id" gorm:"primaryKey;autoIncrement:false"`
+	StatsMetrics
+}
+
+// StatsModelHourly 按 模型 × 渠道 × 小时 统计，只保留最近若干天
+type StatsModelHourly struct {
+	Time      int64  `json:"time" gorm:"primaryKey;autoIncrement:false"` // 整点时间戳（秒）
+	Name      string `json:"name" gorm:"primaryKey;size:191"`
+	ChannelID int    `json:"channel_id" gorm:"primaryKey;autoIncrement:false"`
+	StatsMetrics
+}
+
+// StatsAPIKeyModel 按 天 × API Key × 模型 统计，用于导出用量，不受请求日志开关和清理的影响
+// 费用为最终费用，不参与预扣费
+type StatsAPIKeyModel struct {
+	Date     string `json:"date" gorm:"primaryKey;size:8"` // 格式：20060102
+	APIKeyID int    `json:"api_key_id" gorm:"primaryKey;autoIncrement:false"`
+	Name     string `json:"name" gorm:"primaryKey;size:191"`
+	StatsMetrics
+	CacheReadToken  int64 `json:"cache_read_token" gorm:"bigint"`
+	CacheWriteToken int64 `json:"cache_write_token" gorm:"bigint"`
+}
+
+// StatsModelSummary 时间范围内的汇总结果，ChannelID 为 0 表示未按渠道拆分
+type StatsModelSummary struct {
+	Name        string  `json:"name"`
+	ChannelID   int     `json:"channel_id,omitempty"`
+	SuccessRate float64 `json:"success_rate"` // 0-1
+	AvgLatency  int64   `json:"avg_latency"`  // 平均耗时(毫秒)
+	StatsMetrics
+}
+
+// StatsModelPoint 时间序列中的一个点，Time 为该天或该小时开始的时间戳（秒）
+type StatsModelPoint struct {
+	Time int64 `json:"time"`
 	StatsMetrics
 }
 
@@ -41,6 +77,7 @@ type StatsChannel struct {
 type StatsAPIKey struct {
 	APIKeyID int `json:"api_key_id" gorm:"primaryKey"`
 	StatsMetrics
+	QuotaCost float64 `json:"quota_cost"` // 上次额度重置以来的费用，与 MaxCost 比较，重置时不清空历史统计
 }
 
 // Add aggregates another StatsMetrics into the current one.
@@ -49,7 +86,13 @@ func (s *StatsMetrics) Add(delta StatsMetrics) {
 	s.OutputToken += delta.OutputToken
 	s.InputCost += delta.InputCost
 	s.OutputCost += delta.OutputCost
+	s.UpstreamCost += delta.UpstreamCost
 	s.WaitTime += delta.WaitTime
 	s.RequestSuccess += delta.RequestSuccess
 	s.RequestFailed += delta.RequestFailed
 }
+
+// Requests 总请求数
+func (s *StatsMetrics) Requests() int64 {
+	return s.RequestSuccess + s.RequestFailed
+}
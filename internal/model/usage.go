@@ -0,0 +1,16 @@
+package model
+
+// UsageRow 按 API Key × 模型 × 天 汇总的用量，用于导出账单
+type UsageRow struct {
+	Date             string  `json:"date"` // 格式：2006-01-02，按服务器时区
+	APIKeyID         int     `json:"api_key_id"`
+	APIKeyName       string  `json:"api_key_name"` // API Key 已删除时为空
+	Model            string  `json:"model"`
+	Requests         int64   `json:"requests"`
+	FailedRequests   int64   `json:"failed_requests"`
+	InputTokens      int64   `json:"input_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	CacheReadTokens  int64   `json:"cache_read_tokens"`
+	CacheWriteTokens int64   `json:"cache_write_tokens"`
+	Cost             float64 `json:"cost"`
+}
@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/utils/log"
+)
+
+// 无自动重置的额度在 MaxCost 不变时只通知一次
+var farFuture = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// maxSampleAge 渠道请求数采样的最长保留时间，限制成功率窗口的上限
+const maxSampleAge = 24 * time.Hour
+
+type channelSample struct {
+	time    time.Time
+	success int64
+	failed  int64
+}
+
+var (
+	channelSamples     = make(map[int][]channelSample)
+	channelSamplesLock sync.Mutex
+)
+
+// CheckTask 定时检查额度、当日费用和渠道成功率，需每分钟执行一次
+func CheckTask() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	pruneDedup()
+	checkAPIKeyBudget(ctx)
+	checkDailySpend()
+	checkChannelSuccessRate(ctx)
+}
+
+func checkAPIKeyBudget(ctx context.Context) {
+	rules := op.NotifyRuleListByEvent(model.NotifyEventAPIKeyBudget)
+	if len(rules) == 0 {
+		return
+	}
+	keys, err := op.APIKeyList(ctx)
+	if err != nil {
+		log.Warnf("failed to list api keys for notify: %v", err)
+		return
+	}
+	for _, key := range keys {
+		if !key.Enabled || key.MaxCost <= 0 {
+			continue
+		}
+		used := op.StatsAPIKeyGet(key.ID).QuotaCost
+		percent := used / key.MaxCost * 100
+		// 每个额度周期只通知一次，额度调整后重新计算
+		until := farFuture
+		if key.AutoResetQuota && key.NextResetTime > 0 {
+			until = time.Unix(key.NextResetTime, 0)
+		}
+		for _, rule := range rules {
+			if percent < rule.Threshold {
+				continue
+			}
+			dispatch(rule, Event{
+				Type:    model.NotifyEventAPIKeyBudget,
+				Title:   fmt.Sprintf("API key %s reached %g%% of its budget", key.Name, rule.Threshold),
+				Message: fmt.Sprintf("API key %s has used $%.4f of its $%g budget (%.1f%%).", key.Name, used, key.MaxCost, percent),
+				Data: map[string]any{
+					"api_key_id":   key.ID,
+					"api_key_name": key.Name,
+					"used":         used,
+					"max_cost":     key.MaxCost,
+					"percent":      percent,
+					"threshold":    rule.Threshold,
+				},
+				key:   fmt.Sprintf("apikey:%d:%g:%d", key.ID, key.MaxCost, key.NextResetTime),
+				until: until,
+			})
+		}
+	}
+}
+
+func checkDailySpend() {
+	rules := op.NotifyRuleListByEvent(model.NotifyEventDailySpend)
+	if len(rules) == 0 {
+		return
+	}
+	now := time.Now()
+	today := op.StatsTodayGet()
+	if today.Date != now.Format("20060102") {
+		return
+	}
+	cost := today.InputCost + today.OutputCost
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	for _, rule := range rules {
+		if cost < rule.Threshold {
+			continue
+		}
+		dispatch(rule, Event{
+			Type:    model.NotifyEventDailySpend,
+			Title:   fmt.Sprintf("Daily spend exceeded $%g", rule.Threshold),
+			Message: fmt.Sprintf("Spend today is $%.4f, above the $%g threshold. Upstream cost is $%.4f.", cost, rule.Threshold, today.UpstreamCost),
+			Data: map[string]any{
+				"date":          today.Date,
+				"cost":          cost,
+				"upstream_cost": today.UpstreamCost,
+				"threshold":     rule.Threshold,
+			},
+			key:   "daily:" + today.Date,
+			until: tomorrow,
+		})
+	}
+}
+
+// checkChannelSuccessRate 每次检查时记录各渠道的累计请求数，用窗口两端的差值计算成功率
+func checkChannelSuccessRate(ctx context.Context) {
+	channels, err := op.ChannelList(ctx)
+	if err != nil {
+		log.Warnf("failed to list channels for notify: %v", err)
+		return
+	}
+	rules := op.NotifyRuleListByEvent(model.NotifyEventChannelSuccessRate)
+	now := time.Now()
+
+	channelSamplesLock.Lock()
+	defer channelSamplesLock.Unlock()
+	alive := make(map[int]struct{}, len(channels))
+	for _, channel := range channels {
+		alive[channel.ID] = struct{}{}
+		stats := op.StatsChannelGet(channel.ID)
+		samples := append(channelSamples[channel.ID], channelSample{time: now, success: stats.RequestSuccess, failed: stats.RequestFailed})
+		for len(samples) > 0 && now.Sub(samples[0].time) > maxSampleAge {
+			samples = samples[1:]
+		}
+		channelSamples[channel.ID] = samples
+		if !channel.Enabled {
+			continue
+		}
+		for _, rule := range rules {
+			window := time.Duration(rule.WindowMinutes()) * time.Minute
+			base := windowStart(samples, now.Add(-window))
+			success := stats.RequestSuccess - base.success
+			failed := stats.RequestFailed - base.failed
+			// 统计被重置时差值为负，跳过本次判断
+			if success < 0 || failed < 0 || success+failed < int64(rule.MinRequestCount()) {
+				continue
+			}
+			rate := float64(success) / float64(success+failed) * 100
+			if rate >= rule.Threshold {
+				continue
+			}
+			dispatch(rule, Event{
+				Type:    model.NotifyEventChannelSuccessRate,
+				Title:   fmt.Sprintf("Channel %s success rate dropped to %.1f%%", channel.Name, rate),
+				Message: fmt.Sprintf("Channel %s succeeded %d of %d requests in the last %d minutes (%.1f%%), below the %g%% threshold.", channel.Name, success, success+failed, rule.WindowMinutes(), rate, rule.Threshold),
+				Data: map[string]any{
+					"channel_id":   channel.ID,
+					"channel_name": channel.Name,
+					"success":      success,
+					"failed":       failed,
+					"rate":         rate,
+					"window":       rule.WindowMinutes(),
+					"threshold":    rule.Threshold,
+				},
+				key: fmt.Sprintf("channel:%d", channel.ID),
+			})
+		}
+	}
+	for id := range channelSamples {
+		if _, ok := alive[id]; !ok {
+			delete(channelSamples, id)
+		}
+	}
+}
+
+// windowStart 返回窗口起点之前最近的一次采样，刚启动时采样不足则取最早的一次
+func windowStart(samples []channelSample, start time.Time) channelSample {
+	base := samples[0]
+	for _, sample := range samples {
+		if sample.time.After(start) {
+			break
+		}
+		base = sample
+	}
+	return base
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+// ChannelKeyStatus 渠道 Key 返回 401 或 429 时通知，429 的 Key 会被暂停使用 5 分钟
+func ChannelKeyStatus(channel *model.Channel, key model.ChannelKey) {
+	if channel == nil || key.ID == 0 {
+		return
+	}
+	var title, reason string
+	switch key.StatusCode {
+	case http.StatusUnauthorized:
+		title = fmt.Sprintf("Channel %s key %s returned 401", channel.Name, keyLabel(key))
+		reason = "the upstream rejected the key as unauthorized, it may be revoked or expired"
+	case http.StatusTooManyRequests:
+		title = fmt.Sprintf("Channel %s key %s quarantined after 429", channel.Name, keyLabel(key))
+		reason = "the upstream rate limited the key, it is skipped for 5 minutes"
+	default:
+		return
+	}
+	Emit(Event{
+		Type:    model.NotifyEventChannelKeyError,
+		Title:   title,
+		Message: fmt.Sprintf("Channel %s key %s: %s.", channel.Name, keyLabel(key), reason),
+		Data: map[string]any{
+			"channel_id":     channel.ID,
+			"channel_name":   channel.Name,
+			"channel_key_id": key.ID,
+			"remark":         key.Remark,
+			"status_code":    key.StatusCode,
+		},
+		key: fmt.Sprintf("channelkey:%d:%d", key.ID, key.StatusCode),
+	})
+}
+
+// ModelsRemoved 模型同步时上游删除了模型
+func ModelsRemoved(channel model.Channel, models []string) {
+	if len(models) == 0 {
+		return
+	}
+	Emit(Event{
+		Type:    model.NotifyEventModelSyncRemoved,
+		Title:   fmt.Sprintf("Model sync removed %d models from channel %s", len(models), channel.Name),
+		Message: fmt.Sprintf("The upstream of channel %s no longer lists these models, they were removed from the channel and its groups: %s.", channel.Name, strings.Join(models, ", ")),
+		Data: map[string]any{
+			"channel_id":   channel.ID,
+			"channel_name": channel.Name,
+			"models":       models,
+		},
+		key: fmt.Sprintf("sync:%d:%s", channel.ID, strings.Join(models, ",")),
+	})
+}
+
+// keyLabel 优先使用备注，否则显示 Key 的末四位
+func keyLabel(key model.ChannelKey) string {
+	if key.Remark != "" {
+		return fmt.Sprintf("#%d (%s)", key.ID, key.Remark)
+	}
+	if len(key.ChannelKey) > 4 {
+		return fmt.Sprintf("#%d (...%s)", key.ID, key.ChannelKey[len(key.ChannelKey)-4:])
+	}
+	return fmt.Sprintf("#%d", key.ID)
+}
@@ -0,0 +1,28 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/op"
+)
+
+// TestMain 使用临时 SQLite 数据库初始化缓存，通知渠道通过 httptest 接收
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "octopus-notify-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := db.InitDB("sqlite", filepath.Join(dir, "test.db"), false); err != nil {
+		panic(err)
+	}
+	if err := op.InitCache(); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	db.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
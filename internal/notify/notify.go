@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/utils/log"
+)
+
+// Event 一条通知，Webhook 类型的渠道直接以此结构作为请求体
+type Event struct {
+	Type    model.NotifyEvent `json:"event"`
+	Title   string            `json:"title"`
+	Message string            `json:"message"`
+	Time    int64             `json:"time"`
+	Data    map[string]any    `json:"data,omitempty"`
+
+	key   string    // 去重键，同一规则下相同的键在冷却时间内只通知一次
+	until time.Time // 非零时去重到该时间为止，用于每个周期只通知一次的事件
+}
+
+const sendTimeout = 30 * time.Second
+
+var (
+	dedup       = make(map[string]time.Time)
+	dedupLoaded bool
+	dedupLock   sync.Mutex
+
+	rateWindow     = make(map[int]rateCounter)
+	rateWindowLock sync.Mutex
+)
+
+type rateCounter struct {
+	start int64 // 当前分钟的起始时间戳
+	count int
+}
+
+// Emit 按事件类型匹配已启用的规则并异步发送
+func Emit(ev Event) {
+	for _, rule := range op.NotifyRuleListByEvent(ev.Type) {
+		dispatch(rule, ev)
+	}
+}
+
+// Test 立即向通知渠道发送一条测试通知，不经过去重和限流
+func Test(ctx context.Context, target model.NotifyTarget) error {
+	ev := Event{
+		Type:    model.NotifyEventTest,
+		Title:   "Octopus test notification",
+		Message: fmt.Sprintf("This is a test notification sent to %s.", target.Name),
+		Time:    time.Now().Unix(),
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return send(ctx, target, ev)
+}
+
+// dispatch 去重后将事件发送到规则的全部通知渠道
+// 至少发出一条通知后才记录去重，渠道全部停用或被限流时下次事件仍会尝试发送
+// 去重记录同时写入数据库，重启后无自动重置的额度等事件不会重复通知
+func dispatch(rule model.NotifyRule, ev Event) {
+	if ev.Time == 0 {
+		ev.Time = time.Now().Unix()
+	}
+	now := time.Now()
+	key := fmt.Sprintf("%d:%s", rule.ID, ev.key)
+	dedupLock.Lock()
+	defer dedupLock.Unlock()
+	loadDedup()
+	if expire, ok := dedup[key]; ok && now.Before(expire) {
+		return
+	}
+
+	dispatched := false
+	for _, targetID := range rule.TargetIDs {
+		target, err := op.NotifyTargetGet(targetID, context.Background())
+		if err != nil || !target.Enabled {
+			continue
+		}
+		if !acquireRate(target.ID) {
+			log.Warnf("notify target %s rate limited, dropping %s notification: %s", target.Name, ev.Type, ev.Title)
+			continue
+		}
+		dispatched = true
+		go func(target model.NotifyTarget) {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := send(ctx, target, ev); err != nil {
+				log.Warnf("failed to send %s notification to %s: %v", ev.Type, target.Name, err)
+				return
+			}
+			log.Infof("sent %s notification to %s: %s", ev.Type, target.Name, ev.Title)
+		}(target)
+	}
+	if !dispatched {
+		return
+	}
+	expire := now.Add(time.Duration(rule.CooldownMinutes()) * time.Minute)
+	if !ev.until.IsZero() {
+		expire = ev.until
+	}
+	dedup[key] = expire
+	if err := op.NotifyDedupSave(model.NotifyDedup{Key: key, RuleID: rule.ID, ExpireAt: expire.Unix()}, context.Background()); err != nil {
+		log.Warnf("failed to save notify dedup record %s: %v", key, err)
+	}
+}
+
+// loadDedup 首次去重前从数据库载入未过期的记录，调用方需持有 dedupLock
+func loadDedup() {
+	if dedupLoaded {
+		return
+	}
+	records, err := op.NotifyDedupList(context.Background())
+	if err != nil {
+		log.Warnf("failed to load notify dedup records: %v", err)
+		return
+	}
+	for _, record := range records {
+		if expire := time.Unix(record.ExpireAt, 0); expire.After(dedup[record.Key]) {
+			dedup[record.Key] = expire
+		}
+	}
+	dedupLoaded = true
+}
+
+// pruneDedup 清理已过期的去重记录
+func pruneDedup() {
+	now := time.Now()
+	dedupLock.Lock()
+	defer dedupLock.Unlock()
+	for key, expire := range dedup {
+		if !now.Before(expire) {
+			delete(dedup, key)
+		}
+	}
+	if err := op.NotifyDedupCleanup(context.Background()); err != nil {
+		log.Warnf("failed to clean up notify dedup records: %v", err)
+	}
+}
+
+// acquireRate 每个通知渠道每分钟最多发送 notify_rate_limit 条，超出的通知直接丢弃
+func acquireRate(targetID int) bool {
+	limit, err := op.SettingGetInt(model.SettingKeyNotifyRateLimit)
+	if err != nil || limit <= 0 {
+		return true
+	}
+	minute := time.Now().Truncate(time.Minute).Unix()
+	rateWindowLock.Lock()
+	defer rateWindowLock.Unlock()
+	counter := rateWindow[targetID]
+	if counter.start != minute {
+		counter = rateCounter{start: minute}
+	}
+	if counter.count >= limit {
+		return false
+	}
+	counter.count++
+	rateWindow[targetID] = counter
+	return true
+}
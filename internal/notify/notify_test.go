@@ -0,0 +1,295 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestReceiver 启动接收通知的服务器，收到的请求依次写入返回的 channel
+func newTestReceiver(t *testing.T) (*httptest.Server, chan receivedRequest) {
+	t.Helper()
+	received := make(chan receivedRequest, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedRequest{header: r.Header.Clone(), body: body}
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func newTestTarget(t *testing.T, target model.NotifyTarget) model.NotifyTarget {
+	t.Helper()
+	target.Name = t.Name()
+	if err := op.NotifyTargetCreate(&target, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return target
+}
+
+func newTestRule(t *testing.T, targetIDs ...int) model.NotifyRule {
+	t.Helper()
+	rule := model.NotifyRule{Name: t.Name(), Event: model.NotifyEventDailySpend, Enabled: true, Threshold: 1, TargetIDs: targetIDs}
+	if err := op.NotifyRuleCreate(&rule, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return rule
+}
+
+func setRateLimit(t *testing.T, limit int) {
+	t.Helper()
+	previous, _ := op.SettingGetInt(model.SettingKeyNotifyRateLimit)
+	if err := op.SettingSetInt(model.SettingKeyNotifyRateLimit, limit); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { op.SettingSetInt(model.SettingKeyNotifyRateLimit, previous) })
+}
+
+func receive(t *testing.T, received chan receivedRequest) receivedRequest {
+	t.Helper()
+	select {
+	case r := <-received:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not delivered")
+		return receivedRequest{}
+	}
+}
+
+// expectNone 确认没有更多通知送达，发送是异步的，等待一小段时间
+func expectNone(t *testing.T, received chan receivedRequest) {
+	t.Helper()
+	select {
+	case r := <-received:
+		t.Fatalf("unexpected notification: %s", r.body)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestDispatchWebhook(t *testing.T) {
+	server, received := newTestReceiver(t)
+	target := newTestTarget(t, model.NotifyTarget{
+		Type:    model.NotifyTargetWebhook,
+		Enabled: true,
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer hook-token"},
+	})
+	rule := newTestRule(t, target.ID)
+
+	dispatch(rule, Event{Type: model.NotifyEventDailySpend, Title: "Daily spend", Message: "over $1", Time: 1700000000, Data: map[string]any{"cost": 1.5}, key: "day"})
+
+	r := receive(t, received)
+	if got := r.header.Get("Authorization"); got != "Bearer hook-token" {
+		t.Errorf("Authorization = %q, want custom header", got)
+	}
+	if got := r.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var ev map[string]any
+	if err := json.Unmarshal(r.body, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev["event"] != "daily_spend" || ev["title"] != "Daily spend" || ev["message"] != "over $1" || ev["time"] != float64(1700000000) {
+		t.Fatalf("unexpected webhook body: %s", r.body)
+	}
+	if data, _ := ev["data"].(map[string]any); data["cost"] != 1.5 {
+		t.Fatalf("unexpected webhook data: %s", r.body)
+	}
+}
+
+func TestDispatchJSONTemplate(t *testing.T) {
+	server, received := newTestReceiver(t)
+	target := newTestTarget(t, model.NotifyTarget{
+		Type:     model.NotifyTargetJSON,
+		Enabled:  true,
+		URL:      server.URL,
+		Template: `{"msg_type":"text","content":{"text":"[{{event}}] {{title}}: {{message}}"}}`,
+	})
+	rule := newTestRule(t, target.ID)
+
+	// 内容中的引号和换行按 JSON 转义
+	dispatch(rule, Event{Type: model.NotifyEventDailySpend, Title: `Spend "high"`, Message: "line1\nline2", key: "day"})
+
+	r := receive(t, received)
+	var body struct {
+		MsgType string `json:"msg_type"`
+		Content struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(r.body, &body); err != nil {
+		t.Fatalf("rendered template is not valid json: %s", r.body)
+	}
+	if want := "[daily_spend] Spend \"high\": line1\nline2"; body.MsgType != "text" || body.Content.Text != want {
+		t.Fatalf("unexpected rendered body: %s", r.body)
+	}
+}
+
+func TestRenderTemplateTime(t *testing.T) {
+	ev := Event{Type: model.NotifyEventTest, Time: 1700000000}
+	want := `{"time":"` + time.Unix(1700000000, 0).Format(time.RFC3339) + `","unknown":"{{unknown}}"}`
+	if got := renderTemplate(`{"time":"{{time}}","unknown":"{{unknown}}"}`, ev); got != want {
+		t.Fatalf("renderTemplate = %s, want %s", got, want)
+	}
+}
+
+// 同一规则下相同的去重键在冷却时间内只通知一次，不同的键互不影响
+func TestDispatchDedup(t *testing.T) {
+	server, received := newTestReceiver(t)
+	target := newTestTarget(t, model.NotifyTarget{Type: model.NotifyTargetWebhook, Enabled: true, URL: server.URL})
+	rule := newTestRule(t, target.ID)
+
+	dispatch(rule, Event{Type: model.NotifyEventDailySpend, Title: "first", key: "channel:1"})
+	receive(t, received)
+	dispatch(rule, Event{Type: model.NotifyEventDailySpend, Title: "repeat", key: "channel:1"})
+	expectNone(t, received)
+
+	dispatch(rule, Event{Type: model.NotifyEventDailySpend, Title: "other", key: "channel:2"})
+	if r := receive(t, received); !json.Valid(r.body) {
+		t.Fatalf("invalid body: %s", r.body)
+	}
+
+	// until 已过期的事件不再去重
+	dispatch(rule, Event{Type: model.NotifyEventDailySpend, Title: "expired", key: "period", until: time.Now().Add(-time.Second)})
+	receive(t, received)
+	dispatch(rule, Event{Type: model.NotifyEventDailySpend, Title: "expired again", key: "period", until: time.Now().Add(-time.Second)})
+	receive(t, received)
+}
+
+// 没有任何渠道发出通知时不记录去重，渠道恢复后同一事件仍会通知
+func TestDispatchDedupAfterDelivery(t *testing.T) {
+	server, received := newTestReceiver(t)
+	target := newTestTarget(t, model.NotifyTarget{Type: model.NotifyTargetWebhook, Enabled: true, URL: server.URL})
+	rule := newTestRule(t, target.ID)
+	// Enabled 的数据库默认值为 true，创建后再停用
+	target.Enabled = false
+	if err := op.NotifyTargetUpdate(&target, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ev := Event{Type: model.NotifyEventDailySpend, Title: "spend", key: "day"}
+
+	dispatch(rule, ev)
+	expectNone(t, received)
+
+	target.Enabled = true
+	if err := op.NotifyTargetUpdate(&target, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	dispatch(rule, ev)
+	receive(t, received)
+	dispatch(rule, ev)
+	expectNone(t, received)
+}
+
+// 每个通知渠道每分钟最多发送 notify_rate_limit 条，被限流时不记录去重
+func TestDispatchRateLimit(t *testing.T) {
+	setRateLimit(t, 2)
+	server, received := newTestReceiver(t)
+	target := newTestTarget(t, model.NotifyTarget{Type: model.NotifyTargetWebhook, Enabled: true, URL: server.URL})
+	rule := newTestRule(t, target.ID)
+	// 跨分钟时计数会重置，在分钟开始后再发送
+	if time.Until(time.Now().Truncate(time.Minute).Add(time.Minute)) < 5*time.Second {
+		time.Sleep(5 * time.Second)
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		dispatch(rule, Event{Type: model.NotifyEventDailySpend, Title: key, key: key})
+	}
+	receive(t, received)
+	receive(t, received)
+	expectNone(t, received)
+
+	// 被限流的事件没有记录去重，限流放开后可以再次发送
+	setRateLimit(t, 0)
+	dispatch(rule, Event{Type: model.NotifyEventDailySpend, Title: "c", key: "c"})
+	receive(t, received)
+}
+
+// 规则的其他渠道不受单个渠道限流的影响
+func TestDispatchRateLimitPerTarget(t *testing.T) {
+	setRateLimit(t, 1)
+	server, received := newTestReceiver(t)
+	first := newTestTarget(t, model.NotifyTarget{Type: model.NotifyTargetWebhook, Enabled: true, URL: server.URL + "/first"})
+	second := newTestTarget(t, model.NotifyTarget{Type: model.NotifyTargetWebhook, Enabled: true, URL: server.URL + "/second"})
+	if time.Until(time.Now().Truncate(time.Minute).Add(time.Minute)) < 5*time.Second {
+		time.Sleep(5 * time.Second)
+	}
+
+	dispatch(newTestRule(t, first.ID), Event{Type: model.NotifyEventDailySpend, Title: "one", key: "x"})
+	receive(t, received)
+	dispatch(newTestRule(t, first.ID, second.ID), Event{Type: model.NotifyEventDailySpend, Title: "two", key: "x"})
+	receive(t, received)
+	expectNone(t, received)
+}
+
+// resetDedup 清空内存中的去重记录，模拟服务重启
+func resetDedup() {
+	dedupLock.Lock()
+	defer dedupLock.Unlock()
+	dedup = make(map[string]time.Time)
+	dedupLoaded = false
+}
+
+// 去重记录持久化，重启后同一周期内的事件不会重复通知，删除规则时清理其记录
+func TestDispatchDedupSurvivesRestart(t *testing.T) {
+	server, received := newTestReceiver(t)
+	target := newTestTarget(t, model.NotifyTarget{Type: model.NotifyTargetWebhook, Enabled: true, URL: server.URL})
+	rule := newTestRule(t, target.ID)
+	ev := Event{Type: model.NotifyEventAPIKeyBudget, Title: "budget", key: "apikey:1:10:0", until: farFuture}
+
+	dispatch(rule, ev)
+	receive(t, received)
+	resetDedup()
+	dispatch(rule, ev)
+	expectNone(t, received)
+
+	if err := op.NotifyRuleDelete(rule.ID, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	records, err := op.NotifyDedupList(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if record.RuleID == rule.ID {
+			t.Fatalf("dedup record %s kept after the rule was deleted", record.Key)
+		}
+	}
+}
+
+// 过期的持久化记录在检查时清理，重启后不再去重
+func TestPruneDedupRecords(t *testing.T) {
+	server, received := newTestReceiver(t)
+	target := newTestTarget(t, model.NotifyTarget{Type: model.NotifyTargetWebhook, Enabled: true, URL: server.URL})
+	rule := newTestRule(t, target.ID)
+	ev := Event{Type: model.NotifyEventDailySpend, Title: "spend", key: "daily", until: time.Now().Add(time.Second)}
+
+	dispatch(rule, ev)
+	receive(t, received)
+	time.Sleep(1100 * time.Millisecond)
+	pruneDedup()
+	resetDedup()
+	records, err := op.NotifyDedupList(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if record.RuleID == rule.ID {
+			t.Fatalf("expired dedup record %s not pruned", record.Key)
+		}
+	}
+	dispatch(rule, ev)
+	receive(t, received)
+}
@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/client"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+func send(ctx context.Context, target model.NotifyTarget, ev Event) error {
+	switch target.Type {
+	case model.NotifyTargetWebhook:
+		body, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		return postJSON(ctx, target, body)
+	case model.NotifyTargetJSON:
+		return postJSON(ctx, target, []byte(renderTemplate(target.Template, ev)))
+	case model.NotifyTargetSMTP:
+		if target.SMTP == nil {
+			return fmt.Errorf("smtp config is empty")
+		}
+		return sendMail(ctx, *target.SMTP, ev)
+	default:
+		return fmt.Errorf("invalid target type: %s", target.Type)
+	}
+}
+
+// renderTemplate 替换模板中的占位符，内容按 JSON 字符串转义，模板中的占位符应位于引号内
+func renderTemplate(template string, ev Event) string {
+	values := map[string]string{
+		"title":   ev.Title,
+		"message": ev.Message,
+		"event":   string(ev.Type),
+		"time":    time.Unix(ev.Time, 0).Format(time.RFC3339),
+	}
+	pairs := make([]string, 0, len(values)*2)
+	for _, field := range model.NotifyTemplateFields {
+		escaped, _ := json.Marshal(values[field])
+		pairs = append(pairs, "{{"+field+"}}", string(escaped[1:len(escaped)-1]))
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+func postJSON(ctx context.Context, target model.NotifyTarget, body []byte) error {
+	httpClient, err := client.GetHTTPClientSystemProxy(false)
+	if err != nil {
+		return fmt.Errorf("failed to get http client: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range target.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// sendMail 端口 465 使用 TLS 直连，其余端口在服务器支持时升级为 STARTTLS
+func sendMail(ctx context.Context, cfg model.NotifySMTP, ev Event) error {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if cfg.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create smtp client: %w", err)
+	}
+	defer c.Close()
+	if cfg.Port != 465 {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("failed to start tls: %w", err)
+			}
+		}
+	}
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	if err := c.Mail(cfg.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, to := range cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to set recipient %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start data: %w", err)
+	}
+	var msg strings.Builder
+	msg.WriteString("From: " + cfg.From + "\r\n")
+	msg.WriteString("To: " + strings.Join(cfg.To, ", ") + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", ev.Title) + "\r\n")
+	msg.WriteString("Date: " + time.Unix(ev.Time, 0).Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(ev.Message, "\n", "\r\n") + "\r\n")
+	if _, err := w.Write([]byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return c.Quit()
+}
@@ -65,6 +65,12 @@ func APIKeyDelete(id int, ctx context.Context) error {
 	if err := StatsAPIKeyDel(id); err != nil {
 		return fmt.Errorf("failed to delete stats API key: %v", err)
 	}
+	if err := apiKeyLimitDel(ctx, id); err != nil {
+		return err
+	}
+	if err := apiKeyTransactionDel(ctx, id); err != nil {
+		return err
+	}
 	result := db.GetDB().WithContext(ctx).Delete(&k)
 	if result.RowsAffected == 0 {
 		return fmt.Errorf("API key not found")
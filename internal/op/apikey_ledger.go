@@ -0,0 +1,177 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+// apiKeyLedger 单个 API Key 的余额和进行中请求的预留金额
+// 余额即最后一条流水的 Balance，写入流水时只持有该 Key 的锁，不同 Key 的扣费互不阻塞
+type apiKeyLedger struct {
+	lock     sync.Mutex
+	balance  float64
+	reserved float64
+}
+
+var (
+	apiKeyLedgers     = make(map[int]*apiKeyLedger)
+	apiKeyLedgersLock sync.Mutex
+)
+
+func getAPIKeyLedger(apiKeyID int) *apiKeyLedger {
+	apiKeyLedgersLock.Lock()
+	defer apiKeyLedgersLock.Unlock()
+	ledger, ok := apiKeyLedgers[apiKeyID]
+	if !ok {
+		ledger = &apiKeyLedger{}
+		apiKeyLedgers[apiKeyID] = ledger
+	}
+	return ledger
+}
+
+// APIKeyBalance 返回 API Key 的当前余额
+func APIKeyBalance(apiKeyID int) float64 {
+	ledger := getAPIKeyLedger(apiKeyID)
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+	return ledger.balance
+}
+
+// APIKeyBalanceReserve 请求准入时从可用余额中预留估算费用，可用余额为余额减去进行中请求的预留
+// 可用余额不足时不预留，返回 false 和当前可用余额
+func APIKeyBalanceReserve(apiKeyID int, amount float64) (float64, bool) {
+	ledger := getAPIKeyLedger(apiKeyID)
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+	available := ledger.balance - ledger.reserved
+	if available <= 0 || available < amount {
+		return available, false
+	}
+	ledger.reserved += amount
+	return available, true
+}
+
+// APIKeyBalanceRelease 释放未扣费就结束的请求的预留
+func APIKeyBalanceRelease(apiKeyID int, amount float64) {
+	if amount <= 0 {
+		return
+	}
+	ledger := getAPIKeyLedger(apiKeyID)
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+	ledger.release(amount)
+}
+
+func (l *apiKeyLedger) release(amount float64) {
+	l.reserved = max(l.reserved-amount, 0)
+}
+
+// APIKeyTransactionCreate 追加一条流水并更新余额，tx 的 Balance 和 Time 由此计算
+func APIKeyTransactionCreate(ctx context.Context, tx *model.APIKeyTransaction) error {
+	if !tx.Type.IsValid() {
+		return fmt.Errorf("invalid transaction type: %s", tx.Type)
+	}
+	ledger := getAPIKeyLedger(tx.APIKeyID)
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+	return ledger.append(ctx, tx)
+}
+
+// append 写入流水，调用方需持有 l.lock 保证余额连续
+func (l *apiKeyLedger) append(ctx context.Context, tx *model.APIKeyTransaction) error {
+	tx.ID = 0
+	tx.Time = time.Now().Unix()
+	tx.Balance = l.balance + tx.Amount
+	if err := db.GetDB().WithContext(ctx).Create(tx).Error; err != nil {
+		return fmt.Errorf("failed to create api key transaction: %w", err)
+	}
+	l.balance = tx.Balance
+	return nil
+}
+
+// APIKeyDebit 按请求的实际费用扣减预付费 API Key 的余额，同时释放准入时的预留 reserved
+// 非预付费或费用为零时不记录流水
+func APIKeyDebit(ctx context.Context, apiKeyID int, modelName string, cost, reserved float64) error {
+	apiKey, err := APIKeyGet(apiKeyID, ctx)
+	prepaid := err == nil && apiKey.Prepaid
+	ledger := getAPIKeyLedger(apiKeyID)
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+	ledger.release(reserved)
+	if cost <= 0 || !prepaid {
+		return nil
+	}
+	return ledger.append(ctx, &model.APIKeyTransaction{
+		APIKeyID: apiKeyID,
+		Type:     model.TransactionTypeDebit,
+		Amount:   -cost,
+		Model:    modelName,
+	})
+}
+
+// APIKeyTransactionList 按时间倒序分页返回 API Key 的流水
+func APIKeyTransactionList(ctx context.Context, apiKeyID int, page, pageSize int) ([]model.APIKeyTransaction, int64, error) {
+	query := db.GetDB().WithContext(ctx).Model(&model.APIKeyTransaction{}).Where("api_key_id = ?", apiKeyID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count api key transactions: %w", err)
+	}
+	var transactions []model.APIKeyTransaction
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&transactions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list api key transactions: %w", err)
+	}
+	return transactions, total, nil
+}
+
+// apiKeyBalanceRefreshCache 启动时载入每个 API Key 最后一条流水的余额
+// 已有的账本原地更新，进行中请求的预留保持不变
+func apiKeyBalanceRefreshCache(ctx context.Context) error {
+	dbConn := db.GetDB().WithContext(ctx)
+	var latest []model.APIKeyTransaction
+	lastIDs := dbConn.Model(&model.APIKeyTransaction{}).Select("MAX(id)").Group("api_key_id")
+	if err := dbConn.Where("id IN (?)", lastIDs).Find(&latest).Error; err != nil {
+		return fmt.Errorf("failed to get api key balances: %w", err)
+	}
+	balances := make(map[int]float64, len(latest))
+	for _, tx := range latest {
+		balances[tx.APIKeyID] = tx.Balance
+	}
+
+	apiKeyLedgersLock.Lock()
+	for id := range balances {
+		if _, ok := apiKeyLedgers[id]; !ok {
+			apiKeyLedgers[id] = &apiKeyLedger{}
+		}
+	}
+	ledgers := make(map[int]*apiKeyLedger, len(apiKeyLedgers))
+	for id, ledger := range apiKeyLedgers {
+		ledgers[id] = ledger
+	}
+	apiKeyLedgersLock.Unlock()
+
+	for id, ledger := range ledgers {
+		ledger.lock.Lock()
+		ledger.balance = balances[id]
+		ledger.lock.Unlock()
+	}
+	return nil
+}
+
+// apiKeyTransactionDel 删除 API Key 时一并删除其流水，避免复用的 ID 继承余额
+func apiKeyTransactionDel(ctx context.Context, apiKeyID int) error {
+	ledger := getAPIKeyLedger(apiKeyID)
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+	if err := db.GetDB().WithContext(ctx).Where("api_key_id = ?", apiKeyID).Delete(&model.APIKeyTransaction{}).Error; err != nil {
+		return fmt.Errorf("failed to delete api key transactions: %w", err)
+	}
+	apiKeyLedgersLock.Lock()
+	delete(apiKeyLedgers, apiKeyID)
+	apiKeyLedgersLock.Unlock()
+	return nil
+}
@@ -0,0 +1,149 @@
+package op
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+func newTestPrepaidKey(t *testing.T) model.APIKey {
+	t.Helper()
+	key := model.APIKey{Name: t.Name(), APIKey: "sk-octopus-" + t.Name(), Enabled: true, Prepaid: true}
+	if err := APIKeyCreate(&key, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { APIKeyDelete(key.ID, context.Background()) })
+	return key
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestAPIKeyLedgerRunningBalance(t *testing.T) {
+	ctx := context.Background()
+	key := newTestPrepaidKey(t)
+
+	if err := APIKeyTransactionCreate(ctx, &model.APIKeyTransaction{APIKeyID: key.ID, Type: model.TransactionTypeTopUp, Amount: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := APIKeyDebit(ctx, key.ID, "gpt-4o", 1.5, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := APIKeyTransactionCreate(ctx, &model.APIKeyTransaction{APIKeyID: key.ID, Type: model.TransactionTypeTopUp, Amount: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := APIKeyDebit(ctx, key.ID, "gpt-4o", 0.25, 0); err != nil {
+		t.Fatal(err)
+	}
+	// 零费用不记录流水
+	if err := APIKeyDebit(ctx, key.ID, "gpt-4o", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := APIKeyBalance(key.ID); !approxEqual(got, 13.25) {
+		t.Fatalf("balance = %v, want 13.25", got)
+	}
+	transactions, total, err := APIKeyTransactionList(ctx, key.ID, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 4 {
+		t.Fatalf("total = %d, want 4", total)
+	}
+	// 倒序返回，每条的 Balance 为该流水之后的余额
+	want := []struct {
+		typ     model.TransactionType
+		amount  float64
+		balance float64
+	}{
+		{model.TransactionTypeDebit, -0.25, 13.25},
+		{model.TransactionTypeTopUp, 5, 13.5},
+		{model.TransactionTypeDebit, -1.5, 8.5},
+		{model.TransactionTypeTopUp, 10, 10},
+	}
+	for i, w := range want {
+		tx := transactions[i]
+		if tx.Type != w.typ || !approxEqual(tx.Amount, w.amount) || !approxEqual(tx.Balance, w.balance) {
+			t.Errorf("transaction %d = %s %v -> %v, want %s %v -> %v", i, tx.Type, tx.Amount, tx.Balance, w.typ, w.amount, w.balance)
+		}
+	}
+	if transactions[0].Model != "gpt-4o" {
+		t.Errorf("debit model = %q, want gpt-4o", transactions[0].Model)
+	}
+
+	// 重新载入缓存后余额来自最后一条流水
+	if err := apiKeyBalanceRefreshCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := APIKeyBalance(key.ID); !approxEqual(got, 13.25) {
+		t.Fatalf("balance after refresh = %v, want 13.25", got)
+	}
+}
+
+func TestAPIKeyDebitSkipsNonPrepaid(t *testing.T) {
+	ctx := context.Background()
+	key := model.APIKey{Name: t.Name(), APIKey: "sk-octopus-" + t.Name(), Enabled: true}
+	if err := APIKeyCreate(&key, ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { APIKeyDelete(key.ID, ctx) })
+
+	if err := APIKeyDebit(ctx, key.ID, "gpt-4o", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := APIKeyTransactionList(ctx, key.ID, 1, 10); total != 0 {
+		t.Fatalf("non-prepaid key has %d transactions", total)
+	}
+}
+
+func TestAPIKeyTransactionCreateInvalidType(t *testing.T) {
+	key := newTestPrepaidKey(t)
+	err := APIKeyTransactionCreate(context.Background(), &model.APIKeyTransaction{APIKeyID: key.ID, Type: "gift", Amount: 1})
+	if err == nil {
+		t.Fatal("expected error for invalid transaction type")
+	}
+	if got := APIKeyBalance(key.ID); got != 0 {
+		t.Fatalf("balance = %v, want 0", got)
+	}
+}
+
+func TestAPIKeyBalanceReserve(t *testing.T) {
+	ctx := context.Background()
+	key := newTestPrepaidKey(t)
+	if err := APIKeyTransactionCreate(ctx, &model.APIKeyTransaction{APIKeyID: key.ID, Type: model.TransactionTypeTopUp, Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个进行中的请求各预留 0.4，第三个请求可用余额不足
+	for i := range 2 {
+		if _, ok := APIKeyBalanceReserve(key.ID, 0.4); !ok {
+			t.Fatalf("reservation %d rejected", i)
+		}
+	}
+	available, ok := APIKeyBalanceReserve(key.ID, 0.4)
+	if ok {
+		t.Fatal("reservation beyond the balance accepted")
+	}
+	if !approxEqual(available, 0.2) {
+		t.Fatalf("available = %v, want 0.2", available)
+	}
+	// 预留不改变余额
+	if got := APIKeyBalance(key.ID); got != 1 {
+		t.Fatalf("balance = %v, want 1", got)
+	}
+
+	// 第一个请求按实际费用结算，第二个请求未扣费就结束
+	if err := APIKeyDebit(ctx, key.ID, "gpt-4o", 0.1, 0.4); err != nil {
+		t.Fatal(err)
+	}
+	APIKeyBalanceRelease(key.ID, 0.4)
+	if got := APIKeyBalance(key.ID); !approxEqual(got, 0.9) {
+		t.Fatalf("balance = %v, want 0.9", got)
+	}
+	if available, ok := APIKeyBalanceReserve(key.ID, 0.9); !ok || !approxEqual(available, 0.9) {
+		t.Fatalf("reserve after settle = %v, %t, want 0.9, true", available, ok)
+	}
+}
@@ -0,0 +1,171 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm/clause"
+)
+
+type apiKeyLimitKey struct {
+	APIKeyID int
+	Metric   model.APIKeyLimitMetric
+	Window   model.APIKeyLimitWindow
+}
+
+// 每个 API Key 在所有维度与窗口上都记录用量，新增限额时当前窗口内已有的用量同样计入
+var (
+	apiKeyLimitCache           = make(map[apiKeyLimitKey]model.APIKeyLimitUsage)
+	apiKeyLimitCacheNeedUpdate = make(map[apiKeyLimitKey]struct{})
+	apiKeyLimitCacheLock       sync.Mutex
+)
+
+// apiKeyLimitUsed 返回当前窗口内的用量，调用方需持有锁
+func apiKeyLimitUsed(key apiKeyLimitKey, now time.Time) float64 {
+	usage, ok := apiKeyLimitCache[key]
+	if !ok || usage.Start != key.Window.Start(now).Unix() {
+		return 0
+	}
+	return usage.Value
+}
+
+// apiKeyLimitAdd 累加当前窗口的用量，窗口已过期时从零开始，调用方需持有锁
+func apiKeyLimitAdd(apiKeyID int, metric model.APIKeyLimitMetric, value float64, now time.Time) {
+	if value == 0 {
+		return
+	}
+	for _, window := range model.APIKeyLimitWindows {
+		key := apiKeyLimitKey{APIKeyID: apiKeyID, Metric: metric, Window: window}
+		apiKeyLimitCache[key] = model.APIKeyLimitUsage{
+			APIKeyID: apiKeyID,
+			Metric:   metric,
+			Window:   window,
+			Start:    window.Start(now).Unix(),
+			Value:    apiKeyLimitUsed(key, now) + value,
+		}
+		apiKeyLimitCacheNeedUpdate[key] = struct{}{}
+	}
+}
+
+// apiKeyLimitStates 计算 API Key 各限额的当前状态，调用方需持有锁
+func apiKeyLimitStates(apiKey model.APIKey, now time.Time) []model.APIKeyLimitState {
+	states := make([]model.APIKeyLimitState, 0, len(apiKey.Limits))
+	for _, limit := range apiKey.Limits {
+		key := apiKeyLimitKey{APIKeyID: apiKey.ID, Metric: limit.Metric, Window: limit.Window}
+		states = append(states, model.APIKeyLimitState{
+			APIKeyLimit: limit,
+			Used:        apiKeyLimitUsed(key, now),
+			ResetAt:     limit.Window.End(now).Unix(),
+		})
+	}
+	return states
+}
+
+// APIKeyLimitCheck 返回 API Key 各限额的当前状态，以及第一个已达上限的限额
+func APIKeyLimitCheck(apiKey model.APIKey) ([]model.APIKeyLimitState, *model.APIKeyLimitState) {
+	apiKeyLimitCacheLock.Lock()
+	defer apiKeyLimitCacheLock.Unlock()
+	states := apiKeyLimitStates(apiKey, time.Now())
+	return states, firstExceeded(states)
+}
+
+// APIKeyLimitAcquire 检查限额并计入一次请求，任一限额已达上限时不计数并返回该限额
+// 费用和 token 在请求完成后才能确定，单个请求可能使用量略微超出上限
+func APIKeyLimitAcquire(apiKey model.APIKey) ([]model.APIKeyLimitState, *model.APIKeyLimitState) {
+	now := time.Now()
+	apiKeyLimitCacheLock.Lock()
+	defer apiKeyLimitCacheLock.Unlock()
+	states := apiKeyLimitStates(apiKey, now)
+	if exceeded := firstExceeded(states); exceeded != nil {
+		return states, exceeded
+	}
+	apiKeyLimitAdd(apiKey.ID, model.APIKeyLimitMetricRequests, 1, now)
+	for i := range states {
+		if states[i].Metric == model.APIKeyLimitMetricRequests {
+			states[i].Used++
+		}
+	}
+	return states, nil
+}
+
+// APIKeyLimitRecord 计入请求完成后的 token 数和费用
+func APIKeyLimitRecord(apiKeyID int, tokens int64, cost float64) {
+	if apiKeyID == 0 {
+		return
+	}
+	now := time.Now()
+	apiKeyLimitCacheLock.Lock()
+	defer apiKeyLimitCacheLock.Unlock()
+	apiKeyLimitAdd(apiKeyID, model.APIKeyLimitMetricTokens, float64(tokens), now)
+	apiKeyLimitAdd(apiKeyID, model.APIKeyLimitMetricCost, cost, now)
+}
+
+func firstExceeded(states []model.APIKeyLimitState) *model.APIKeyLimitState {
+	for i := range states {
+		if states[i].Exceeded() {
+			return &states[i]
+		}
+	}
+	return nil
+}
+
+// apiKeyLimitSnapshot 取出待保存的用量
+func apiKeyLimitSnapshot() []model.APIKeyLimitUsage {
+	apiKeyLimitCacheLock.Lock()
+	defer apiKeyLimitCacheLock.Unlock()
+	usages := make([]model.APIKeyLimitUsage, 0, len(apiKeyLimitCacheNeedUpdate))
+	for key := range apiKeyLimitCacheNeedUpdate {
+		usages = append(usages, apiKeyLimitCache[key])
+	}
+	apiKeyLimitCacheNeedUpdate = make(map[apiKeyLimitKey]struct{})
+	return usages
+}
+
+func persistAPIKeyLimit(ctx context.Context, usages []model.APIKeyLimitUsage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+	if err := db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "api_key_id"}, {Name: "metric"}, {Name: "time_window"}},
+		UpdateAll: true,
+	}).Create(&usages).Error; err != nil {
+		return fmt.Errorf("failed to save api key limit usage: %w", err)
+	}
+	return nil
+}
+
+// apiKeyLimitRefreshCache 启动时载入各窗口的用量，已过期的窗口在使用时按零处理
+func apiKeyLimitRefreshCache(ctx context.Context) error {
+	var usages []model.APIKeyLimitUsage
+	if err := db.GetDB().WithContext(ctx).Find(&usages).Error; err != nil {
+		return fmt.Errorf("failed to get api key limit usage: %w", err)
+	}
+	apiKeyLimitCacheLock.Lock()
+	defer apiKeyLimitCacheLock.Unlock()
+	apiKeyLimitCache = make(map[apiKeyLimitKey]model.APIKeyLimitUsage, len(usages))
+	apiKeyLimitCacheNeedUpdate = make(map[apiKeyLimitKey]struct{})
+	for _, v := range usages {
+		apiKeyLimitCache[apiKeyLimitKey{APIKeyID: v.APIKeyID, Metric: v.Metric, Window: v.Window}] = v
+	}
+	return nil
+}
+
+// apiKeyLimitDel 删除 API Key 的全部用量记录
+func apiKeyLimitDel(ctx context.Context, apiKeyID int) error {
+	apiKeyLimitCacheLock.Lock()
+	for key := range apiKeyLimitCache {
+		if key.APIKeyID == apiKeyID {
+			delete(apiKeyLimitCache, key)
+			delete(apiKeyLimitCacheNeedUpdate, key)
+		}
+	}
+	apiKeyLimitCacheLock.Unlock()
+	if err := db.GetDB().WithContext(ctx).Where("api_key_id = ?", apiKeyID).Delete(&model.APIKeyLimitUsage{}).Error; err != nil {
+		return fmt.Errorf("failed to delete api key limit usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package op
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+func resetAPIKeyLimitCache(t *testing.T) {
+	t.Helper()
+	apiKeyLimitCacheLock.Lock()
+	apiKeyLimitCache = make(map[apiKeyLimitKey]model.APIKeyLimitUsage)
+	apiKeyLimitCacheNeedUpdate = make(map[apiKeyLimitKey]struct{})
+	apiKeyLimitCacheLock.Unlock()
+}
+
+func TestAPIKeyLimitAcquire(t *testing.T) {
+	resetAPIKeyLimitCache(t)
+	apiKey := model.APIKey{ID: 1, Limits: []model.APIKeyLimit{
+		{Metric: model.APIKeyLimitMetricRequests, Window: model.APIKeyLimitWindowDay, Max: 3},
+		{Metric: model.APIKeyLimitMetricRequests, Window: model.APIKeyLimitWindowMonth, Max: 100},
+	}}
+
+	for i := 1; i <= 3; i++ {
+		states, exceeded := APIKeyLimitAcquire(apiKey)
+		if exceeded != nil {
+			t.Fatalf("request %d rejected: %+v", i, exceeded)
+		}
+		if states[0].Used != float64(i) || states[1].Used != float64(i) {
+			t.Fatalf("request %d: used = %v/%v", i, states[0].Used, states[1].Used)
+		}
+	}
+
+	// 达到上限后拒绝且不再计数
+	states, exceeded := APIKeyLimitAcquire(apiKey)
+	if exceeded == nil || exceeded.Window != model.APIKeyLimitWindowDay {
+		t.Fatalf("exceeded = %+v, want day window", exceeded)
+	}
+	if states[1].Used != 3 {
+		t.Fatalf("rejected request counted: month used = %v", states[1].Used)
+	}
+	if _, exceeded := APIKeyLimitCheck(apiKey); exceeded == nil {
+		t.Fatal("check should report the exceeded limit")
+	}
+
+	// 其他 API Key 不受影响
+	if _, exceeded := APIKeyLimitAcquire(model.APIKey{ID: 2, Limits: apiKey.Limits}); exceeded != nil {
+		t.Fatalf("other key rejected: %+v", exceeded)
+	}
+}
+
+func TestAPIKeyLimitRecord(t *testing.T) {
+	resetAPIKeyLimitCache(t)
+	apiKey := model.APIKey{ID: 1, Limits: []model.APIKeyLimit{
+		{Metric: model.APIKeyLimitMetricTokens, Window: model.APIKeyLimitWindowHour, Max: 1000},
+		{Metric: model.APIKeyLimitMetricCost, Window: model.APIKeyLimitWindowWeek, Max: 0.5},
+	}}
+
+	APIKeyLimitRecord(apiKey.ID, 600, 0.2)
+	states, exceeded := APIKeyLimitCheck(apiKey)
+	if exceeded != nil || states[0].Used != 600 || states[1].Used != 0.2 {
+		t.Fatalf("states = %+v, exceeded = %+v", states, exceeded)
+	}
+
+	// 单个请求可以超出上限，之后的请求被拒绝
+	APIKeyLimitRecord(apiKey.ID, 600, 0.2)
+	if _, exceeded := APIKeyLimitAcquire(apiKey); exceeded == nil || exceeded.Metric != model.APIKeyLimitMetricTokens {
+		t.Fatalf("exceeded = %+v, want tokens", exceeded)
+	}
+
+	// api_key_id 为 0 (如管理端测试请求) 不计数
+	APIKeyLimitRecord(0, 100, 1)
+	if usages := apiKeyLimitSnapshot(); len(usages) != 2*len(model.APIKeyLimitWindows) {
+		t.Fatalf("snapshot has %d usages, want %d", len(usages), 2*len(model.APIKeyLimitWindows))
+	}
+	if usages := apiKeyLimitSnapshot(); len(usages) != 0 {
+		t.Fatalf("snapshot not cleared: %d usages", len(usages))
+	}
+}
+
+func TestAPIKeyLimitWindowRollover(t *testing.T) {
+	resetAPIKeyLimitCache(t)
+	apiKey := model.APIKey{ID: 1, Limits: []model.APIKeyLimit{
+		{Metric: model.APIKeyLimitMetricRequests, Window: model.APIKeyLimitWindowMinute, Max: 2},
+		{Metric: model.APIKeyLimitMetricRequests, Window: model.APIKeyLimitWindowHour, Max: 10},
+	}}
+	now := time.Date(2026, 10, 19, 10, 30, 59, 0, time.UTC)
+
+	apiKeyLimitCacheLock.Lock()
+	defer apiKeyLimitCacheLock.Unlock()
+	apiKeyLimitAdd(apiKey.ID, model.APIKeyLimitMetricRequests, 2, now)
+	states := apiKeyLimitStates(apiKey, now)
+	if firstExceeded(states) == nil {
+		t.Fatal("minute limit should be reached")
+	}
+	if want := time.Date(2026, 10, 19, 10, 31, 0, 0, time.UTC).Unix(); states[0].ResetAt != want {
+		t.Fatalf("reset at = %d, want %d", states[0].ResetAt, want)
+	}
+
+	// 下一分钟分钟窗口清零，小时窗口继续累计
+	next := now.Add(time.Second)
+	states = apiKeyLimitStates(apiKey, next)
+	if firstExceeded(states) != nil || states[0].Used != 0 || states[1].Used != 2 {
+		t.Fatalf("states after rollover = %+v", states)
+	}
+	apiKeyLimitAdd(apiKey.ID, model.APIKeyLimitMetricRequests, 1, next)
+	states = apiKeyLimitStates(apiKey, next)
+	if states[0].Used != 1 || states[1].Used != 3 {
+		t.Fatalf("states after add = %+v", states)
+	}
+}
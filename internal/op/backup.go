@@ -44,6 +44,15 @@ func DBExportAll(ctx context.Context, includeLogs, includeStats bool) (*model.DB
 	if err := conn.Find(&d.Settings).Error; err != nil {
 		return nil, fmt.Errorf("export settings: %w", err)
 	}
+	if err := conn.Find(&d.APIKeyTransactions).Error; err != nil {
+		return nil, fmt.Errorf("export api_key_transactions: %w", err)
+	}
+	if err := conn.Find(&d.NotifyTargets).Error; err != nil {
+		return nil, fmt.Errorf("export notify_targets: %w", err)
+	}
+	if err := conn.Find(&d.NotifyRules).Error; err != nil {
+		return nil, fmt.Errorf("export notify_rules: %w", err)
+	}
 
 	if includeStats {
 		if err := conn.Find(&d.StatsTotal).Error; err != nil {
@@ -58,6 +67,12 @@ func DBExportAll(ctx context.Context, includeLogs, includeStats bool) (*model.DB
 		if err := conn.Find(&d.StatsModel).Error; err != nil {
 			return nil, fmt.Errorf("export stats_model: %w", err)
 		}
+		if err := conn.Find(&d.StatsModelHourly).Error; err != nil {
+			return nil, fmt.Errorf("export stats_model_hourly: %w", err)
+		}
+		if err := conn.Find(&d.StatsAPIKeyModel).Error; err != nil {
+			return nil, fmt.Errorf("export stats_api_key_model: %w", err)
+		}
 		if err := conn.Find(&d.StatsChannel).Error; err != nil {
 			return nil, fmt.Errorf("export stats_channel: %w", err)
 		}
@@ -124,6 +139,21 @@ func DBImportIncremental(ctx context.Context, dump *model.DBDump) (*model.DBImpo
 		} else {
 			res.RowsAffected["settings"] = n
 		}
+		if n, err := createDoNothing(tx, dump.APIKeyTransactions); err != nil {
+			return fmt.Errorf("import api_key_transactions: %w", err)
+		} else {
+			res.RowsAffected["api_key_transactions"] = n
+		}
+		if n, err := createDoNothing(tx, dump.NotifyTargets); err != nil {
+			return fmt.Errorf("import notify_targets: %w", err)
+		} else {
+			res.RowsAffected["notify_targets"] = n
+		}
+		if n, err := createDoNothing(tx, dump.NotifyRules); err != nil {
+			return fmt.Errorf("import notify_rules: %w", err)
+		} else {
+			res.RowsAffected["notify_rules"] = n
+		}
 
 		if dump.IncludeStats {
 			if n, err := createUpsertAll(tx, dump.StatsTotal, []clause.Column{{Name: "id"}}); err != nil {
@@ -141,11 +171,21 @@ func DBImportIncremental(ctx context.Context, dump *model.DBDump) (*model.DBImpo
 			} else {
 				res.RowsAffected["stats_hourly"] = n
 			}
-			if n, err := createUpsertAll(tx, dump.StatsModel, []clause.Column{{Name: "id"}}); err != nil {
+			if n, err := createUpsertAll(tx, dump.StatsModel, []clause.Column{{Name: "date"}, {Name: "name"}, {Name: "channel_id"}}); err != nil {
 				return fmt.Errorf("import stats_model: %w", err)
 			} else {
 				res.RowsAffected["stats_model"] = n
 			}
+			if n, err := createUpsertAll(tx, dump.StatsModelHourly, []clause.Column{{Name: "time"}, {Name: "name"}, {Name: "channel_id"}}); err != nil {
+				return fmt.Errorf("import stats_model_hourly: %w", err)
+			} else {
+				res.RowsAffected["stats_model_hourly"] = n
+			}
+			if n, err := createUpsertAll(tx, dump.StatsAPIKeyModel, []clause.Column{{Name: "date"}, {Name: "api_key_id"}, {Name: "name"}}); err != nil {
+				return fmt.Errorf("import stats_api_key_model: %w", err)
+			} else {
+				res.RowsAffected["stats_api_key_model"] = n
+			}
 			if n, err := createUpsertAll(tx, dump.StatsChannel, []clause.Column{{Name: "channel_id"}}); err != nil {
 				return fmt.Errorf("import stats_channel: %w", err)
 			} else {
@@ -0,0 +1,213 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFileNotFound  = errors.New("file not found")
+	ErrBatchNotFound = errors.New("batch not found")
+)
+
+func FileCreate(file *model.File, ctx context.Context) error {
+	if err := db.GetDB().WithContext(ctx).Create(file).Error; err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	return nil
+}
+
+// FileGet 获取文件信息，withContent 为 false 时不加载文件内容
+func FileGet(id string, apiKeyID int, withContent bool, ctx context.Context) (*model.File, error) {
+	var file model.File
+	query := db.GetDB().WithContext(ctx).Where("id = ? AND api_key_id = ?", id, apiKeyID)
+	if !withContent {
+		query = query.Omit("content")
+	}
+	err := query.First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	return &file, nil
+}
+
+// FileList 按创建时间倒序列出文件，purpose 为空时不过滤
+func FileList(apiKeyID int, purpose string, limit int, ctx context.Context) ([]model.File, error) {
+	files := make([]model.File, 0)
+	query := db.GetDB().WithContext(ctx).Omit("content").Where("api_key_id = ?", apiKeyID)
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Find(&files).Error; err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	return files, nil
+}
+
+func FileDelete(id string, apiKeyID int, ctx context.Context) error {
+	result := db.GetDB().WithContext(ctx).Where("id = ? AND api_key_id = ?", id, apiKeyID).Delete(&model.File{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete file: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrFileNotFound
+	}
+	return nil
+}
+
+func BatchCreate(batch *model.Batch, ctx context.Context) error {
+	if err := db.GetDB().WithContext(ctx).Create(batch).Error; err != nil {
+		return fmt.Errorf("failed to create batch: %w", err)
+	}
+	return nil
+}
+
+func BatchGet(id string, apiKeyID int, ctx context.Context) (*model.Batch, error) {
+	var batch model.Batch
+	err := db.GetDB().WithContext(ctx).Where("id = ? AND api_key_id = ?", id, apiKeyID).First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	return &batch, nil
+}
+
+// BatchGetByID 不校验 API Key 获取批处理，仅供后台执行使用
+func BatchGetByID(id string, ctx context.Context) (*model.Batch, error) {
+	var batch model.Batch
+	err := db.GetDB().WithContext(ctx).Where("id = ?", id).First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	return &batch, nil
+}
+
+// BatchList 按创建时间倒序列出批处理，after 为上一页最后一个批处理的 ID
+func BatchList(apiKeyID int, after string, limit int, ctx context.Context) ([]model.Batch, error) {
+	batches := make([]model.Batch, 0)
+	query := db.GetDB().WithContext(ctx).Where("api_key_id = ?", apiKeyID)
+	if after != "" {
+		var cursor model.Batch
+		if err := db.GetDB().WithContext(ctx).Select("created_at").Where("id = ? AND api_key_id = ?", after, apiKeyID).First(&cursor).Error; err == nil {
+			query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.CreatedAt, cursor.CreatedAt, after)
+		}
+	}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&batches).Error; err != nil {
+		return nil, fmt.Errorf("failed to list batches: %w", err)
+	}
+	return batches, nil
+}
+
+// BatchListUnfinished 列出所有未结束的批处理，用于调度与重启后恢复
+func BatchListUnfinished(ctx context.Context) ([]model.Batch, error) {
+	batches := make([]model.Batch, 0)
+	err := db.GetDB().WithContext(ctx).
+		Where("status IN ?", []model.BatchStatus{
+			model.BatchStatusValidating,
+			model.BatchStatusInProgress,
+			model.BatchStatusFinalizing,
+			model.BatchStatusCancelling,
+		}).
+		Order("created_at ASC").
+		Find(&batches).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unfinished batches: %w", err)
+	}
+	return batches, nil
+}
+
+func BatchUpdate(batch *model.Batch, ctx context.Context) error {
+	if err := db.GetDB().WithContext(ctx).Save(batch).Error; err != nil {
+		return fmt.Errorf("failed to update batch: %w", err)
+	}
+	return nil
+}
+
+// BatchUpdateCounts 仅更新请求计数，避免覆盖并发修改的状态
+func BatchUpdateCounts(id string, counts model.BatchRequestCounts, ctx context.Context) error {
+	err := db.GetDB().WithContext(ctx).Model(&model.Batch{}).Where("id = ?", id).Updates(map[string]any{
+		"request_total":     counts.Total,
+		"request_completed": counts.Completed,
+		"request_failed":    counts.Failed,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update batch counts: %w", err)
+	}
+	return nil
+}
+
+// BatchMarkInProgress 将校验通过的批处理标记为 in_progress，已被取消时返回 false
+func BatchMarkInProgress(id string, total int, now int64, ctx context.Context) (bool, error) {
+	result := db.GetDB().WithContext(ctx).Model(&model.Batch{}).
+		Where("id = ? AND status = ?", id, model.BatchStatusValidating).
+		Updates(map[string]any{
+			"status":         model.BatchStatusInProgress,
+			"in_progress_at": now,
+			"request_total":  total,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to start batch: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// BatchCancel 将未结束的批处理标记为 cancelling，返回更新后的批处理
+func BatchCancel(id string, apiKeyID int, now int64, ctx context.Context) (*model.Batch, error) {
+	result := db.GetDB().WithContext(ctx).Model(&model.Batch{}).
+		Where("id = ? AND api_key_id = ? AND status IN ?", id, apiKeyID, []model.BatchStatus{
+			model.BatchStatusValidating,
+			model.BatchStatusInProgress,
+		}).
+		Updates(map[string]any{
+			"status":        model.BatchStatusCancelling,
+			"cancelling_at": now,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel batch: %w", result.Error)
+	}
+	return BatchGet(id, apiKeyID, ctx)
+}
+
+// BatchStatusGet 读取批处理当前状态
+func BatchStatusGet(id string, ctx context.Context) (model.BatchStatus, error) {
+	var batch model.Batch
+	if err := db.GetDB().WithContext(ctx).Select("status").Where("id = ?", id).First(&batch).Error; err != nil {
+		return "", fmt.Errorf("failed to get batch status: %w", err)
+	}
+	return batch.Status, nil
+}
+
+func BatchResultSave(result *model.BatchResult, ctx context.Context) error {
+	if err := db.GetDB().WithContext(ctx).Create(result).Error; err != nil {
+		return fmt.Errorf("failed to save batch result: %w", err)
+	}
+	return nil
+}
+
+func BatchResultList(batchID string, ctx context.Context) ([]model.BatchResult, error) {
+	results := make([]model.BatchResult, 0)
+	if err := db.GetDB().WithContext(ctx).Where("batch_id = ?", batchID).Order("line ASC").Find(&results).Error; err != nil {
+		return nil, fmt.Errorf("failed to list batch results: %w", err)
+	}
+	return results, nil
+}
+
+func BatchResultDelete(batchID string, ctx context.Context) error {
+	if err := db.GetDB().WithContext(ctx).Where("batch_id = ?", batchID).Delete(&model.BatchResult{}).Error; err != nil {
+		return fmt.Errorf("failed to delete batch results: %w", err)
+	}
+	return nil
+}
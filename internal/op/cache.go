@@ -21,12 +21,18 @@ func InitCache() error {
 	if err := apiKeyRefreshCache(ctx); err != nil {
 		return fmt.Errorf("api key refresh cache error: %v", err)
 	}
+	if err := apiKeyBalanceRefreshCache(ctx); err != nil {
+		return fmt.Errorf("api key balance refresh cache error: %v", err)
+	}
 	if err := llmRefreshCache(ctx); err != nil {
 		return fmt.Errorf("llm refresh cache error: %v", err)
 	}
 	if err := statsRefreshCache(ctx); err != nil {
 		return fmt.Errorf("stats refresh cache error: %v", err)
 	}
+	if err := notifyRefreshCache(ctx); err != nil {
+		return fmt.Errorf("notify refresh cache error: %v", err)
+	}
 	return nil
 }
 
@@ -177,6 +177,38 @@ func ChannelUpdate(req *model.ChannelUpdateRequest, ctx context.Context) (*model
 		selectFields = append(selectFields, "match_regex")
 		updates.MatchRegex = req.MatchRegex
 	}
+	if req.ModelMapping != nil {
+		selectFields = append(selectFields, "model_mapping")
+		updates.ModelMapping = req.ModelMapping
+	}
+	if req.PromptToolCall != nil {
+		selectFields = append(selectFields, "prompt_tool_call")
+		updates.PromptToolCall = *req.PromptToolCall
+	}
+	if req.MediaMode != nil {
+		selectFields = append(selectFields, "media_mode")
+		updates.MediaMode = *req.MediaMode
+	}
+	if req.StreamMode != nil {
+		selectFields = append(selectFields, "stream_mode")
+		updates.StreamMode = *req.StreamMode
+	}
+	if req.PromptCache != nil {
+		selectFields = append(selectFields, "prompt_cache")
+		updates.PromptCache = *req.PromptCache
+	}
+	if req.PromptCacheMinTokens != nil {
+		selectFields = append(selectFields, "prompt_cache_min_tokens")
+		updates.PromptCacheMinTokens = *req.PromptCacheMinTokens
+	}
+	if req.PriceMultiplier != nil {
+		selectFields = append(selectFields, "price_multiplier")
+		updates.PriceMultiplier = req.PriceMultiplier
+	}
+	if req.PriceOverrides != nil {
+		selectFields = append(selectFields, "price_overrides")
+		updates.PriceOverrides = *req.PriceOverrides
+	}
 
 	// 只有当有字段需要更新时才执行 UPDATE
 	if len(selectFields) > 0 {
@@ -88,6 +88,18 @@ func GroupUpdate(req *model.GroupUpdateRequest, ctx context.Context) (*model.Gro
 		selectFields = append(selectFields, "first_token_time_out")
 		updates.FirstTokenTimeOut = *req.FirstTokenTimeOut
 	}
+	if req.StructuredOutput != nil {
+		selectFields = append(selectFields, "structured_output")
+		updates.StructuredOutput = *req.StructuredOutput
+	}
+	if req.StructuredOutputRetries != nil {
+		selectFields = append(selectFields, "structured_output_retries")
+		updates.StructuredOutputRetries = *req.StructuredOutputRetries
+	}
+	if req.ReasoningPolicy != nil {
+		selectFields = append(selectFields, "reasoning_policy")
+		updates.ReasoningPolicy = *req.ReasoningPolicy
+	}
 
 	if len(selectFields) > 0 {
 		if err := tx.Model(&model.Group{}).Where("id = ?", req.ID).Select(selectFields).Updates(&updates).Error; err != nil {
@@ -28,7 +28,7 @@ func LLMUpdate(model model.LLMInfo, ctx context.Context) error {
 	if !ok {
 		return fmt.Errorf("model not found")
 	}
-	if err := db.GetDB().WithContext(ctx).Save(model).Error; err != nil {
+	if err := db.GetDB().WithContext(ctx).Save(&model).Error; err != nil {
 		return err
 	}
 	llmModelCache.Set(model.Name, model.LLMPrice)
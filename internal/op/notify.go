@@ -0,0 +1,180 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/utils/cache"
+)
+
+var notifyTargetCache = cache.New[int, model.NotifyTarget](16)
+var notifyRuleCache = cache.New[int, model.NotifyRule](16)
+
+func NotifyTargetList(ctx context.Context) ([]model.NotifyTarget, error) {
+	targets := make([]model.NotifyTarget, 0, notifyTargetCache.Len())
+	for _, target := range notifyTargetCache.GetAll() {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+	return targets, nil
+}
+
+func NotifyTargetGet(id int, ctx context.Context) (model.NotifyTarget, error) {
+	target, ok := notifyTargetCache.Get(id)
+	if !ok {
+		return model.NotifyTarget{}, fmt.Errorf("notify target not found")
+	}
+	return target, nil
+}
+
+func NotifyTargetCreate(target *model.NotifyTarget, ctx context.Context) error {
+	if err := db.GetDB().WithContext(ctx).Create(target).Error; err != nil {
+		return fmt.Errorf("failed to create notify target: %w", err)
+	}
+	notifyTargetCache.Set(target.ID, *target)
+	return nil
+}
+
+func NotifyTargetUpdate(target *model.NotifyTarget, ctx context.Context) error {
+	if _, ok := notifyTargetCache.Get(target.ID); !ok {
+		return fmt.Errorf("notify target not found")
+	}
+	if err := db.GetDB().WithContext(ctx).Save(target).Error; err != nil {
+		return fmt.Errorf("failed to update notify target: %w", err)
+	}
+	notifyTargetCache.Set(target.ID, *target)
+	return nil
+}
+
+// NotifyTargetDelete 删除通知渠道，并从引用它的规则中移除
+func NotifyTargetDelete(id int, ctx context.Context) error {
+	result := db.GetDB().WithContext(ctx).Delete(&model.NotifyTarget{ID: id})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete notify target: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("notify target not found")
+	}
+	notifyTargetCache.Del(id)
+	for _, rule := range notifyRuleCache.GetAll() {
+		targetIDs := make([]int, 0, len(rule.TargetIDs))
+		for _, targetID := range rule.TargetIDs {
+			if targetID != id {
+				targetIDs = append(targetIDs, targetID)
+			}
+		}
+		if len(targetIDs) == len(rule.TargetIDs) {
+			continue
+		}
+		rule.TargetIDs = targetIDs
+		if err := db.GetDB().WithContext(ctx).Save(&rule).Error; err != nil {
+			return fmt.Errorf("failed to update notify rule: %w", err)
+		}
+		notifyRuleCache.Set(rule.ID, rule)
+	}
+	return nil
+}
+
+func NotifyRuleList(ctx context.Context) ([]model.NotifyRule, error) {
+	rules := make([]model.NotifyRule, 0, notifyRuleCache.Len())
+	for _, rule := range notifyRuleCache.GetAll() {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+// NotifyRuleListByEvent 返回指定事件已启用的规则
+func NotifyRuleListByEvent(event model.NotifyEvent) []model.NotifyRule {
+	rules := make([]model.NotifyRule, 0)
+	for _, rule := range notifyRuleCache.GetAll() {
+		if rule.Enabled && rule.Event == event {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+func NotifyRuleCreate(rule *model.NotifyRule, ctx context.Context) error {
+	if err := db.GetDB().WithContext(ctx).Create(rule).Error; err != nil {
+		return fmt.Errorf("failed to create notify rule: %w", err)
+	}
+	notifyRuleCache.Set(rule.ID, *rule)
+	return nil
+}
+
+func NotifyRuleUpdate(rule *model.NotifyRule, ctx context.Context) error {
+	if _, ok := notifyRuleCache.Get(rule.ID); !ok {
+		return fmt.Errorf("notify rule not found")
+	}
+	if err := db.GetDB().WithContext(ctx).Save(rule).Error; err != nil {
+		return fmt.Errorf("failed to update notify rule: %w", err)
+	}
+	notifyRuleCache.Set(rule.ID, *rule)
+	return nil
+}
+
+func NotifyRuleDelete(id int, ctx context.Context) error {
+	result := db.GetDB().WithContext(ctx).Delete(&model.NotifyRule{ID: id})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete notify rule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("notify rule not found")
+	}
+	notifyRuleCache.Del(id)
+	if err := db.GetDB().WithContext(ctx).Where("rule_id = ?", id).Delete(&model.NotifyDedup{}).Error; err != nil {
+		return fmt.Errorf("failed to delete notify dedup records: %w", err)
+	}
+	return nil
+}
+
+// NotifyDedupList 返回未过期的通知去重记录
+func NotifyDedupList(ctx context.Context) ([]model.NotifyDedup, error) {
+	records := []model.NotifyDedup{}
+	if err := db.GetDB().WithContext(ctx).Where("expire_at > ?", time.Now().Unix()).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to get notify dedup records: %w", err)
+	}
+	return records, nil
+}
+
+// NotifyDedupSave 写入或更新通知去重记录
+func NotifyDedupSave(record model.NotifyDedup, ctx context.Context) error {
+	if err := db.GetDB().WithContext(ctx).Save(&record).Error; err != nil {
+		return fmt.Errorf("failed to save notify dedup record: %w", err)
+	}
+	return nil
+}
+
+// NotifyDedupCleanup 删除已过期的通知去重记录
+func NotifyDedupCleanup(ctx context.Context) error {
+	if err := db.GetDB().WithContext(ctx).Where("expire_at <= ?", time.Now().Unix()).Delete(&model.NotifyDedup{}).Error; err != nil {
+		return fmt.Errorf("failed to delete notify dedup records: %w", err)
+	}
+	return nil
+}
+
+func notifyRefreshCache(ctx context.Context) error {
+	targets := []model.NotifyTarget{}
+	if err := db.GetDB().WithContext(ctx).Find(&targets).Error; err != nil {
+		return fmt.Errorf("failed to get notify targets: %w", err)
+	}
+	rules := []model.NotifyRule{}
+	if err := db.GetDB().WithContext(ctx).Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to get notify rules: %w", err)
+	}
+	notifyTargetCache.Clear()
+	for _, target := range targets {
+		notifyTargetCache.Set(target.ID, target)
+	}
+	notifyRuleCache.Clear()
+	for _, rule := range rules {
+		notifyRuleCache.Set(rule.ID, rule)
+	}
+	return nil
+}
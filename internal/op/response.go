@@ -0,0 +1,58 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm"
+)
+
+// ErrResponseNotFound 表示响应不存在或不属于当前 API Key
+var ErrResponseNotFound = errors.New("response not found")
+
+func ResponseCreate(obj *model.ResponseObject, ctx context.Context) error {
+	if err := db.GetDB().WithContext(ctx).Create(obj).Error; err != nil {
+		return fmt.Errorf("failed to create response: %w", err)
+	}
+	return nil
+}
+
+func ResponseGet(id string, apiKeyID int, ctx context.Context) (*model.ResponseObject, error) {
+	var obj model.ResponseObject
+	err := db.GetDB().WithContext(ctx).Where("id = ? AND api_key_id = ?", id, apiKeyID).First(&obj).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrResponseNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response: %w", err)
+	}
+	return &obj, nil
+}
+
+func ResponseDelete(id string, apiKeyID int, ctx context.Context) error {
+	result := db.GetDB().WithContext(ctx).Where("id = ? AND api_key_id = ?", id, apiKeyID).Delete(&model.ResponseObject{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete response: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrResponseNotFound
+	}
+	return nil
+}
+
+// ResponseCleanup 删除超出保存时间的响应，保存天数 <= 0 时不清理
+func ResponseCleanup(ctx context.Context) error {
+	keepPeriod, err := SettingGetInt(model.SettingKeyResponseKeepPeriod)
+	if err != nil {
+		return err
+	}
+	if keepPeriod <= 0 {
+		return nil
+	}
+	cutoffTime := time.Now().Add(-time.Duration(keepPeriod) * 24 * time.Hour).Unix()
+	return db.GetDB().WithContext(ctx).Where("created_at < ?", cutoffTime).Delete(&model.ResponseObject{}).Error
+}
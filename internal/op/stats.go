@@ -28,10 +28,6 @@ var statsChannelCache = cache.New[int, model.StatsChannel](16)
 var statsChannelCacheNeedUpdate = make(map[int]struct{})
 var statsChannelCacheNeedUpdateLock sync.Mutex
 
-var statsModelCache = cache.New[int, model.StatsModel](16)
-var statsModelCacheNeedUpdate = make(map[int]struct{})
-var statsModelCacheNeedUpdateLock sync.Mutex
-
 var statsAPIKeyCache = cache.New[int, model.StatsAPIKey](16)
 var statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
 var statsAPIKeyCacheNeedUpdateLock sync.Mutex
@@ -74,13 +70,7 @@ func StatsSaveDB(ctx context.Context) error {
 	statsChannelCacheNeedUpdate = make(map[int]struct{})
 	statsChannelCacheNeedUpdateLock.Unlock()
 
-	statsModelCacheNeedUpdateLock.Lock()
-	modelIDs := make([]int, 0, len(statsModelCacheNeedUpdate))
-	for id := range statsModelCacheNeedUpdate {
-		modelIDs = append(modelIDs, id)
-	}
-	statsModelCacheNeedUpdate = make(map[int]struct{})
-	statsModelCacheNeedUpdateLock.Unlock()
+	modelDaily, modelHourly := statsModelSnapshot()
 
 	statsAPIKeyCacheNeedUpdateLock.Lock()
 	apiKeyIDs := make([]int, 0, len(statsAPIKeyCacheNeedUpdate))
@@ -90,7 +80,7 @@ func StatsSaveDB(ctx context.Context) error {
 	statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
 	statsAPIKeyCacheNeedUpdateLock.Unlock()
 
-	return persistStatsSnapshots(ctx, totalSnap, dailySnap, hourlyAll, channelIDs, modelIDs, apiKeyIDs)
+	return persistStatsSnapshots(ctx, totalSnap, dailySnap, hourlyAll, channelIDs, modelDaily, modelHourly, statsAPIKeyModelSnapshot(), apiKeyIDs, apiKeyLimitSnapshot())
 }
 
 func persistStatsSnapshots(
@@ -99,8 +89,11 @@ func persistStatsSnapshots(
 	dailySnap model.StatsDaily,
 	hourlyAll [24]model.StatsHourly,
 	channelIDs []int,
-	modelIDs []int,
+	modelDaily []model.StatsModel,
+	modelHourly []model.StatsModelHourly,
+	apiKeyModel []model.StatsAPIKeyModel,
 	apiKeyIDs []int,
+	apiKeyLimits []model.APIKeyLimitUsage,
 ) error {
 	dbConn := db.GetDB().WithContext(ctx)
 
@@ -137,14 +130,11 @@ func persistStatsSnapshots(
 		}
 	}
 
-	for _, id := range modelIDs {
-		m, ok := statsModelCache.Get(id)
-		if !ok {
-			continue
-		}
-		if result := dbConn.Save(&m); result.Error != nil {
-			return result.Error
-		}
+	if err := persistStatsModel(ctx, modelDaily, modelHourly); err != nil {
+		return err
+	}
+	if err := persistStatsAPIKeyModel(ctx, apiKeyModel); err != nil {
+		return err
 	}
 
 	for _, id := range apiKeyIDs {
@@ -157,7 +147,7 @@ func persistStatsSnapshots(
 		}
 	}
 
-	return nil
+	return persistAPIKeyLimit(ctx, apiKeyLimits)
 }
 
 func statsSaveDBWithDailyOverride(ctx context.Context, dailyOverride model.StatsDaily) error {
@@ -180,13 +170,7 @@ func statsSaveDBWithDailyOverride(ctx context.Context, dailyOverride model.Stats
 	statsChannelCacheNeedUpdate = make(map[int]struct{})
 	statsChannelCacheNeedUpdateLock.Unlock()
 
-	statsModelCacheNeedUpdateLock.Lock()
-	modelIDs := make([]int, 0, len(statsModelCacheNeedUpdate))
-	for id := range statsModelCacheNeedUpdate {
-		modelIDs = append(modelIDs, id)
-	}
-	statsModelCacheNeedUpdate = make(map[int]struct{})
-	statsModelCacheNeedUpdateLock.Unlock()
+	modelDaily, modelHourly := statsModelSnapshot()
 
 	statsAPIKeyCacheNeedUpdateLock.Lock()
 	apiKeyIDs := make([]int, 0, len(statsAPIKeyCacheNeedUpdate))
@@ -196,7 +180,7 @@ func statsSaveDBWithDailyOverride(ctx context.Context, dailyOverride model.Stats
 	statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
 	statsAPIKeyCacheNeedUpdateLock.Unlock()
 
-	return persistStatsSnapshots(ctx, totalSnap, dailyOverride, hourlyAll, channelIDs, modelIDs, apiKeyIDs)
+	return persistStatsSnapshots(ctx, totalSnap, dailyOverride, hourlyAll, channelIDs, modelDaily, modelHourly, statsAPIKeyModelSnapshot(), apiKeyIDs, apiKeyLimitSnapshot())
 }
 
 func StatsDailyUpdate(ctx context.Context, metrics model.StatsMetrics) error {
@@ -261,21 +245,6 @@ func StatsHourlyUpdate(metrics model.StatsMetrics) error {
 	return nil
 }
 
-func StatsModelUpdate(stats model.StatsModel) error {
-	modelCache, ok := statsModelCache.Get(stats.ID)
-	if !ok {
-		modelCache = model.StatsModel{
-			ID: stats.ID,
-		}
-	}
-	modelCache.StatsMetrics.Add(stats.StatsMetrics)
-	statsModelCache.Set(stats.ID, modelCache)
-	statsModelCacheNeedUpdateLock.Lock()
-	statsModelCacheNeedUpdate[stats.ID] = struct{}{}
-	statsModelCacheNeedUpdateLock.Unlock()
-	return nil
-}
-
 func StatsAPIKeyUpdate(apiKeyID int, metrics model.StatsMetrics) error {
 	apiKeyCache, ok := statsAPIKeyCache.Get(apiKeyID)
 	if !ok {
@@ -284,6 +253,7 @@ func StatsAPIKeyUpdate(apiKeyID int, metrics model.StatsMetrics) error {
 		}
 	}
 	apiKeyCache.StatsMetrics.Add(metrics)
+	apiKeyCache.QuotaCost += metrics.InputCost + metrics.OutputCost
 	statsAPIKeyCache.Set(apiKeyID, apiKeyCache)
 	statsAPIKeyCacheNeedUpdateLock.Lock()
 	statsAPIKeyCacheNeedUpdate[apiKeyID] = struct{}{}
@@ -291,10 +261,15 @@ func StatsAPIKeyUpdate(apiKeyID int, metrics model.StatsMetrics) error {
 	return nil
 }
 
+// StatsAPIKeyReset 重置额度，只清零 QuotaCost，历史统计保留
 func StatsAPIKeyReset(apiKeyID int) error {
-	apiKeyCache := model.StatsAPIKey{
-		APIKeyID: apiKeyID,
+	apiKeyCache, ok := statsAPIKeyCache.Get(apiKeyID)
+	if !ok {
+		apiKeyCache = model.StatsAPIKey{
+			APIKeyID: apiKeyID,
+		}
 	}
+	apiKeyCache.QuotaCost = 0
 	statsAPIKeyCache.Set(apiKeyID, apiKeyCache)
 
 	// Immediately save to DB
@@ -488,5 +463,11 @@ func statsRefreshCache(ctx context.Context) error {
 	}
 	statsHourlyCacheLock.Unlock()
 
-	return nil
+	if err := statsModelRefreshCache(ctx); err != nil {
+		return err
+	}
+	if err := statsAPIKeyModelRefreshCache(ctx); err != nil {
+		return err
+	}
+	return apiKeyLimitRefreshCache(ctx)
 }
@@ -0,0 +1,342 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm/clause"
+)
+
+type statsModelKey struct {
+	Date      string
+	Name      string
+	ChannelID int
+}
+
+type statsModelHourlyKey struct {
+	Time      int64
+	Name      string
+	ChannelID int
+}
+
+// 缓存当天及当前小时的累计值，跨天或跨小时后在下一次保存时写入数据库并移出缓存
+var (
+	statsModelCache            = make(map[statsModelKey]model.StatsModel)
+	statsModelHourlyCache      = make(map[statsModelHourlyKey]model.StatsModelHourly)
+	statsModelCacheNeedUpdate  = make(map[statsModelKey]struct{})
+	statsModelHourlyNeedUpdate = make(map[statsModelHourlyKey]struct{})
+	statsModelCacheLock        sync.Mutex
+)
+
+// StatsModelUpdate 累加模型在渠道上的统计，同时计入当天和当前小时
+func StatsModelUpdate(name string, channelID int, metrics model.StatsMetrics) error {
+	now := time.Now()
+	dayKey := statsModelKey{Date: now.Format("20060102"), Name: name, ChannelID: channelID}
+	hourKey := statsModelHourlyKey{Time: hourStart(now), Name: name, ChannelID: channelID}
+
+	statsModelCacheLock.Lock()
+	defer statsModelCacheLock.Unlock()
+
+	daily, ok := statsModelCache[dayKey]
+	if !ok {
+		daily = model.StatsModel{Date: dayKey.Date, Name: name, ChannelID: channelID}
+	}
+	daily.StatsMetrics.Add(metrics)
+	statsModelCache[dayKey] = daily
+	statsModelCacheNeedUpdate[dayKey] = struct{}{}
+
+	hourly, ok := statsModelHourlyCache[hourKey]
+	if !ok {
+		hourly = model.StatsModelHourly{Time: hourKey.Time, Name: name, ChannelID: channelID}
+	}
+	hourly.StatsMetrics.Add(metrics)
+	statsModelHourlyCache[hourKey] = hourly
+	statsModelHourlyNeedUpdate[hourKey] = struct{}{}
+	return nil
+}
+
+// statsModelSnapshot 取出待保存的记录，并移除已经过去的天和小时
+func statsModelSnapshot() ([]model.StatsModel, []model.StatsModelHourly) {
+	now := time.Now()
+	today := now.Format("20060102")
+	currentHour := hourStart(now)
+
+	statsModelCacheLock.Lock()
+	defer statsModelCacheLock.Unlock()
+
+	daily := make([]model.StatsModel, 0, len(statsModelCacheNeedUpdate))
+	for key := range statsModelCacheNeedUpdate {
+		daily = append(daily, statsModelCache[key])
+	}
+	statsModelCacheNeedUpdate = make(map[statsModelKey]struct{})
+	for key := range statsModelCache {
+		if key.Date != today {
+			delete(statsModelCache, key)
+		}
+	}
+
+	hourly := make([]model.StatsModelHourly, 0, len(statsModelHourlyNeedUpdate))
+	for key := range statsModelHourlyNeedUpdate {
+		hourly = append(hourly, statsModelHourlyCache[key])
+	}
+	statsModelHourlyNeedUpdate = make(map[statsModelHourlyKey]struct{})
+	for key := range statsModelHourlyCache {
+		if key.Time != currentHour {
+			delete(statsModelHourlyCache, key)
+		}
+	}
+	return daily, hourly
+}
+
+// statsModelRefreshCache 启动时载入当天和当前小时的记录，之后在其基础上累加
+func statsModelRefreshCache(ctx context.Context) error {
+	now := time.Now()
+	dbConn := db.GetDB().WithContext(ctx)
+
+	var daily []model.StatsModel
+	if err := dbConn.Where("date = ?", now.Format("20060102")).Find(&daily).Error; err != nil {
+		return fmt.Errorf("failed to get model stats: %w", err)
+	}
+	var hourly []model.StatsModelHourly
+	if err := dbConn.Where("time = ?", hourStart(now)).Find(&hourly).Error; err != nil {
+		return fmt.Errorf("failed to get model hourly stats: %w", err)
+	}
+
+	statsModelCacheLock.Lock()
+	defer statsModelCacheLock.Unlock()
+	statsModelCache = make(map[statsModelKey]model.StatsModel, len(daily))
+	statsModelCacheNeedUpdate = make(map[statsModelKey]struct{})
+	for _, v := range daily {
+		statsModelCache[statsModelKey{Date: v.Date, Name: v.Name, ChannelID: v.ChannelID}] = v
+	}
+	statsModelHourlyCache = make(map[statsModelHourlyKey]model.StatsModelHourly, len(hourly))
+	statsModelHourlyNeedUpdate = make(map[statsModelHourlyKey]struct{})
+	for _, v := range hourly {
+		statsModelHourlyCache[statsModelHourlyKey{Time: v.Time, Name: v.Name, ChannelID: v.ChannelID}] = v
+	}
+	return nil
+}
+
+// StatsModelHourlyCleanup 删除超过保存时间的小时数据，按天的数据永久保留
+func StatsModelHourlyCleanup(ctx context.Context) error {
+	keepDays, err := SettingGetInt(model.SettingKeyStatsModelHourlyKeep)
+	if err != nil {
+		return err
+	}
+	if keepDays <= 0 {
+		return nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -keepDays).Unix()
+	return db.GetDB().WithContext(ctx).Where("time < ?", cutoff).Delete(&model.StatsModelHourly{}).Error
+}
+
+// StatsModelQuery 按模型统计的查询条件，Name 和 ChannelID 为空时不过滤
+type StatsModelQuery struct {
+	Start     time.Time
+	End       time.Time
+	Name      string
+	ChannelID int
+}
+
+// StatsModelSummaryList 汇总时间范围内各模型的用量，byChannel 为 true 时按 模型 × 渠道 拆分
+// 按天统计的数据覆盖 [Start, End] 所在的每一天，结果按费用从高到低排序
+func StatsModelSummaryList(ctx context.Context, query StatsModelQuery, byChannel bool) ([]model.StatsModelSummary, error) {
+	rows, err := statsModelDailyRows(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	type summaryKey struct {
+		Name      string
+		ChannelID int
+	}
+	merged := make(map[summaryKey]*model.StatsModelSummary)
+	for _, row := range rows {
+		key := summaryKey{Name: row.Name}
+		if byChannel {
+			key.ChannelID = row.ChannelID
+		}
+		summary, ok := merged[key]
+		if !ok {
+			summary = &model.StatsModelSummary{Name: key.Name, ChannelID: key.ChannelID}
+			merged[key] = summary
+		}
+		summary.StatsMetrics.Add(row.StatsMetrics)
+	}
+
+	result := make([]model.StatsModelSummary, 0, len(merged))
+	for _, summary := range merged {
+		if requests := summary.Requests(); requests > 0 {
+			summary.SuccessRate = float64(summary.RequestSuccess) / float64(requests)
+			summary.AvgLatency = summary.WaitTime / requests
+		}
+		result = append(result, *summary)
+	}
+	slices.SortFunc(result, func(a, b model.StatsModelSummary) int {
+		costA, costB := a.InputCost+a.OutputCost, b.InputCost+b.OutputCost
+		switch {
+		case costA > costB:
+			return -1
+		case costA < costB:
+			return 1
+		case a.Name != b.Name:
+			if a.Name < b.Name {
+				return -1
+			}
+			return 1
+		default:
+			return a.ChannelID - b.ChannelID
+		}
+	})
+	return result, nil
+}
+
+// StatsModelSeries 返回时间范围内按天或按小时的用量序列，没有数据的时间点不返回
+func StatsModelSeries(ctx context.Context, query StatsModelQuery, hourly bool) ([]model.StatsModelPoint, error) {
+	points := make(map[int64]*model.StatsModelPoint)
+	add := func(t int64, metrics model.StatsMetrics) {
+		point, ok := points[t]
+		if !ok {
+			point = &model.StatsModelPoint{Time: t}
+			points[t] = point
+		}
+		point.StatsMetrics.Add(metrics)
+	}
+
+	if hourly {
+		rows, err := statsModelHourlyRows(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			add(row.Time, row.StatsMetrics)
+		}
+	} else {
+		rows, err := statsModelDailyRows(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			day, err := time.ParseInLocation("20060102", row.Date, time.Local)
+			if err != nil {
+				continue
+			}
+			add(day.Unix(), row.StatsMetrics)
+		}
+	}
+
+	result := make([]model.StatsModelPoint, 0, len(points))
+	for _, point := range points {
+		result = append(result, *point)
+	}
+	slices.SortFunc(result, func(a, b model.StatsModelPoint) int {
+		return int(a.Time - b.Time)
+	})
+	return result, nil
+}
+
+// statsModelDailyRows 查询数据库中的按天记录，缓存中尚未保存的当天数据覆盖数据库中的同一条记录
+func statsModelDailyRows(ctx context.Context, query StatsModelQuery) ([]model.StatsModel, error) {
+	startDate, endDate := query.Start.Format("20060102"), query.End.Format("20060102")
+	dbQuery := db.GetDB().WithContext(ctx).Where("date >= ? AND date <= ?", startDate, endDate)
+	if query.Name != "" {
+		dbQuery = dbQuery.Where("name = ?", query.Name)
+	}
+	if query.ChannelID != 0 {
+		dbQuery = dbQuery.Where("channel_id = ?", query.ChannelID)
+	}
+	var rows []model.StatsModel
+	if err := dbQuery.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query model stats: %w", err)
+	}
+
+	statsModelCacheLock.Lock()
+	defer statsModelCacheLock.Unlock()
+	for i, row := range rows {
+		if cached, ok := statsModelCache[statsModelKey{Date: row.Date, Name: row.Name, ChannelID: row.ChannelID}]; ok {
+			rows[i] = cached
+		}
+	}
+	for key := range statsModelCacheNeedUpdate {
+		if key.Date < startDate || key.Date > endDate || !query.match(key.Name, key.ChannelID) {
+			continue
+		}
+		if !slices.ContainsFunc(rows, func(row model.StatsModel) bool {
+			return row.Date == key.Date && row.Name == key.Name && row.ChannelID == key.ChannelID
+		}) {
+			rows = append(rows, statsModelCache[key])
+		}
+	}
+	return rows, nil
+}
+
+// statsModelHourlyRows 查询数据库中的按小时记录，合并方式同 statsModelDailyRows
+func statsModelHourlyRows(ctx context.Context, query StatsModelQuery) ([]model.StatsModelHourly, error) {
+	start, end := hourStart(query.Start), query.End.Unix()
+	dbQuery := db.GetDB().WithContext(ctx).Where("time >= ? AND time <= ?", start, end)
+	if query.Name != "" {
+		dbQuery = dbQuery.Where("name = ?", query.Name)
+	}
+	if query.ChannelID != 0 {
+		dbQuery = dbQuery.Where("channel_id = ?", query.ChannelID)
+	}
+	var rows []model.StatsModelHourly
+	if err := dbQuery.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query model hourly stats: %w", err)
+	}
+
+	statsModelCacheLock.Lock()
+	defer statsModelCacheLock.Unlock()
+	for i, row := range rows {
+		if cached, ok := statsModelHourlyCache[statsModelHourlyKey{Time: row.Time, Name: row.Name, ChannelID: row.ChannelID}]; ok {
+			rows[i] = cached
+		}
+	}
+	for key := range statsModelHourlyNeedUpdate {
+		if key.Time < start || key.Time > end || !query.match(key.Name, key.ChannelID) {
+			continue
+		}
+		if !slices.ContainsFunc(rows, func(row model.StatsModelHourly) bool {
+			return row.Time == key.Time && row.Name == key.Name && row.ChannelID == key.ChannelID
+		}) {
+			rows = append(rows, statsModelHourlyCache[key])
+		}
+	}
+	return rows, nil
+}
+
+func (q StatsModelQuery) match(name string, channelID int) bool {
+	return (q.Name == "" || q.Name == name) && (q.ChannelID == 0 || q.ChannelID == channelID)
+}
+
+// persistStatsModel 以主键覆盖写入缓存中的累计值
+func persistStatsModel(ctx context.Context, daily []model.StatsModel, hourly []model.StatsModelHourly) error {
+	dbConn := db.GetDB().WithContext(ctx)
+	if len(daily) > 0 {
+		if result := dbConn.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "date"}, {Name: "name"}, {Name: "channel_id"}},
+			UpdateAll: true,
+		}).Create(&daily); result.Error != nil {
+			return result.Error
+		}
+	}
+	if len(hourly) > 0 {
+		if result := dbConn.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "time"}, {Name: "name"}, {Name: "channel_id"}},
+			UpdateAll: true,
+		}).Create(&hourly); result.Error != nil {
+			return result.Error
+		}
+	}
+	return nil
+}
+
+// hourStart 所在小时开始的时间戳（秒）
+func hourStart(t time.Time) int64 {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Unix()
+}
@@ -0,0 +1,188 @@
+package op
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+func TestStatsModelSummaryList(t *testing.T) {
+	ctx := context.Background()
+	name := "summary-" + t.Name()
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	// 已保存的前一天数据
+	persisted := model.StatsModel{Date: yesterday.Format("20060102"), Name: name, ChannelID: 1, StatsMetrics: model.StatsMetrics{InputCost: 1, RequestSuccess: 1, WaitTime: 300}}
+	if err := db.GetDB().Create(&persisted).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	StatsModelUpdate(name, 1, model.StatsMetrics{InputToken: 10, InputCost: 0.1, RequestSuccess: 1, WaitTime: 100})
+	StatsModelUpdate(name, 1, model.StatsMetrics{InputToken: 10, OutputCost: 0.1, RequestFailed: 1, WaitTime: 200})
+	StatsModelUpdate(name, 2, model.StatsMetrics{InputToken: 5, InputCost: 2, RequestSuccess: 1, WaitTime: 50})
+
+	query := StatsModelQuery{Start: yesterday, End: now, Name: name}
+	check := func(byChannel bool, want []model.StatsModelSummary) {
+		t.Helper()
+		got, err := StatsModelSummaryList(ctx, query, byChannel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("summary = %+v, want %+v", got, want)
+		}
+		for i := range want {
+			if got[i].ChannelID != want[i].ChannelID || got[i].Requests() != want[i].Requests() ||
+				got[i].SuccessRate != want[i].SuccessRate || got[i].AvgLatency != want[i].AvgLatency || got[i].InputToken != want[i].InputToken {
+				t.Errorf("summary %d = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	}
+	// 按费用从高到低排序
+	byChannel := []model.StatsModelSummary{
+		{ChannelID: 2, SuccessRate: 1, AvgLatency: 50, StatsMetrics: model.StatsMetrics{InputToken: 5, RequestSuccess: 1}},
+		{ChannelID: 1, SuccessRate: 2.0 / 3, AvgLatency: 200, StatsMetrics: model.StatsMetrics{InputToken: 20, RequestSuccess: 2, RequestFailed: 1}},
+	}
+	check(true, byChannel)
+	check(false, []model.StatsModelSummary{
+		{SuccessRate: 0.75, AvgLatency: 162, StatsMetrics: model.StatsMetrics{InputToken: 25, RequestSuccess: 3, RequestFailed: 1}},
+	})
+
+	// 保存后数据库中的当天记录被缓存覆盖，不会重复计算
+	if err := StatsSaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check(true, byChannel)
+
+	query.ChannelID = 2
+	check(true, byChannel[:1])
+	query.ChannelID = 0
+	query.Start = now
+	check(true, []model.StatsModelSummary{
+		{ChannelID: 2, SuccessRate: 1, AvgLatency: 50, StatsMetrics: model.StatsMetrics{InputToken: 5, RequestSuccess: 1}},
+		{ChannelID: 1, SuccessRate: 0.5, AvgLatency: 150, StatsMetrics: model.StatsMetrics{InputToken: 20, RequestSuccess: 1, RequestFailed: 1}},
+	})
+}
+
+func TestStatsModelSeries(t *testing.T) {
+	ctx := context.Background()
+	name := "series-" + t.Name()
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	hour := hourStart(now)
+	persistedDaily := []model.StatsModel{
+		{Date: today.AddDate(0, 0, -2).Format("20060102"), Name: name, ChannelID: 1, StatsMetrics: model.StatsMetrics{RequestSuccess: 3}},
+		{Date: today.AddDate(0, 0, -2).Format("20060102"), Name: name, ChannelID: 2, StatsMetrics: model.StatsMetrics{RequestSuccess: 4}},
+	}
+	persistedHourly := []model.StatsModelHourly{
+		{Time: hour - 7200, Name: name, ChannelID: 1, StatsMetrics: model.StatsMetrics{RequestSuccess: 5}},
+	}
+	if err := persistStatsModel(ctx, persistedDaily, persistedHourly); err != nil {
+		t.Fatal(err)
+	}
+	StatsModelUpdate(name, 1, model.StatsMetrics{RequestSuccess: 1})
+	StatsModelUpdate(name, 2, model.StatsMetrics{RequestFailed: 1})
+
+	tests := []struct {
+		name      string
+		channelID int
+		hourly    bool
+		// 各时间点的开始时间和请求数，按时间升序
+		times    []int64
+		requests []int64
+	}{
+		{"daily merges channels", 0, false, []int64{today.AddDate(0, 0, -2).Unix(), today.Unix()}, []int64{7, 2}},
+		{"daily by channel", 2, false, []int64{today.AddDate(0, 0, -2).Unix(), today.Unix()}, []int64{4, 1}},
+		{"hourly", 0, true, []int64{hour - 7200, hour}, []int64{5, 2}},
+		{"hourly by channel", 2, true, []int64{hour}, []int64{1}},
+	}
+	query := StatsModelQuery{Start: today.AddDate(0, 0, -3), End: now, Name: name}
+	for _, tt := range tests {
+		query.ChannelID = tt.channelID
+		points, err := StatsModelSeries(ctx, query, tt.hourly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != len(tt.times) {
+			t.Errorf("%s: points = %+v", tt.name, points)
+			continue
+		}
+		for i, point := range points {
+			if point.Time != tt.times[i] || point.Requests() != tt.requests[i] {
+				t.Errorf("%s: point %d = %+v, want time %d requests %d", tt.name, i, point, tt.times[i], tt.requests[i])
+			}
+		}
+	}
+}
+
+// 重启后从数据库载入当天和当前小时的记录，继续累加
+func TestStatsModelRefreshCache(t *testing.T) {
+	ctx := context.Background()
+	name := "refresh-" + t.Name()
+	now := time.Now()
+	StatsModelUpdate(name, 1, model.StatsMetrics{InputToken: 10, RequestSuccess: 1})
+	if err := StatsSaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := statsModelRefreshCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	StatsModelUpdate(name, 1, model.StatsMetrics{InputToken: 5, RequestSuccess: 1})
+	if err := StatsSaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var daily model.StatsModel
+	if err := db.GetDB().Where("date = ? AND name = ? AND channel_id = ?", now.Format("20060102"), name, 1).First(&daily).Error; err != nil {
+		t.Fatal(err)
+	}
+	var hourly model.StatsModelHourly
+	if err := db.GetDB().Where("time = ? AND name = ? AND channel_id = ?", hourStart(now), name, 1).First(&hourly).Error; err != nil {
+		t.Fatal(err)
+	}
+	if daily.InputToken != 15 || daily.RequestSuccess != 2 || hourly.InputToken != 15 || hourly.RequestSuccess != 2 {
+		t.Fatalf("daily %+v, hourly %+v", daily.StatsMetrics, hourly.StatsMetrics)
+	}
+}
+
+func TestStatsModelHourlyCleanup(t *testing.T) {
+	ctx := context.Background()
+	name := "cleanup-" + t.Name()
+	hour := hourStart(time.Now())
+	rows := []model.StatsModelHourly{
+		{Time: hour - 10*86400, Name: name, ChannelID: 1},
+		{Time: hour - 86400, Name: name, ChannelID: 1},
+	}
+	if err := persistStatsModel(ctx, nil, rows); err != nil {
+		t.Fatal(err)
+	}
+	count := func() int64 {
+		var n int64
+		db.GetDB().Model(&model.StatsModelHourly{}).Where("name = ?", name).Count(&n)
+		return n
+	}
+
+	// 保存时间为 0 时不清理
+	if err := SettingSetString(model.SettingKeyStatsModelHourlyKeep, "0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := StatsModelHourlyCleanup(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 2 {
+		t.Fatalf("rows after disabled cleanup = %d, want 2", n)
+	}
+
+	if err := SettingSetString(model.SettingKeyStatsModelHourlyKeep, "7"); err != nil {
+		t.Fatal(err)
+	}
+	if err := StatsModelHourlyCleanup(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("rows after cleanup = %d, want 1", n)
+	}
+}
@@ -0,0 +1,153 @@
+package op
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+	"gorm.io/gorm/clause"
+)
+
+type statsAPIKeyModelKey struct {
+	Date     string
+	APIKeyID int
+	Name     string
+}
+
+// 缓存当天的累计值，跨天后在下一次保存时写入数据库并移出缓存
+var (
+	statsAPIKeyModelCache           = make(map[statsAPIKeyModelKey]model.StatsAPIKeyModel)
+	statsAPIKeyModelCacheNeedUpdate = make(map[statsAPIKeyModelKey]struct{})
+	statsAPIKeyModelCacheLock       sync.Mutex
+)
+
+// StatsAPIKeyModelUpdate 累加 API Key 在模型上当天的用量，delta 的 Date、APIKeyID、Name 由参数决定
+func StatsAPIKeyModelUpdate(apiKeyID int, name string, delta model.StatsAPIKeyModel) error {
+	key := statsAPIKeyModelKey{Date: time.Now().Format("20060102"), APIKeyID: apiKeyID, Name: name}
+
+	statsAPIKeyModelCacheLock.Lock()
+	defer statsAPIKeyModelCacheLock.Unlock()
+
+	row, ok := statsAPIKeyModelCache[key]
+	if !ok {
+		row = model.StatsAPIKeyModel{Date: key.Date, APIKeyID: apiKeyID, Name: name}
+	}
+	row.StatsMetrics.Add(delta.StatsMetrics)
+	row.CacheReadToken += delta.CacheReadToken
+	row.CacheWriteToken += delta.CacheWriteToken
+	statsAPIKeyModelCache[key] = row
+	statsAPIKeyModelCacheNeedUpdate[key] = struct{}{}
+	return nil
+}
+
+// statsAPIKeyModelSnapshot 取出待保存的记录，并移除已经过去的天
+func statsAPIKeyModelSnapshot() []model.StatsAPIKeyModel {
+	today := time.Now().Format("20060102")
+
+	statsAPIKeyModelCacheLock.Lock()
+	defer statsAPIKeyModelCacheLock.Unlock()
+
+	rows := make([]model.StatsAPIKeyModel, 0, len(statsAPIKeyModelCacheNeedUpdate))
+	for key := range statsAPIKeyModelCacheNeedUpdate {
+		rows = append(rows, statsAPIKeyModelCache[key])
+	}
+	statsAPIKeyModelCacheNeedUpdate = make(map[statsAPIKeyModelKey]struct{})
+	for key := range statsAPIKeyModelCache {
+		if key.Date != today {
+			delete(statsAPIKeyModelCache, key)
+		}
+	}
+	return rows
+}
+
+// statsAPIKeyModelRefreshCache 启动时载入当天的记录，之后在其基础上累加
+func statsAPIKeyModelRefreshCache(ctx context.Context) error {
+	var rows []model.StatsAPIKeyModel
+	if err := db.GetDB().WithContext(ctx).Where("date = ?", time.Now().Format("20060102")).Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to get api key model stats: %w", err)
+	}
+
+	statsAPIKeyModelCacheLock.Lock()
+	defer statsAPIKeyModelCacheLock.Unlock()
+	statsAPIKeyModelCache = make(map[statsAPIKeyModelKey]model.StatsAPIKeyModel, len(rows))
+	statsAPIKeyModelCacheNeedUpdate = make(map[statsAPIKeyModelKey]struct{})
+	for _, v := range rows {
+		statsAPIKeyModelCache[statsAPIKeyModelKey{Date: v.Date, APIKeyID: v.APIKeyID, Name: v.Name}] = v
+	}
+	return nil
+}
+
+// persistStatsAPIKeyModel 以主键覆盖写入缓存中的累计值
+func persistStatsAPIKeyModel(ctx context.Context, rows []model.StatsAPIKeyModel) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}, {Name: "api_key_id"}, {Name: "name"}},
+		UpdateAll: true,
+	}).Create(&rows).Error
+}
+
+// UsageExport 从 天 × API Key × 模型 的统计中导出 [start, end) 内的用量
+// apiKeyID 为 0 时导出全部 Key，缓存中尚未保存的当天数据覆盖数据库中的同一条记录
+func UsageExport(ctx context.Context, start, end time.Time, apiKeyID int) ([]model.UsageRow, error) {
+	startDate, endDate := start.Format("20060102"), end.Format("20060102")
+	query := db.GetDB().WithContext(ctx).Where("date >= ? AND date < ?", startDate, endDate)
+	if apiKeyID > 0 {
+		query = query.Where("api_key_id = ?", apiKeyID)
+	}
+	var stats []model.StatsAPIKeyModel
+	if err := query.Find(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to query api key model stats: %w", err)
+	}
+
+	merged := make(map[statsAPIKeyModelKey]model.StatsAPIKeyModel, len(stats))
+	for _, row := range stats {
+		merged[statsAPIKeyModelKey{Date: row.Date, APIKeyID: row.APIKeyID, Name: row.Name}] = row
+	}
+	statsAPIKeyModelCacheLock.Lock()
+	for key, row := range statsAPIKeyModelCache {
+		if key.Date < startDate || key.Date >= endDate || (apiKeyID > 0 && key.APIKeyID != apiKeyID) {
+			continue
+		}
+		merged[key] = row
+	}
+	statsAPIKeyModelCacheLock.Unlock()
+
+	result := make([]model.UsageRow, 0, len(merged))
+	for _, row := range merged {
+		day, err := time.ParseInLocation("20060102", row.Date, time.Local)
+		if err != nil {
+			continue
+		}
+		usage := model.UsageRow{
+			Date:             day.Format(time.DateOnly),
+			APIKeyID:         row.APIKeyID,
+			Model:            row.Name,
+			Requests:         row.RequestSuccess + row.RequestFailed,
+			FailedRequests:   row.RequestFailed,
+			InputTokens:      row.InputToken,
+			OutputTokens:     row.OutputToken,
+			CacheReadTokens:  row.CacheReadToken,
+			CacheWriteTokens: row.CacheWriteToken,
+			Cost:             row.InputCost + row.OutputCost,
+		}
+		if key, ok := apiKeyCache.Get(row.APIKeyID); ok {
+			usage.APIKeyName = key.Name
+		}
+		result = append(result, usage)
+	}
+	slices.SortFunc(result, func(a, b model.UsageRow) int {
+		return cmp.Or(
+			cmp.Compare(a.Date, b.Date),
+			cmp.Compare(a.APIKeyID, b.APIKeyID),
+			cmp.Compare(a.Model, b.Model),
+		)
+	})
+	return result, nil
+}
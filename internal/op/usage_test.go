@@ -0,0 +1,106 @@
+package op
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+func TestUsageExport(t *testing.T) {
+	ctx := context.Background()
+	key := model.APIKey{Name: "usage-key", APIKey: "sk-octopus-" + t.Name(), Enabled: true}
+	if err := APIKeyCreate(&key, ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { APIKeyDelete(key.ID, ctx) })
+	otherKeyID := key.ID + 1000
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	yesterday := today.AddDate(0, 0, -1)
+	// 已保存的前一天数据，以及范围之外的数据
+	persisted := []model.StatsAPIKeyModel{
+		{Date: yesterday.Format("20060102"), APIKeyID: key.ID, Name: "gpt-4o", StatsMetrics: model.StatsMetrics{InputToken: 100, OutputToken: 50, InputCost: 1, RequestSuccess: 2, RequestFailed: 1}, CacheReadToken: 40},
+		{Date: today.AddDate(0, 0, -10).Format("20060102"), APIKeyID: key.ID, Name: "gpt-4o", StatsMetrics: model.StatsMetrics{RequestSuccess: 1}},
+	}
+	if err := db.GetDB().Create(&persisted).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	success := func(input, output int64, cost float64) model.StatsAPIKeyModel {
+		return model.StatsAPIKeyModel{StatsMetrics: model.StatsMetrics{InputToken: input, OutputToken: output, InputCost: cost, RequestSuccess: 1}}
+	}
+	StatsAPIKeyModelUpdate(key.ID, "gpt-4o", success(10, 5, 0.1))
+	StatsAPIKeyModelUpdate(key.ID, "gpt-4o", model.StatsAPIKeyModel{StatsMetrics: model.StatsMetrics{OutputCost: 0.05, RequestFailed: 1}, CacheWriteToken: 7})
+	StatsAPIKeyModelUpdate(key.ID, "claude", success(20, 10, 0.2))
+	StatsAPIKeyModelUpdate(otherKeyID, "gpt-4o", success(1, 1, 0.01))
+
+	check := func(rows []model.UsageRow, want []model.UsageRow) {
+		t.Helper()
+		if len(rows) != len(want) {
+			t.Fatalf("rows = %+v, want %+v", rows, want)
+		}
+		for i := range want {
+			got, w := rows[i], want[i]
+			costMatch := math.Abs(got.Cost-w.Cost) < 1e-9
+			got.Cost, w.Cost = 0, 0
+			if got != w || !costMatch {
+				t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+			}
+		}
+	}
+	yesterdayRow := model.UsageRow{Date: yesterday.Format(time.DateOnly), APIKeyID: key.ID, APIKeyName: "usage-key", Model: "gpt-4o", Requests: 3, FailedRequests: 1, InputTokens: 100, OutputTokens: 50, CacheReadTokens: 40, Cost: 1}
+	todayRows := []model.UsageRow{
+		{Date: today.Format(time.DateOnly), APIKeyID: key.ID, APIKeyName: "usage-key", Model: "claude", Requests: 1, InputTokens: 20, OutputTokens: 10, Cost: 0.2},
+		{Date: today.Format(time.DateOnly), APIKeyID: key.ID, APIKeyName: "usage-key", Model: "gpt-4o", Requests: 2, FailedRequests: 1, InputTokens: 10, OutputTokens: 5, CacheWriteTokens: 7, Cost: 0.15},
+	}
+
+	// 当天的数据尚未保存，从缓存中读取
+	rows, err := UsageExport(ctx, yesterday, today.AddDate(0, 0, 1), key.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(rows, append([]model.UsageRow{yesterdayRow}, todayRows...))
+
+	// 保存后继续累加，数据库中的当天记录被缓存覆盖，不会重复计算
+	if err := StatsSaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+	StatsAPIKeyModelUpdate(key.ID, "claude", success(20, 10, 0.2))
+	todayRows[0].Requests, todayRows[0].InputTokens, todayRows[0].OutputTokens, todayRows[0].Cost = 2, 40, 20, 0.4
+	rows, err = UsageExport(ctx, today, today.AddDate(0, 0, 1), key.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(rows, todayRows)
+
+	// 不指定 API Key 时包含已删除的 Key，名称为空
+	rows, err = UsageExport(ctx, today, today.AddDate(0, 0, 1), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other []model.UsageRow
+	for _, row := range rows {
+		if row.APIKeyID == otherKeyID {
+			other = append(other, row)
+		}
+	}
+	check(other, []model.UsageRow{{Date: today.Format(time.DateOnly), APIKeyID: otherKeyID, Model: "gpt-4o", Requests: 1, InputTokens: 1, OutputTokens: 1, Cost: 0.01}})
+
+	// 重新载入缓存后当天的累计值来自数据库
+	if err := StatsSaveDB(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := statsAPIKeyModelRefreshCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rows, err = UsageExport(ctx, today, today.AddDate(0, 0, 1), key.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(rows, todayRows)
+}
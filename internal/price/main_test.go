@@ -0,0 +1,28 @@
+package price
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/db"
+	"github.com/bestruirui/octopus/internal/op"
+)
+
+// TestMain 使用临时 SQLite 数据库初始化缓存
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "octopus-price-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := db.InitDB("sqlite", filepath.Join(dir, "test.db"), false); err != nil {
+		panic(err)
+	}
+	if err := op.InitCache(); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	db.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -2,89 +2,97 @@ package price
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"maps"
+	"os"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/bestruirui/octopus/internal/client"
 	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/utils/log"
 )
 
-const llmPriceUrl = "https://models.dev/api.json"
-
-var Provider = []string{
-	"openai",     // GPT 系列
-	"anthropic",  // Claude 系列
-	"google",     // Gemini 系列
-	"deepseek",   // DeepSeek 系列
-	"xai",        // Grok 系列
-	"alibaba",    // Qwen 系列
-	"zhipuai",    // GLM 系列
-	"minimax",    // MiniMax 系列
-	"moonshotai", // Kimi/Moonshot
-	"v0",         // v0 系列
-}
-
 var lastUpdateTime time.Time
 
+// UpdateLLMPrice 依次读取配置的价格来源和上传的价格文件，后面的来源覆盖前面的同名模型
+// 部分来源失败时仍应用其余来源的价格，并返回失败原因
 func UpdateLLMPrice(ctx context.Context) error {
 	log.Debugf("update LLM price task started")
 	startTime := time.Now()
 	defer func() {
 		log.Debugf("update LLM price task finished, update time: %s", time.Since(startTime))
 	}()
-	client, err := client.GetHTTPClientSystemProxy(false)
-	if err != nil {
-		return err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, llmPriceUrl, nil)
-	if err != nil {
-		return err
-	}
-	resp, err := client.Do(req)
+	sourceSetting, err := op.SettingGetString(model.SettingKeyPriceSources)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to fetch LLM info: %s", resp.Status)
+	sources := model.ParsePriceSources(sourceSetting)
+	if _, err := os.Stat(UploadedSourcePath); err == nil {
+		sources = append(sources, UploadedSourcePath)
 	}
-	var rawPrice map[string]struct {
-		Models map[string]struct {
-			ID   string         `json:"id"`
-			Cost model.LLMPrice `json:"cost"`
-		} `json:"models"`
+	if len(sources) == 0 {
+		return fmt.Errorf("no price source configured")
 	}
-	body, err := io.ReadAll(resp.Body)
+	providerSetting, err := op.SettingGetString(model.SettingKeyPriceProviders)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
-	}
-	if err := json.Unmarshal(body, &rawPrice); err != nil {
-		return fmt.Errorf("failed to parse LLM info: %w", err)
+		return err
 	}
-	llmPriceLock.Lock()
-	for _, provider := range Provider {
-		for _, model := range rawPrice[provider].Models {
-			model.ID = strings.ToLower(model.ID)
-			llmPrice[model.ID] = model.Cost
+	providers := parseProviders(providerSetting)
+
+	prices := make(map[string]model.LLMPrice)
+	var errs []error
+	loaded := 0
+	for _, source := range sources {
+		data, err := readSource(ctx, source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load price source %s: %w", source, err))
+			continue
 		}
+		parsed, err := parsePrices(data, providers)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse price source %s: %w", source, err))
+			continue
+		}
+		maps.Copy(prices, parsed)
+		loaded++
 	}
-	llmPriceLock.Unlock()
-	lastUpdateTime = time.Now()
-	return nil
+	if loaded > 0 {
+		llmPriceLock.Lock()
+		maps.Copy(llmPrice, prices)
+		llmPriceLock.Unlock()
+		lastUpdateTime = time.Now()
+	}
+	return errors.Join(errs...)
 }
 
 func GetLastUpdateTime() time.Time {
 	return lastUpdateTime
 }
 
+// GetLLMPrice 返回模型价格，找不到时依次按改写规则改写模型名后再查找
 func GetLLMPrice(modelName string) *model.LLMPrice {
 	modelName = strings.ToLower(modelName)
+	if price := lookupLLMPrice(modelName); price != nil {
+		return price
+	}
+	name := modelName
+	for _, alias := range priceAliases() {
+		if !alias.match.MatchString(name) {
+			continue
+		}
+		name = alias.match.ReplaceAllString(name, alias.replace)
+		if price := lookupLLMPrice(name); price != nil {
+			return price
+		}
+	}
+	return nil
+}
+
+func lookupLLMPrice(modelName string) *model.LLMPrice {
 	price, err := op.LLMGet(modelName)
 	if err == nil {
 		return &price
@@ -97,3 +105,41 @@ func GetLLMPrice(modelName string) *model.LLMPrice {
 	}
 	return &price
 }
+
+type priceAlias struct {
+	match   *regexp.Regexp
+	replace string
+}
+
+// 按设置值缓存编译后的改写规则，设置变化时重新编译
+var (
+	priceAliasLock    sync.Mutex
+	priceAliasSetting string
+	priceAliasRules   []priceAlias
+)
+
+func priceAliases() []priceAlias {
+	value, err := op.SettingGetString(model.SettingKeyPriceAliases)
+	if err != nil {
+		return nil
+	}
+	priceAliasLock.Lock()
+	defer priceAliasLock.Unlock()
+	if value == priceAliasSetting {
+		return priceAliasRules
+	}
+	aliases, err := model.ParsePriceAliases(value)
+	if err != nil {
+		log.Warnf("invalid price aliases: %v", err)
+	}
+	rules := make([]priceAlias, 0, len(aliases))
+	for _, alias := range aliases {
+		rules = append(rules, priceAlias{
+			match:   regexp.MustCompile(alias.Match),
+			replace: strings.ToLower(alias.Replace),
+		})
+	}
+	priceAliasSetting = value
+	priceAliasRules = rules
+	return rules
+}
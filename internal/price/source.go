@@ -0,0 +1,239 @@
+package price
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/client"
+	"github.com/bestruirui/octopus/internal/model"
+)
+
+// UploadedSourcePath 上传的价格文件，每次更新时作为最后一个来源读取
+const UploadedSourcePath = "data/price_source.json"
+
+// contextOver200kThreshold models.dev 中 context_over_200k 价格的生效阈值
+const contextOver200kThreshold = 200000
+
+// providerFilter 采用价格的供应商，为 nil 时不过滤
+type providerFilter map[string]struct{}
+
+func parseProviders(value string) providerFilter {
+	value = strings.TrimSpace(value)
+	if value == "*" {
+		return nil
+	}
+	if value == "" {
+		value = model.DefaultPriceProviders
+	}
+	filter := make(providerFilter)
+	for _, provider := range strings.Split(value, ",") {
+		if provider = strings.ToLower(strings.TrimSpace(provider)); provider != "" {
+			filter[provider] = struct{}{}
+		}
+	}
+	return filter
+}
+
+func (f providerFilter) allows(provider string) bool {
+	if f == nil {
+		return true
+	}
+	_, ok := f[strings.ToLower(provider)]
+	return ok
+}
+
+// readSource 读取 http(s) URL 或本地文件
+func readSource(ctx context.Context, source string) ([]byte, error) {
+	if !strings.Contains(source, "://") {
+		return os.ReadFile(source)
+	}
+	httpClient, err := client.GetHTTPClientSystemProxy(false)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, nil
+}
+
+// SaveUploadedSource 校验并保存上传的价格文件，返回其中的模型数量
+func SaveUploadedSource(data []byte) (int, error) {
+	prices, err := parsePrices(data, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("no model prices found")
+	}
+	if err := os.MkdirAll(filepath.Dir(UploadedSourcePath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.WriteFile(UploadedSourcePath, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to save price source: %w", err)
+	}
+	return len(prices), nil
+}
+
+// DeleteUploadedSource 删除上传的价格文件，已载入的价格保留到下次重启
+func DeleteUploadedSource() error {
+	if err := os.Remove(UploadedSourcePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete price source: %w", err)
+	}
+	return nil
+}
+
+// parsePrices 识别 models.dev 或 LiteLLM 格式并解析出各模型的价格，模型名统一为小写
+func parsePrices(data []byte, providers providerFilter) (map[string]model.LLMPrice, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("price source must be a JSON object: %w", err)
+	}
+	for _, value := range raw {
+		var probe struct {
+			Models          json.RawMessage `json:"models"`
+			InputCost       *float64        `json:"input_cost_per_token"`
+			LiteLLMProvider string          `json:"litellm_provider"`
+		}
+		if json.Unmarshal(value, &probe) != nil {
+			continue
+		}
+		if probe.Models != nil {
+			return parseModelsDev(raw, providers)
+		}
+		if probe.InputCost != nil || probe.LiteLLMProvider != "" {
+			return parseLiteLLM(raw, providers)
+		}
+	}
+	return nil, fmt.Errorf("unrecognized price format, expected models.dev or LiteLLM")
+}
+
+// modelsDevCost models.dev 的价格格式，context_over_200k 为上下文超过 200k token 时的价格
+type modelsDevCost struct {
+	model.LLMPrice
+	ContextOver200k *model.LLMPriceTier `json:"context_over_200k"`
+}
+
+func parseModelsDev(raw map[string]json.RawMessage, providers providerFilter) (map[string]model.LLMPrice, error) {
+	prices := make(map[string]model.LLMPrice)
+	for provider, value := range raw {
+		if !providers.allows(provider) {
+			continue
+		}
+		var data struct {
+			Models map[string]struct {
+				ID   string         `json:"id"`
+				Cost *modelsDevCost `json:"cost"`
+			} `json:"models"`
+		}
+		if err := json.Unmarshal(value, &data); err != nil {
+			return nil, fmt.Errorf("failed to parse provider %s: %w", provider, err)
+		}
+		for _, m := range data.Models {
+			if m.ID == "" || m.Cost == nil {
+				continue
+			}
+			price := m.Cost.LLMPrice
+			if m.Cost.ContextOver200k != nil {
+				tier := *m.Cost.ContextOver200k
+				tier.Threshold = contextOver200kThreshold
+				price.ContextTiers = []model.LLMPriceTier{tier}
+			}
+			prices[strings.ToLower(m.ID)] = price
+		}
+	}
+	return prices, nil
+}
+
+// LiteLLM 的分档价格，如 input_cost_per_token_above_200k_tokens
+var liteLLMTierPattern = regexp.MustCompile(`^(input_cost_per_token|output_cost_per_token|cache_read_input_token_cost|cache_creation_input_token_cost)_above_(\d+)k_tokens$`)
+
+// parseLiteLLM 解析 LiteLLM 的 model_prices_and_context_window.json，价格单位为美元每 token
+func parseLiteLLM(raw map[string]json.RawMessage, providers providerFilter) (map[string]model.LLMPrice, error) {
+	prices := make(map[string]model.LLMPrice)
+	for name, value := range raw {
+		if name == "sample_spec" {
+			continue
+		}
+		var fields map[string]any
+		if json.Unmarshal(value, &fields) != nil {
+			continue
+		}
+		if provider, _ := fields["litellm_provider"].(string); !providers.allows(provider) {
+			continue
+		}
+		cost := func(key string) float64 {
+			v, _ := fields[key].(float64)
+			// 换算为每百万 token，并消除浮点误差
+			return math.Round(v*1e12) / 1e6
+		}
+		price := model.LLMPrice{
+			Input:        cost("input_cost_per_token"),
+			Output:       cost("output_cost_per_token"),
+			CacheRead:    cost("cache_read_input_token_cost"),
+			CacheWrite:   cost("cache_creation_input_token_cost"),
+			CacheWrite1h: cost("cache_creation_input_token_cost_above_1hr"),
+			Reasoning:    cost("output_cost_per_reasoning_token"),
+			InputAudio:   cost("input_cost_per_audio_token"),
+			OutputAudio:  cost("output_cost_per_audio_token"),
+			InputImage:   cost("input_cost_per_image_token"),
+			OutputImage:  cost("output_cost_per_image_token"),
+		}
+		if price.Input == 0 && price.Output == 0 {
+			continue
+		}
+		tiers := make(map[int64]*model.LLMPriceTier)
+		for key := range fields {
+			match := liteLLMTierPattern.FindStringSubmatch(key)
+			if match == nil {
+				continue
+			}
+			thousands, _ := strconv.ParseInt(match[2], 10, 64)
+			tier, ok := tiers[thousands]
+			if !ok {
+				tier = &model.LLMPriceTier{Threshold: thousands * 1000}
+				tiers[thousands] = tier
+			}
+			switch match[1] {
+			case "input_cost_per_token":
+				tier.Input = cost(key)
+			case "output_cost_per_token":
+				tier.Output = cost(key)
+			case "cache_read_input_token_cost":
+				tier.CacheRead = cost(key)
+			case "cache_creation_input_token_cost":
+				tier.CacheWrite = cost(key)
+			}
+		}
+		for _, tier := range tiers {
+			price.ContextTiers = append(price.ContextTiers, *tier)
+		}
+		slices.SortFunc(price.ContextTiers, func(a, b model.LLMPriceTier) int { return cmp.Compare(a.Threshold, b.Threshold) })
+		prices[strings.ToLower(name)] = price
+	}
+	return prices, nil
+}
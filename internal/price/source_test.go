@@ -0,0 +1,161 @@
+package price
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+)
+
+const modelsDevSource = `{
+	"openai": {"models": {
+		"gpt-x": {"id": "GPT-X", "cost": {"input": 1.25, "output": 10, "cache_read": 0.125}},
+		"no-cost": {"id": "no-cost"}
+	}},
+	"google": {"models": {
+		"gemini-x": {"id": "gemini-x", "cost": {"input": 1.25, "output": 10, "context_over_200k": {"input": 2.5, "output": 15, "cache_read": 0.25}}}
+	}},
+	"reseller": {"models": {
+		"gpt-x": {"id": "reseller/gpt-x", "cost": {"input": 9, "output": 9}}
+	}}
+}`
+
+const liteLLMSource = `{
+	"sample_spec": {"input_cost_per_token": 1, "litellm_provider": "openai"},
+	"GPT-X": {
+		"litellm_provider": "openai",
+		"input_cost_per_token": 1.25e-6,
+		"output_cost_per_token": 1e-5,
+		"cache_read_input_token_cost": 1.25e-7,
+		"output_cost_per_reasoning_token": 1.2e-5,
+		"input_cost_per_audio_token": 3e-5,
+		"output_cost_per_image_token": 4e-5
+	},
+	"claude-x": {
+		"litellm_provider": "anthropic",
+		"input_cost_per_token": 3e-6,
+		"output_cost_per_token": 1.5e-5,
+		"cache_creation_input_token_cost": 3.75e-6,
+		"cache_creation_input_token_cost_above_1hr": 6e-6,
+		"input_cost_per_token_above_200k_tokens": 6e-6,
+		"output_cost_per_token_above_200k_tokens": 2.25e-5,
+		"cache_read_input_token_cost_above_1000k_tokens": 6e-7,
+		"input_cost_per_token_above_128k": 9e-6
+	},
+	"free-embedding": {"litellm_provider": "openai", "input_cost_per_token": 0, "output_cost_per_token": 0},
+	"mistral-x": {"litellm_provider": "mistral", "input_cost_per_token": 2e-6, "output_cost_per_token": 6e-6}
+}`
+
+func TestParseModelsDev(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers providerFilter
+		want      map[string]model.LLMPrice
+	}{
+		{
+			"default providers", parseProviders(""),
+			map[string]model.LLMPrice{
+				"gpt-x": {Input: 1.25, Output: 10, CacheRead: 0.125},
+				"gemini-x": {Input: 1.25, Output: 10, ContextTiers: []model.LLMPriceTier{
+					{Threshold: 200000, Input: 2.5, Output: 15, CacheRead: 0.25},
+				}},
+			},
+		},
+		{
+			"selected provider", parseProviders(" OpenAI "),
+			map[string]model.LLMPrice{"gpt-x": {Input: 1.25, Output: 10, CacheRead: 0.125}},
+		},
+		{
+			"all providers", parseProviders("*"),
+			map[string]model.LLMPrice{
+				"gpt-x": {Input: 1.25, Output: 10, CacheRead: 0.125},
+				"gemini-x": {Input: 1.25, Output: 10, ContextTiers: []model.LLMPriceTier{
+					{Threshold: 200000, Input: 2.5, Output: 15, CacheRead: 0.25},
+				}},
+				"reseller/gpt-x": {Input: 9, Output: 9},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := parsePrices([]byte(modelsDevSource), tt.providers)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseLiteLLM(t *testing.T) {
+	got, err := parsePrices([]byte(liteLLMSource), parseProviders("openai,anthropic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]model.LLMPrice{
+		// 每 token 价格换算为每百万 token
+		"gpt-x": {Input: 1.25, Output: 10, CacheRead: 0.125, Reasoning: 12, InputAudio: 30, OutputImage: 40},
+		// 分档按阈值排序，不符合 _above_<n>k_tokens 格式的字段忽略
+		"claude-x": {Input: 3, Output: 15, CacheWrite: 3.75, CacheWrite1h: 6, ContextTiers: []model.LLMPriceTier{
+			{Threshold: 200000, Input: 6, Output: 22.5},
+			{Threshold: 1000000, CacheRead: 0.6},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParsePricesFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"not an object", `[]`, true},
+		{"unknown format", `{"a": {"b": 1}}`, true},
+		{"models.dev", `{"openai": {"models": {}}}`, false},
+		{"malformed models.dev provider", `{"openai": {"models": "invalid"}}`, true},
+		{"litellm", `{"m": {"litellm_provider": "openai"}}`, false},
+	}
+	for _, tt := range tests {
+		if _, err := parsePrices([]byte(tt.data), nil); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGetLLMPriceAliases(t *testing.T) {
+	llmPriceLock.Lock()
+	llmPrice["alias-test-model"] = model.LLMPrice{Input: 1, Output: 2}
+	llmPriceLock.Unlock()
+	t.Cleanup(func() {
+		llmPriceLock.Lock()
+		delete(llmPrice, "alias-test-model")
+		llmPriceLock.Unlock()
+	})
+
+	tests := []struct {
+		name    string
+		aliases string
+		model   string
+		found   bool
+	}{
+		{"exact name is case-insensitive", model.DefaultPriceAliases, "Alias-Test-Model", true},
+		{"default alias strips the provider prefix", model.DefaultPriceAliases, "openrouter/alias-test-model", true},
+		{"no aliases", "", "openrouter/alias-test-model", false},
+		// 规则依次应用在上一条改写的结果上
+		{"chained aliases", `[{"match":"^[^/]+/(.+)$","replace":"$1"},{"match":"-latest$","replace":""}]`, "vendor/ALIAS-TEST-MODEL-latest", true},
+		{"unmatched", model.DefaultPriceAliases, "other-model", false},
+	}
+	for _, tt := range tests {
+		if err := op.SettingSetString(model.SettingKeyPriceAliases, tt.aliases); err != nil {
+			t.Fatal(err)
+		}
+		if got := GetLLMPrice(tt.model); (got != nil) != tt.found {
+			t.Errorf("%s: GetLLMPrice(%q) = %+v, want found %t", tt.name, tt.model, got, tt.found)
+		}
+	}
+	op.SettingSetString(model.SettingKeyPriceAliases, model.DefaultPriceAliases)
+}
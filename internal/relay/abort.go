@@ -0,0 +1,40 @@
+package relay
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+)
+
+// streamDrainGrace 客户端中断流式请求后继续读取上游的时长
+func streamDrainGrace() time.Duration {
+	seconds, err := op.SettingGetInt(dbmodel.SettingKeyStreamDrainGrace)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// drainContext 返回上游流式请求使用的 context，客户端断开后再保持 grace 时长才取消
+// 上游按完整生成计费，在此期间读完剩余内容可以拿到最终的用量
+func drainContext(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	if grace <= 0 {
+		return ctx, func() {}
+	}
+	upstream, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(grace, cancel)
+	})
+	return upstream, func() {
+		stop()
+		cancel()
+	}
+}
+
+// draining 客户端已断开但上游请求仍在宽限期内
+func draining(ctx context.Context, response *http.Response) bool {
+	return ctx.Err() != nil && response.Request != nil && response.Request.Context().Err() == nil
+}
@@ -0,0 +1,183 @@
+package relay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/helper"
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/inbound"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDrainContext(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+	if ctx, cancel := drainContext(parent, 0); ctx != parent {
+		cancel()
+		t.Fatal("zero grace should use the client context")
+	}
+
+	upstream, cancel := drainContext(parent, 50*time.Millisecond)
+	defer cancel()
+	cancelParent()
+	time.Sleep(10 * time.Millisecond)
+	if upstream.Err() != nil {
+		t.Fatal("upstream canceled with the client")
+	}
+	select {
+	case <-upstream.Done():
+	case <-time.After(time.Second):
+		t.Fatal("upstream not canceled after the grace period")
+	}
+
+	upstream, cancel = drainContext(context.Background(), time.Hour)
+	cancel()
+	if upstream.Err() == nil {
+		t.Fatal("cancel did not stop the upstream request")
+	}
+}
+
+func TestDraining(t *testing.T) {
+	live := context.Background()
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	response := func(ctx context.Context) *http.Response {
+		return &http.Response{Request: httptest.NewRequestWithContext(ctx, http.MethodPost, "/", nil)}
+	}
+
+	cases := []struct {
+		name     string
+		client   context.Context
+		response *http.Response
+		want     bool
+	}{
+		{"client connected", live, response(live), false},
+		{"within grace", done, response(live), true},
+		{"grace expired", done, response(done), false},
+		{"no request", done, &http.Response{}, false},
+	}
+	for _, c := range cases {
+		if got := draining(c.client, c.response); got != c.want {
+			t.Errorf("%s: draining = %t, want %t", c.name, got, c.want)
+		}
+	}
+}
+
+// newAbortServer 与 newIdempotencyServer 相同，另外返回服务端收到的请求 context，用于等待服务端观察到客户端断开
+func newAbortServer(t *testing.T, key dbmodel.APIKey) (*httptest.Server, <-chan context.Context) {
+	t.Helper()
+	requests := make(chan context.Context, 1)
+	engine := gin.New()
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		requests <- c.Request.Context()
+		helper.SetAPIKeyContext(c, key, "openai", false)
+		Handler(inbound.InboundTypeOpenAIChat, c)
+	})
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+// abortAfterStart 发送请求，firstEvent 时收到首个流式事件后断开，否则在上游收到请求后断开，之后等待服务端观察到断开
+func abortAfterStart(t *testing.T, server *httptest.Server, requests <-chan context.Context, upstream *testUpstream, body string, firstEvent bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/v1/chat/completions", strings.NewReader(body))
+	go func() {
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		if firstEvent {
+			readSSE(resp.Body, func(string) bool { return false })
+			cancel()
+		}
+	}()
+	serverCtx := <-requests
+	if !firstEvent {
+		waitFor(t, "the upstream request", func() bool { return upstream.hits.Load() > 0 })
+		cancel()
+	}
+	waitFor(t, "the server to see the disconnect", func() bool { return serverCtx.Err() != nil })
+}
+
+func setDrainGrace(t *testing.T, seconds string) {
+	t.Helper()
+	if err := op.SettingSetString(dbmodel.SettingKeyStreamDrainGrace, seconds); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { op.SettingSetString(dbmodel.SettingKeyStreamDrainGrace, "30") })
+}
+
+// 客户端中断后，宽限期内读到上游用量时记为 drained，否则按已传输的内容估算
+func TestAbortedStreamUsageSource(t *testing.T) {
+	cases := []struct {
+		name         string
+		grace        string
+		streamMode   dbmodel.StreamMode
+		clientStream bool
+		abort        bool
+		want         dbmodel.UsageSource
+	}{
+		{"completed", "30", dbmodel.StreamModeAuto, true, false, dbmodel.UsageSourceUpstream},
+		{"drained", "30", dbmodel.StreamModeAuto, true, true, dbmodel.UsageSourceDrained},
+		{"no grace", "0", dbmodel.StreamModeAuto, true, true, dbmodel.UsageSourceEstimated},
+		{"forced non-stream upstream", "30", dbmodel.StreamModeNonStream, true, true, dbmodel.UsageSourceDrained},
+		{"forced stream upstream", "30", dbmodel.StreamModeStream, false, true, dbmodel.UsageSourceDrained},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setDrainGrace(t, c.grace)
+			release := make(chan struct{})
+			closeRelease := sync.OnceFunc(func() { close(release) })
+			t.Cleanup(closeRelease)
+			var upstream *testUpstream
+			upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+				if c.streamMode == dbmodel.StreamModeNonStream {
+					<-release
+					upstream.writeCompletion(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				writeStreamContent(w, upstream.model, "hello")
+				<-release
+				writeStreamEnd(w, upstream.model)
+			})
+			if c.streamMode != dbmodel.StreamModeAuto {
+				if _, err := op.ChannelUpdate(&dbmodel.ChannelUpdateRequest{ID: upstream.channelID, StreamMode: &c.streamMode}, context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			key := newTestAPIKey(t)
+			server, requests := newAbortServer(t, key)
+			logs := subscribeRelayLogs(t)
+
+			if c.abort {
+				firstEvent := c.clientStream && c.streamMode != dbmodel.StreamModeNonStream
+				abortAfterStart(t, server, requests, upstream, chatBody(upstream.model, c.clientStream), firstEvent)
+				closeRelease()
+			} else {
+				closeRelease()
+				if status, body := postChat(t, server, chatBody(upstream.model, c.clientStream)); status != http.StatusOK {
+					t.Fatalf("status %d: %s", status, body)
+				}
+			}
+
+			relayLog := nextRelayLog(t, logs, key.ID)
+			if relayLog.UsageSource != c.want {
+				t.Fatalf("usage source = %q, want %q (usage %d/%d)", relayLog.UsageSource, c.want, relayLog.InputTokens, relayLog.OutputTokens)
+			}
+			if c.want != dbmodel.UsageSourceEstimated && (relayLog.InputTokens != 10 || relayLog.OutputTokens != 5) {
+				t.Fatalf("logged usage = %d/%d, want 10/5", relayLog.InputTokens, relayLog.OutputTokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,524 @@
+package relay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/helper"
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/inbound"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+// MaxBatchRequests 单个批处理允许的最大请求数
+const MaxBatchRequests = 50000
+
+// BatchEndpoints 批处理支持的接口及对应的入站类型
+var BatchEndpoints = map[string]inbound.InboundType{
+	"/v1/chat/completions": inbound.InboundTypeOpenAIChat,
+	"/v1/responses":        inbound.InboundTypeOpenAIResponse,
+	"/v1/embeddings":       inbound.InboundTypeOpenAIEmbedding,
+	"/v1/messages":         inbound.InboundTypeAnthropic,
+}
+
+// batchLine 输入文件中的一行请求
+type batchLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type batchOutputResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type batchOutputLine struct {
+	ID       string               `json:"id"`
+	CustomID string               `json:"custom_id"`
+	Response *batchOutputResponse `json:"response"`
+	Error    *dbmodel.BatchError  `json:"error"`
+}
+
+var (
+	runningBatches   = make(map[string]context.CancelFunc)
+	runningBatchesMu sync.Mutex
+)
+
+// BatchDispatchTask 调度所有未结束的批处理，服务重启后从数据库恢复执行
+func BatchDispatchTask() {
+	batches, err := op.BatchListUnfinished(context.Background())
+	if err != nil {
+		log.Warnf("failed to list unfinished batches: %v", err)
+		return
+	}
+	for _, batch := range batches {
+		BatchStart(batch.ID)
+	}
+}
+
+// BatchStart 在后台执行批处理，已在执行中时忽略
+func BatchStart(id string) {
+	runningBatchesMu.Lock()
+	if _, ok := runningBatches[id]; ok {
+		runningBatchesMu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	runningBatches[id] = cancel
+	runningBatchesMu.Unlock()
+
+	go func() {
+		defer func() {
+			runningBatchesMu.Lock()
+			delete(runningBatches, id)
+			runningBatchesMu.Unlock()
+			cancel()
+		}()
+		if err := runBatch(ctx, id); err != nil {
+			log.Warnf("batch %s run failed: %v", id, err)
+		}
+	}()
+}
+
+// BatchStop 停止正在执行的批处理，已完成的请求结果会被保留
+func BatchStop(id string) {
+	runningBatchesMu.Lock()
+	cancel, ok := runningBatches[id]
+	runningBatchesMu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func runBatch(ctx context.Context, id string) error {
+	// 批处理不属于任何客户端请求，数据库操作使用独立的 context
+	dbCtx := context.Background()
+
+	batch, err := op.BatchGetByID(id, dbCtx)
+	if err != nil {
+		return err
+	}
+	if batch.IsFinished() {
+		return nil
+	}
+
+	switch batch.Status {
+	case dbmodel.BatchStatusCancelling:
+		return finalizeBatch(batch, dbmodel.BatchStatusCancelled)
+	case dbmodel.BatchStatusFinalizing:
+		return finalizeBatch(batch, dbmodel.BatchStatusCompleted)
+	}
+
+	input, err := op.FileGet(batch.InputFileID, batch.APIKeyID, true, dbCtx)
+	if err != nil {
+		return failBatch(batch, "invalid_file", fmt.Sprintf("failed to load input file: %v", err))
+	}
+	lines, batchErrs := parseBatchInput(input.Content, batch.Endpoint)
+
+	if batch.Status == dbmodel.BatchStatusValidating {
+		if len(batchErrs) > 0 {
+			batch.Errors = &dbmodel.BatchErrors{Object: "list", Data: batchErrs}
+			return finishBatch(batch, dbmodel.BatchStatusFailed)
+		}
+		now := time.Now().Unix()
+		started, err := op.BatchMarkInProgress(batch.ID, len(lines), now, dbCtx)
+		if err != nil {
+			return err
+		}
+		if !started {
+			// 校验期间被取消
+			return finalizeBatch(batch, dbmodel.BatchStatusCancelled)
+		}
+		batch.Status = dbmodel.BatchStatusInProgress
+		batch.InProgressAt = &now
+		batch.RequestCounts.Total = len(lines)
+	}
+
+	status := executeBatch(ctx, batch, lines)
+	if status == "" {
+		// 服务关闭等原因中断，等待下次调度继续执行
+		return nil
+	}
+	return finalizeBatch(batch, status)
+}
+
+// executeBatch 以受控并发执行尚未完成的请求，返回批处理的最终状态，返回空表示被中断
+func executeBatch(ctx context.Context, batch *dbmodel.Batch, lines []batchLine) dbmodel.BatchStatus {
+	dbCtx := context.Background()
+
+	results, err := op.BatchResultList(batch.ID, dbCtx)
+	if err != nil {
+		log.Warnf("failed to load results of batch %s: %v", batch.ID, err)
+		return ""
+	}
+	done := make(map[int]bool, len(results))
+	batch.RequestCounts = dbmodel.BatchRequestCounts{Total: len(lines)}
+	for _, r := range results {
+		done[r.Line] = true
+		if r.Success {
+			batch.RequestCounts.Completed++
+		} else {
+			batch.RequestCounts.Failed++
+		}
+	}
+
+	concurrency, err := op.SettingGetInt(dbmodel.SettingKeyBatchConcurrency)
+	if err != nil || concurrency < 1 {
+		concurrency = 1
+	}
+
+	var expireC <-chan time.Time
+	if batch.ExpiresAt != nil {
+		timer := time.NewTimer(time.Until(time.Unix(*batch.ExpiresAt, 0)))
+		defer timer.Stop()
+		expireC = timer.C
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var expired atomic.Bool
+	go func() {
+		select {
+		case <-expireC:
+			expired.Store(true)
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+
+	var countsMu sync.Mutex
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				if runCtx.Err() != nil {
+					// 尚未发出的请求不记录结果，恢复后执行
+					continue
+				}
+				// 已发出的请求一定已经计费，取消或到期时也执行完毕并保存结果，恢复后不会重复执行
+				output, success := executeBatchLine(context.WithoutCancel(runCtx), batch.APIKeyID, lines[idx])
+				if err := op.BatchResultSave(&dbmodel.BatchResult{
+					BatchID: batch.ID,
+					Line:    idx,
+					Success: success,
+					Output:  string(output),
+				}, dbCtx); err != nil {
+					log.Warnf("failed to save result of batch %s line %d: %v", batch.ID, idx, err)
+					continue
+				}
+				countsMu.Lock()
+				if success {
+					batch.RequestCounts.Completed++
+				} else {
+					batch.RequestCounts.Failed++
+				}
+				counts := batch.RequestCounts
+				countsMu.Unlock()
+				if err := op.BatchUpdateCounts(batch.ID, counts, dbCtx); err != nil {
+					log.Warnf("%v", err)
+				}
+			}
+		}()
+	}
+
+	for idx := range lines {
+		if done[idx] {
+			continue
+		}
+		select {
+		case jobs <- idx:
+			continue
+		case <-runCtx.Done():
+		}
+		break
+	}
+	close(jobs)
+	wg.Wait()
+
+	if expired.Load() {
+		return dbmodel.BatchStatusExpired
+	}
+	if ctx.Err() != nil {
+		// 区分用户取消与服务关闭
+		if status, err := op.BatchStatusGet(batch.ID, dbCtx); err == nil && status == dbmodel.BatchStatusCancelling {
+			return dbmodel.BatchStatusCancelled
+		}
+		return ""
+	}
+	return dbmodel.BatchStatusCompleted
+}
+
+// batchLineAuthKey 批处理请求在 context 中携带已校验的 API Key
+type batchLineAuthKey struct{}
+
+type batchLineAuth struct {
+	apiKey        dbmodel.APIKey
+	quotaExceeded bool
+}
+
+// batchEngine 批处理的每行请求作为独立的 HTTP 请求经过 gin 处理，与 /v1 接口共用中继流程
+// 延迟创建，使其使用服务启动时设置的 gin 模式
+var batchEngine = sync.OnceValue(func() *gin.Engine {
+	engine := gin.New()
+	for path, inboundType := range BatchEndpoints {
+		requestType := lo.Ternary(inboundType == inbound.InboundTypeAnthropic, "anthropic", "openai")
+		engine.POST(path, func(c *gin.Context) {
+			auth := c.Request.Context().Value(batchLineAuthKey{}).(batchLineAuth)
+			helper.SetAPIKeyContext(c, auth.apiKey, requestType, auth.quotaExceeded)
+			Handler(inboundType, c)
+		})
+	}
+	return engine
+})
+
+// executeBatchLine 复用中继处理单行请求，计费归属提交批处理的 API Key
+func executeBatchLine(ctx context.Context, apiKeyID int, line batchLine) ([]byte, bool) {
+	requestID := "batch_req_" + lo.RandomString(24, lo.AlphanumericCharset)
+	output := batchOutputLine{ID: requestID, CustomID: line.CustomID}
+	fail := func(code, message string) ([]byte, bool) {
+		output.Error = &dbmodel.BatchError{Code: code, Message: message}
+		data, _ := json.Marshal(output)
+		return data, false
+	}
+
+	apiKey, err := op.APIKeyGet(apiKeyID, ctx)
+	if err != nil {
+		return fail("invalid_api_key", "API key has been deleted")
+	}
+	quotaExceeded, err := helper.APIKeyAuthorize(&apiKey, ctx)
+	if err != nil {
+		return fail("invalid_api_key", err.Error())
+	}
+
+	ctx = context.WithValue(ctx, batchLineAuthKey{}, batchLineAuth{apiKey: apiKey, quotaExceeded: quotaExceeded})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, line.URL, bytes.NewReader(stripStream(line.Body)))
+	if err != nil {
+		return fail("invalid_request", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	w := &batchResponseWriter{header: make(http.Header)}
+	batchEngine().ServeHTTP(w, req)
+
+	statusCode := w.status
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	respBody := w.body.Bytes()
+	if !json.Valid(respBody) {
+		respBody, _ = json.Marshal(string(respBody))
+	}
+	output.Response = &batchOutputResponse{
+		StatusCode: statusCode,
+		RequestID:  requestID,
+		Body:       respBody,
+	}
+	data, _ := json.Marshal(output)
+	return data, statusCode >= 200 && statusCode < 300
+}
+
+// finalizeBatch 根据已保存的结果生成 output / error 文件并结束批处理
+func finalizeBatch(batch *dbmodel.Batch, status dbmodel.BatchStatus) error {
+	dbCtx := context.Background()
+
+	batch.Status = dbmodel.BatchStatusFinalizing
+	batch.FinalizingAt = lo.ToPtr(time.Now().Unix())
+	if err := op.BatchUpdate(batch, dbCtx); err != nil {
+		return err
+	}
+
+	results, err := op.BatchResultList(batch.ID, dbCtx)
+	if err != nil {
+		return err
+	}
+	var outputBuf, errorBuf bytes.Buffer
+	counts := dbmodel.BatchRequestCounts{Total: batch.RequestCounts.Total}
+	for _, r := range results {
+		if r.Success {
+			counts.Completed++
+			outputBuf.WriteString(r.Output)
+			outputBuf.WriteByte('\n')
+		} else {
+			counts.Failed++
+			errorBuf.WriteString(r.Output)
+			errorBuf.WriteByte('\n')
+		}
+	}
+	batch.RequestCounts = counts
+
+	if outputBuf.Len() > 0 {
+		file, err := createBatchOutputFile(batch, "output", outputBuf.Bytes())
+		if err != nil {
+			return err
+		}
+		batch.OutputFileID = &file.ID
+	}
+	if errorBuf.Len() > 0 {
+		file, err := createBatchOutputFile(batch, "error", errorBuf.Bytes())
+		if err != nil {
+			return err
+		}
+		batch.ErrorFileID = &file.ID
+	}
+
+	if err := finishBatch(batch, status); err != nil {
+		return err
+	}
+	return op.BatchResultDelete(batch.ID, dbCtx)
+}
+
+func finishBatch(batch *dbmodel.Batch, status dbmodel.BatchStatus) error {
+	now := lo.ToPtr(time.Now().Unix())
+	batch.Status = status
+	switch status {
+	case dbmodel.BatchStatusCompleted:
+		batch.CompletedAt = now
+	case dbmodel.BatchStatusFailed:
+		batch.FailedAt = now
+	case dbmodel.BatchStatusExpired:
+		batch.ExpiredAt = now
+	case dbmodel.BatchStatusCancelled:
+		batch.CancelledAt = now
+	}
+	log.Infof("batch %s finished with status %s (%d/%d completed, %d failed)", batch.ID, status, batch.RequestCounts.Completed, batch.RequestCounts.Total, batch.RequestCounts.Failed)
+	return op.BatchUpdate(batch, context.Background())
+}
+
+func failBatch(batch *dbmodel.Batch, code, message string) error {
+	batch.Errors = &dbmodel.BatchErrors{
+		Object: "list",
+		Data:   []dbmodel.BatchError{{Code: code, Message: message}},
+	}
+	return finishBatch(batch, dbmodel.BatchStatusFailed)
+}
+
+func createBatchOutputFile(batch *dbmodel.Batch, kind string, content []byte) (*dbmodel.File, error) {
+	file := &dbmodel.File{
+		ID:        NewFileID(),
+		APIKeyID:  batch.APIKeyID,
+		Filename:  fmt.Sprintf("%s_%s.jsonl", batch.ID, kind),
+		Purpose:   dbmodel.FilePurposeBatchOutput,
+		Bytes:     int64(len(content)),
+		Content:   content,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := op.FileCreate(file, context.Background()); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// parseBatchInput 解析并校验输入 JSONL，返回所有请求和校验错误
+func parseBatchInput(content []byte, endpoint string) ([]batchLine, []dbmodel.BatchError) {
+	var lines []batchLine
+	var errs []dbmodel.BatchError
+	customIDs := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSEEventSize)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		addErr := func(code, message string) {
+			errs = append(errs, dbmodel.BatchError{Code: code, Message: message, Line: lo.ToPtr(lineNum)})
+		}
+
+		var line batchLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			addErr("invalid_json_line", fmt.Sprintf("line is not valid JSON: %v", err))
+			continue
+		}
+		switch {
+		case line.CustomID == "":
+			addErr("missing_required_parameter", "custom_id is required")
+		case customIDs[line.CustomID]:
+			addErr("duplicate_custom_id", fmt.Sprintf("custom_id %q is duplicated", line.CustomID))
+		case line.Method != http.MethodPost:
+			addErr("invalid_method", "method must be POST")
+		case line.URL != endpoint:
+			addErr("mismatched_endpoint", fmt.Sprintf("url %q does not match batch endpoint %q", line.URL, endpoint))
+		case len(line.Body) == 0 || line.Body[0] != '{':
+			addErr("invalid_request", "body must be a JSON object")
+		default:
+			customIDs[line.CustomID] = true
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, dbmodel.BatchError{Code: "invalid_file", Message: err.Error()})
+	}
+	if len(lines) == 0 && len(errs) == 0 {
+		errs = append(errs, dbmodel.BatchError{Code: "empty_file", Message: "input file contains no requests"})
+	}
+	if len(lines) > MaxBatchRequests {
+		errs = append(errs, dbmodel.BatchError{Code: "too_many_requests", Message: fmt.Sprintf("batch exceeds the maximum of %d requests", MaxBatchRequests)})
+	}
+	return lines, errs
+}
+
+// stripStream 批处理不支持流式输出，移除请求中的 stream 参数
+func stripStream(body json.RawMessage) []byte {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return body
+	}
+	if _, ok := obj["stream"]; !ok {
+		return body
+	}
+	delete(obj, "stream")
+	delete(obj, "stream_options")
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return body
+	}
+	return data
+}
+
+// NewFileID 生成 OpenAI 风格的文件 ID
+func NewFileID() string {
+	return "file-" + lo.RandomString(24, lo.AlphanumericCharset)
+}
+
+// NewBatchID 生成 OpenAI 风格的批处理 ID
+func NewBatchID() string {
+	return "batch_" + lo.RandomString(32, lo.AlphanumericCharset)
+}
+
+// batchResponseWriter 在内存中收集中继响应
+type batchResponseWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (w *batchResponseWriter) Header() http.Header { return w.header }
+
+func (w *batchResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *batchResponseWriter) WriteHeader(statusCode int) { w.status = statusCode }
+
+func (w *batchResponseWriter) Flush() {}
@@ -0,0 +1,118 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/samber/lo"
+)
+
+func testBatchLine(model string) batchLine {
+	return batchLine{
+		CustomID: "req-1",
+		Method:   http.MethodPost,
+		URL:      "/v1/chat/completions",
+		Body:     json.RawMessage(fmt.Sprintf(`{"model":%q,"messages":[{"role":"user","content":"hi"}]}`, model)),
+	}
+}
+
+func TestExecuteBatchLine(t *testing.T) {
+	upstream := newTestUpstream(t, nil)
+	key := newTestAPIKey(t)
+
+	data, success := executeBatchLine(context.Background(), key.ID, testBatchLine(upstream.model))
+	if !success {
+		t.Fatalf("batch line failed: %s", data)
+	}
+	var output batchOutputLine
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatal(err)
+	}
+	if output.CustomID != "req-1" || output.Response == nil || output.Response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected output: %s", data)
+	}
+	if upstream.hits.Load() != 1 {
+		t.Fatalf("upstream hits = %d, want 1", upstream.hits.Load())
+	}
+	// 用量计入提交批处理的 API Key
+	if stats := op.StatsAPIKeyGet(key.ID); stats.InputToken != 10 || stats.OutputToken != 5 {
+		t.Fatalf("api key stats = %+v, want 10 input and 5 output tokens", stats.StatsMetrics)
+	}
+}
+
+func TestExecuteBatchLineDisabledKey(t *testing.T) {
+	upstream := newTestUpstream(t, nil)
+	key := newTestAPIKey(t)
+	key.Enabled = false
+	if err := op.APIKeyUpdate(&key, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, success := executeBatchLine(context.Background(), key.ID, testBatchLine(upstream.model))
+	if success {
+		t.Fatalf("disabled key should fail: %s", data)
+	}
+	var output batchOutputLine
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatal(err)
+	}
+	if output.Error == nil || output.Error.Code != "invalid_api_key" {
+		t.Fatalf("unexpected output: %s", data)
+	}
+	if upstream.hits.Load() != 0 {
+		t.Fatalf("upstream hits = %d, want 0", upstream.hits.Load())
+	}
+}
+
+// 取消时已发出的请求执行完毕并保存结果，未发出的请求留待恢复后执行
+// 行数多于并发数，取消时一部分请求尚未发出
+func TestExecuteBatchCancelKeepsSentLines(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var startOnce sync.Once
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		startOnce.Do(func() { close(started) })
+		<-release
+		upstream.writeCompletion(w, r)
+	})
+	key := newTestAPIKey(t)
+	batch := &dbmodel.Batch{ID: "batch_" + lo.RandomString(24, lo.AlphanumericCharset), APIKeyID: key.ID, Status: dbmodel.BatchStatusInProgress}
+	if err := op.BatchCreate(batch, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lines := make([]batchLine, 8)
+	for i := range lines {
+		lines[i] = testBatchLine(upstream.model)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	statusC := make(chan dbmodel.BatchStatus)
+	go func() { statusC <- executeBatch(ctx, batch, lines) }()
+	<-started
+	cancel()
+	close(release)
+
+	if status := <-statusC; status != "" {
+		t.Fatalf("status = %q, want interrupted", status)
+	}
+	results, err := op.BatchResultList(batch.ID, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := int(upstream.hits.Load())
+	if hits == 0 || hits == len(lines) || len(results) != hits {
+		t.Fatalf("saved %d results for %d upstream requests out of %d lines", len(results), hits, len(lines))
+	}
+	for _, result := range results {
+		if !result.Success {
+			t.Fatalf("line %d failed: %s", result.Line, result.Output)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/outbound"
+)
+
+// fanOutResults 非流式拆分 n 时已成功的响应，按请求编号保存，切换渠道时保留
+// 之后的渠道只重新发送失败的请求，已向上游付费的结果不会再次请求
+type fanOutResults struct {
+	responses []*model.InternalLLMResponse
+}
+
+// newFanOutResults 请求 n > 1 的非流式对话时返回非 nil
+func newFanOutResults(request *model.InternalLLMRequest) *fanOutResults {
+	n := request.ChoiceCount()
+	if n <= 1 || !request.IsChatRequest() || (request.Stream != nil && *request.Stream) {
+		return nil
+	}
+	return &fanOutResults{responses: make([]*model.InternalLLMResponse, n)}
+}
+
+// completed 返回已成功的响应
+func (f *fanOutResults) completed() []*model.InternalLLMResponse {
+	if f == nil {
+		return nil
+	}
+	var responses []*model.InternalLLMResponse
+	for _, resp := range f.responses {
+		if resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	return responses
+}
+
+// record 未能返回完整响应时，已成功的请求同样已向上游付费，按其实际用量计入统计
+func (f *fanOutResults) record(metrics *RelayMetrics) {
+	responses := f.completed()
+	if len(responses) == 0 {
+		return
+	}
+	metrics.SetUpstreamRequests(len(responses))
+	metrics.SetInternalResponse(mergeChoices(responses))
+}
+
+// fanOutCount 请求 n > 1 且渠道不支持 n 参数时返回需要拆分的请求数，否则返回 1
+// 之前的渠道已返回部分结果时，即使当前渠道支持 n 也继续拆分，只补发缺少的请求
+func (rc *relayContext) fanOutCount() int {
+	n := rc.internalRequest.ChoiceCount()
+	if n <= 1 || !rc.internalRequest.IsChatRequest() {
+		return 1
+	}
+	if outbound.SupportsMultipleChoices(rc.channel.Type) && len(rc.fanOut.completed()) == 0 {
+		return 1
+	}
+	return n
+}
+
+// branch 返回单个拆分请求使用的上下文副本：独立的出站适配器，请求中去掉 n
+// 并发读取流时首 Token 时间记在副本自己的统计中，由主流程在写回客户端时记录
+func (rc *relayContext) branch() *relayContext {
+	b := *rc
+	b.outAdapter = rc.newOutbound()
+	request := *rc.internalRequest
+	request.N = nil
+	b.internalRequest = &request
+	b.metrics = NewRelayMetrics(rc.metrics.RequestModel)
+	return &b
+}
+
+// forwardFanOut 将 n 个 choice 拆分为 n 个并发请求，合并为一个响应，choice 按请求顺序编号
+// 任一请求失败则整体失败，由上层切换渠道重试，成功的响应保留在 rc.fanOut 中不会重新请求
+func (rc *relayContext) forwardFanOut(ctx context.Context, clientStream bool, n int) (int, error) {
+	if clientStream {
+		return rc.streamFanOut(ctx, n)
+	}
+
+	results := rc.fanOut
+	branches := make([]*relayContext, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		branches[i] = rc.branch()
+		if results.responses[i] != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := branches[i].roundTrip(ctx, branches[i].internalRequest)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results.responses[i] = resp
+		}()
+	}
+	wg.Wait()
+	for _, branch := range branches {
+		if branch.metrics.ClientAborted {
+			rc.metrics.SetClientAborted()
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return 0, err
+	}
+
+	// 结构化输出的重试只针对未通过校验的那个请求，逐个执行以免并发写入校验结果
+	if rc.structured != nil {
+		for i, branch := range branches {
+			resp, err := rc.structured.ensure(ctx, branch, results.responses[i])
+			if err != nil {
+				return 0, err
+			}
+			results.responses[i] = resp
+		}
+	}
+
+	if err := rc.writeResponse(ctx, mergeChoices(results.responses)); err != nil {
+		return 0, err
+	}
+	return http.StatusOK, nil
+}
+
+// streamFanOut 并发请求上游，流式块按请求编号改写 choice index 后依次写回客户端
+// 各请求的用量在结束时合并为一个用量块，之后发送 [DONE]
+// 与单个流式请求相同，客户端断开后上游请求保留一段时间，合并后的用量仍交给入站适配器用于计费
+func (rc *relayContext) streamFanOut(ctx context.Context, n int) (int, error) {
+	upstreamCtx, cancelDrain := drainContext(ctx, streamDrainGrace())
+	defer cancelDrain()
+	branchCtx, cancel := context.WithCancel(upstreamCtx)
+	defer cancel()
+
+	chunks := make(chan *model.InternalLLMResponse)
+	errc := make(chan error, n)
+	for i := range n {
+		branch := rc.branch()
+		go func() {
+			errc <- branch.streamBranch(ctx, branchCtx, i, chunks)
+		}()
+	}
+
+	var (
+		base    *model.InternalLLMResponse
+		usage   *model.Usage
+		started bool
+	)
+	// write 经入站适配器转换后写回客户端，客户端已断开时只累积内容和用量
+	write := func(chunk *model.InternalLLMResponse) error {
+		out, err := rc.inAdapter.TransformStream(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		if len(out) == 0 || ctx.Err() != nil {
+			return nil
+		}
+		if !started {
+			rc.setStreamHeaders()
+			started = true
+		}
+		if rc.metrics.FirstTokenTime.IsZero() {
+			rc.metrics.SetFirstTokenTime(time.Now())
+		}
+		rc.c.Writer.Write(out)
+		rc.c.Writer.Flush()
+		return nil
+	}
+	usageChunk := func() *model.InternalLLMResponse {
+		if usage == nil || base == nil {
+			return nil
+		}
+		return &model.InternalLLMResponse{
+			ID:      base.ID,
+			Object:  "chat.completion.chunk",
+			Created: base.Created,
+			Model:   base.Model,
+			Usage:   usage,
+		}
+	}
+
+	for remaining := n; remaining > 0; {
+		select {
+		case err := <-errc:
+			if err != nil {
+				// 流已开始时不再重试，已结束的请求的用量不写回客户端，只交给入站适配器计费
+				if chunk := usageChunk(); chunk != nil && started {
+					rc.inAdapter.TransformStream(ctx, chunk)
+				}
+				return 0, err
+			}
+			remaining--
+		case chunk := <-chunks:
+			if chunk.Object == "[DONE]" {
+				continue
+			}
+			if chunk.Usage != nil {
+				usage = sumUsage(usage, chunk.Usage)
+				stripped := *chunk
+				stripped.Usage = nil
+				chunk = &stripped
+				if len(chunk.Choices) == 0 {
+					continue
+				}
+			}
+			// 各上游返回的 id 不同，统一使用首个块的 id
+			if base == nil {
+				base = chunk
+			} else {
+				chunk.ID = base.ID
+			}
+			if err := write(chunk); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		rc.metrics.SetClientAborted()
+	}
+
+	if chunk := usageChunk(); chunk != nil {
+		if err := write(chunk); err != nil {
+			return 0, err
+		}
+	}
+	if err := write(&model.InternalLLMResponse{Object: "[DONE]"}); err != nil {
+		return 0, err
+	}
+	return http.StatusOK, nil
+}
+
+// streamBranch 发送单个拆分请求，将内部格式的流式块改写为第 index 个 choice 后发送到 out
+// 上游请求使用 upstreamCtx，ctx 为客户端请求的 context，用于判断客户端是否断开
+// 渠道强制非流式时请求完整响应后拆分为流式块
+func (rc *relayContext) streamBranch(ctx, upstreamCtx context.Context, index int, out chan<- *model.InternalLLMResponse) error {
+	send := func(chunk *model.InternalLLMResponse) bool {
+		for i := range chunk.Choices {
+			chunk.Choices[i].Index = index
+		}
+		select {
+		case out <- chunk:
+			return true
+		case <-upstreamCtx.Done():
+			return false
+		}
+	}
+
+	if !rc.channel.UpstreamStream(true) {
+		internalResponse, err := rc.roundTrip(upstreamCtx, rc.internalRequest)
+		if err != nil {
+			return err
+		}
+		for _, chunk := range synthesizeStreamChunks(internalResponse) {
+			if !send(chunk) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	response, err := rc.doRequest(upstreamCtx, withStream(rc.internalRequest, true))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if err := checkStreamContentType(response); err != nil {
+		return err
+	}
+	return rc.readStream(ctx, response, func(data string) bool {
+		chunk, err := rc.outAdapter.TransformStream(ctx, []byte(data))
+		if err != nil || chunk == nil {
+			return false
+		}
+		return send(chunk) && len(chunk.Choices) > 0
+	})
+}
+
+// mergeChoices 按顺序合并各请求的 choice 并重新编号，用量求和
+func mergeChoices(responses []*model.InternalLLMResponse) *model.InternalLLMResponse {
+	merged := *responses[0]
+	merged.Choices = nil
+	merged.Usage = nil
+	for _, resp := range responses {
+		for _, choice := range resp.Choices {
+			choice.Index = len(merged.Choices)
+			merged.Choices = append(merged.Choices, choice)
+		}
+		if resp.Usage != nil {
+			merged.Usage = sumUsage(merged.Usage, resp.Usage)
+		}
+	}
+	return &merged
+}
+
+// sumUsage 返回两份用量之和，不修改参数，任一方为 nil 时返回另一方的副本
+func sumUsage(a, b *model.Usage) *model.Usage {
+	if b == nil {
+		if a == nil {
+			return nil
+		}
+		return sumUsage(nil, a)
+	}
+	if a == nil {
+		sum := *b
+		if b.PromptTokensDetails != nil {
+			details := *b.PromptTokensDetails
+			sum.PromptTokensDetails = &details
+		}
+		if b.CompletionTokensDetails != nil {
+			details := *b.CompletionTokensDetails
+			sum.CompletionTokensDetails = &details
+		}
+		return &sum
+	}
+	sum := sumUsage(nil, a)
+	sum.PromptTokens += b.PromptTokens
+	sum.CompletionTokens += b.CompletionTokens
+	sum.TotalTokens += b.TotalTokens
+	sum.CacheCreationInputTokens += b.CacheCreationInputTokens
+	sum.CacheCreation1hInputTokens += b.CacheCreation1hInputTokens
+	sum.PromptModalityTokenDetails = sumModalityTokens(sum.PromptModalityTokenDetails, b.PromptModalityTokenDetails)
+	sum.CompletionModalityTokenDetails = sumModalityTokens(sum.CompletionModalityTokenDetails, b.CompletionModalityTokenDetails)
+	sum.AnthropicUsage = sum.AnthropicUsage || b.AnthropicUsage
+	if b.PromptTokensDetails != nil {
+		if sum.PromptTokensDetails == nil {
+			sum.PromptTokensDetails = &model.PromptTokensDetails{}
+		}
+		sum.PromptTokensDetails.CachedTokens += b.PromptTokensDetails.CachedTokens
+		sum.PromptTokensDetails.AudioTokens += b.PromptTokensDetails.AudioTokens
+	}
+	if b.CompletionTokensDetails != nil {
+		if sum.CompletionTokensDetails == nil {
+			sum.CompletionTokensDetails = &model.CompletionTokensDetails{}
+		}
+		sum.CompletionTokensDetails.ReasoningTokens += b.CompletionTokensDetails.ReasoningTokens
+		sum.CompletionTokensDetails.AudioTokens += b.CompletionTokensDetails.AudioTokens
+		sum.CompletionTokensDetails.AcceptedPredictionTokens += b.CompletionTokensDetails.AcceptedPredictionTokens
+		sum.CompletionTokensDetails.RejectedPredictionTokens += b.CompletionTokensDetails.RejectedPredictionTokens
+	}
+	return sum
+}
+
+// sumModalityTokens 按模态合并 token 数，返回新的切片
+func sumModalityTokens(a, b []model.ModalityTokenCount) []model.ModalityTokenCount {
+	if len(b) == 0 {
+		return a
+	}
+	sum := slices.Clone(a)
+	for _, detail := range b {
+		idx := slices.IndexFunc(sum, func(d model.ModalityTokenCount) bool { return d.Modality == detail.Modality })
+		if idx < 0 {
+			sum = append(sum, detail)
+		} else {
+			sum[idx].TokenCount += detail.TokenCount
+		}
+	}
+	return sum
+}
@@ -0,0 +1,282 @@
+package relay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/outbound"
+)
+
+// withoutNativeN 测试期间将 OpenAI Chat 渠道视为不支持 n，使请求走拆分路径
+func withoutNativeN(t *testing.T) {
+	outbound.MultiChoiceChannelTypes[outbound.OutboundTypeOpenAIChat] = false
+	t.Cleanup(func() { outbound.MultiChoiceChannelTypes[outbound.OutboundTypeOpenAIChat] = true })
+}
+
+func fanOutBody(model string, n int, stream bool) string {
+	return fmt.Sprintf(`{"model":%q,"n":%d,"stream":%t,"messages":[{"role":"user","content":"hi"}]}`, model, n, stream)
+}
+
+func postChat(t *testing.T, server *httptest.Server, body string) (int, string) {
+	t.Helper()
+	resp, err := server.Client().Post(server.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+type fanOutCompletion struct {
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int64 `json:"prompt_tokens"`
+		CompletionTokens int64 `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// 每个 choice 一个上游请求，choice 按顺序重新编号，用量求和
+func TestFanOutMergesChoices(t *testing.T) {
+	withoutNativeN(t)
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		if _, ok := req["n"]; ok {
+			http.Error(w, "n is not supported", http.StatusBadRequest)
+			return
+		}
+		upstream.writeCompletion(w, r)
+	})
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	logs := subscribeRelayLogs(t)
+
+	status, body := postChat(t, server, fanOutBody(upstream.model, 3, false))
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	var resp fanOutCompletion
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Choices) != 3 {
+		t.Fatalf("choices = %d, want 3: %s", len(resp.Choices), body)
+	}
+	for i, choice := range resp.Choices {
+		if choice.Index != i || choice.Message.Content != "ok" {
+			t.Fatalf("choice %d = %+v", i, choice)
+		}
+	}
+	if resp.Usage.PromptTokens != 30 || resp.Usage.CompletionTokens != 15 {
+		t.Fatalf("usage = %+v, want 30/15", resp.Usage)
+	}
+	if hits := upstream.hits.Load(); hits != 3 {
+		t.Fatalf("upstream hits = %d, want 3", hits)
+	}
+	if relayLog := nextRelayLog(t, logs, key.ID); relayLog.InputTokens != 30 || relayLog.OutputTokens != 15 {
+		t.Fatalf("logged usage = %d/%d, want 30/15", relayLog.InputTokens, relayLog.OutputTokens)
+	}
+}
+
+// 部分请求失败后切换渠道，只重新发送失败的请求
+func TestFanOutReissuesOnlyFailedBranches(t *testing.T) {
+	withoutNativeN(t)
+	var first *testUpstream
+	first = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if first.hits.Load() == 2 {
+			http.Error(w, "overloaded", http.StatusInternalServerError)
+			return
+		}
+		first.writeCompletion(w, r)
+	})
+	second := newTestUpstream(t, nil)
+	group := &dbmodel.Group{
+		Name: first.model + "-failover",
+		Mode: dbmodel.GroupModeFailover,
+		Items: []dbmodel.GroupItem{
+			{ChannelID: first.channelID, ModelName: first.model, Priority: 1, Weight: 1},
+			{ChannelID: second.channelID, ModelName: second.model, Priority: 2, Weight: 1},
+		},
+	}
+	if err := op.GroupCreate(group, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	logs := subscribeRelayLogs(t)
+
+	status, body := postChat(t, server, fanOutBody(group.Name, 2, false))
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	var resp fanOutCompletion
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Choices) != 2 || resp.Usage.PromptTokens != 20 || resp.Usage.CompletionTokens != 10 {
+		t.Fatalf("unexpected response: %s", body)
+	}
+	if a, b := first.hits.Load(), second.hits.Load(); a != 2 || b != 1 {
+		t.Fatalf("upstream hits = %d/%d, want 2/1", a, b)
+	}
+	if relayLog := nextRelayLog(t, logs, key.ID); relayLog.InputTokens != 20 || relayLog.OutputTokens != 10 {
+		t.Fatalf("logged usage = %d/%d, want 20/10", relayLog.InputTokens, relayLog.OutputTokens)
+	}
+}
+
+// 所有渠道都失败时，已成功的请求的用量仍计入统计
+func TestFanOutRecordsCompletedBranchesOnFailure(t *testing.T) {
+	withoutNativeN(t)
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if upstream.hits.Load() > 1 {
+			http.Error(w, "overloaded", http.StatusInternalServerError)
+			return
+		}
+		upstream.writeCompletion(w, r)
+	})
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	logs := subscribeRelayLogs(t)
+
+	status, body := postChat(t, server, fanOutBody(upstream.model, 2, false))
+	if status != http.StatusBadGateway {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	// 首轮发送 2 个请求，之后每轮只重发失败的 1 个
+	if hits := upstream.hits.Load(); hits != 4 {
+		t.Fatalf("upstream hits = %d, want 4", hits)
+	}
+	relayLog := nextRelayLog(t, logs, key.ID)
+	if relayLog.Error == "" || relayLog.InputTokens != 10 || relayLog.OutputTokens != 5 || relayLog.UsageSource != dbmodel.UsageSourceUpstream {
+		t.Fatalf("unexpected relay log: error %q, usage %d/%d (%s)", relayLog.Error, relayLog.InputTokens, relayLog.OutputTokens, relayLog.UsageSource)
+	}
+	if stats := op.StatsAPIKeyGet(key.ID); stats.InputToken != 10 || stats.OutputToken != 5 || stats.RequestFailed != 1 {
+		t.Fatalf("api key stats = %+v", stats.StatsMetrics)
+	}
+}
+
+// readSSE 读取 SSE 响应中的 data 行，onData 返回 false 时停止
+func readSSE(r io.Reader, onData func(data string) bool) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if ok && !onData(data) {
+			return
+		}
+	}
+}
+
+type fanOutChunk struct {
+	ID      string `json:"id"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int64 `json:"prompt_tokens"`
+		CompletionTokens int64 `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// 流式拆分按请求编号改写 choice index，结束时发送一个合并的用量块
+func TestStreamFanOut(t *testing.T) {
+	withoutNativeN(t)
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeStreamContent(w, upstream.model, "hello")
+		writeStreamEnd(w, upstream.model)
+	})
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	logs := subscribeRelayLogs(t)
+
+	status, body := postChat(t, server, fanOutBody(upstream.model, 2, true))
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	var (
+		indexes []int
+		usages  int
+		done    bool
+		ids     = map[string]bool{}
+	)
+	readSSE(strings.NewReader(body), func(data string) bool {
+		if data == "[DONE]" {
+			done = true
+			return false
+		}
+		var chunk fanOutChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %s: %v", data, err)
+		}
+		ids[chunk.ID] = true
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				indexes = append(indexes, choice.Index)
+			}
+		}
+		if chunk.Usage != nil {
+			usages++
+			if chunk.Usage.PromptTokens != 20 || chunk.Usage.CompletionTokens != 10 {
+				t.Fatalf("usage = %+v, want 20/10", *chunk.Usage)
+			}
+		}
+		return true
+	})
+	slices.Sort(indexes)
+	if !slices.Equal(indexes, []int{0, 1}) || usages != 1 || !done || len(ids) != 1 {
+		t.Fatalf("indexes %v, usage chunks %d, done %t, ids %v:\n%s", indexes, usages, done, ids, body)
+	}
+	if relayLog := nextRelayLog(t, logs, key.ID); relayLog.InputTokens != 20 || relayLog.OutputTokens != 10 {
+		t.Fatalf("logged usage = %d/%d, want 20/10", relayLog.InputTokens, relayLog.OutputTokens)
+	}
+}
+
+// 客户端中断流式拆分后继续读取各上游，按上游返回的用量计费
+func TestStreamFanOutClientAbortDrainsUsage(t *testing.T) {
+	withoutNativeN(t)
+	release := make(chan struct{})
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeStreamContent(w, upstream.model, "hello")
+		<-release
+		writeStreamEnd(w, upstream.model)
+	})
+	key := newTestAPIKey(t)
+	server, requests := newAbortServer(t, key)
+	logs := subscribeRelayLogs(t)
+
+	abortAfterStart(t, server, requests, upstream, fanOutBody(upstream.model, 2, true), true)
+	waitFor(t, "both upstream requests", func() bool { return upstream.hits.Load() == 2 })
+	close(release)
+
+	relayLog := nextRelayLog(t, logs, key.ID)
+	if relayLog.InputTokens != 20 || relayLog.OutputTokens != 10 || relayLog.UsageSource != dbmodel.UsageSourceDrained {
+		t.Fatalf("logged usage = %d/%d (%s), want 20/10 (%s)", relayLog.InputTokens, relayLog.OutputTokens, relayLog.UsageSource, dbmodel.UsageSourceDrained)
+	}
+}
@@ -0,0 +1,334 @@
+package relay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyHeader         = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	idempotencyMaxKeyLength   = 255
+)
+
+// idempotencyEntry 记录同一 Idempotency-Key 首次请求写回客户端的响应
+// 重复请求在首次请求进行中时等待其结果，流式响应跟随其输出，完成后按记录重放
+type idempotencyEntry struct {
+	key         string
+	fingerprint [sha256.Size]byte
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	started bool // 已开始写出响应，status 和 header 可用
+	status  int
+	header  http.Header
+	body    []byte
+	dropped bool // 响应超过大小上限被丢弃，只保留防重复计费的记录
+	done    bool
+	expires time.Time // 完成后开始计算
+
+	// consumers 仍在接收输出的客户端数，包括首次请求的客户端和等待其结果的重复请求
+	// 首次请求完成前降为 0 时取消请求，按客户端断开处理
+	consumers int
+	cancel    context.CancelFunc
+}
+
+var (
+	idempotencyEntries = make(map[string]*idempotencyEntry)
+	idempotencyQueue   []*idempotencyEntry // 按创建顺序，用于过期清理和按大小淘汰
+	idempotencySize    int
+	idempotencyLock    sync.Mutex
+)
+
+// idempotencyWriter 写回客户端的同时记录响应
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	entry *idempotencyEntry
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	idempotencyAppend(w.entry, w.Status(), w.Header(), data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	idempotencyAppend(w.entry, w.Status(), w.Header(), []byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotencyRequest 持有 Idempotency-Key 的首次请求
+type idempotencyRequest struct {
+	entry  *idempotencyEntry
+	cancel context.CancelFunc
+	stop   func() bool
+}
+
+// beginIdempotency 处理 Idempotency-Key 请求头，按 API Key 隔离
+// 首次请求返回非 nil 的 idempotencyRequest，客户端断开后只在仍有重复请求等待结果时继续执行
+// 重复请求直接写回首次请求的响应并返回 false，不会再次转发和计费
+func beginIdempotency(c *gin.Context, apiKeyID int, body []byte) (*idempotencyRequest, bool) {
+	value := c.GetHeader(idempotencyHeader)
+	if value == "" {
+		return nil, true
+	}
+	ttl := idempotencyTTL()
+	if ttl <= 0 {
+		return nil, true
+	}
+	if len(value) > idempotencyMaxKeyLength {
+		resp.Error(c, http.StatusBadRequest, "Idempotency-Key must be at most "+strconv.Itoa(idempotencyMaxKeyLength)+" characters")
+		return nil, false
+	}
+
+	key := strconv.Itoa(apiKeyID) + ":" + value
+	fingerprint := sha256.Sum256(append([]byte(c.Request.URL.Path+"\n"), body...))
+	now := time.Now()
+	// 请求不直接随客户端断开而取消，由 detach 在没有客户端接收输出时取消
+	ctx, cancel := context.WithCancel(context.WithoutCancel(c.Request.Context()))
+
+	idempotencyLock.Lock()
+	idempotencyPrune(now)
+	entry, ok := idempotencyEntries[key]
+	if !ok {
+		entry = &idempotencyEntry{key: key, fingerprint: fingerprint, consumers: 1, cancel: cancel}
+		entry.cond = sync.NewCond(&entry.mu)
+		idempotencyEntries[key] = entry
+		idempotencyQueue = append(idempotencyQueue, entry)
+	}
+	idempotencyLock.Unlock()
+
+	if ok {
+		cancel()
+		if entry.fingerprint != fingerprint {
+			resp.Error(c, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+			return nil, false
+		}
+		replayIdempotency(c, entry)
+		return nil, false
+	}
+
+	stop := context.AfterFunc(c.Request.Context(), entry.detach)
+	c.Request = c.Request.WithContext(ctx)
+	c.Writer = &idempotencyWriter{ResponseWriter: c.Writer, entry: entry}
+	return &idempotencyRequest{entry: entry, cancel: cancel, stop: stop}, true
+}
+
+// detach 一个客户端不再接收输出，首次请求未完成且没有其他客户端时取消请求
+// 流式请求随后按客户端断开处理，在宽限期内读取上游用量 (见 drainContext)
+func (e *idempotencyEntry) detach() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.consumers--
+	if e.consumers == 0 && !e.done {
+		e.cancel()
+	}
+}
+
+// finish 结束首次请求，只保留按上游响应计费的结果；未到达上游 (如参数错误、超出限额)
+// 或返回 5xx (如全部渠道失败) 的请求不保留记录，之后的重试会正常处理
+func (r *idempotencyRequest) finish(metrics *RelayMetrics) {
+	if r == nil {
+		return
+	}
+	r.stop()
+	r.cancel()
+	entry := r.entry
+
+	idempotencyLock.Lock()
+	defer idempotencyLock.Unlock()
+	entry.mu.Lock()
+	entry.done = true
+	entry.expires = time.Now().Add(idempotencyTTL())
+	entry.cond.Broadcast()
+	billed := metrics.CostDeducted && entry.started && entry.status < http.StatusInternalServerError
+	entry.mu.Unlock()
+	if !billed && idempotencyEntries[entry.key] == entry {
+		delete(idempotencyEntries, entry.key)
+	}
+}
+
+// replayIdempotency 将记录的响应写回客户端
+// 首次请求未完成时，流式响应持续跟随其输出，非流式响应等待完成后一次写回
+func replayIdempotency(c *gin.Context, entry *idempotencyEntry) {
+	ctx := c.Request.Context()
+	stop := context.AfterFunc(ctx, func() {
+		entry.mu.Lock()
+		entry.cond.Broadcast()
+		entry.mu.Unlock()
+	})
+	defer stop()
+
+	entry.mu.Lock()
+	attached := !entry.done
+	if attached {
+		entry.consumers++
+	}
+	entry.mu.Unlock()
+	if attached {
+		defer entry.detach()
+	}
+
+	offset := 0
+	for {
+		entry.mu.Lock()
+		for !entry.done && !entry.dropped && ctx.Err() == nil &&
+			!(entry.started && idempotencyStreaming(entry.header) && offset < len(entry.body)) {
+			entry.cond.Wait()
+		}
+		if ctx.Err() != nil {
+			entry.mu.Unlock()
+			return
+		}
+		if offset == 0 && (!entry.started || entry.dropped) {
+			entry.mu.Unlock()
+			resp.Error(c, http.StatusConflict, "the original request with this Idempotency-Key has no response available for replay")
+			return
+		}
+		if offset == 0 {
+			for k, v := range entry.header {
+				c.Writer.Header()[k] = v
+			}
+			c.Header(idempotencyReplayedHeader, "true")
+			c.Status(entry.status)
+		}
+		// 响应已被丢弃时不会再有新内容
+		var chunk []byte
+		if !entry.dropped {
+			chunk = entry.body[offset:]
+		}
+		dropped := entry.dropped
+		finished := entry.done || entry.dropped
+		entry.mu.Unlock()
+
+		if len(chunk) > 0 {
+			c.Writer.Write(chunk)
+			c.Writer.Flush()
+			offset += len(chunk)
+		}
+		if dropped {
+			writeReplayTruncated(c)
+			return
+		}
+		if finished {
+			return
+		}
+	}
+}
+
+// idempotencyStreaming 响应是否为流式 (SSE 或 NDJSON)，流式响应在重放时跟随首次请求的输出
+func idempotencyStreaming(header http.Header) bool {
+	contentType := header.Get("Content-Type")
+	return strings.Contains(strings.ToLower(contentType), "text/event-stream") || isNDJSONContentType(contentType)
+}
+
+// writeReplayTruncated 跟随中的流式响应因超过存储上限被丢弃，以错误事件结束，避免客户端把截断的内容当作完整响应
+func writeReplayTruncated(c *gin.Context) {
+	data, _ := json.Marshal(gin.H{"error": gin.H{
+		"message": "the original response with this Idempotency-Key exceeded the replay storage limit and was truncated",
+		"type":    "idempotency_replay_truncated",
+	}})
+	if isNDJSONContentType(c.Writer.Header().Get("Content-Type")) {
+		c.Writer.Write(append(data, '\n'))
+	} else {
+		fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", data)
+	}
+	c.Writer.Flush()
+}
+
+// idempotencyAppend 记录写出的内容，总大小超过上限时先丢弃最早完成的响应，仍不够时丢弃当前响应
+func idempotencyAppend(entry *idempotencyEntry, status int, header http.Header, data []byte) {
+	idempotencyLock.Lock()
+	defer idempotencyLock.Unlock()
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	defer entry.cond.Broadcast()
+
+	if !entry.started {
+		entry.started = true
+		entry.status = status
+		entry.header = header.Clone()
+	}
+	if entry.dropped {
+		return
+	}
+	entry.body = append(entry.body, data...)
+	idempotencySize += len(data)
+
+	maxSize := idempotencyMaxSize()
+	for _, e := range idempotencyQueue {
+		if idempotencySize <= maxSize {
+			return
+		}
+		if e == entry {
+			continue
+		}
+		e.mu.Lock()
+		if e.done && !e.dropped {
+			idempotencyDrop(e)
+		}
+		e.mu.Unlock()
+	}
+	if idempotencySize > maxSize {
+		idempotencyDrop(entry)
+	}
+}
+
+// idempotencyDrop 丢弃响应内容，调用方需持有 idempotencyLock 和 entry.mu
+func idempotencyDrop(entry *idempotencyEntry) {
+	idempotencySize -= len(entry.body)
+	entry.body = nil
+	entry.dropped = true
+}
+
+// idempotencyPrune 清理已过期的记录，调用方需持有 idempotencyLock
+func idempotencyPrune(now time.Time) {
+	kept := idempotencyQueue[:0]
+	for _, entry := range idempotencyQueue {
+		entry.mu.Lock()
+		expired := entry.done && now.After(entry.expires)
+		if expired {
+			idempotencySize -= len(entry.body)
+			entry.body = nil
+		}
+		entry.mu.Unlock()
+		if !expired {
+			kept = append(kept, entry)
+			continue
+		}
+		if idempotencyEntries[entry.key] == entry {
+			delete(idempotencyEntries, entry.key)
+		}
+	}
+	clear(idempotencyQueue[len(kept):])
+	idempotencyQueue = kept
+}
+
+// idempotencyTTL Idempotency-Key 的有效期，0 为不启用
+func idempotencyTTL() time.Duration {
+	minutes, err := op.SettingGetInt(dbmodel.SettingKeyIdempotencyTTL)
+	if err != nil || minutes < 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// idempotencyMaxSize 保存的响应总大小上限(字节)
+func idempotencyMaxSize() int {
+	mb, err := op.SettingGetInt(dbmodel.SettingKeyIdempotencyMaxSize)
+	if err != nil || mb < 1 {
+		mb = 1
+	}
+	return mb * 1024 * 1024
+}
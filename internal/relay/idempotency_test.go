@@ -0,0 +1,410 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/helper"
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/inbound"
+	"github.com/gin-gonic/gin"
+)
+
+// newIdempotencyServer 以 key 的身份处理 /v1/chat/completions，客户端断开时请求 context 随之取消
+func newIdempotencyServer(t *testing.T, key dbmodel.APIKey) *httptest.Server {
+	t.Helper()
+	engine := gin.New()
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		helper.SetAPIKeyContext(c, key, "openai", false)
+		Handler(inbound.InboundTypeOpenAIChat, c)
+	})
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+type idempotencyResult struct {
+	status   int
+	replayed bool
+	body     string
+	err      error
+}
+
+func postIdempotent(ctx context.Context, server *httptest.Server, idempotencyKey, body string) idempotencyResult {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/v1/chat/completions", strings.NewReader(body))
+	if err != nil {
+		return idempotencyResult{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotencyHeader, idempotencyKey)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		return idempotencyResult{err: err}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return idempotencyResult{status: resp.StatusCode, replayed: resp.Header.Get(idempotencyReplayedHeader) == "true", body: string(data), err: err}
+}
+
+func chatBody(model string, stream bool) string {
+	return fmt.Sprintf(`{"model":%q,"stream":%t,"messages":[{"role":"user","content":"hi"}]}`, model, stream)
+}
+
+// idempotencyConsumers 返回 Idempotency-Key 当前接收输出的客户端数，记录不存在时返回 -1
+func idempotencyConsumers(apiKeyID int, idempotencyKey string) int {
+	idempotencyLock.Lock()
+	entry, ok := idempotencyEntries[strconv.Itoa(apiKeyID)+":"+idempotencyKey]
+	idempotencyLock.Unlock()
+	if !ok {
+		return -1
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	return entry.consumers
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// writeStreamChunk 写出一个 chat.completion.chunk 事件
+func writeStreamChunk(w http.ResponseWriter, model string, chunk map[string]any) {
+	chunk["id"], chunk["object"], chunk["created"], chunk["model"] = "chatcmpl-test", "chat.completion.chunk", 1, model
+	data, _ := json.Marshal(chunk)
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	w.(http.Flusher).Flush()
+}
+
+func writeStreamContent(w http.ResponseWriter, model, content string) {
+	writeStreamChunk(w, model, map[string]any{"choices": []map[string]any{{"index": 0, "delta": map[string]any{"content": content}}}})
+}
+
+// writeStreamEnd 写出带固定用量 (10 输入、5 输出) 的结束块和 [DONE]
+func writeStreamEnd(w http.ResponseWriter, model string) {
+	writeStreamChunk(w, model, map[string]any{"choices": []map[string]any{{"index": 0, "delta": map[string]any{}, "finish_reason": "stop"}}})
+	writeStreamChunk(w, model, map[string]any{"choices": []map[string]any{}, "usage": map[string]any{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}})
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	w.(http.Flusher).Flush()
+}
+
+// 相同键的并发请求只转发一次，其余请求等待并得到相同的响应
+func TestIdempotencyConcurrentRequests(t *testing.T) {
+	release := make(chan struct{})
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		upstream.writeCompletion(w, r)
+	})
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+
+	const n = 5
+	results := make([]idempotencyResult, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = postIdempotent(context.Background(), server, "concurrent", chatBody(upstream.model, false))
+		}()
+	}
+	waitFor(t, "all requests to attach", func() bool { return idempotencyConsumers(key.ID, "concurrent") == n })
+	close(release)
+	wg.Wait()
+
+	replayed := 0
+	for i, r := range results {
+		if r.err != nil || r.status != http.StatusOK {
+			t.Fatalf("request %d: status %d, err %v, body %s", i, r.status, r.err, r.body)
+		}
+		if r.body != results[0].body {
+			t.Fatalf("request %d body differs:\n%s\n%s", i, r.body, results[0].body)
+		}
+		if r.replayed {
+			replayed++
+		}
+	}
+	if replayed != n-1 {
+		t.Fatalf("replayed = %d, want %d", replayed, n-1)
+	}
+	if hits := upstream.hits.Load(); hits != 1 {
+		t.Fatalf("upstream hits = %d, want 1", hits)
+	}
+	if stats := op.StatsAPIKeyGet(key.ID); stats.RequestSuccess != 1 {
+		t.Fatalf("api key requests = %d, want 1", stats.RequestSuccess)
+	}
+}
+
+// 流式响应进行中时重试跟随其输出，完成后重试重放完整的流
+func TestIdempotencyStreamReplay(t *testing.T) {
+	gate := make(chan struct{})
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeStreamContent(w, upstream.model, "Hello")
+		<-gate
+		writeStreamContent(w, upstream.model, " world")
+		writeStreamEnd(w, upstream.model)
+	})
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	body := chatBody(upstream.model, true)
+
+	// 首次请求读到第一个块后再发起重试
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set(idempotencyHeader, "stream")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(resp.Body, first); err != nil {
+		t.Fatal(err)
+	}
+
+	follower := make(chan idempotencyResult, 1)
+	go func() { follower <- postIdempotent(context.Background(), server, "stream", body) }()
+	waitFor(t, "the retry to attach", func() bool { return idempotencyConsumers(key.ID, "stream") == 2 })
+	close(gate)
+
+	rest, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := string(first) + string(rest)
+	if !strings.Contains(original, "Hello") || !strings.Contains(original, " world") || !strings.HasSuffix(original, "data: [DONE]\n\n") {
+		t.Fatalf("unexpected original stream:\n%s", original)
+	}
+
+	followed := <-follower
+	if followed.err != nil || followed.status != http.StatusOK || !followed.replayed || followed.body != original {
+		t.Fatalf("followed stream: status %d, replayed %t, err %v\n%s", followed.status, followed.replayed, followed.err, followed.body)
+	}
+	replayed := postIdempotent(context.Background(), server, "stream", body)
+	if replayed.status != http.StatusOK || !replayed.replayed || replayed.body != original {
+		t.Fatalf("replayed stream: status %d, replayed %t\n%s", replayed.status, replayed.replayed, replayed.body)
+	}
+	if hits := upstream.hits.Load(); hits != 1 {
+		t.Fatalf("upstream hits = %d, want 1", hits)
+	}
+}
+
+func TestIdempotencyDifferentBody(t *testing.T) {
+	upstream := newTestUpstream(t, nil)
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+
+	if r := postIdempotent(context.Background(), server, "body", chatBody(upstream.model, false)); r.status != http.StatusOK {
+		t.Fatalf("first request: status %d, body %s", r.status, r.body)
+	}
+	other := fmt.Sprintf(`{"model":%q,"messages":[{"role":"user","content":"something else"}]}`, upstream.model)
+	if r := postIdempotent(context.Background(), server, "body", other); r.status != http.StatusUnprocessableEntity {
+		t.Fatalf("different body: status %d, want 422, body %s", r.status, r.body)
+	}
+	if hits := upstream.hits.Load(); hits != 1 {
+		t.Fatalf("upstream hits = %d, want 1", hits)
+	}
+}
+
+// 全部渠道失败的 5xx 响应不保留，重试会再次转发
+func TestIdempotencyFailureNotStored(t *testing.T) {
+	var fail sync.Mutex
+	failing := true
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		fail.Lock()
+		defer fail.Unlock()
+		if failing {
+			http.Error(w, `{"error":{"message":"overloaded"}}`, http.StatusServiceUnavailable)
+			return
+		}
+		upstream.writeCompletion(w, r)
+	})
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	body := chatBody(upstream.model, false)
+
+	if r := postIdempotent(context.Background(), server, "failure", body); r.status != http.StatusBadGateway {
+		t.Fatalf("first request: status %d, want 502, body %s", r.status, r.body)
+	}
+	failedHits := upstream.hits.Load()
+	fail.Lock()
+	failing = false
+	fail.Unlock()
+
+	r := postIdempotent(context.Background(), server, "failure", body)
+	if r.status != http.StatusOK || r.replayed {
+		t.Fatalf("retry: status %d, replayed %t, body %s", r.status, r.replayed, r.body)
+	}
+	if hits := upstream.hits.Load(); hits != failedHits+1 {
+		t.Fatalf("upstream hits = %d, want %d", hits, failedHits+1)
+	}
+}
+
+// 客户端断开且没有重试等待时取消上游请求；有重试等待时继续执行，重试得到结果
+func TestIdempotencyClientDisconnect(t *testing.T) {
+	canceled := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		// 读完请求体后服务端才会检测到连接断开
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+			canceled <- struct{}{}
+		case <-release:
+			upstream.writeCompletion(w, r)
+		}
+	})
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	body := chatBody(upstream.model, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go postIdempotent(ctx, server, "alone", body)
+	waitFor(t, "the upstream request", func() bool { return upstream.hits.Load() == 1 })
+	cancel()
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream request not canceled after the client disconnected")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go postIdempotent(ctx, server, "followed", body)
+	waitFor(t, "the upstream request", func() bool { return upstream.hits.Load() == 2 })
+	follower := make(chan idempotencyResult, 1)
+	go func() { follower <- postIdempotent(context.Background(), server, "followed", body) }()
+	waitFor(t, "the retry to attach", func() bool { return idempotencyConsumers(key.ID, "followed") == 2 })
+	cancel()
+	waitFor(t, "the client to detach", func() bool { return idempotencyConsumers(key.ID, "followed") == 1 })
+	select {
+	case <-canceled:
+		t.Fatal("upstream request canceled while a retry was waiting")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+
+	r := <-follower
+	if r.status != http.StatusOK || !r.replayed || !strings.Contains(r.body, "chatcmpl-test") {
+		t.Fatalf("retry: status %d, replayed %t, body %s", r.status, r.replayed, r.body)
+	}
+}
+
+func setIdempotencyMaxSize(t *testing.T, mb int) {
+	t.Helper()
+	if err := op.SettingSetInt(dbmodel.SettingKeyIdempotencyMaxSize, mb); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { op.SettingSetInt(dbmodel.SettingKeyIdempotencyMaxSize, 64) })
+}
+
+// 超过存储上限时先丢弃最早完成的响应，被丢弃的键重试返回 409，不会再次转发
+func TestIdempotencyEviction(t *testing.T) {
+	setIdempotencyMaxSize(t, 1)
+	content := strings.Repeat("a", 600*1024)
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "chatcmpl-test", "object": "chat.completion", "created": 1, "model": upstream.model,
+			"choices": []map[string]any{{"index": 0, "message": map[string]any{"role": "assistant", "content": content}, "finish_reason": "stop"}},
+			"usage":   map[string]any{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+		})
+	})
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	body := chatBody(upstream.model, false)
+
+	older := postIdempotent(context.Background(), server, "older", body)
+	newer := postIdempotent(context.Background(), server, "newer", body)
+	if older.status != http.StatusOK || newer.status != http.StatusOK {
+		t.Fatalf("status %d and %d, want 200", older.status, newer.status)
+	}
+
+	if r := postIdempotent(context.Background(), server, "older", body); r.status != http.StatusConflict {
+		t.Fatalf("evicted key: status %d, want 409", r.status)
+	}
+	if r := postIdempotent(context.Background(), server, "newer", body); r.status != http.StatusOK || !r.replayed || r.body != newer.body {
+		t.Fatalf("kept key: status %d, replayed %t", r.status, r.replayed)
+	}
+	if hits := upstream.hits.Load(); hits != 2 {
+		t.Fatalf("upstream hits = %d, want 2", hits)
+	}
+}
+
+// 跟随中的流式响应超过存储上限被丢弃时，重试以错误事件结束而不是截断后正常结束
+func TestIdempotencyEvictionWhileFollowing(t *testing.T) {
+	setIdempotencyMaxSize(t, 1)
+	gate := make(chan struct{})
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeStreamContent(w, upstream.model, "Hello")
+		<-gate
+		writeStreamContent(w, upstream.model, strings.Repeat("a", 1200*1024))
+		writeStreamEnd(w, upstream.model)
+	})
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	body := chatBody(upstream.model, true)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set(idempotencyHeader, "large-stream")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(resp.Body, first); err != nil {
+		t.Fatal(err)
+	}
+
+	follower := make(chan idempotencyResult, 1)
+	go func() { follower <- postIdempotent(context.Background(), server, "large-stream", body) }()
+	waitFor(t, "the retry to attach", func() bool { return idempotencyConsumers(key.ID, "large-stream") == 2 })
+	close(gate)
+
+	original, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(original, []byte("data: [DONE]\n\n")) {
+		t.Fatal("original client did not receive the full stream")
+	}
+
+	r := <-follower
+	if r.status != http.StatusOK || !strings.Contains(r.body, "Hello") || strings.Contains(r.body, "[DONE]") {
+		t.Fatalf("followed stream: status %d\n%.200s", r.status, r.body)
+	}
+	if !strings.HasSuffix(r.body, "\n\n") || !strings.Contains(r.body, "event: error\ndata: ") || !strings.Contains(r.body, "idempotency_replay_truncated") {
+		t.Fatalf("followed stream did not end with an error event:\n%.200s", r.body)
+	}
+	// 响应已丢弃，但已计费，之后的重试不会再次转发
+	if r := postIdempotent(context.Background(), server, "large-stream", body); r.status != http.StatusConflict {
+		t.Fatalf("retry after drop: status %d, want 409", r.status)
+	}
+	if hits := upstream.hits.Load(); hits != 1 {
+		t.Fatalf("upstream hits = %d, want 1", hits)
+	}
+}
@@ -0,0 +1,61 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/helper"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/gin-gonic/gin"
+)
+
+// checkAPIKeyLimits 校验 API Key 的总额度、预付费余额和多窗口限额，通过时计入一次请求并预留预付费余额
+// 调用方需在请求结束时调用 metrics.releaseBalance 释放未结算的预留
+// 超出时记录失败日志并返回 429。这是限额唯一的执行位置，鉴权中间件只返回当前状态
+func checkAPIKeyLimits(ctx context.Context, c *gin.Context, metrics *RelayMetrics) bool {
+	apiKey, err := op.APIKeyGet(c.GetInt("api_key_id"), ctx)
+	if err != nil {
+		return true
+	}
+
+	if c.GetBool("quota_exceeded") {
+		message := fmt.Sprintf("quota exhausted: total budget $%s used", strconv.FormatFloat(apiKey.MaxCost, 'f', -1, 64))
+		if apiKey.AutoResetQuota && apiKey.NextResetTime > 0 {
+			retryAfter := max(apiKey.NextResetTime-time.Now().Unix(), 1)
+			message += fmt.Sprintf(", resets in %s", time.Duration(retryAfter)*time.Second)
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+		}
+		metrics.Save(ctx, false, errors.New(message), 0)
+		resp.Error(c, http.StatusTooManyRequests, message)
+		return false
+	}
+
+	// 预付费余额按估算费用预留，并发请求不会同时通过同一笔余额，结束时按实际费用结算
+	if apiKey.Prepaid {
+		estimate := metrics.estimateCost(metrics.RequestModel)
+		available, ok := op.APIKeyBalanceReserve(apiKey.ID, estimate)
+		if !ok {
+			message := fmt.Sprintf("insufficient balance: $%s available", strconv.FormatFloat(available, 'f', -1, 64))
+			metrics.Save(ctx, false, errors.New(message), 0)
+			resp.Error(c, http.StatusTooManyRequests, message)
+			return false
+		}
+		metrics.balanceReserved = estimate
+	}
+
+	states, exceeded := op.APIKeyLimitAcquire(apiKey)
+	helper.SetRateLimitHeaders(c.Writer.Header(), states)
+	if exceeded != nil {
+		message := helper.RateLimitMessage(*exceeded)
+		c.Header("Retry-After", helper.RetryAfter(*exceeded))
+		metrics.Save(ctx, false, errors.New(message), 0)
+		resp.Error(c, http.StatusTooManyRequests, message)
+		return false
+	}
+	return true
+}
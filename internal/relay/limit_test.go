@@ -0,0 +1,70 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+)
+
+// 预付费余额在准入时按估算费用 (100 输入、50 输出 token，即 $0.0002) 预留
+// 进行中的请求占用余额，并发请求不能同时通过同一笔余额；结束后按实际费用结算
+func TestPrepaidBalanceReservedAtAdmission(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		upstream.writeCompletion(w, r)
+	})
+	ctx := context.Background()
+	key := newTestAPIKey(t)
+	key.Prepaid = true
+	if err := op.APIKeyUpdate(&key, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.APIKeyTransactionCreate(ctx, &dbmodel.APIKeyTransaction{APIKeyID: key.ID, Type: dbmodel.TransactionTypeTopUp, Amount: 0.0003}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan bool)
+	go func() {
+		_, success := executeBatchLine(ctx, key.ID, testBatchLine(upstream.model))
+		done <- success
+	}()
+	<-started
+
+	data, success := executeBatchLine(ctx, key.ID, testBatchLine(upstream.model))
+	if success {
+		t.Fatalf("concurrent request should be rejected: %s", data)
+	}
+	var output batchOutputLine
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatal(err)
+	}
+	if output.Response == nil || output.Response.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("unexpected output: %s", data)
+	}
+
+	close(release)
+	if !<-done {
+		t.Fatal("first request failed")
+	}
+	// 实际费用 10 输入、5 输出 token，即 $0.00002
+	if got := op.APIKeyBalance(key.ID); math.Abs(got-0.00028) > 1e-12 {
+		t.Fatalf("balance = %v, want 0.00028", got)
+	}
+
+	// 预留已释放，剩余余额足够下一个请求
+	if data, success := executeBatchLine(ctx, key.ID, testBatchLine(upstream.model)); !success {
+		t.Fatalf("request after settle failed: %s", data)
+	}
+	if hits := upstream.hits.Load(); hits != 2 {
+		t.Fatalf("upstream hits = %d, want 2", hits)
+	}
+}
@@ -0,0 +1,144 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/db"
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/outbound"
+	"github.com/gin-gonic/gin"
+)
+
+// TestMain 使用临时 SQLite 数据库初始化缓存，中继测试通过 httptest 模拟上游
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	dir, err := os.MkdirTemp("", "octopus-relay-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := db.InitDB("sqlite", filepath.Join(dir, "test.db"), false); err != nil {
+		panic(err)
+	}
+	if err := op.InitCache(); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	db.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testUpstream OpenAI 兼容的模拟上游，handle 为空时返回固定的非流式响应
+type testUpstream struct {
+	server    *httptest.Server
+	hits      atomic.Int64
+	model     string
+	channelID int
+}
+
+var testUpstreamSeq atomic.Int64
+
+// newTestUpstream 启动模拟上游并创建同名的渠道、分组和模型价格 (输入 $1/M，输出 $2/M)
+func newTestUpstream(t *testing.T, handle http.HandlerFunc) *testUpstream {
+	t.Helper()
+	ctx := context.Background()
+	u := &testUpstream{model: fmt.Sprintf("test-model-%d", testUpstreamSeq.Add(1))}
+	if handle == nil {
+		handle = u.writeCompletion
+	}
+	u.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u.hits.Add(1)
+		handle(w, r)
+	}))
+	t.Cleanup(u.server.Close)
+
+	channel := &dbmodel.Channel{
+		Name:     u.model,
+		Type:     outbound.OutboundTypeOpenAIChat,
+		Enabled:  true,
+		BaseUrls: []dbmodel.BaseUrl{{URL: u.server.URL + "/v1"}},
+		Keys:     []dbmodel.ChannelKey{{Enabled: true, ChannelKey: "sk-test"}},
+		Model:    u.model,
+	}
+	if err := op.ChannelCreate(channel, ctx); err != nil {
+		t.Fatal(err)
+	}
+	u.channelID = channel.ID
+	group := &dbmodel.Group{
+		Name:  u.model,
+		Mode:  dbmodel.GroupModeRoundRobin,
+		Items: []dbmodel.GroupItem{{ChannelID: channel.ID, ModelName: u.model, Priority: 1, Weight: 1}},
+	}
+	if err := op.GroupCreate(group, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.LLMCreate(dbmodel.LLMInfo{Name: u.model, LLMPrice: dbmodel.LLMPrice{Input: 1, Output: 2}}, ctx); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// writeCompletion 返回固定用量 (10 输入、5 输出) 的 chat completion
+func (u *testUpstream) writeCompletion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": 1,
+		"model":   u.model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": "ok"},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]any{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+	})
+}
+
+// newTestAPIKey 创建不限额的 API Key
+func newTestAPIKey(t *testing.T) dbmodel.APIKey {
+	t.Helper()
+	key := dbmodel.APIKey{
+		Name:    t.Name(),
+		APIKey:  fmt.Sprintf("sk-octopus-%s", t.Name()),
+		Enabled: true,
+	}
+	if err := op.APIKeyCreate(&key, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// subscribeRelayLogs 订阅之后保存的请求日志，测试结束时取消订阅
+func subscribeRelayLogs(t *testing.T) chan dbmodel.RelayLog {
+	t.Helper()
+	logs := op.RelayLogSubscribe()
+	t.Cleanup(func() { op.RelayLogUnsubscribe(logs) })
+	return logs
+}
+
+// nextRelayLog 等待 API Key 的下一条请求日志
+func nextRelayLog(t *testing.T, logs chan dbmodel.RelayLog, apiKeyID int) dbmodel.RelayLog {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case relayLog := <-logs:
+			if relayLog.APIKeyID == apiKeyID {
+				return relayLog
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for the relay log of api key %d", apiKeyID)
+		}
+	}
+}
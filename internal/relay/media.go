@@ -0,0 +1,54 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/bestruirui/octopus/internal/helper"
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/media"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+)
+
+// mediaOutbound 发送前将请求中的远程图片/文件抓取为内联数据
+// 通过渠道的 http 客户端抓取，与上游请求使用相同的代理
+type mediaOutbound struct {
+	wrappedOutbound
+	channel *dbmodel.Channel
+}
+
+func newMediaOutbound(outAdapter model.Outbound, channel *dbmodel.Channel) *mediaOutbound {
+	return &mediaOutbound{wrappedOutbound: wrappedOutbound{outAdapter}, channel: channel}
+}
+
+func (o *mediaOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	if media.HasRemoteMedia(request) {
+		client, err := helper.ChannelHttpClient(o.channel)
+		if err != nil {
+			return nil, err
+		}
+		inlined, err := media.InlineRequest(ctx, client, request, mediaOptions())
+		if err != nil {
+			// 地址或内容不合法时换渠道也无法成功，直接返回给客户端
+			if errors.Is(err, media.ErrInvalidMedia) {
+				return nil, &model.NonRetryableError{StatusCode: http.StatusBadRequest, Code: "invalid_media", Message: err.Error()}
+			}
+			return nil, err
+		}
+		request = inlined
+	}
+	return o.Outbound.TransformRequest(ctx, request, baseUrl, key)
+}
+
+func mediaOptions() media.Options {
+	var opts media.Options
+	if size, err := op.SettingGetInt(dbmodel.SettingKeyMediaMaxSize); err == nil && size > 0 {
+		opts.MaxBytes = int64(size) << 20
+	}
+	if dimension, err := op.SettingGetInt(dbmodel.SettingKeyMediaMaxDimension); err == nil && dimension > 0 {
+		opts.MaxDimension = dimension
+	}
+	return opts
+}
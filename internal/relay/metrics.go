@@ -13,6 +13,7 @@ import (
 	transformerModel "github.com/bestruirui/octopus/internal/transformer/model"
 	"github.com/bestruirui/octopus/internal/utils/log"
 	"github.com/bestruirui/octopus/internal/utils/tokenizer"
+	"github.com/samber/lo"
 )
 
 // RelayMetrics 统一管理请求的日志记录和统计信息
@@ -40,6 +41,23 @@ type RelayMetrics struct {
 	
 	// 重试信息
 	Attempts []model.ChannelAttempt
+
+	// 结构化输出校验结果
+	StructuredOutput string
+
+	// UpstreamRequests 拆分 n 后实际发送的上游请求数，按次计费时按该数量计费
+	UpstreamRequests int
+
+	// 已计入统计的费用，按实际用量调整时只更新与它的差额
+	chargedCost float64
+	// ClientAborted 客户端在流式响应结束前断开
+	ClientAborted bool
+	// UsageSource 用量的计算方式
+	UsageSource model.UsageSource
+	// UpstreamCost 按渠道实际价格计算的上游费用，计费金额仍按模型价格
+	UpstreamCost float64
+	// balanceReserved 准入时从预付费余额中预留的金额，扣费时结算
+	balanceReserved float64
 }
 
 // NewRelayMetrics 创建新的 RelayMetrics
@@ -54,6 +72,16 @@ func (m *RelayMetrics) SetAPIKeyID(apiKeyID int) {
 	m.APIKeyID = apiKeyID
 }
 
+// SetUpstreamRequests 设置单次转发实际发送的上游请求数
+func (m *RelayMetrics) SetUpstreamRequests(count int) {
+	m.UpstreamRequests = count
+}
+
+// requestCount 上游请求数，至少为 1
+func (m *RelayMetrics) requestCount() float64 {
+	return float64(max(m.UpstreamRequests, 1))
+}
+
 // SetChannel 设置通道信息
 func (m *RelayMetrics) SetChannel(channelID int, channelName string, actualModel string) {
 	m.ChannelID = channelID
@@ -68,30 +96,12 @@ func (m *RelayMetrics) EstimateAndDeductCost(ctx context.Context) {
 		return // 已经扣除过，避免重复扣除
 	}
 
-	modelPrice := price.GetLLMPrice(m.ActualModel)
-	if modelPrice == nil {
-		// 没有定价信息，使用默认最小成本
-		m.EstimatedCost = 0.0001 // $0.0001 作为最小成本
-	} else if modelPrice.Type == "request" {
-		// 按请求计费的模型，使用固定成本
-		m.EstimatedCost = modelPrice.Request
-	} else {
-		// 按 token 计费的模型，估算合理的最小成本
-		// 使用更合理的估算：假设最少 100 个输入 token 和 50 个输出 token
-		// 这样可以减少大部分请求的成本调整幅度
-		estimatedInputTokens := 100.0
-		estimatedOutputTokens := 50.0
-		m.EstimatedCost = (estimatedInputTokens*modelPrice.Input + estimatedOutputTokens*modelPrice.Output) * 1e-6
-		
-		// 如果估算成本太小，使用最小成本
-		if m.EstimatedCost < 0.0001 {
-			m.EstimatedCost = 0.0001
-		}
-	}
+	m.EstimatedCost = m.estimateCost(m.ActualModel)
 
 	// 立即扣除估算成本
 	m.Stats.InputCost = m.EstimatedCost
 	m.Stats.OutputCost = 0
+	m.chargedCost = m.EstimatedCost
 
 	// 更新统计信息（标记为请求开始）
 	op.StatsChannelUpdate(m.ChannelID, m.Stats)
@@ -106,6 +116,32 @@ func (m *RelayMetrics) EstimateAndDeductCost(ctx context.Context) {
 		m.ChannelID, m.ActualModel, m.EstimatedCost)
 }
 
+// estimateCost 按模型价格估算单次请求的最小成本，用于预扣费和预付费余额的预留
+func (m *RelayMetrics) estimateCost(modelName string) float64 {
+	modelPrice := price.GetLLMPrice(modelName)
+	if modelPrice == nil {
+		// 没有定价信息，使用默认最小成本
+		return 0.0001 // $0.0001 作为最小成本
+	}
+	if modelPrice.Type == "request" {
+		// 按请求计费的模型，使用固定成本
+		return modelPrice.Request * m.requestCount()
+	}
+	// 按 token 计费的模型，估算合理的最小成本
+	// 使用更合理的估算：假设最少 100 个输入 token 和 50 个输出 token
+	// 这样可以减少大部分请求的成本调整幅度
+	estimatedInputTokens := 100.0
+	estimatedOutputTokens := 50.0
+	// 如果估算成本太小，使用最小成本
+	return max((estimatedInputTokens*modelPrice.Input+estimatedOutputTokens*modelPrice.Output)*1e-6*m.requestCount(), 0.0001)
+}
+
+// releaseBalance 释放准入时预留的预付费余额，已在保存时结算则不做任何事
+func (m *RelayMetrics) releaseBalance() {
+	op.APIKeyBalanceRelease(m.APIKeyID, m.balanceReserved)
+	m.balanceReserved = 0
+}
+
 // SetFirstTokenTime 设置首个 Token 时间
 func (m *RelayMetrics) SetFirstTokenTime(t time.Time) {
 	m.FirstTokenTime = t
@@ -133,6 +169,16 @@ func (m *RelayMetrics) AddAttempt(round int, attemptNum int, success bool, err e
 	m.Attempts = append(m.Attempts, attempt)
 }
 
+// SetClientAborted 标记客户端提前断开，之后读取到的上游用量记为 drained
+func (m *RelayMetrics) SetClientAborted() {
+	m.ClientAborted = true
+}
+
+// SetStructuredOutput 记录结构化输出的处理结果
+func (m *RelayMetrics) SetStructuredOutput(outcome string) {
+	m.StructuredOutput = outcome
+}
+
 // SetInternalResponse 设置内部响应并计算费用
 func (m *RelayMetrics) SetInternalResponse(resp *transformerModel.InternalLLMResponse) {
 	m.InternalResponse = resp
@@ -145,74 +191,145 @@ func (m *RelayMetrics) SetInternalResponse(resp *transformerModel.InternalLLMRes
 	usage := resp.Usage
 	m.Stats.InputToken = usage.PromptTokens
 	m.Stats.OutputToken = usage.CompletionTokens
+	m.UsageSource = lo.Ternary(m.ClientAborted, model.UsageSourceDrained, model.UsageSourceUpstream)
 
 	// 计算实际费用
 	modelPrice := price.GetLLMPrice(m.ActualModel)
 	if modelPrice == nil {
 		return
 	}
+	m.applyCost(usageCost(modelPrice, usage, m.requestCount()))
+}
 
-	var actualInputCost, actualOutputCost float64
-
+// usageCost 按价格和上游返回的用量计算输入、输出费用
+// 上下文超过分档阈值时使用分档价格，音频、图片、推理 token 和 1 小时缓存写入按各自的价格计算
+func usageCost(modelPrice *model.LLMPrice, usage *transformerModel.Usage, requests float64) (float64, float64) {
 	if modelPrice.Type == "request" {
-		actualInputCost = modelPrice.Request
-		actualOutputCost = 0
+		return modelPrice.Request * requests, 0
+	}
+	var cachedTokens int64
+	if usage.PromptTokensDetails != nil {
+		cachedTokens = usage.PromptTokensDetails.CachedTokens
+	}
+	// Anthropic 的 input_tokens 不含缓存读取和写入部分
+	textInput := usage.PromptTokens
+	contextTokens := usage.PromptTokens
+	if usage.AnthropicUsage {
+		contextTokens += cachedTokens + usage.CacheCreationInputTokens
 	} else {
-		if usage.PromptTokensDetails == nil {
-			usage.PromptTokensDetails = &transformerModel.PromptTokensDetails{
-				CachedTokens: 0,
-			}
-		}
-		if usage.AnthropicUsage {
-			actualInputCost = (float64(usage.PromptTokensDetails.CachedTokens)*modelPrice.CacheRead +
-				float64(usage.PromptTokens)*modelPrice.Input +
-				float64(usage.CacheCreationInputTokens)*modelPrice.CacheWrite) * 1e-6
-		} else {
-			actualInputCost = (float64(usage.PromptTokensDetails.CachedTokens)*modelPrice.CacheRead + float64(usage.PromptTokens-usage.PromptTokensDetails.CachedTokens)*modelPrice.Input) * 1e-6
+		textInput -= cachedTokens
+	}
+	// 拆分 n 时用量为各请求之和，分档按单个请求的上下文判断
+	rates := modelPrice.ForContext(int64(float64(contextTokens) / max(requests, 1)))
+
+	audioInput := modalityTokens(usage.PromptModalityTokenDetails, "audio")
+	if audioInput == 0 && usage.PromptTokensDetails != nil {
+		audioInput = usage.PromptTokensDetails.AudioTokens
+	}
+	imageInput := modalityTokens(usage.PromptModalityTokenDetails, "image")
+	textInput = max(textInput-audioInput-imageInput, 0)
+	cacheWrite1h := usage.CacheCreation1hInputTokens
+	cacheWrite5m := usage.CacheCreationInputTokens - cacheWrite1h
+	inputCost := (float64(textInput)*rates.Input +
+		float64(audioInput)*rates.InputAudioRate() +
+		float64(imageInput)*rates.InputImageRate() +
+		float64(cachedTokens)*rates.CacheRead +
+		float64(cacheWrite5m)*rates.CacheWrite +
+		float64(cacheWrite1h)*rates.CacheWrite1hRate()) * 1e-6
+
+	var reasoningOutput, audioOutput int64
+	if usage.CompletionTokensDetails != nil {
+		reasoningOutput = usage.CompletionTokensDetails.ReasoningTokens
+		audioOutput = usage.CompletionTokensDetails.AudioTokens
+	}
+	if tokens := modalityTokens(usage.CompletionModalityTokenDetails, "audio"); tokens > 0 {
+		audioOutput = tokens
+	}
+	imageOutput := modalityTokens(usage.CompletionModalityTokenDetails, "image")
+	textOutput := max(usage.CompletionTokens-reasoningOutput-audioOutput-imageOutput, 0)
+	outputCost := (float64(textOutput)*rates.Output +
+		float64(reasoningOutput)*rates.ReasoningRate() +
+		float64(audioOutput)*rates.OutputAudioRate() +
+		float64(imageOutput)*rates.OutputImageRate()) * 1e-6
+	return inputCost, outputCost
+}
+
+func modalityTokens(details []transformerModel.ModalityTokenCount, modality string) int64 {
+	var tokens int64
+	for _, detail := range details {
+		if detail.Modality == modality {
+			tokens += detail.TokenCount
 		}
-		actualOutputCost = float64(usage.CompletionTokens) * modelPrice.Output * 1e-6
 	}
+	return tokens
+}
 
-	// 如果已经扣除过预估成本，需要调整差额
-	if m.CostDeducted {
-		// 计算差额（实际成本 - 已扣除的预估成本）
-		costDifference := (actualInputCost + actualOutputCost) - m.EstimatedCost
-		
-		// 更新为实际成本
-		m.Stats.InputCost = actualInputCost
-		m.Stats.OutputCost = actualOutputCost
-		
-		// 只更新差额部分到统计
-		// 将差额按照实际成本的比例分配到 InputCost 和 OutputCost
-		if costDifference != 0 {
-			totalActualCost := actualInputCost + actualOutputCost
-			var inputDiff, outputDiff float64
-			
-			if totalActualCost > 0 {
-				// 按实际成本比例分配差额
-				inputDiff = costDifference * (actualInputCost / totalActualCost)
-				outputDiff = costDifference * (actualOutputCost / totalActualCost)
-			} else {
-				// 如果实际成本为0，将差额全部计入 InputCost（退回预估成本）
-				inputDiff = costDifference
-				outputDiff = 0
-			}
-			
-			adjustmentMetrics := model.StatsMetrics{
-				InputCost:  inputDiff,
-				OutputCost: outputDiff,
-			}
-			op.StatsChannelUpdate(m.ChannelID, adjustmentMetrics)
-			op.StatsTotalUpdate(adjustmentMetrics)
-			op.StatsHourlyUpdate(adjustmentMetrics)
-			op.StatsDailyUpdate(context.Background(), adjustmentMetrics)
-			op.StatsAPIKeyUpdate(m.APIKeyID, adjustmentMetrics)
+// resolveUpstreamCost 计算实际支付给上游的费用
+// 渠道覆盖了该模型的价格时按覆盖价格重新计算，否则为计费金额乘以渠道的价格倍率
+func (m *RelayMetrics) resolveUpstreamCost() {
+	if !m.CostDeducted {
+		return
+	}
+	m.UpstreamCost = m.chargedCost
+	channel, err := op.ChannelGet(m.ChannelID, context.Background())
+	if err != nil {
+		return
+	}
+	override := channel.PriceOverride(m.ActualModel)
+	switch {
+	case override == nil:
+		if channel.PriceMultiplier != nil {
+			m.UpstreamCost = m.chargedCost * *channel.PriceMultiplier
 		}
+	case m.InternalResponse != nil && m.InternalResponse.Usage != nil && m.UsageSource != model.UsageSourceEstimated:
+		inputCost, outputCost := usageCost(override, m.InternalResponse.Usage, m.requestCount())
+		m.UpstreamCost = inputCost + outputCost
+	case override.Type == "request":
+		m.UpstreamCost = override.Request * m.requestCount()
+	default:
+		m.UpstreamCost = (float64(m.Stats.InputToken)*override.Input + float64(m.Stats.OutputToken)*override.Output) * 1e-6
+	}
+}
+
+// applyCost 将费用更新为实际费用
+func (m *RelayMetrics) applyCost(actualInputCost, actualOutputCost float64) {
+	m.Stats.InputCost = actualInputCost
+	m.Stats.OutputCost = actualOutputCost
+
+	// 如果之前没有扣除预估成本，直接设置实际成本
+	if !m.CostDeducted {
+		return
+	}
+
+	// 已经扣除过预估成本，只更新差额部分到统计
+	costDifference := (actualInputCost + actualOutputCost) - m.chargedCost
+	m.chargedCost = actualInputCost + actualOutputCost
+	if costDifference == 0 {
+		return
+	}
+
+	// 将差额按照实际成本的比例分配到 InputCost 和 OutputCost
+	totalActualCost := actualInputCost + actualOutputCost
+	var inputDiff, outputDiff float64
+	if totalActualCost > 0 {
+		// 按实际成本比例分配差额
+		inputDiff = costDifference * (actualInputCost / totalActualCost)
+		outputDiff = costDifference * (actualOutputCost / totalActualCost)
 	} else {
-		// 如果之前没有扣除预估成本，直接设置实际成本
-		m.Stats.InputCost = actualInputCost
-		m.Stats.OutputCost = actualOutputCost
+		// 如果实际成本为0，将差额全部计入 InputCost（退回预估成本）
+		inputDiff = costDifference
+		outputDiff = 0
 	}
+
+	adjustmentMetrics := model.StatsMetrics{
+		InputCost:  inputDiff,
+		OutputCost: outputDiff,
+	}
+	op.StatsChannelUpdate(m.ChannelID, adjustmentMetrics)
+	op.StatsTotalUpdate(adjustmentMetrics)
+	op.StatsHourlyUpdate(adjustmentMetrics)
+	op.StatsDailyUpdate(context.Background(), adjustmentMetrics)
+	op.StatsAPIKeyUpdate(m.APIKeyID, adjustmentMetrics)
 }
 
 // Save 保存日志和统计信息
@@ -224,6 +341,7 @@ func (m *RelayMetrics) Save(ctx context.Context, success bool, err error, succes
 
 	// Ensure stats are calculated even if usage info was missing
 	m.resolveMissingStats()
+	m.resolveUpstreamCost()
 
 	// 保存统计信息
 	m.saveStats(success, duration)
@@ -233,24 +351,48 @@ func (m *RelayMetrics) Save(ctx context.Context, success bool, err error, succes
 }
 
 func (m *RelayMetrics) resolveMissingStats() {
+	inputMissing := m.Stats.InputToken == 0
+
 	// If InputToken is 0, try to calculate it from request
-	if m.Stats.InputToken == 0 && m.InternalRequest != nil {
-		m.Stats.InputToken = int64(countRequestTokens(m.InternalRequest, m.ActualModel))
+	if inputMissing && m.InternalRequest != nil {
+		// 拆分 n 时每个上游请求都包含完整的输入
+		m.Stats.InputToken = int64(countRequestTokens(m.InternalRequest, m.ActualModel)) * int64(m.requestCount())
 	}
 
 	// If OutputToken is 0, try to calculate it from response (if available)
+	outputEstimated := false
 	if m.Stats.OutputToken == 0 && m.InternalResponse != nil {
 		m.Stats.OutputToken = int64(countResponseTokens(m.InternalResponse, m.ActualModel))
+		outputEstimated = m.Stats.OutputToken > 0
 	}
 
-	// Recalculate cost if needed (if cost is 0 but tokens are > 0)
 	modelPrice := price.GetLLMPrice(m.ActualModel)
+
+	// 有响应但上游未返回 (完整的) 用量，如客户端中断流式请求，按已传输的内容计费
+	if m.InternalResponse != nil && (inputMissing || outputEstimated) {
+		m.UsageSource = model.UsageSourceEstimated
+		if modelPrice == nil {
+			return
+		}
+		if modelPrice.Type == "request" {
+			m.applyCost(modelPrice.Request*m.requestCount(), 0)
+			return
+		}
+		inputCost := m.Stats.InputCost
+		if inputMissing || m.InternalResponse.Usage == nil {
+			inputCost = float64(m.Stats.InputToken) * modelPrice.Input * 1e-6
+		}
+		m.applyCost(inputCost, float64(m.Stats.OutputToken)*modelPrice.Output*1e-6)
+		return
+	}
+
+	// Recalculate cost if needed (if cost is 0 but tokens are > 0)
 	if modelPrice == nil {
 		return
 	}
 
 	if modelPrice.Type == "request" {
-		m.Stats.InputCost = modelPrice.Request
+		m.Stats.InputCost = modelPrice.Request * m.requestCount()
 		m.Stats.OutputCost = 0
 	} else {
 		// Simple recalculation based on tokens
@@ -277,6 +419,8 @@ func countRequestTokens(req *transformerModel.InternalLLMRequest, modelName stri
 			text += s
 		}
 	}
+	// 图片、工具定义与消息格式开销单独计数，文本合并后统一分词
+	extra := 0
 	for _, msg := range req.Messages {
 		if msg.Content.Content != nil {
 			text += *msg.Content.Content
@@ -285,9 +429,25 @@ func countRequestTokens(req *transformerModel.InternalLLMRequest, modelName stri
 			if part.Text != nil {
 				text += *part.Text
 			}
+			if part.Type == "image_url" && part.ImageURL != nil {
+				extra += tokenizer.CountImageTokens(modelName, part.ImageURL.URL, lo.FromPtr(part.ImageURL.Detail))
+			}
+		}
+		for _, toolCall := range msg.ToolCalls {
+			text += toolCall.Function.Name + toolCall.Function.Arguments
 		}
 	}
-	return tokenizer.CountTokens(text, modelName)
+	extra += tokenizer.MessagesOverhead(modelName, len(req.Messages))
+	tools := 0
+	for _, tool := range req.Tools {
+		if tool.Type != "function" {
+			continue
+		}
+		tools++
+		extra += tokenizer.CountToolTokens(modelName, tool.Function.Name, tool.Function.Description, string(tool.Function.Parameters))
+	}
+	extra += tokenizer.ToolsOverhead(modelName, tools)
+	return tokenizer.CountTokens(text, modelName) + extra
 }
 
 func countResponseTokens(resp *transformerModel.InternalLLMResponse, modelName string) int {
@@ -305,6 +465,11 @@ func countResponseTokens(resp *transformerModel.InternalLLMResponse, modelName s
 					text += *part.Text
 				}
 			}
+			// 推理内容和工具调用参数同样按输出计费
+			text += choice.Message.GetReasoningContent()
+			for _, toolCall := range choice.Message.ToolCalls {
+				text += toolCall.Function.Name + toolCall.Function.Arguments
+			}
 		}
 	}
 	return tokenizer.CountTokens(text, modelName)
@@ -314,9 +479,10 @@ func countResponseTokens(resp *transformerModel.InternalLLMResponse, modelName s
 func (m *RelayMetrics) saveStats(success bool, duration time.Duration) {
 	// 创建用于更新的指标（不包括成本，因为成本已经在前面扣除了）
 	updateMetrics := model.StatsMetrics{
-		InputToken:  m.Stats.InputToken,
-		OutputToken: m.Stats.OutputToken,
-		WaitTime:    duration.Milliseconds(),
+		InputToken:   m.Stats.InputToken,
+		OutputToken:  m.Stats.OutputToken,
+		UpstreamCost: m.UpstreamCost,
+		WaitTime:     duration.Milliseconds(),
 	}
 
 	if success {
@@ -331,6 +497,30 @@ func (m *RelayMetrics) saveStats(success bool, duration time.Duration) {
 	op.StatsDailyUpdate(context.Background(), updateMetrics)
 	op.StatsAPIKeyUpdate(m.APIKeyID, updateMetrics)
 
+	// 按模型统计不参与预扣费，直接计入最终费用
+	modelMetrics := updateMetrics
+	modelMetrics.InputCost = m.Stats.InputCost
+	modelMetrics.OutputCost = m.Stats.OutputCost
+	op.StatsModelUpdate(lo.CoalesceOrEmpty(m.ActualModel, m.RequestModel), m.ChannelID, modelMetrics)
+
+	// 按 API Key × 模型 统计用于导出用量，同样计入最终费用
+	cacheRead, cacheWrite := m.cacheTokens()
+	op.StatsAPIKeyModelUpdate(m.APIKeyID, lo.CoalesceOrEmpty(m.ActualModel, m.RequestModel), model.StatsAPIKeyModel{
+		StatsMetrics:    modelMetrics,
+		CacheReadToken:  cacheRead,
+		CacheWriteToken: cacheWrite,
+	})
+
+	// 限额按 API Key 统计中实际扣除的费用计算，与 MaxCost 保持一致
+	if m.CostDeducted {
+		op.APIKeyLimitRecord(m.APIKeyID, m.Stats.InputToken+m.Stats.OutputToken, m.chargedCost)
+		if err := op.APIKeyDebit(context.Background(), m.APIKeyID, lo.CoalesceOrEmpty(m.ActualModel, m.RequestModel), m.chargedCost, m.balanceReserved); err != nil {
+			log.Errorf("failed to debit api key %d: %v", m.APIKeyID, err)
+		}
+		m.balanceReserved = 0
+	}
+	m.releaseBalance()
+
 	m.ActualCostSaved = true
 
 	log.Infof("channel: %d, model: %s, success: %t, wait time: %d, input token: %d, output token: %d, input cost: %f, output cost: %f total cost: %f",
@@ -339,10 +529,23 @@ func (m *RelayMetrics) saveStats(success bool, duration time.Duration) {
 		m.Stats.InputCost, m.Stats.OutputCost, m.Stats.InputCost+m.Stats.OutputCost)
 }
 
+// cacheTokens 返回上游用量中的缓存命中和缓存写入 Token 数
+func (m *RelayMetrics) cacheTokens() (read int64, write int64) {
+	if m.InternalResponse == nil || m.InternalResponse.Usage == nil {
+		return 0, 0
+	}
+	usage := m.InternalResponse.Usage
+	if usage.PromptTokensDetails != nil {
+		read = usage.PromptTokensDetails.CachedTokens
+	}
+	return read, usage.CacheCreationInputTokens
+}
+
 // saveLog 保存日志
 func (m *RelayMetrics) saveLog(ctx context.Context, err error, duration time.Duration, successfulRound int) {
 	relayLog := model.RelayLog{
 		Time:             m.StartTime.Unix(),
+		APIKeyID:         m.APIKeyID,
 		RequestModelName: m.RequestModel,
 		ChannelName:      m.ChannelName,
 		ChannelId:        m.ChannelID,
@@ -351,6 +554,7 @@ func (m *RelayMetrics) saveLog(ctx context.Context, err error, duration time.Dur
 		Attempts:         m.Attempts,
 		TotalAttempts:    len(m.Attempts),
 		SuccessfulRound:  successfulRound,
+		StructuredOutput: m.StructuredOutput,
 	}
 
 	// 设置首字时间（流式场景）
@@ -362,8 +566,19 @@ func (m *RelayMetrics) saveLog(ctx context.Context, err error, duration time.Dur
 	if m.InternalResponse != nil && m.InternalResponse.Usage != nil {
 		relayLog.InputTokens = int(m.InternalResponse.Usage.PromptTokens)
 		relayLog.OutputTokens = int(m.InternalResponse.Usage.CompletionTokens)
+		cacheRead, cacheWrite := m.cacheTokens()
+		relayLog.CacheReadTokens = int(cacheRead)
+		relayLog.CacheWriteTokens = int(cacheWrite)
+		relayLog.Cost = m.Stats.InputCost + m.Stats.OutputCost
+	}
+	// 本地估算的用量以统计值为准
+	if m.UsageSource == model.UsageSourceEstimated {
+		relayLog.InputTokens = int(m.Stats.InputToken)
+		relayLog.OutputTokens = int(m.Stats.OutputToken)
 		relayLog.Cost = m.Stats.InputCost + m.Stats.OutputCost
 	}
+	relayLog.UsageSource = m.UsageSource
+	relayLog.UpstreamCost = m.UpstreamCost
 
 	// 设置请求内容
 	if m.InternalRequest != nil {
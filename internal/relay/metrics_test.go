@@ -0,0 +1,134 @@
+package relay
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/samber/lo"
+)
+
+func TestUsageCost(t *testing.T) {
+	price := dbmodel.LLMPrice{
+		Input: 1, Output: 2, CacheRead: 0.1, CacheWrite: 1.25,
+		Reasoning: 3, InputAudio: 10, OutputAudio: 20,
+		ContextTiers: []dbmodel.LLMPriceTier{{Threshold: 200000, Input: 2, Output: 4, CacheRead: 0.2}},
+	}
+	explicit1h := price
+	explicit1h.CacheWrite1h = 3
+	perRequest := dbmodel.LLMPrice{Type: "request", Request: 0.01, Input: 1}
+
+	tests := []struct {
+		name          string
+		price         dbmodel.LLMPrice
+		usage         model.Usage
+		requests      float64
+		input, output float64
+	}{
+		{
+			// OpenAI 的 prompt_tokens 包含缓存读取部分
+			"openai cached tokens", price,
+			model.Usage{PromptTokens: 100000, CompletionTokens: 10000, PromptTokensDetails: &model.PromptTokensDetails{CachedTokens: 40000}},
+			1, 0.064, 0.02,
+		},
+		{
+			// Anthropic 的 input_tokens 不含缓存，1 小时写入默认按 5 分钟写入价的 1.6 倍
+			"anthropic cache read and writes", price,
+			model.Usage{AnthropicUsage: true, PromptTokens: 60000, CompletionTokens: 10000, PromptTokensDetails: &model.PromptTokensDetails{CachedTokens: 40000},
+				CacheCreationInputTokens: 50000, CacheCreation1hInputTokens: 20000},
+			1, 0.1415, 0.02,
+		},
+		{
+			"explicit 1h cache write price", explicit1h,
+			model.Usage{AnthropicUsage: true, CacheCreationInputTokens: 20000, CacheCreation1hInputTokens: 20000},
+			1, 0.06, 0,
+		},
+		{
+			// 缓存部分计入上下文长度，使请求进入分档
+			"anthropic cache pushes into tier", price,
+			model.Usage{AnthropicUsage: true, PromptTokens: 60000, CompletionTokens: 10000, PromptTokensDetails: &model.PromptTokensDetails{CachedTokens: 150000}},
+			1, 0.15, 0.04,
+		},
+		{"tier threshold is exclusive", price, model.Usage{PromptTokens: 200000}, 1, 0.2, 0},
+		{"above tier threshold", price, model.Usage{PromptTokens: 200001}, 1, 0.400002, 0},
+		{"fan-out divides context per request", price, model.Usage{PromptTokens: 300000}, 2, 0.3, 0},
+		{"single request over tier", price, model.Usage{PromptTokens: 300000}, 1, 0.6, 0},
+		{
+			// 图片未设置单独价格时按输入、输出价格计算
+			"audio, image and reasoning", price,
+			model.Usage{
+				PromptTokens: 100000, CompletionTokens: 50000,
+				PromptTokensDetails:            &model.PromptTokensDetails{AudioTokens: 10000},
+				PromptModalityTokenDetails:     []model.ModalityTokenCount{{Modality: "image", TokenCount: 20000}},
+				CompletionTokensDetails:        &model.CompletionTokensDetails{ReasoningTokens: 20000, AudioTokens: 10000},
+				CompletionModalityTokenDetails: []model.ModalityTokenCount{{Modality: "image", TokenCount: 5000}},
+			},
+			1, 0.19, 0.3,
+		},
+		{
+			// Gemini 按模态返回的音频 token 优先于 details 中的数量
+			"modality audio overrides details", price,
+			model.Usage{
+				PromptTokens:               100000,
+				PromptTokensDetails:        &model.PromptTokensDetails{AudioTokens: 10000},
+				PromptModalityTokenDetails: []model.ModalityTokenCount{{Modality: "audio", TokenCount: 5000}},
+			},
+			1, 0.145, 0,
+		},
+		{"per-request price", perRequest, model.Usage{PromptTokens: 1000000}, 3, 0.03, 0},
+	}
+	for _, tt := range tests {
+		input, output := usageCost(&tt.price, &tt.usage, tt.requests)
+		if math.Abs(input-tt.input) > 1e-9 || math.Abs(output-tt.output) > 1e-9 {
+			t.Errorf("%s: cost = %v/%v, want %v/%v", tt.name, input, output, tt.input, tt.output)
+		}
+	}
+}
+
+func TestResolveUpstreamCost(t *testing.T) {
+	usage := &model.Usage{PromptTokens: 100000, CompletionTokens: 10000}
+	override := func(p dbmodel.LLMPrice) *[]dbmodel.ChannelPriceOverride {
+		return &[]dbmodel.ChannelPriceOverride{{Model: "UPSTREAM-MODEL", LLMPrice: p}}
+	}
+	tests := []struct {
+		name       string
+		multiplier *float64
+		overrides  *[]dbmodel.ChannelPriceOverride
+		source     dbmodel.UsageSource
+		requests   int
+		deducted   bool
+		want       float64
+	}{
+		{"no pricing", nil, nil, dbmodel.UsageSourceUpstream, 1, true, 0.5},
+		{"multiplier", lo.ToPtr(0.4), nil, dbmodel.UsageSourceUpstream, 1, true, 0.2},
+		{"override recomputes from usage", nil, override(dbmodel.LLMPrice{Input: 0.5, Output: 1}), dbmodel.UsageSourceUpstream, 1, true, 0.06},
+		{"override wins over multiplier", lo.ToPtr(0.4), override(dbmodel.LLMPrice{Input: 0.5, Output: 1}), dbmodel.UsageSourceDrained, 1, true, 0.06},
+		// 估算用量没有细分，按输入、输出 token 数计算
+		{"override with estimated usage", nil, override(dbmodel.LLMPrice{Input: 0.5, Output: 1, CacheRead: 0.01}), dbmodel.UsageSourceEstimated, 1, true, 0.07},
+		{"per-request override", nil, override(dbmodel.LLMPrice{Type: "request", Request: 0.02}), dbmodel.UsageSourceEstimated, 3, true, 0.06},
+		{"not deducted", lo.ToPtr(0.4), nil, dbmodel.UsageSourceUpstream, 1, false, 0},
+	}
+	for _, tt := range tests {
+		upstream := newTestUpstream(t, nil)
+		req := &dbmodel.ChannelUpdateRequest{ID: upstream.channelID, PriceMultiplier: tt.multiplier, PriceOverrides: tt.overrides}
+		if _, err := op.ChannelUpdate(req, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		m := NewRelayMetrics("upstream-model")
+		m.SetChannel(upstream.channelID, "test", "upstream-model")
+		m.SetUpstreamRequests(tt.requests)
+		m.CostDeducted = tt.deducted
+		m.chargedCost = 0.5
+		m.InternalResponse = &model.InternalLLMResponse{Usage: usage}
+		m.UsageSource = tt.source
+		m.Stats.InputToken, m.Stats.OutputToken = 120000, 10000
+		m.resolveUpstreamCost()
+		if math.Abs(m.UpstreamCost-tt.want) > 1e-9 {
+			t.Errorf("%s: upstream cost = %v, want %v", tt.name, m.UpstreamCost, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package relay
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+)
+
+// isNDJSONContentType 判断上游是否返回按行分隔的 JSON 流（如 Ollama / llama.cpp）
+func isNDJSONContentType(ct string) bool {
+	ct = strings.ToLower(ct)
+	return strings.Contains(ct, "application/x-ndjson") ||
+		strings.Contains(ct, "application/jsonl") ||
+		strings.Contains(ct, "application/json-lines")
+}
+
+// readNDJSON 逐行读取 NDJSON 流，跳过空行，单行大小受 maxSize 限制
+func readNDJSON(r io.Reader, maxSize int) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		reader := bufio.NewReader(r)
+		var line []byte
+		for {
+			chunk, isPrefix, err := reader.ReadLine()
+			if len(chunk) > 0 {
+				if maxSize > 0 && len(line)+len(chunk) > maxSize {
+					yield("", fmt.Errorf("ndjson line exceeds max size %d", maxSize))
+					return
+				}
+				line = append(line, chunk...)
+			}
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					if data := bytes.TrimSpace(line); len(data) > 0 {
+						yield(string(data), nil)
+					}
+					return
+				}
+				yield("", err)
+				return
+			}
+			if isPrefix {
+				continue
+			}
+			if data := bytes.TrimSpace(line); len(data) > 0 {
+				if !yield(string(data), nil) {
+					return
+				}
+			}
+			line = line[:0]
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package relay
+
+import (
+	"strings"
+	"testing"
+)
+
+func collectNDJSON(t *testing.T, input string, maxSize int) ([]string, error) {
+	t.Helper()
+	var lines []string
+	for line, err := range readNDJSON(strings.NewReader(input), maxSize) {
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func TestReadNDJSON(t *testing.T) {
+	// 第二行超过 bufio 默认缓冲区，需要拼接多个分片
+	long := `{"data":"` + strings.Repeat("x", 10000) + `"}`
+	input := "{\"a\":1}\n\n  \r\n" + long + "\r\n{\"b\":2}"
+
+	lines, err := collectNDJSON(t, input, 0)
+	if err != nil {
+		t.Fatalf("readNDJSON: %v", err)
+	}
+	want := []string{`{"a":1}`, long, `{"b":2}`}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("line %d = %.40q, want %.40q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestReadNDJSONMaxSize(t *testing.T) {
+	input := "{\"a\":1}\n{\"data\":\"" + strings.Repeat("x", 100) + "\"}\n{\"b\":2}\n"
+	lines, err := collectNDJSON(t, input, 64)
+	if err == nil {
+		t.Fatal("oversized line should fail")
+	}
+	if len(lines) != 1 || lines[0] != `{"a":1}` {
+		t.Fatalf("lines before error = %v", lines)
+	}
+}
+
+func TestReadNDJSONStopsWhenConsumerBreaks(t *testing.T) {
+	count := 0
+	for range readNDJSON(strings.NewReader("{}\n{}\n{}\n"), 0) {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Fatalf("yielded %d lines after break", count)
+	}
+}
+
+func TestIsNDJSONContentType(t *testing.T) {
+	for ct, want := range map[string]bool{
+		"application/x-ndjson":                true,
+		"application/x-ndjson; charset=utf-8": true,
+		"application/jsonl":                   true,
+		"text/event-stream":                   false,
+		"application/json":                    false,
+	} {
+		if got := isNDJSONContentType(ct); got != want {
+			t.Errorf("isNDJSONContentType(%q) = %v, want %v", ct, got, want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package relay
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/outbound/authropic"
+)
+
+// promptCacheOutbound 发送前为 Anthropic 请求自动插入缓存断点
+// OpenAI 格式的客户端无法表达 cache_control，长系统提示词和工具列表由此获得缓存命中
+type promptCacheOutbound struct {
+	wrappedOutbound
+	minTokens int
+	ttl       string
+}
+
+func newPromptCacheOutbound(outAdapter model.Outbound, minTokens int, ttl string) *promptCacheOutbound {
+	return &promptCacheOutbound{wrappedOutbound: wrappedOutbound{outAdapter}, minTokens: minTokens, ttl: ttl}
+}
+
+func (o *promptCacheOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	return o.Outbound.TransformRequest(ctx, authropic.ApplyCacheBreakpoints(request, o.minTokens, o.ttl), baseUrl, key)
+}
@@ -0,0 +1,356 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/samber/lo"
+)
+
+const (
+	toolCallOpenTag  = "<tool_call>"
+	toolCallCloseTag = "</tool_call>"
+)
+
+// promptToolOutbound 为不支持原生工具调用的上游模拟函数调用：
+// 工具定义和调用格式写入提示词，历史中的工具调用与结果转为文本，模型输出的调用块解析回 ToolCall
+type promptToolOutbound struct {
+	wrappedOutbound
+
+	// tools 本次请求注入提示词的工具，为空时响应原样返回
+	tools map[string]bool
+	// parsers 流式响应按 choice 维护解析状态
+	parsers map[int]*toolCallParser
+}
+
+func newPromptToolOutbound(outAdapter model.Outbound) model.Outbound {
+	return &promptToolOutbound{wrappedOutbound: wrappedOutbound{outAdapter}}
+}
+
+func (o *promptToolOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	o.tools = nil
+	o.parsers = make(map[int]*toolCallParser)
+	if request.IsChatRequest() && (len(request.Tools) > 0 || hasToolHistory(request.Messages)) {
+		request = o.prepareRequest(request)
+	}
+	return o.Outbound.TransformRequest(ctx, request, baseUrl, key)
+}
+
+func (o *promptToolOutbound) TransformResponse(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
+	resp, err := o.Outbound.TransformResponse(ctx, response)
+	if err != nil || resp == nil || len(o.tools) == 0 {
+		return resp, err
+	}
+	for i := range resp.Choices {
+		choice := &resp.Choices[i]
+		if choice.Message == nil {
+			continue
+		}
+		parser := &toolCallParser{tools: o.tools}
+		text, calls := parser.feed(messageText(choice.Message.Content))
+		rest, restCalls := parser.flush()
+		text += rest
+		calls = append(calls, restCalls...)
+		if len(calls) == 0 {
+			continue
+		}
+		choice.Message.ToolCalls = calls
+		choice.Message.Content = model.MessageContent{}
+		if text = strings.TrimSpace(text); text != "" {
+			choice.Message.Content.Content = lo.ToPtr(text)
+		}
+		choice.FinishReason = lo.ToPtr("tool_calls")
+	}
+	return resp, nil
+}
+
+func (o *promptToolOutbound) TransformStream(ctx context.Context, eventData []byte) (*model.InternalLLMResponse, error) {
+	stream, err := o.Outbound.TransformStream(ctx, eventData)
+	if err != nil || stream == nil || len(o.tools) == 0 {
+		return stream, err
+	}
+	for i := range stream.Choices {
+		choice := &stream.Choices[i]
+		parser, ok := o.parsers[choice.Index]
+		if !ok {
+			parser = &toolCallParser{tools: o.tools}
+			o.parsers[choice.Index] = parser
+		}
+
+		var text string
+		var calls []model.ToolCall
+		if choice.Delta != nil && choice.Delta.Content.Content != nil {
+			text, calls = parser.feed(*choice.Delta.Content.Content)
+		}
+		// 结束时输出缓冲中的剩余内容
+		if choice.FinishReason != nil {
+			rest, restCalls := parser.flush()
+			text += rest
+			calls = append(calls, restCalls...)
+		}
+		if choice.Delta == nil && (text != "" || len(calls) > 0) {
+			choice.Delta = &model.Message{Role: "assistant"}
+		}
+		if choice.Delta != nil {
+			choice.Delta.Content = model.MessageContent{}
+			if text != "" {
+				choice.Delta.Content.Content = lo.ToPtr(text)
+			}
+			choice.Delta.ToolCalls = calls
+		}
+		if choice.FinishReason != nil && parser.count > 0 {
+			choice.FinishReason = lo.ToPtr("tool_calls")
+		}
+	}
+	return stream, nil
+}
+
+// prepareRequest 返回去除原生工具参数的请求副本，不修改原请求
+func (o *promptToolOutbound) prepareRequest(req *model.InternalLLMRequest) *model.InternalLLMRequest {
+	clone := *req
+	clone.Tools = nil
+	clone.ToolChoice = nil
+	clone.ParallelToolCalls = nil
+	clone.Messages = toolHistoryToText(req.Messages)
+
+	tools := lo.Filter(req.Tools, func(tool model.Tool, _ int) bool { return tool.Type == "" || tool.Type == "function" })
+	if len(tools) == 0 || (req.ToolChoice != nil && lo.FromPtr(req.ToolChoice.ToolChoice) == "none") {
+		return &clone
+	}
+
+	o.tools = make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		o.tools[tool.Function.Name] = true
+	}
+	clone.Messages = insertSystemMessage(clone.Messages, toolPrompt(tools, req.ToolChoice, req.ParallelToolCalls))
+	return &clone
+}
+
+// toolPrompt 生成工具定义与调用格式说明
+func toolPrompt(tools []model.Tool, choice *model.ToolChoice, parallel *bool) string {
+	var sb strings.Builder
+	sb.WriteString("You have access to the following tools:\n\n<tools>\n")
+	for _, tool := range tools {
+		parameters := tool.Function.Parameters
+		if len(parameters) == 0 {
+			parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		definition, _ := json.Marshal(struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description,omitempty"`
+			Parameters  json.RawMessage `json:"parameters"`
+		}{tool.Function.Name, tool.Function.Description, parameters})
+		sb.Write(definition)
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("</tools>\n\n")
+	sb.WriteString("To call a tool, respond with one block per call in exactly this format:\n")
+	sb.WriteString(toolCallOpenTag + "\n{\"name\": \"<tool name>\", \"arguments\": {<arguments as a JSON object>}}\n" + toolCallCloseTag + "\n\n")
+	sb.WriteString("Write any text for the user before the tool calls and nothing after them. ")
+	sb.WriteString("Tool results are returned in <tool_response> blocks. ")
+	sb.WriteString("If no tool is needed, answer normally without any " + toolCallOpenTag + " block.")
+
+	switch {
+	case choice != nil && choice.NamedToolChoice != nil:
+		sb.WriteString(fmt.Sprintf("\n\nYou must call the tool %q in this response.", choice.NamedToolChoice.Function.Name))
+	case choice != nil && lo.FromPtr(choice.ToolChoice) == "required":
+		sb.WriteString("\n\nYou must call at least one tool in this response.")
+	}
+	if parallel != nil && !*parallel {
+		sb.WriteString("\n\nCall at most one tool per response.")
+	}
+	return sb.String()
+}
+
+func hasToolHistory(messages []model.Message) bool {
+	return lo.SomeBy(messages, func(msg model.Message) bool {
+		return msg.Role == "tool" || len(msg.ToolCalls) > 0
+	})
+}
+
+// toolHistoryToText 将历史中的工具调用写回助手文本，工具结果合并为用户消息
+func toolHistoryToText(messages []model.Message) []model.Message {
+	names := make(map[string]string)
+	result := make([]model.Message, 0, len(messages))
+	lastTool := false
+	for _, msg := range messages {
+		isTool := msg.Role == "tool"
+		switch {
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			var sb strings.Builder
+			sb.WriteString(messageText(msg.Content))
+			for _, toolCall := range msg.ToolCalls {
+				names[toolCall.ID] = toolCall.Function.Name
+				arguments := json.RawMessage(toolCall.Function.Arguments)
+				if !json.Valid(arguments) {
+					arguments = json.RawMessage("{}")
+				}
+				call, _ := json.Marshal(struct {
+					Name      string          `json:"name"`
+					Arguments json.RawMessage `json:"arguments"`
+				}{toolCall.Function.Name, arguments})
+				if sb.Len() > 0 {
+					sb.WriteByte('\n')
+				}
+				sb.WriteString(toolCallOpenTag + "\n" + string(call) + "\n" + toolCallCloseTag)
+			}
+			converted := msg
+			converted.ToolCalls = nil
+			converted.Content = model.MessageContent{Content: lo.ToPtr(sb.String())}
+			result = append(result, converted)
+
+		case isTool:
+			name := lo.FromPtr(msg.ToolCallName)
+			if name == "" {
+				name = names[lo.FromPtr(msg.ToolCallID)]
+			}
+			block := fmt.Sprintf("<tool_response name=%q>\n%s\n</tool_response>", name, messageText(msg.Content))
+			// 连续的工具结果合并为一条用户消息
+			if lastTool {
+				last := &result[len(result)-1]
+				last.Content.Content = lo.ToPtr(*last.Content.Content + "\n" + block)
+			} else {
+				result = append(result, model.Message{
+					Role:    "user",
+					Content: model.MessageContent{Content: lo.ToPtr(block)},
+				})
+			}
+
+		default:
+			result = append(result, msg)
+		}
+		lastTool = isTool
+	}
+	return result
+}
+
+// toolCallParser 从模型文本输出中解析 <tool_call> 块，支持流式增量输入
+// 第一个工具调用之后的文本会被丢弃
+type toolCallParser struct {
+	tools map[string]bool
+
+	pending string
+	inCall  bool
+	call    strings.Builder
+	count   int
+}
+
+// feed 输入一段文本，返回可以输出的文本和解析完成的工具调用
+func (p *toolCallParser) feed(text string) (string, []model.ToolCall) {
+	var out strings.Builder
+	var calls []model.ToolCall
+	data := p.pending + text
+	p.pending = ""
+
+	for data != "" {
+		if p.inCall {
+			end := strings.Index(data, toolCallCloseTag)
+			if end < 0 {
+				p.call.WriteString(data)
+				break
+			}
+			p.call.WriteString(data[:end])
+			data = data[end+len(toolCallCloseTag):]
+			p.inCall = false
+			if call, ok := p.parse(p.call.String()); ok {
+				calls = append(calls, call)
+			} else if p.count == 0 {
+				out.WriteString(toolCallOpenTag + p.call.String() + toolCallCloseTag)
+			}
+			p.call.Reset()
+			continue
+		}
+
+		start := strings.Index(data, toolCallOpenTag)
+		if start < 0 {
+			// 保留可能是开始标签前缀的结尾部分
+			keep := partialTagSuffix(data, toolCallOpenTag)
+			p.write(&out, data[:len(data)-keep])
+			p.pending = data[len(data)-keep:]
+			break
+		}
+		p.write(&out, data[:start])
+		data = data[start+len(toolCallOpenTag):]
+		p.inCall = true
+	}
+	return out.String(), calls
+}
+
+// flush 输出缓冲中剩余的内容，未闭合的调用块尽量解析
+func (p *toolCallParser) flush() (string, []model.ToolCall) {
+	var out strings.Builder
+	var calls []model.ToolCall
+	if p.inCall {
+		if call, ok := p.parse(p.call.String()); ok {
+			calls = append(calls, call)
+		} else {
+			p.write(&out, toolCallOpenTag+p.call.String())
+		}
+		p.inCall = false
+		p.call.Reset()
+	}
+	p.write(&out, p.pending)
+	p.pending = ""
+	return out.String(), calls
+}
+
+func (p *toolCallParser) write(out *strings.Builder, text string) {
+	if p.count == 0 {
+		out.WriteString(text)
+	}
+}
+
+// parse 解析调用块内容，工具不存在或格式错误时返回 false
+func (p *toolCallParser) parse(raw string) (model.ToolCall, bool) {
+	text, ok := repairJSON(raw)
+	if !ok {
+		return model.ToolCall{}, false
+	}
+	var block struct {
+		Name       string          `json:"name"`
+		Arguments  json.RawMessage `json:"arguments"`
+		Parameters json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal([]byte(text), &block); err != nil || !p.tools[block.Name] {
+		return model.ToolCall{}, false
+	}
+
+	arguments := block.Arguments
+	if len(arguments) == 0 {
+		arguments = block.Parameters
+	}
+	// 部分模型会把参数写成 JSON 字符串
+	var encoded string
+	if err := json.Unmarshal(arguments, &encoded); err == nil {
+		arguments = json.RawMessage(encoded)
+	}
+	if len(arguments) == 0 || !json.Valid(arguments) || string(arguments) == "null" {
+		arguments = json.RawMessage("{}")
+	}
+
+	call := model.ToolCall{
+		ID:    "call_" + lo.RandomString(24, lo.AlphanumericCharset),
+		Type:  "function",
+		Index: p.count,
+		Function: model.FunctionCall{
+			Name:      block.Name,
+			Arguments: string(arguments),
+		},
+	}
+	p.count++
+	return call, true
+}
+
+// partialTagSuffix 返回 text 结尾与 tag 前缀重合的长度
+func partialTagSuffix(text, tag string) int {
+	for n := min(len(text), len(tag)-1); n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,262 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/samber/lo"
+)
+
+func TestPartialTagSuffix(t *testing.T) {
+	cases := []struct {
+		text string
+		want int
+	}{
+		{"hello", 0},
+		{"hello <", 1},
+		{"hello <tool_cal", 9},
+		{"<tool_call", 10},
+		{"hello <tool_call>", 0},
+		{"<t", 2},
+		{"", 0},
+	}
+	for _, c := range cases {
+		if got := partialTagSuffix(c.text, toolCallOpenTag); got != c.want {
+			t.Errorf("partialTagSuffix(%q) = %d, want %d", c.text, got, c.want)
+		}
+	}
+}
+
+func TestToolCallParser(t *testing.T) {
+	cases := []struct {
+		name   string
+		deltas []string
+		text   string
+		// calls 为 "名称 参数"
+		calls []string
+	}{
+		{"plain text", []string{"hello ", "world"}, "hello world", nil},
+		{"text ending like a tag", []string{"a <", "b"}, "a <b", nil},
+		{"pending prefix flushed", []string{"a <tool_"}, "a <tool_", nil},
+		{
+			"single call",
+			[]string{"Let me check.\n<tool_call>\n{\"name\": \"get_weather\", \"arguments\": {\"city\": \"Paris\"}}\n</tool_call>"},
+			"Let me check.\n", []string{`get_weather {"city": "Paris"}`},
+		},
+		{
+			"tags split across deltas",
+			[]string{"ok <tool", "_call>{\"name\":\"get_weather\",", "\"arguments\":{}}</tool_", "call>"},
+			"ok ", []string{`get_weather {}`},
+		},
+		{
+			"multiple calls drop trailing text",
+			[]string{`<tool_call>{"name":"get_weather","arguments":{"city":"A"}}</tool_call>` + "\nthen " + `<tool_call>{"name":"get_time","arguments":{}}</tool_call> done`},
+			"", []string{`get_weather {"city":"A"}`, `get_time {}`},
+		},
+		{
+			"unknown tool kept as text",
+			[]string{`<tool_call>{"name":"rm","arguments":{}}</tool_call>`},
+			`<tool_call>{"name":"rm","arguments":{}}</tool_call>`, nil,
+		},
+		{
+			"malformed JSON kept as text",
+			[]string{`<tool_call>{"name": get_weather}</tool_call>`},
+			`<tool_call>{"name": get_weather}</tool_call>`, nil,
+		},
+		{
+			"fenced JSON with string arguments",
+			[]string{"<tool_call>```json\n{\"name\":\"get_weather\",\"arguments\":\"{\\\"city\\\":\\\"B\\\"}\"}\n```</tool_call>"},
+			"", []string{`get_weather {"city":"B"}`},
+		},
+		{
+			"parameters alias",
+			[]string{`<tool_call>{"name":"get_time","parameters":{"tz":"UTC"}}</tool_call>`},
+			"", []string{`get_time {"tz":"UTC"}`},
+		},
+		{
+			"unclosed call parsed on flush",
+			[]string{`<tool_call>{"name":"get_time","arguments":{"tz":"UTC"}}`},
+			"", []string{`get_time {"tz":"UTC"}`},
+		},
+		{
+			"unclosed invalid call flushed as text",
+			[]string{`x <tool_call>{"name":`},
+			`x <tool_call>{"name":`, nil,
+		},
+	}
+	for _, c := range cases {
+		parser := &toolCallParser{tools: map[string]bool{"get_weather": true, "get_time": true}}
+		var text strings.Builder
+		var calls []model.ToolCall
+		for _, delta := range c.deltas {
+			out, got := parser.feed(delta)
+			text.WriteString(out)
+			calls = append(calls, got...)
+		}
+		out, got := parser.flush()
+		text.WriteString(out)
+		calls = append(calls, got...)
+
+		if text.String() != c.text {
+			t.Errorf("%s: text = %q, want %q", c.name, text.String(), c.text)
+		}
+		gotCalls := lo.Map(calls, func(call model.ToolCall, _ int) string { return call.Function.Name + " " + call.Function.Arguments })
+		if strings.Join(gotCalls, "\n") != strings.Join(c.calls, "\n") {
+			t.Errorf("%s: calls = %q, want %q", c.name, gotCalls, c.calls)
+		}
+		for i, call := range calls {
+			if call.Index != i || call.Type != "function" || !strings.HasPrefix(call.ID, "call_") {
+				t.Errorf("%s: call %d = %+v", c.name, i, call)
+			}
+		}
+	}
+}
+
+func TestToolHistoryToText(t *testing.T) {
+	messages := []model.Message{
+		{Role: "user", Content: model.MessageContent{Content: lo.ToPtr("weather?")}},
+		{Role: "assistant", ToolCalls: []model.ToolCall{
+			{ID: "a", Function: model.FunctionCall{Name: "get_weather", Arguments: `{"city":"A"}`}},
+			{ID: "b", Function: model.FunctionCall{Name: "get_time", Arguments: `not json`}},
+		}},
+		{Role: "tool", ToolCallID: lo.ToPtr("a"), Content: model.MessageContent{Content: lo.ToPtr("sunny")}},
+		{Role: "tool", ToolCallID: lo.ToPtr("b"), Content: model.MessageContent{Content: lo.ToPtr("noon")}},
+	}
+	got := toolHistoryToText(messages)
+	if len(got) != 3 || got[1].Role != "assistant" || len(got[1].ToolCalls) != 0 || got[2].Role != "user" {
+		t.Fatalf("messages = %+v", got)
+	}
+	want := "<tool_call>\n{\"name\":\"get_weather\",\"arguments\":{\"city\":\"A\"}}\n</tool_call>\n" +
+		"<tool_call>\n{\"name\":\"get_time\",\"arguments\":{}}\n</tool_call>"
+	if text := messageText(got[1].Content); text != want {
+		t.Fatalf("assistant text = %q, want %q", text, want)
+	}
+	want = "<tool_response name=\"get_weather\">\nsunny\n</tool_response>\n<tool_response name=\"get_time\">\nnoon\n</tool_response>"
+	if text := messageText(got[2].Content); text != want {
+		t.Fatalf("tool results = %q, want %q", text, want)
+	}
+}
+
+// newPromptToolUpstream 创建开启提示词工具调用的上游，并检查请求中不含原生工具参数
+func newPromptToolUpstream(t *testing.T, handle func(u *testUpstream, w http.ResponseWriter)) *testUpstream {
+	t.Helper()
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		messages, _ := req["messages"].([]any)
+		system, _ := messages[0].(map[string]any)
+		if _, ok := req["tools"]; ok || system["role"] != "system" || !strings.Contains(fmt.Sprint(system["content"]), "get_weather") {
+			http.Error(w, "native tools sent", http.StatusBadRequest)
+			return
+		}
+		handle(upstream, w)
+	})
+	enabled := true
+	if _, err := op.ChannelUpdate(&dbmodel.ChannelUpdateRequest{ID: upstream.channelID, PromptToolCall: &enabled}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return upstream
+}
+
+func promptToolBody(model string, stream bool) string {
+	return fmt.Sprintf(`{"model":%q,"stream":%t,"messages":[{"role":"user","content":"weather?"}],`+
+		`"tools":[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}]}`, model, stream)
+}
+
+const promptToolCall = "Checking.\n<tool_call>\n{\"name\": \"get_weather\", \"arguments\": {\"city\": \"Paris\"}}\n</tool_call>"
+
+// 解析出工具调用时 finish_reason 改写为 tool_calls
+func TestPromptToolCall(t *testing.T) {
+	upstream := newPromptToolUpstream(t, func(u *testUpstream, w http.ResponseWriter) {
+		completionWithContent(w, u.model, promptToolCall)
+	})
+	server := newIdempotencyServer(t, newTestAPIKey(t))
+
+	status, body := postChat(t, server, promptToolBody(upstream.model, false))
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	var resp struct {
+		Choices []struct {
+			Message      model.Message `json:"message"`
+			FinishReason string        `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	json.Unmarshal([]byte(body), &resp)
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || messageText(choice.Message.Content) != "Checking." || len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("unexpected response: %s", body)
+	}
+	if call := choice.Message.ToolCalls[0].Function; call.Name != "get_weather" || call.Arguments != `{"city": "Paris"}` {
+		t.Fatalf("tool call = %+v", call)
+	}
+}
+
+func TestPromptToolCallStream(t *testing.T) {
+	upstream := newPromptToolUpstream(t, func(u *testUpstream, w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		// 按 7 字节切分，使标签跨越多个增量
+		for chunk := range chunkText(promptToolCall, 7) {
+			writeStreamContent(w, u.model, chunk)
+		}
+		writeStreamEnd(w, u.model)
+	})
+	server := newIdempotencyServer(t, newTestAPIKey(t))
+
+	status, body := postChat(t, server, promptToolBody(upstream.model, true))
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	var text strings.Builder
+	var calls []model.ToolCall
+	var finish string
+	readSSE(strings.NewReader(body), func(data string) bool {
+		if data == "[DONE]" {
+			return false
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta        model.Message `json:"delta"`
+				FinishReason *string       `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %s: %v", data, err)
+		}
+		for _, choice := range chunk.Choices {
+			text.WriteString(messageText(choice.Delta.Content))
+			calls = append(calls, choice.Delta.ToolCalls...)
+			if choice.FinishReason != nil {
+				finish = *choice.FinishReason
+			}
+		}
+		return true
+	})
+	if text.String() != "Checking.\n" || strings.Contains(body, "tool_call>") {
+		t.Fatalf("text = %q:\n%s", text.String(), body)
+	}
+	if len(calls) != 1 || calls[0].Function.Name != "get_weather" || calls[0].Function.Arguments != `{"city": "Paris"}` || finish != "tool_calls" {
+		t.Fatalf("calls %+v, finish %q", calls, finish)
+	}
+}
+
+// chunkText 将文本按固定字节数切分
+func chunkText(text string, size int) func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		for len(text) > 0 {
+			n := min(size, len(text))
+			if !yield(text[:n]) {
+				return
+			}
+			text = text[n:]
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/helper"
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/notify"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/relay/balancer"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/outbound"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/bestruirui/octopus/internal/utils/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	realtimeHandshakeTimeout = 30 * time.Second
+	realtimeProtocol         = "realtime"
+	realtimeBetaProtocol     = "openai-beta."
+)
+
+// RealtimeHandler 代理 OpenAI Realtime API (/v1/realtime) 的 WebSocket 会话
+// 先通过分组选出渠道并与上游完成握手，再升级客户端连接并双向转发消息
+// 每个会话记录一条日志，用量取自上游的 response.done 事件
+func RealtimeHandler(c *gin.Context) {
+	if !websocket.IsUpgradeRequest(c.Request) {
+		resp.Error(c, http.StatusUpgradeRequired, "websocket upgrade required")
+		return
+	}
+	modelName := c.Query("model")
+	if modelName == "" {
+		resp.Error(c, http.StatusBadRequest, "model is required")
+		return
+	}
+	supportedModels := c.GetString("supported_models")
+	if supportedModels != "" && !slices.Contains(strings.Split(supportedModels, ","), modelName) {
+		resp.Error(c, http.StatusBadRequest, "model not supported")
+		return
+	}
+
+	apiKeyID := c.GetInt("api_key_id")
+	metrics := NewRelayMetrics(modelName)
+	metrics.SetAPIKeyID(apiKeyID)
+	// 会话结束时请求上下文可能已取消，日志和统计使用独立的上下文
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	if !checkAPIKeyLimits(ctx, c, metrics) {
+		return
+	}
+	defer metrics.releaseBalance()
+
+	group, err := op.GroupGetMap(modelName, c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusNotFound, "model not found")
+		return
+	}
+
+	upstream, usedKey, round, err := dialRealtime(c, group, metrics)
+	if err != nil {
+		metrics.Save(ctx, false, err, 0)
+		resp.Error(c, http.StatusBadGateway, "all channels failed")
+		return
+	}
+
+	var subprotocol string
+	if slices.Contains(websocket.Subprotocols(c.Request), realtimeProtocol) {
+		subprotocol = realtimeProtocol
+	}
+	client, err := websocket.Accept(c.Writer, c.Request, subprotocol)
+	if err != nil {
+		upstream.Close()
+		metrics.Save(ctx, false, fmt.Errorf("failed to accept client websocket: %w", err), 0)
+		return
+	}
+
+	// 会话建立即视为请求已发送
+	metrics.EstimateAndDeductCost(ctx)
+
+	session := &realtimeSession{client: client, upstream: upstream, metrics: metrics}
+	err = session.run()
+
+	metrics.SetInternalResponse(&model.InternalLLMResponse{
+		Object: "realtime.session",
+		Model:  metrics.ActualModel,
+		Usage:  &session.usage,
+	})
+	metrics.Save(ctx, err == nil, err, round)
+	usedKey.StatusCode = http.StatusSwitchingProtocols
+	usedKey.LastUseTimeStamp = time.Now().Unix()
+	usedKey.TotalCost += metrics.UpstreamCost
+	op.ChannelKeyUpdate(usedKey)
+}
+
+// dialRealtime 按分组负载均衡依次尝试渠道，返回首个握手成功的上游连接
+func dialRealtime(c *gin.Context, group dbmodel.Group, metrics *RelayMetrics) (*websocket.Conn, dbmodel.ChannelKey, int, error) {
+	const maxRounds = 3
+	var lastErr error
+	itemCount := len(group.Items)
+	b := balancer.GetBalancer(group.Mode)
+	for round := 0; round < maxRounds; round++ {
+		item := b.Select(group.Items)
+		if item == nil {
+			return nil, dbmodel.ChannelKey{}, 0, fmt.Errorf("no available channel")
+		}
+		for i := 0; i < itemCount; i++ {
+			if c.Request.Context().Err() != nil {
+				return nil, dbmodel.ChannelKey{}, 0, c.Request.Context().Err()
+			}
+			attemptStart := time.Now()
+			channel, err := op.ChannelGet(item.ChannelID, c.Request.Context())
+			if err != nil {
+				lastErr = err
+				item = b.Next(group.Items, item)
+				continue
+			}
+			if !channel.Enabled {
+				lastErr = fmt.Errorf("channel %s is disabled", channel.Name)
+				item = b.Next(group.Items, item)
+				continue
+			}
+			if !outbound.IsRealtimeChannelType(channel.Type) {
+				log.Warnf("channel type %d is not compatible with realtime session for channel: %s", channel.Type, channel.Name)
+				lastErr = fmt.Errorf("channel type %d not compatible with realtime session", channel.Type)
+				item = b.Next(group.Items, item)
+				continue
+			}
+
+			log.Infof("realtime session model %s, forwarding to channel: %s model: %s (round %d/%d, item %d/%d)", metrics.RequestModel, channel.Name, item.ModelName, round+1, maxRounds, i+1, itemCount)
+			metrics.SetChannel(channel.ID, channel.Name, item.ModelName)
+			usedKey := channel.GetChannelKey()
+
+			conn, statusCode, err := dialRealtimeChannel(c, channel, usedKey, channel.GetMappedModel(item.ModelName))
+			metrics.AddAttempt(round+1, i+1, err == nil, err, time.Since(attemptStart))
+			if err == nil {
+				return conn, usedKey, round + 1, nil
+			}
+			usedKey.StatusCode = statusCode
+			usedKey.LastUseTimeStamp = time.Now().Unix()
+			op.ChannelKeyUpdate(usedKey)
+			notify.ChannelKeyStatus(channel, usedKey)
+			lastErr = fmt.Errorf("channel %s failed: %v", channel.Name, err)
+			item = b.Next(group.Items, item)
+		}
+	}
+	return nil, dbmodel.ChannelKey{}, 0, lastErr
+}
+
+// dialRealtimeChannel 使用渠道的 http 客户端与上游握手，返回上游的状态码
+func dialRealtimeChannel(c *gin.Context, channel *dbmodel.Channel, key dbmodel.ChannelKey, upstreamModel string) (*websocket.Conn, int, error) {
+	httpClient, err := helper.ChannelHttpClient(channel)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := c.Request.URL.Query()
+	query.Set("model", upstreamModel)
+	upstreamURL := strings.TrimSuffix(channel.GetBaseUrl(), "/") + "/realtime?" + query.Encode()
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+key.ChannelKey)
+	if beta := realtimeBetaHeader(c.Request); beta != "" {
+		header.Set("OpenAI-Beta", beta)
+	}
+	for _, h := range channel.CustomHeader {
+		header.Set(h.HeaderKey, h.HeaderValue)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), realtimeHandshakeTimeout)
+	defer cancel()
+	conn, response, err := websocket.Dial(ctx, httpClient, upstreamURL, header)
+	if err != nil {
+		if response != nil {
+			body, _ := io.ReadAll(response.Body)
+			return nil, response.StatusCode, fmt.Errorf("upstream error: %d: %s", response.StatusCode, string(body))
+		}
+		return nil, 0, err
+	}
+	return conn, response.StatusCode, nil
+}
+
+// realtimeBetaHeader 浏览器客户端通过 openai-beta.realtime-v1 子协议传递 beta 标识
+func realtimeBetaHeader(r *http.Request) string {
+	if beta := r.Header.Get("OpenAI-Beta"); beta != "" {
+		return beta
+	}
+	for _, protocol := range websocket.Subprotocols(r) {
+		if beta, ok := strings.CutPrefix(protocol, realtimeBetaProtocol); ok {
+			// realtime-v1 -> realtime=v1
+			if idx := strings.LastIndexByte(beta, '-'); idx > 0 {
+				return beta[:idx] + "=" + beta[idx+1:]
+			}
+			return beta
+		}
+	}
+	return ""
+}
+
+// realtimeSession 在客户端和上游之间转发消息，并从上游事件中累计用量
+type realtimeSession struct {
+	client   *websocket.Conn
+	upstream *websocket.Conn
+	metrics  *RelayMetrics
+	usage    model.Usage
+}
+
+// realtimeEvent 只解析计费相关字段
+type realtimeEvent struct {
+	Type     string `json:"type"`
+	Response *struct {
+		Usage *struct {
+			TotalTokens       int64 `json:"total_tokens"`
+			InputTokens       int64 `json:"input_tokens"`
+			OutputTokens      int64 `json:"output_tokens"`
+			InputTokenDetails struct {
+				CachedTokens int64 `json:"cached_tokens"`
+			} `json:"input_token_details"`
+		} `json:"usage"`
+	} `json:"response"`
+}
+
+// run 任一方向结束后关闭两侧连接，正常关闭时返回 nil
+func (s *realtimeSession) run() error {
+	s.usage.PromptTokensDetails = &model.PromptTokensDetails{}
+	errc := make(chan error, 2)
+	go func() { errc <- pipeRealtime(s.upstream, s.client, nil) }()
+	go func() { errc <- pipeRealtime(s.client, s.upstream, s.observe) }()
+
+	err := <-errc
+	s.client.Close()
+	s.upstream.Close()
+	<-errc
+
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		switch closeErr.Code {
+		case websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatus:
+			return nil
+		}
+	}
+	return err
+}
+
+// pipeRealtime 将 src 的消息转发到 dst，src 关闭时把关闭状态转发给 dst
+func pipeRealtime(dst, src *websocket.Conn, observe func([]byte)) error {
+	for {
+		messageType, data, err := src.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				dst.WriteClose(closeErr.Code, closeErr.Text)
+			} else {
+				dst.WriteClose(websocket.CloseGoingAway, "")
+			}
+			return err
+		}
+		if observe != nil && messageType == websocket.TextMessage {
+			observe(data)
+		}
+		if err := dst.WriteMessage(messageType, data); err != nil {
+			return err
+		}
+	}
+}
+
+// observe 处理上游事件，音频增量等大消息只做字节匹配，不做完整解析
+func (s *realtimeSession) observe(data []byte) {
+	if s.metrics.FirstTokenTime.IsZero() && bytes.Contains(data, []byte(`.delta"`)) {
+		s.metrics.SetFirstTokenTime(time.Now())
+	}
+	if !bytes.Contains(data, []byte(`"response.done"`)) {
+		return
+	}
+	var event realtimeEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Warnf("failed to parse realtime event: %v", err)
+		return
+	}
+	if event.Type != "response.done" || event.Response == nil || event.Response.Usage == nil {
+		return
+	}
+	usage := event.Response.Usage
+	s.usage.PromptTokens += usage.InputTokens
+	s.usage.CompletionTokens += usage.OutputTokens
+	s.usage.TotalTokens += usage.TotalTokens
+	s.usage.PromptTokensDetails.CachedTokens += usage.InputTokenDetails.CachedTokens
+}
@@ -0,0 +1,138 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/utils/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+const realtimeDoneEvent = `{"type":"response.done","response":{"usage":{"total_tokens":28,"input_tokens":20,"output_tokens":8,"input_token_details":{"cached_tokens":4}}}}`
+
+// newRealtimeUpstream 模拟上游 Realtime 会话：建立后发送 session.created，
+// 每收到一条 response.create 回复一条增量和带用量的 response.done，收到的消息写入 received
+func newRealtimeUpstream(t *testing.T, received chan<- string) *testUpstream {
+	return newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/realtime" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, err := websocket.Accept(w, r, "")
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"session.created"}`))
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				var closeErr *websocket.CloseError
+				if errors.As(err, &closeErr) {
+					conn.WriteClose(closeErr.Code, "")
+				}
+				return
+			}
+			received <- string(data)
+			if messageType == websocket.TextMessage && strings.Contains(string(data), `"response.create"`) {
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"response.output_text.delta","delta":"hi"}`))
+				conn.WriteMessage(websocket.TextMessage, []byte(realtimeDoneEvent))
+			}
+		}
+	})
+}
+
+func TestRealtimeHandler(t *testing.T) {
+	received := make(chan string, 16)
+	upstream := newRealtimeUpstream(t, received)
+	key := newTestAPIKey(t)
+
+	handlerDone := make(chan struct{})
+	engine := gin.New()
+	engine.GET("/v1/realtime", func(c *gin.Context) {
+		defer close(handlerDone)
+		c.Set("api_key_id", key.ID)
+		RealtimeHandler(c)
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	client, _, err := websocket.Dial(context.Background(), server.Client(), server.URL+"/v1/realtime?model="+upstream.model, http.Header{"Sec-WebSocket-Protocol": {"realtime"}})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+
+	read := func(want string) {
+		t.Helper()
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("client got %s, want %s", data, want)
+		}
+	}
+
+	// 上游 -> 客户端
+	read(`"session.created"`)
+	for range 2 {
+		// 客户端 -> 上游
+		request := `{"type":"response.create"}`
+		if err := client.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatal(err)
+		}
+		if got := <-received; got != request {
+			t.Fatalf("upstream got %s, want %s", got, request)
+		}
+		read(`"response.output_text.delta"`)
+		read(`"response.done"`)
+	}
+	if err := client.WriteMessage(websocket.BinaryMessage, []byte{0x01, 0x02}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; got != "\x01\x02" {
+		t.Fatalf("upstream got binary %q", got)
+	}
+
+	client.WriteClose(websocket.CloseNormalClosure, "")
+	if _, _, err := client.ReadMessage(); err == nil {
+		t.Fatal("expected close after client closed the session")
+	}
+	select {
+	case <-handlerDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("realtime handler did not return after close")
+	}
+
+	logs, err := op.RelayLogList(context.Background(), nil, nil, 1, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	for _, relayLog := range logs {
+		if relayLog.APIKeyID != key.ID {
+			continue
+		}
+		count++
+		// 两次 response.done 的用量累加到同一条日志
+		if relayLog.InputTokens != 40 || relayLog.OutputTokens != 16 || relayLog.CacheReadTokens != 8 {
+			t.Fatalf("log tokens = in %d out %d cache %d, want 40/16/8", relayLog.InputTokens, relayLog.OutputTokens, relayLog.CacheReadTokens)
+		}
+		if relayLog.Error != "" || relayLog.Cost <= 0 {
+			t.Fatalf("log error = %q, cost = %f", relayLog.Error, relayLog.Cost)
+		}
+	}
+	if count != 1 {
+		t.Fatalf("got %d relay logs for the session, want 1", count)
+	}
+	if stats := op.StatsAPIKeyGet(key.ID); stats.InputToken != 40 || stats.OutputToken != 16 {
+		t.Fatalf("api key stats = %+v", stats.StatsMetrics)
+	}
+}
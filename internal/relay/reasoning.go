@@ -0,0 +1,245 @@
+package relay
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/samber/lo"
+)
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// resolveReasoningPolicy API Key 配置优先于分组配置
+func resolveReasoningPolicy(ctx context.Context, group dbmodel.Group, apiKeyID int) dbmodel.ReasoningPolicy {
+	var keyPolicy dbmodel.ReasoningPolicy
+	if apiKey, err := op.APIKeyGet(apiKeyID, ctx); err == nil {
+		keyPolicy = apiKey.ReasoningPolicy
+	}
+	return dbmodel.ResolveReasoningPolicy(group.ReasoningPolicy, keyPolicy)
+}
+
+// wrapReasoning 透传策略不包装
+func wrapReasoning(outAdapter model.Outbound, policy dbmodel.ReasoningPolicy) model.Outbound {
+	if policy == dbmodel.ReasoningPolicyPassthrough || policy == dbmodel.ReasoningPolicyDefault {
+		return outAdapter
+	}
+	return &reasoningOutbound{wrappedOutbound: wrappedOutbound{outAdapter}, policy: policy}
+}
+
+// reasoningOutbound 在上游响应转为内部格式时按策略统一推理内容
+// 带签名的推理内容 (Anthropic thinking) 始终原样保留，否则客户端多轮对话回传时无法通过签名校验
+type reasoningOutbound struct {
+	wrappedOutbound
+	policy dbmodel.ReasoningPolicy
+	// states 流式响应按 choice 维护解析状态
+	states map[int]*reasoningState
+}
+
+// reasoningState 流式推理内容的处理状态
+type reasoningState struct {
+	tags thinkTagParser
+	// buffer 尚未确认是否带签名的推理内容，收到签名或正文开始后再决定如何输出
+	buffer strings.Builder
+}
+
+func (o *reasoningOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	o.states = make(map[int]*reasoningState)
+	return o.Outbound.TransformRequest(ctx, request, baseUrl, key)
+}
+
+func (o *reasoningOutbound) TransformResponse(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
+	resp, err := o.Outbound.TransformResponse(ctx, response)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	for i := range resp.Choices {
+		if msg := resp.Choices[i].Message; msg != nil {
+			o.applyMessage(msg)
+		}
+	}
+	return resp, nil
+}
+
+func (o *reasoningOutbound) TransformStream(ctx context.Context, eventData []byte) (*model.InternalLLMResponse, error) {
+	stream, err := o.Outbound.TransformStream(ctx, eventData)
+	if err != nil || stream == nil {
+		return stream, err
+	}
+	for i := range stream.Choices {
+		o.applyDelta(&stream.Choices[i])
+	}
+	return stream, nil
+}
+
+func (o *reasoningOutbound) parsesTags() bool {
+	return o.policy == dbmodel.ReasoningPolicyStrip || o.policy == dbmodel.ReasoningPolicyThinkTags
+}
+
+func (o *reasoningOutbound) applyMessage(msg *model.Message) {
+	if o.parsesTags() && msg.Content.Content != nil {
+		var parser thinkTagParser
+		text, thinking := parser.feed(*msg.Content.Content)
+		restText, restThinking := parser.flush()
+		text += restText
+		thinking = strings.TrimSpace(thinking + restThinking)
+		msg.Content.Content = lo.ToPtr(text)
+		if o.policy == dbmodel.ReasoningPolicyThinkTags && thinking != "" {
+			msg.SetReasoningContent(msg.GetReasoningContent() + thinking)
+			msg.Reasoning = nil
+		}
+	}
+
+	reasoning := msg.GetReasoningContent()
+	if reasoning == "" || lo.FromPtr(msg.ReasoningSignature) != "" {
+		return
+	}
+	switch o.policy {
+	case dbmodel.ReasoningPolicyStrip:
+		msg.ReasoningContent = nil
+		msg.Reasoning = nil
+	case dbmodel.ReasoningPolicyText:
+		msg.ReasoningContent = nil
+		msg.Reasoning = nil
+		prependText(&msg.Content, thinkText(reasoning))
+	}
+}
+
+func (o *reasoningOutbound) applyDelta(choice *model.Choice) {
+	st, ok := o.states[choice.Index]
+	if !ok {
+		st = &reasoningState{}
+		o.states[choice.Index] = st
+	}
+	delta := choice.Delta
+	if delta == nil {
+		if choice.FinishReason == nil {
+			return
+		}
+		delta = &model.Message{}
+	}
+
+	if o.parsesTags() && delta.Content.Content != nil {
+		text, thinking := st.tags.feed(*delta.Content.Content)
+		delta.Content.Content = lo.ToPtr(text)
+		o.addThinking(delta, thinking)
+	}
+	if choice.FinishReason != nil && o.parsesTags() {
+		text, thinking := st.tags.flush()
+		if text != "" {
+			delta.Content.Content = lo.ToPtr(lo.FromPtr(delta.Content.Content) + text)
+		}
+		o.addThinking(delta, thinking)
+	}
+
+	if o.policy == dbmodel.ReasoningPolicyStrip || o.policy == dbmodel.ReasoningPolicyText {
+		if reasoning := delta.GetReasoningContent(); reasoning != "" {
+			st.buffer.WriteString(reasoning)
+			delta.ReasoningContent = nil
+			delta.Reasoning = nil
+		}
+		hasOutput := lo.FromPtr(delta.Content.Content) != "" || len(delta.ToolCalls) > 0 || choice.FinishReason != nil
+		switch {
+		case lo.FromPtr(delta.ReasoningSignature) != "":
+			// 带签名的推理内容原样输出
+			if st.buffer.Len() > 0 {
+				delta.SetReasoningContent(st.buffer.String())
+				st.buffer.Reset()
+			}
+		case hasOutput && st.buffer.Len() > 0:
+			if o.policy == dbmodel.ReasoningPolicyText {
+				prependText(&delta.Content, thinkText(st.buffer.String()))
+			}
+			st.buffer.Reset()
+		}
+	}
+
+	if choice.Delta == nil && (lo.FromPtr(delta.Content.Content) != "" || delta.ReasoningContent != nil) {
+		choice.Delta = delta
+	}
+}
+
+// addThinking 正文中解析出的 <think> 内容，仅在 think_tags 策略下转为推理内容
+func (o *reasoningOutbound) addThinking(delta *model.Message, thinking string) {
+	if thinking == "" || o.policy != dbmodel.ReasoningPolicyThinkTags {
+		return
+	}
+	delta.SetReasoningContent(delta.GetReasoningContent() + thinking)
+	delta.Reasoning = nil
+}
+
+func thinkText(reasoning string) string {
+	return thinkOpenTag + "\n" + strings.TrimSpace(reasoning) + "\n" + thinkCloseTag + "\n\n"
+}
+
+func prependText(content *model.MessageContent, text string) {
+	if content.Content == nil && len(content.MultipleContent) > 0 {
+		content.MultipleContent = append([]model.MessageContentPart{{Type: "text", Text: lo.ToPtr(text)}}, content.MultipleContent...)
+		return
+	}
+	content.Content = lo.ToPtr(text + lo.FromPtr(content.Content))
+}
+
+// thinkTagParser 从正文中分离 <think> 标签内容，支持流式增量输入
+type thinkTagParser struct {
+	pending string
+	inThink bool
+	// trim 标签之后的空白不输出
+	trim bool
+}
+
+// feed 输入一段正文，返回标签外的正文和标签内的推理内容
+func (p *thinkTagParser) feed(text string) (string, string) {
+	var content, thinking strings.Builder
+	data := p.pending + text
+	p.pending = ""
+
+	for data != "" {
+		tag := thinkOpenTag
+		if p.inThink {
+			tag = thinkCloseTag
+		}
+		idx := strings.Index(data, tag)
+		if idx < 0 {
+			// 保留可能是标签前缀的结尾部分
+			keep := partialTagSuffix(data, tag)
+			p.write(&content, &thinking, data[:len(data)-keep])
+			p.pending = data[len(data)-keep:]
+			break
+		}
+		p.write(&content, &thinking, data[:idx])
+		data = data[idx+len(tag):]
+		p.inThink = !p.inThink
+		p.trim = true
+	}
+	return content.String(), thinking.String()
+}
+
+// flush 输出缓冲中剩余的内容
+func (p *thinkTagParser) flush() (string, string) {
+	var content, thinking strings.Builder
+	p.write(&content, &thinking, p.pending)
+	p.pending = ""
+	return content.String(), thinking.String()
+}
+
+func (p *thinkTagParser) write(content, thinking *strings.Builder, text string) {
+	if p.trim {
+		text = strings.TrimLeft(text, " \t\r\n")
+		if text == "" {
+			return
+		}
+		p.trim = false
+	}
+	if p.inThink {
+		thinking.WriteString(text)
+	} else {
+		content.WriteString(text)
+	}
+}
@@ -2,6 +2,7 @@ package relay
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +11,8 @@ import (
 	"time"
 
 	"github.com/bestruirui/octopus/internal/helper"
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/notify"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/relay/balancer"
 	"github.com/bestruirui/octopus/internal/server/resp"
@@ -43,10 +46,20 @@ func Handler(inboundType inbound.InboundType, c *gin.Context) {
 	metrics.SetInternalRequest(internalRequest)
 	metrics.SetAPIKeyID(apiKeyID)
 
-	if c.GetBool("quota_exceeded") {
-		err := fmt.Errorf("quota exhausted")
-		metrics.Save(c.Request.Context(), false, err, 0)
-		resp.Error(c, http.StatusForbidden, "quota exhausted")
+	// 相同 Idempotency-Key 的重试复用首次请求的结果，不会重复转发和计费
+	idempotency, ok := beginIdempotency(c, apiKeyID, internalRequest.RawRequest)
+	if !ok {
+		return
+	}
+	defer idempotency.finish(metrics)
+
+	if !checkAPIKeyLimits(c.Request.Context(), c, metrics) {
+		return
+	}
+	defer metrics.releaseBalance()
+
+	// Responses API: 展开 previous_response_id
+	if !expandPreviousResponse(c, inAdapter, internalRequest, apiKeyID) {
 		return
 	}
 
@@ -57,6 +70,11 @@ func Handler(inboundType inbound.InboundType, c *gin.Context) {
 		return
 	}
 
+	// 结构化输出模拟与校验
+	structured := newStructuredOutput(group, internalRequest, metrics)
+	fanOut := newFanOutResults(internalRequest)
+	reasoningPolicy := resolveReasoningPolicy(c.Request.Context(), group, apiKeyID)
+
 	const maxRounds = 3
 	var lastErr error
 	itemCount := len(group.Items)
@@ -93,10 +111,11 @@ func Handler(inboundType inbound.InboundType, c *gin.Context) {
 
 			log.Infof("request model %s, mode: %d, forwarding to channel: %s model: %s (round %d/%d, item %d/%d)", internalRequest.Model, group.Mode, channel.Name, item.ModelName, round+1, maxRounds, i+1, itemCount)
 
-			internalRequest.Model = item.ModelName
 			metrics.SetChannel(channel.ID, channel.Name, item.ModelName)
+			// 按渠道模型映射转换为上游实际名称（如 Azure 部署名）
+			internalRequest.Model = channel.GetMappedModel(item.ModelName)
 
-			outAdapter := outbound.Get(channel.Type)
+			outAdapter := buildOutbound(channel, internalRequest, reasoningPolicy, structured)
 			if outAdapter == nil {
 				log.Warnf("unsupported channel type: %d for channel: %s", channel.Type, channel.Name)
 				lastErr = fmt.Errorf("unsupported channel type: %d", channel.Type)
@@ -120,43 +139,63 @@ func Handler(inboundType inbound.InboundType, c *gin.Context) {
 			}
 
 			rc := &relayContext{
-				c:                    c,
-				inAdapter:            inAdapter,
-				outAdapter:           outAdapter,
+				c:           c,
+				inboundType: inboundType,
+				inAdapter:   inAdapter,
+				outAdapter:  outAdapter,
+				newOutbound: func() model.Outbound {
+					return buildOutbound(channel, internalRequest, reasoningPolicy, structured)
+				},
 				internalRequest:      internalRequest,
 				channel:              channel,
 				metrics:              metrics,
 				usedKey:              channel.GetChannelKey(),
 				firstTokenTimeOutSec: group.FirstTokenTimeOut,
+				structured:           structured,
+				fanOut:               fanOut,
 			}
 
 			// 立即扣除预估成本（严格计费：请求一旦发送就必须付费）
+			metrics.SetUpstreamRequests(rc.fanOutCount())
 			metrics.EstimateAndDeductCost(c.Request.Context())
 
 			if statusCode, err := rc.forward(); err == nil {
 				// 成功
 				attemptDuration := time.Since(attemptStart)
 				metrics.AddAttempt(round+1, i+1, true, nil, attemptDuration)
-				rc.collectResponse()
+				internalResponse := rc.collectResponse()
+				if structured != nil && internalRequest.Stream != nil && *internalRequest.Stream {
+					structured.finish(internalResponse)
+				}
+				saveResponseState(c.Request.Context(), inAdapter, internalRequest, internalResponse, apiKeyID)
+				metrics.Save(c.Request.Context(), true, nil, round+1)
+				// 渠道 Key 累计的是实际支付给上游的费用
 				rc.usedKey.StatusCode = statusCode
 				rc.usedKey.LastUseTimeStamp = time.Now().Unix()
-				rc.usedKey.TotalCost += metrics.Stats.InputCost + metrics.Stats.OutputCost
+				rc.usedKey.TotalCost += metrics.UpstreamCost
 				op.ChannelKeyUpdate(rc.usedKey)
-				metrics.Save(c.Request.Context(), true, nil, round+1)
 				return
 			} else {
 				// 失败
 				attemptDuration := time.Since(attemptStart)
 				metrics.AddAttempt(round+1, i+1, false, err, attemptDuration)
-				rc.usedKey.StatusCode = statusCode
+				rc.usedKey.StatusCode = upstreamStatusCode(statusCode, err)
 				rc.usedKey.LastUseTimeStamp = time.Now().Unix()
 				op.ChannelKeyUpdate(rc.usedKey)
+				notify.ChannelKeyStatus(channel, rc.usedKey)
 				if c.Writer.Written() {
 					// Streaming responses may have already started; retrying would corrupt the client stream.
 					rc.collectResponse()
 					metrics.Save(c.Request.Context(), false, err, 0)
 					return
 				}
+				// 不可重试的错误（如内容审核拦截）直接返回给客户端
+				if nre, ok := model.AsNonRetryable(err); ok {
+					fanOut.record(metrics)
+					metrics.Save(c.Request.Context(), false, err, 0)
+					resp.Error(c, nre.StatusCode, nre.Error())
+					return
+				}
 				lastErr = fmt.Errorf("channel %s failed: %v", channel.Name, err)
 			}
 			item = b.Next(group.Items, item)
@@ -164,10 +203,46 @@ func Handler(inboundType inbound.InboundType, c *gin.Context) {
 	}
 
 	// 所有通道都失败
+	fanOut.record(metrics)
 	metrics.Save(c.Request.Context(), false, lastErr, 0)
 	resp.Error(c, http.StatusBadGateway, "all channels failed")
 }
 
+// buildOutbound 创建渠道的出站适配器并套上各包装层，渠道类型不支持时返回 nil
+// 包装层带有流式解析状态，拆分 n 时每个并发请求需要独立的实例
+func buildOutbound(channel *dbmodel.Channel, request *model.InternalLLMRequest, policy dbmodel.ReasoningPolicy, structured *structuredOutput) model.Outbound {
+	outAdapter := outbound.Get(channel.Type)
+	if outAdapter == nil {
+		return nil
+	}
+	// 适配器自行发出的请求 (如 Vertex 换取 token) 与上游请求使用相同的代理
+	if setter, ok := outAdapter.(model.OutboundHTTPClientSetter); ok {
+		if httpClient, err := helper.ChannelHttpClient(channel); err == nil {
+			setter.SetHTTPClient(httpClient)
+		} else {
+			log.Warnf("failed to get http client: %v", err)
+		}
+	}
+	// 缓存断点按最终发送的内容计算，需在提示词模拟等改写请求的包装层之内
+	if ttl, ok := channel.PromptCacheTTL(); ok && channel.Type == outbound.OutboundTypeAnthropic && request.IsChatRequest() {
+		outAdapter = newPromptCacheOutbound(outAdapter, channel.PromptCacheMinTokens, ttl)
+	}
+	// 远程媒体内联紧贴上游适配器，其余包装层看到的仍是原始地址
+	if channel.NeedsInlineMedia() && request.IsChatRequest() {
+		outAdapter = newMediaOutbound(outAdapter, channel)
+	}
+	// 推理内容处理在最内层，之后的工具解析和结构化输出校验只看到正文
+	outAdapter = wrapReasoning(outAdapter, policy)
+	// 提示词模拟工具调用需在结构化输出之内，使工具模式的强制调用同样可以模拟
+	if channel.PromptToolCall && request.IsChatRequest() {
+		outAdapter = newPromptToolOutbound(outAdapter)
+	}
+	if structured != nil {
+		outAdapter = structured.wrap(outAdapter)
+	}
+	return outAdapter
+}
+
 // parseRequest 解析并验证入站请求
 func parseRequest(inboundType inbound.InboundType, c *gin.Context) (*model.InternalLLMRequest, model.Inbound, error) {
 	body, err := io.ReadAll(c.Request.Body)
@@ -185,6 +260,7 @@ func parseRequest(inboundType inbound.InboundType, c *gin.Context) (*model.Inter
 
 	// Pass through the original query parameters
 	internalRequest.Query = c.Request.URL.Query()
+	internalRequest.RawRequest = body
 
 	if err := internalRequest.Validate(); err != nil {
 		resp.Error(c, http.StatusBadRequest, err.Error())
@@ -198,16 +274,59 @@ func parseRequest(inboundType inbound.InboundType, c *gin.Context) (*model.Inter
 func (rc *relayContext) forward() (int, error) {
 	ctx := rc.c.Request.Context()
 
+	// 渠道强制的流式模式与客户端不一致时，在流式和非流式之间转换
+	clientStream := rc.internalRequest.Stream != nil && *rc.internalRequest.Stream
+	// 渠道不支持 n 时拆分为多个请求，各请求自行处理流式模式
+	if n := rc.fanOutCount(); n > 1 {
+		return rc.forwardFanOut(ctx, clientStream, n)
+	}
+	if rc.internalRequest.IsChatRequest() && rc.channel.UpstreamStream(clientStream) != clientStream {
+		return rc.forwardAdapted(ctx, clientStream)
+	}
+
+	// 流式请求的上游连接在客户端断开后保留一段时间，以读取最终用量
+	upstreamCtx := ctx
+	if clientStream {
+		var cancel context.CancelFunc
+		upstreamCtx, cancel = drainContext(ctx, streamDrainGrace())
+		defer cancel()
+	}
+	response, err := rc.doRequest(upstreamCtx, rc.internalRequest)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	// 处理响应
+	if clientStream {
+		if err := rc.handleStreamResponse(ctx, response); err != nil {
+			return 0, err
+		}
+		return response.StatusCode, nil
+	}
+	internalResponse, err := rc.outAdapter.TransformResponse(ctx, response)
+	if err != nil {
+		log.Warnf("failed to transform response: %v", err)
+		return 0, fmt.Errorf("failed to transform outbound response: %w", err)
+	}
+	if err := rc.handleResponse(ctx, internalResponse); err != nil {
+		return 0, err
+	}
+	return response.StatusCode, nil
+}
+
+// doRequest 构建并发送出站请求，非 2xx 响应转为错误
+func (rc *relayContext) doRequest(ctx context.Context, request *model.InternalLLMRequest) (*http.Response, error) {
 	// 构建出站请求
 	outboundRequest, err := rc.outAdapter.TransformRequest(
 		ctx,
-		rc.internalRequest,
+		request,
 		rc.channel.GetBaseUrl(),
 		rc.usedKey.ChannelKey,
 	)
 	if err != nil {
 		log.Warnf("failed to create request: %v", err)
-		return 0, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// 复制请求头
@@ -216,30 +335,73 @@ func (rc *relayContext) forward() (int, error) {
 	// 发送请求
 	response, err := rc.sendRequest(outboundRequest)
 	if err != nil {
-		return 0, fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
-	defer response.Body.Close()
 
 	// 检查响应状态
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		defer response.Body.Close()
 		body, err := io.ReadAll(response.Body)
 		if err != nil {
-			return 0, fmt.Errorf("failed to read response body: %w", err)
+			return nil, fmt.Errorf("failed to read response body: %w", err)
 		}
-		return 0, fmt.Errorf("upstream error: %d: %s", response.StatusCode, string(body))
+		if handler, ok := rc.outAdapter.(model.OutboundErrorHandler); ok {
+			if err := handler.TransformError(ctx, response.StatusCode, body); err != nil {
+				return nil, &upstreamStatusError{statusCode: response.StatusCode, err: fmt.Errorf("upstream error: %d: %w", response.StatusCode, err)}
+			}
+		}
+		return nil, &upstreamStatusError{statusCode: response.StatusCode, err: fmt.Errorf("upstream error: %d: %s", response.StatusCode, string(body))}
 	}
+	return response, nil
+}
 
-	// 处理响应
-	if rc.internalRequest.Stream != nil && *rc.internalRequest.Stream {
-		if err := rc.handleStreamResponse(ctx, response); err != nil {
-			return 0, err
-		}
-		return response.StatusCode, nil
+// upstreamStatusError 上游返回非 2xx 状态码，保留状态码供渠道 Key 记录
+type upstreamStatusError struct {
+	statusCode int
+	err        error
+}
+
+func (e *upstreamStatusError) Error() string { return e.err.Error() }
+
+func (e *upstreamStatusError) Unwrap() error { return e.err }
+
+// upstreamStatusCode 从错误中取出上游的状态码，非上游状态错误时返回 statusCode
+func upstreamStatusCode(statusCode int, err error) int {
+	var statusErr *upstreamStatusError
+	if statusCode == 0 && errors.As(err, &statusErr) {
+		return statusErr.statusCode
 	}
-	if err := rc.handleResponse(ctx, response); err != nil {
-		return 0, err
+	return statusCode
+}
+
+// roundTrip 发送请求并转换为完整的内部响应，不写回客户端
+// 渠道强制流式时以流式请求上游并聚合，客户端断开后同样在宽限期内读取最终用量
+func (rc *relayContext) roundTrip(ctx context.Context, request *model.InternalLLMRequest) (*model.InternalLLMResponse, error) {
+	// 客户端断开后不再发起新的请求 (如结构化输出的重试)
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	return response.StatusCode, nil
+	stream := rc.channel.UpstreamStream(false)
+	upstreamCtx := ctx
+	if stream {
+		var cancel context.CancelFunc
+		upstreamCtx, cancel = drainContext(ctx, streamDrainGrace())
+		defer cancel()
+	}
+	response, err := rc.doRequest(upstreamCtx, withStream(request, stream))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if stream {
+		return rc.aggregateStream(ctx, response)
+	}
+	internalResponse, err := rc.outAdapter.TransformResponse(ctx, response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform outbound response: %w", err)
+	}
+	return internalResponse, nil
 }
 
 // copyHeaders 复制请求头，过滤 hop-by-hop 头
@@ -278,19 +440,52 @@ func (rc *relayContext) sendRequest(req *http.Request) (*http.Response, error) {
 
 // handleStreamResponse 处理流式响应
 func (rc *relayContext) handleStreamResponse(ctx context.Context, response *http.Response) error {
-	// 流式响应应当是 SSE
-	// 某些上游可能会返回非SSE的JSON响应 (由于 Accept headers 配置错误)
-	if ct := response.Header.Get("Content-Type"); ct != "" && !strings.Contains(strings.ToLower(ct), "text/event-stream") {
+	if err := checkStreamContentType(response); err != nil {
+		return err
+	}
+	rc.setStreamHeaders()
+
+	return rc.readStream(ctx, response, func(data string) bool {
+		// 转换流式数据
+		out, err := rc.transformStreamData(ctx, data)
+		if err != nil || len(out) == 0 {
+			return false
+		}
+		// 客户端已断开时只经过入站适配器累积内容和用量，不再写出
+		if ctx.Err() != nil {
+			return true
+		}
+		rc.c.Writer.Write(out)
+		rc.c.Writer.Flush()
+		return true
+	})
+}
+
+// checkStreamContentType 流式响应应当是 SSE 或 NDJSON
+// 某些上游可能会返回非SSE的JSON响应 (由于 Accept headers 配置错误)
+func checkStreamContentType(response *http.Response) error {
+	ct := response.Header.Get("Content-Type")
+	if ct != "" && !isNDJSONContentType(ct) && !strings.Contains(strings.ToLower(ct), "text/event-stream") {
 		body, _ := io.ReadAll(io.LimitReader(response.Body, 16*1024))
 		return fmt.Errorf("upstream returned non-SSE content-type %q for stream request: %s", ct, string(body))
 	}
+	return nil
+}
 
-	// 设置 SSE 响应头
+// setStreamHeaders 设置 SSE 响应头
+func (rc *relayContext) setStreamHeaders() {
 	rc.c.Header("Content-Type", "text/event-stream")
 	rc.c.Header("Cache-Control", "no-cache")
 	rc.c.Header("Connection", "keep-alive")
 	rc.c.Header("X-Accel-Buffering", "no")
+	rc.setInboundHeaders(true)
+}
 
+// readStream 逐个读取上游流式事件交给 handle 处理，handle 返回 true 表示产生了有效输出
+// 首个有效输出前超过 firstTokenTimeOutSec 时中止并返回错误，以便切换渠道
+// 客户端断开时若上游请求仍在宽限期内 (见 drainContext)，继续读取到上游结束或宽限期满
+func (rc *relayContext) readStream(ctx context.Context, response *http.Response, handle func(data string) bool) error {
+	ndjson := isNDJSONContentType(response.Header.Get("Content-Type"))
 	firstToken := true
 
 	// Streaming "time to first token" timeout: only applies before we write anything to the client.
@@ -302,6 +497,16 @@ func (rc *relayContext) handleStreamResponse(ctx context.Context, response *http
 	results := make(chan sseReadResult, 1)
 	go func() {
 		defer close(results)
+		if ndjson {
+			for line, err := range readNDJSON(response.Body, maxSSEEventSize) {
+				if err != nil {
+					results <- sseReadResult{err: err}
+					return
+				}
+				results <- sseReadResult{data: line}
+			}
+			return
+		}
 		readCfg := &sse.ReadConfig{MaxEventSize: maxSSEEventSize}
 		for ev, err := range sse.Read(response.Body, readCfg) {
 			if err != nil {
@@ -324,12 +529,26 @@ func (rc *relayContext) handleStreamResponse(ctx context.Context, response *http
 		}()
 	}
 
+	clientDone := ctx.Done()
+	drained := false
 	for {
 		// 检查客户端是否断开
 		select {
-		case <-ctx.Done():
-			log.Infof("client disconnected, stopping stream")
-			return nil
+		case <-clientDone:
+			if !draining(ctx, response) {
+				log.Infof("client disconnected, stopping stream")
+				return nil
+			}
+			log.Infof("client disconnected, draining upstream for usage")
+			rc.metrics.SetClientAborted()
+			clientDone = nil
+			drained = true
+			// 不再切换渠道，首 Token 超时不再生效
+			if firstTokenTimer != nil {
+				firstTokenTimer.Stop()
+				firstTokenTimer = nil
+				firstTokenC = nil
+			}
 		case <-firstTokenC:
 			// Abort upstream stream before any client writes; caller will retry next channel.
 			log.Warnf("first token timeout (%ds), switching channel", rc.firstTokenTimeOutSec)
@@ -341,13 +560,15 @@ func (rc *relayContext) handleStreamResponse(ctx context.Context, response *http
 				return nil
 			}
 			if r.err != nil {
+				if drained {
+					log.Infof("stream drain grace period expired")
+					return nil
+				}
 				log.Warnf("failed to read event: %v", r.err)
 				return fmt.Errorf("failed to read stream event: %w", r.err)
 			}
 
-			// 转换流式数据
-			data, err := rc.transformStreamData(ctx, r.data)
-			if err != nil || len(data) == 0 {
+			if !handle(r.data) {
 				continue
 			}
 			// 记录首个 Token 时间
@@ -366,9 +587,6 @@ func (rc *relayContext) handleStreamResponse(ctx context.Context, response *http
 					firstTokenC = nil
 				}
 			}
-
-			rc.c.Writer.Write(data)
-			rc.c.Writer.Flush()
 		}
 	}
 }
@@ -395,15 +613,21 @@ func (rc *relayContext) transformStreamData(ctx context.Context, data string) ([
 	return inStream, nil
 }
 
-// handleResponse 处理非流式响应
-func (rc *relayContext) handleResponse(ctx context.Context, response *http.Response) error {
-	// 上游格式 → 内部格式
-	internalResponse, err := rc.outAdapter.TransformResponse(ctx, response)
-	if err != nil {
-		log.Warnf("failed to transform response: %v", err)
-		return fmt.Errorf("failed to transform outbound response: %w", err)
+// handleResponse 处理已转为内部格式的非流式响应
+func (rc *relayContext) handleResponse(ctx context.Context, internalResponse *model.InternalLLMResponse) error {
+	var err error
+	// 结构化输出校验，必要时修复或重试
+	if rc.structured != nil {
+		internalResponse, err = rc.structured.ensure(ctx, rc, internalResponse)
+		if err != nil {
+			return err
+		}
 	}
+	return rc.writeResponse(ctx, internalResponse)
+}
 
+// writeResponse 将内部格式的完整响应转为入站格式写回客户端
+func (rc *relayContext) writeResponse(ctx context.Context, internalResponse *model.InternalLLMResponse) error {
 	// 内部格式 → 入站格式
 	inResponse, err := rc.inAdapter.TransformResponse(ctx, internalResponse)
 	if err != nil {
@@ -411,17 +635,31 @@ func (rc *relayContext) handleResponse(ctx context.Context, response *http.Respo
 		return fmt.Errorf("failed to transform inbound response: %w", err)
 	}
 
+	// 已设置的 Content-Type 不会被 c.Data 覆盖
+	rc.setInboundHeaders(false)
 	rc.c.Data(http.StatusOK, "application/json", inResponse)
 	return nil
 }
 
+// setInboundHeaders 设置入站适配器自定义的响应头
+func (rc *relayContext) setInboundHeaders(stream bool) {
+	h, ok := rc.inAdapter.(model.InboundResponseHeader)
+	if !ok {
+		return
+	}
+	for k, v := range h.ResponseHeaders(stream) {
+		rc.c.Header(k, v)
+	}
+}
+
 // collectResponse 收集响应信息
-func (rc *relayContext) collectResponse() {
+func (rc *relayContext) collectResponse() *model.InternalLLMResponse {
 	internalResponse, err := rc.inAdapter.GetInternalResponse(rc.c.Request.Context())
 	if err != nil || internalResponse == nil {
-		return
+		return nil
 	}
 
 	// 设置响应内容
 	rc.metrics.SetInternalResponse(internalResponse)
+	return internalResponse
 }
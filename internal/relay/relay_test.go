@@ -0,0 +1,155 @@
+package relay
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/outbound"
+	"github.com/bestruirui/octopus/internal/transformer/outbound/vertex"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+// 客户端携带的认证头不能覆盖出站适配器设置的渠道密钥
+func TestCopyHeadersKeepsChannelCredentials(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Request.Header.Set("Authorization", "Bearer sk-octopus-client")
+	c.Request.Header.Set("X-Api-Key", "sk-octopus-client")
+	c.Request.Header.Set("Api-Key", "sk-octopus-client")
+	c.Request.Header.Set("X-Goog-Api-Key", "sk-octopus-client")
+	c.Request.Header.Set("X-Custom", "kept")
+
+	rc := &relayContext{c: c, channel: &dbmodel.Channel{}}
+	outboundRequest := httptest.NewRequest(http.MethodPost, "https://example.openai.azure.com/openai", nil)
+	outboundRequest.Header.Set("api-key", "channel-key")
+	rc.copyHeaders(outboundRequest)
+
+	if got := outboundRequest.Header.Get("api-key"); got != "channel-key" {
+		t.Fatalf("api-key = %q, want channel-key", got)
+	}
+	for _, key := range []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key"} {
+		if got := outboundRequest.Header.Get(key); got != "" {
+			t.Fatalf("%s forwarded: %q", key, got)
+		}
+	}
+	if got := outboundRequest.Header.Get("X-Custom"); got != "kept" {
+		t.Fatalf("X-Custom = %q, want kept", got)
+	}
+}
+
+// 用量导出读取按 API Key × 模型 的统计，关闭历史日志时仍会记录
+func TestUsageRecordedWithoutRelayLog(t *testing.T) {
+	if err := op.SettingSetString(dbmodel.SettingKeyRelayLogKeepEnabled, "false"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { op.SettingSetString(dbmodel.SettingKeyRelayLogKeepEnabled, "true") })
+	upstream := newTestUpstream(t, nil)
+	key := newTestAPIKey(t)
+
+	for range 2 {
+		if data, success := executeBatchLine(context.Background(), key.ID, testBatchLine(upstream.model)); !success {
+			t.Fatalf("request failed: %s", data)
+		}
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	rows, err := op.UsageExport(context.Background(), today, today.AddDate(0, 0, 1), key.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("rows = %+v, want one row", rows)
+	}
+	row := rows[0]
+	// 每次 10 输入、5 输出 token，按 输入 $1/M、输出 $2/M 计费
+	if row.Model != upstream.model || row.APIKeyName != key.Name || row.Requests != 2 || row.FailedRequests != 0 ||
+		row.InputTokens != 20 || row.OutputTokens != 10 || math.Abs(row.Cost-0.00004) > 1e-12 {
+		t.Fatalf("unexpected usage row: %+v", row)
+	}
+}
+
+type errorOutbound struct {
+	model.Outbound
+	err error
+}
+
+func (o errorOutbound) TransformError(ctx context.Context, statusCode int, body []byte) error {
+	return o.err
+}
+
+// 各层包装适配器都保留被包装适配器的错误处理
+func TestWrappedOutboundKeepsErrorHandler(t *testing.T) {
+	want := errors.New("quota exceeded")
+	var outAdapter model.Outbound = errorOutbound{err: want}
+	outAdapter = newPromptCacheOutbound(outAdapter, 1024, "")
+	outAdapter = newMediaOutbound(outAdapter, &dbmodel.Channel{})
+	outAdapter = wrapReasoning(outAdapter, dbmodel.ReasoningPolicyStrip)
+	outAdapter = newPromptToolOutbound(outAdapter)
+	outAdapter = (&structuredOutput{mode: dbmodel.StructuredOutputPrompt}).wrap(outAdapter)
+
+	handler, ok := outAdapter.(model.OutboundErrorHandler)
+	if !ok {
+		t.Fatal("wrapped outbound does not implement OutboundErrorHandler")
+	}
+	if err := handler.TransformError(context.Background(), http.StatusTooManyRequests, nil); err != want {
+		t.Fatalf("TransformError = %v, want %v", err, want)
+	}
+	if err := (wrappedOutbound{}).TransformError(context.Background(), http.StatusTooManyRequests, nil); err != nil {
+		t.Fatalf("TransformError without handler = %v, want nil", err)
+	}
+}
+
+// Vertex 换取 access token 的请求与上游请求一样经过渠道代理
+func TestVertexTokenUsesChannelProxy(t *testing.T) {
+	var tokenRequests atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "oauth.test" {
+			http.Error(w, "unexpected host", http.StatusBadGateway)
+			return
+		}
+		tokenRequests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"proxied-token","expires_in":3600}`))
+	}))
+	defer proxy.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	serviceAccount, _ := json.Marshal(vertex.ServiceAccount{
+		Type:        "service_account",
+		ProjectID:   "proxy-project",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail: "proxy@proxy-project.iam.gserviceaccount.com",
+		TokenURI:    "http://oauth.test/token",
+	})
+
+	channel := &dbmodel.Channel{Type: outbound.OutboundTypeVertex, Proxy: true, ChannelProxy: lo.ToPtr(proxy.URL)}
+	request := &model.InternalLLMRequest{Model: "gemini-2.5-pro", Messages: []model.Message{{Role: "user", Content: model.MessageContent{Content: lo.ToPtr("hi")}}}}
+	outAdapter := buildOutbound(channel, request, dbmodel.ReasoningPolicyDefault, nil)
+	req, err := outAdapter.TransformRequest(context.Background(), request, "https://us-east5-aiplatform.googleapis.com/v1", string(serviceAccount))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer proxied-token" || tokenRequests.Load() != 1 {
+		t.Fatalf("authorization %q, token requests through proxy %d", got, tokenRequests.Load())
+	}
+}
@@ -0,0 +1,87 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/transformer/inbound/openai"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/utils/log"
+	"github.com/gin-gonic/gin"
+)
+
+// expandPreviousResponse 将 previous_response_id 展开为完整的对话历史，
+// 使任意渠道（包括 Anthropic、Gemini）都能处理多轮 Responses 对话。
+// 返回 false 表示已向客户端返回错误
+func expandPreviousResponse(c *gin.Context, inAdapter model.Inbound, req *model.InternalLLMRequest, apiKeyID int) bool {
+	responseInbound, ok := inAdapter.(*openai.ResponseInbound)
+	if !ok || responseInbound.PreviousResponseID() == "" {
+		return true
+	}
+
+	previousID := responseInbound.PreviousResponseID()
+	previous, err := op.ResponseGet(previousID, apiKeyID, c.Request.Context())
+	if err != nil {
+		if errors.Is(err, op.ErrResponseNotFound) {
+			resp.Error(c, http.StatusNotFound, fmt.Sprintf("previous response with id '%s' not found", previousID))
+		} else {
+			resp.Error(c, http.StatusInternalServerError, err.Error())
+		}
+		return false
+	}
+
+	var history []model.Message
+	if err := json.Unmarshal([]byte(previous.Messages), &history); err != nil {
+		resp.Error(c, http.StatusInternalServerError, fmt.Sprintf("failed to decode previous response: %v", err))
+		return false
+	}
+	responseInbound.ExpandHistory(req, history)
+	return true
+}
+
+// saveResponseState 持久化 Responses API 的响应及输入项，供后续 previous_response_id、检索与删除使用
+func saveResponseState(ctx context.Context, inAdapter model.Inbound, req *model.InternalLLMRequest, internalResponse *model.InternalLLMResponse, apiKeyID int) {
+	responseInbound, ok := inAdapter.(*openai.ResponseInbound)
+	if !ok || !responseInbound.ShouldStore() || internalResponse == nil {
+		return
+	}
+
+	response := responseInbound.BuildResponse(internalResponse)
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		log.Warnf("failed to marshal response %s: %v", response.ID, err)
+		return
+	}
+	inputItemsJSON, err := json.Marshal(responseInbound.InputItems())
+	if err != nil {
+		log.Warnf("failed to marshal input items of response %s: %v", response.ID, err)
+		return
+	}
+	messagesJSON, err := json.Marshal(responseInbound.Conversation(req, internalResponse))
+	if err != nil {
+		log.Warnf("failed to marshal conversation of response %s: %v", response.ID, err)
+		return
+	}
+
+	obj := &dbmodel.ResponseObject{
+		ID:                 response.ID,
+		APIKeyID:           apiKeyID,
+		PreviousResponseID: responseInbound.PreviousResponseID(),
+		Model:              response.Model,
+		Response:           string(responseJSON),
+		InputItems:         string(inputItemsJSON),
+		Messages:           string(messagesJSON),
+		CreatedAt:          time.Now().Unix(),
+	}
+	// 客户端可能在流结束后立即断开，使用独立的 context 保存
+	if err := op.ResponseCreate(obj, context.WithoutCancel(ctx)); err != nil {
+		log.Warnf("failed to save response %s: %v", response.ID, err)
+	}
+}
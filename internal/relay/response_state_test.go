@@ -0,0 +1,146 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/helper"
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/inbound"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/gin-gonic/gin"
+)
+
+// newResponsesServer 以 key 的身份处理 Responses API 请求
+func newResponsesServer(t *testing.T, key dbmodel.APIKey) *httptest.Server {
+	t.Helper()
+	engine := gin.New()
+	engine.POST("/v1/responses", func(c *gin.Context) {
+		helper.SetAPIKeyContext(c, key, "openai", false)
+		Handler(inbound.InboundTypeOpenAIResponse, c)
+	})
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func postResponse(t *testing.T, server *httptest.Server, body string) (int, string) {
+	t.Helper()
+	resp, err := server.Client().Post(server.URL+"/v1/responses", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// storedResponse 等待响应写入数据库，保存发生在响应发送给客户端之后
+func storedResponse(t *testing.T, id string, apiKeyID int) *dbmodel.ResponseObject {
+	t.Helper()
+	var obj *dbmodel.ResponseObject
+	waitFor(t, "response "+id+" to be stored", func() bool {
+		obj, _ = op.ResponseGet(id, apiKeyID, context.Background())
+		return obj != nil
+	})
+	return obj
+}
+
+// 存储的对话在下一轮按 previous_response_id 展开，上一轮的 instructions 不会带入
+func TestPreviousResponseExpandsHistory(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		received [][]string
+	)
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		var req model.InternalLLMRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		var messages []string
+		for _, msg := range req.Messages {
+			messages = append(messages, msg.Role+": "+messageText(msg.Content))
+		}
+		lock.Lock()
+		received = append(received, messages)
+		lock.Unlock()
+		upstream.writeCompletion(w, r)
+	})
+	key := newTestAPIKey(t)
+	server := newResponsesServer(t, key)
+
+	status, body := postResponse(t, server, fmt.Sprintf(`{"model":%q,"instructions":"be brief","input":"hi"}`, upstream.model))
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	var first struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal([]byte(body), &first)
+	stored := storedResponse(t, first.ID, key.ID)
+	var items []map[string]any
+	if err := json.Unmarshal([]byte(stored.InputItems), &items); err != nil || len(items) != 1 || items[0]["role"] != "user" || items[0]["id"] == "" {
+		t.Fatalf("input items = %s", stored.InputItems)
+	}
+	var history []model.Message
+	if err := json.Unmarshal([]byte(stored.Messages), &history); err != nil || len(history) != 2 || history[1].Role != "assistant" {
+		t.Fatalf("stored conversation = %s", stored.Messages)
+	}
+
+	status, body = postResponse(t, server, fmt.Sprintf(`{"model":%q,"instructions":"be terse","previous_response_id":%q,"input":"again"}`, upstream.model, first.ID))
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	lock.Lock()
+	got := strings.Join(received[1], "\n")
+	lock.Unlock()
+	if want := "system: be terse\nuser: hi\nassistant: ok\nuser: again"; got != want {
+		t.Fatalf("upstream messages:\n%s\nwant:\n%s", got, want)
+	}
+	var second struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal([]byte(body), &second)
+	if stored := storedResponse(t, second.ID, key.ID); stored.PreviousResponseID != first.ID {
+		t.Fatalf("previous response id = %q, want %q", stored.PreviousResponseID, first.ID)
+	}
+
+	// 其他 API Key 不能引用该响应
+	other := newResponsesServer(t, newTestAPIKey(t))
+	status, body = postResponse(t, other, fmt.Sprintf(`{"model":%q,"previous_response_id":%q,"input":"again"}`, upstream.model, first.ID))
+	if status != http.StatusNotFound || !strings.Contains(body, first.ID) {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	if hits := upstream.hits.Load(); hits != 2 {
+		t.Fatalf("upstream hits = %d, want 2", hits)
+	}
+}
+
+func TestResponseNotStored(t *testing.T) {
+	upstream := newTestUpstream(t, nil)
+	key := newTestAPIKey(t)
+	server := newResponsesServer(t, key)
+	logs := subscribeRelayLogs(t)
+
+	status, body := postResponse(t, server, fmt.Sprintf(`{"model":%q,"store":false,"input":"hi"}`, upstream.model))
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	var resp struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal([]byte(body), &resp)
+	// 保存在写入日志之前完成
+	nextRelayLog(t, logs, key.ID)
+	if _, err := op.ResponseGet(resp.ID, key.ID, context.Background()); !errors.Is(err, op.ErrResponseNotFound) {
+		t.Fatalf("response stored with store=false: %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/transformer/inbound"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/utils/log"
+)
+
+// forwardAdapted 渠道强制的流式模式与客户端请求不一致时转发
+// 客户端非流式：以流式请求上游，聚合后返回完整响应，首 Token 超时同样生效
+// 客户端流式：以非流式请求上游，将完整响应拆为流式事件返回
+func (rc *relayContext) forwardAdapted(ctx context.Context, clientStream bool) (int, error) {
+	if !clientStream {
+		internalResponse, err := rc.roundTrip(ctx, rc.internalRequest)
+		if err != nil {
+			return 0, err
+		}
+		if err := rc.handleResponse(ctx, internalResponse); err != nil {
+			return 0, err
+		}
+		return http.StatusOK, nil
+	}
+
+	// 客户端断开后上游请求保留一段时间，拿到完整响应后仍按其用量计费
+	upstreamCtx, cancel := drainContext(ctx, streamDrainGrace())
+	defer cancel()
+	response, err := rc.doRequest(upstreamCtx, withStream(rc.internalRequest, false))
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	internalResponse, err := rc.outAdapter.TransformResponse(ctx, response)
+	if err != nil {
+		log.Warnf("failed to transform response: %v", err)
+		return 0, fmt.Errorf("failed to transform outbound response: %w", err)
+	}
+	if ctx.Err() != nil {
+		rc.metrics.SetClientAborted()
+	}
+	if err := rc.writeSyntheticStream(ctx, internalResponse); err != nil {
+		return 0, err
+	}
+	return response.StatusCode, nil
+}
+
+// aggregateStream 读取上游流式响应，使用入站适配器的聚合逻辑合并为完整响应
+// 聚合使用独立的入站适配器实例，失败重试时不会混入上一次的数据
+func (rc *relayContext) aggregateStream(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
+	if err := checkStreamContentType(response); err != nil {
+		return nil, err
+	}
+	aggregator := inbound.Get(rc.inboundType)
+	if aggregator == nil {
+		return nil, fmt.Errorf("unsupported inbound type: %d", rc.inboundType)
+	}
+
+	err := rc.readStream(ctx, response, func(data string) bool {
+		internalStream, err := rc.outAdapter.TransformStream(ctx, []byte(data))
+		if err != nil {
+			log.Warnf("failed to transform stream: %v", err)
+			return false
+		}
+		if internalStream == nil {
+			return false
+		}
+		out, err := aggregator.TransformStream(ctx, internalStream)
+		return err == nil && len(out) > 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 客户端断开且未在宽限期内读取上游时，聚合结果不完整
+	if err := ctx.Err(); err != nil && !rc.metrics.ClientAborted {
+		return nil, err
+	}
+
+	internalResponse, err := aggregator.GetInternalResponse(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate stream response: %w", err)
+	}
+	if internalResponse == nil {
+		return nil, fmt.Errorf("upstream stream ended without data")
+	}
+	return internalResponse, nil
+}
+
+// writeSyntheticStream 将完整响应按上游流式的事件顺序写回客户端
+// 客户端已断开时只经过入站适配器累积内容和用量
+func (rc *relayContext) writeSyntheticStream(ctx context.Context, internalResponse *model.InternalLLMResponse) error {
+	rc.setStreamHeaders()
+	for _, chunk := range synthesizeStreamChunks(internalResponse) {
+		out, err := rc.inAdapter.TransformStream(ctx, chunk)
+		if err != nil {
+			return fmt.Errorf("failed to transform inbound stream: %w", err)
+		}
+		if len(out) == 0 || ctx.Err() != nil {
+			continue
+		}
+		if rc.metrics.FirstTokenTime.IsZero() {
+			rc.metrics.SetFirstTokenTime(time.Now())
+		}
+		rc.c.Writer.Write(out)
+	}
+	rc.c.Writer.Flush()
+	return nil
+}
+
+// synthesizeStreamChunks 按 OpenAI 流式格式拆分完整响应：
+// 每个 choice 一个内容块和一个结束块，随后是用量块和 [DONE]
+func synthesizeStreamChunks(resp *model.InternalLLMResponse) []*model.InternalLLMResponse {
+	newChunk := func() *model.InternalLLMResponse {
+		return &model.InternalLLMResponse{
+			ID:                resp.ID,
+			Object:            "chat.completion.chunk",
+			Created:           resp.Created,
+			Model:             resp.Model,
+			SystemFingerprint: resp.SystemFingerprint,
+			ServiceTier:       resp.ServiceTier,
+		}
+	}
+
+	var chunks []*model.InternalLLMResponse
+	for _, choice := range resp.Choices {
+		message := choice.Message
+		if message == nil {
+			message = choice.Delta
+		}
+		if message != nil {
+			delta := *message
+			if delta.Role == "" {
+				delta.Role = "assistant"
+			}
+			if len(delta.ToolCalls) > 0 {
+				delta.ToolCalls = make([]model.ToolCall, len(message.ToolCalls))
+				for i, toolCall := range message.ToolCalls {
+					toolCall.Index = i
+					if toolCall.Type == "" {
+						toolCall.Type = "function"
+					}
+					delta.ToolCalls[i] = toolCall
+				}
+			}
+			chunk := newChunk()
+			chunk.Choices = []model.Choice{{Index: choice.Index, Delta: &delta, Logprobs: choice.Logprobs}}
+			chunks = append(chunks, chunk)
+		}
+
+		finishReason := choice.FinishReason
+		if finishReason == nil {
+			stop := "stop"
+			finishReason = &stop
+		}
+		chunk := newChunk()
+		chunk.Choices = []model.Choice{{Index: choice.Index, Delta: &model.Message{}, FinishReason: finishReason}}
+		chunks = append(chunks, chunk)
+	}
+
+	if resp.Usage != nil {
+		chunk := newChunk()
+		chunk.Usage = resp.Usage
+		chunks = append(chunks, chunk)
+	}
+	return append(chunks, &model.InternalLLMResponse{Object: "[DONE]"})
+}
+
+// withStream 返回按指定流式模式修改后的请求副本，不修改原请求
+func withStream(request *model.InternalLLMRequest, stream bool) *model.InternalLLMRequest {
+	if (request.Stream != nil && *request.Stream) == stream {
+		return request
+	}
+	clone := *request
+	clone.Stream = &stream
+	if !stream {
+		clone.StreamOptions = nil
+	}
+	return &clone
+}
@@ -0,0 +1,349 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/schema"
+	"github.com/samber/lo"
+)
+
+// structuredToolName 工具模拟时 schema 未命名所使用的工具名
+const structuredToolName = "structured_output"
+
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// structuredOutput 按分组配置处理 response_format 结构化输出：模拟、校验、修复与重试
+// 流式响应已写回客户端，只能记录校验结果，修复与重试仅作用于非流式响应
+type structuredOutput struct {
+	mode    dbmodel.StructuredOutputMode
+	retries int
+	schema  map[string]any
+	metrics *RelayMetrics
+
+	// 工具模拟相关
+	toolName string
+	// wrapped 根节点不是对象时，工具参数包装为 {"value": ...}
+	wrapped bool
+}
+
+// newStructuredOutput 分组未启用或请求未要求 JSON 输出时返回 nil
+func newStructuredOutput(group dbmodel.Group, req *model.InternalLLMRequest, metrics *RelayMetrics) *structuredOutput {
+	if group.StructuredOutput == dbmodel.StructuredOutputOff || !req.IsChatRequest() {
+		return nil
+	}
+	name, s, err := schema.FromResponseFormat(req.ResponseFormat)
+	if err != nil || s == nil {
+		return nil
+	}
+
+	so := &structuredOutput{
+		mode:     group.StructuredOutput,
+		retries:  min(max(group.StructuredOutputRetries, 0), dbmodel.MaxStructuredOutputRetries),
+		schema:   s,
+		metrics:  metrics,
+		toolName: structuredToolName,
+	}
+	if toolNamePattern.MatchString(name) {
+		so.toolName = name
+	}
+
+	if so.mode == dbmodel.StructuredOutputTool {
+		rootType, _ := s["type"].(string)
+		so.wrapped = rootType != "object"
+		// 请求自带工具时无法强制调用，流式响应也无法解包 value，退回提示词模拟
+		if len(req.Tools) > 0 || (so.wrapped && req.Stream != nil && *req.Stream) {
+			so.mode = dbmodel.StructuredOutputPrompt
+		}
+	}
+	return so
+}
+
+// wrap 包装出站适配器，在请求和响应中完成模拟
+func (so *structuredOutput) wrap(outAdapter model.Outbound) model.Outbound {
+	if so.mode == dbmodel.StructuredOutputNative {
+		return outAdapter
+	}
+	return &structuredOutbound{wrappedOutbound: wrappedOutbound{outAdapter}, so: so}
+}
+
+// prepareRequest 返回模拟结构化输出的请求副本，不修改原请求
+func (so *structuredOutput) prepareRequest(req *model.InternalLLMRequest) *model.InternalLLMRequest {
+	clone := *req
+	clone.ResponseFormat = nil
+	schemaJSON, _ := json.Marshal(so.schema)
+
+	switch so.mode {
+	case dbmodel.StructuredOutputPrompt:
+		instruction := "You must respond with a single JSON value that conforms to the following JSON schema. " +
+			"Output only the JSON value, without explanations or markdown code fences.\n\nJSON schema:\n" + string(schemaJSON)
+		clone.Messages = insertSystemMessage(req.Messages, instruction)
+
+	case dbmodel.StructuredOutputTool:
+		parameters := json.RawMessage(schemaJSON)
+		if so.wrapped {
+			parameters, _ = json.Marshal(map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"value": so.schema},
+				"required":             []string{"value"},
+				"additionalProperties": false,
+			})
+		}
+		clone.Tools = []model.Tool{{
+			Type: "function",
+			Function: model.Function{
+				Name:        so.toolName,
+				Description: "Respond by calling this function with the final answer as its arguments.",
+				Parameters:  parameters,
+			},
+		}}
+		clone.ToolChoice = &model.ToolChoice{NamedToolChoice: &model.NamedToolChoice{
+			Type:     "function",
+			Function: model.ToolFunction{Name: so.toolName},
+		}}
+		clone.ParallelToolCalls = lo.ToPtr(false)
+	}
+	return &clone
+}
+
+// unwrapToolCall 将强制工具调用的参数转为消息内容
+func (so *structuredOutput) unwrapToolCall(resp *model.InternalLLMResponse) {
+	for i := range resp.Choices {
+		choice := &resp.Choices[i]
+		msg := choice.Message
+		if msg == nil || len(msg.ToolCalls) == 0 {
+			continue
+		}
+		args := msg.ToolCalls[0].Function.Arguments
+		if so.wrapped {
+			var wrapper struct {
+				Value json.RawMessage `json:"value"`
+			}
+			if err := json.Unmarshal([]byte(args), &wrapper); err == nil && len(wrapper.Value) > 0 {
+				args = string(wrapper.Value)
+			}
+		}
+		msg.Content = model.MessageContent{Content: lo.ToPtr(args)}
+		msg.ToolCalls = nil
+		if choice.FinishReason != nil && *choice.FinishReason == "tool_calls" {
+			choice.FinishReason = lo.ToPtr("stop")
+		}
+	}
+}
+
+// unwrapToolCallDelta 将流式工具调用的参数增量转为内容增量
+func (so *structuredOutput) unwrapToolCallDelta(stream *model.InternalLLMResponse) {
+	for i := range stream.Choices {
+		choice := &stream.Choices[i]
+		if delta := choice.Delta; delta != nil && len(delta.ToolCalls) > 0 {
+			var args strings.Builder
+			for _, toolCall := range delta.ToolCalls {
+				args.WriteString(toolCall.Function.Arguments)
+			}
+			delta.ToolCalls = nil
+			if args.Len() > 0 {
+				delta.Content = model.MessageContent{Content: lo.ToPtr(messageText(delta.Content) + args.String())}
+			}
+		}
+		if choice.FinishReason != nil && *choice.FinishReason == "tool_calls" {
+			choice.FinishReason = lo.ToPtr("stop")
+		}
+	}
+}
+
+// ensure 校验非流式响应，失败时先尝试本地修复，仍失败则在重试次数内带上错误信息重新请求
+func (so *structuredOutput) ensure(ctx context.Context, rc *relayContext, resp *model.InternalLLMResponse) (*model.InternalLLMResponse, error) {
+	req := rc.internalRequest
+	for attempt := 0; ; attempt++ {
+		repaired, err := so.check(resp)
+		if err == nil {
+			outcome := "valid"
+			if repaired {
+				outcome = "repaired"
+			}
+			if attempt > 0 {
+				outcome += fmt.Sprintf(" (retries: %d)", attempt)
+			}
+			so.record(outcome)
+			return resp, nil
+		}
+		if attempt >= so.retries {
+			so.record("invalid: " + err.Error())
+			return nil, &model.NonRetryableError{
+				StatusCode: http.StatusBadGateway,
+				Code:       "invalid_structured_output",
+				Message:    fmt.Sprintf("model output does not match the response_format schema (retries: %d): %v", attempt, err),
+			}
+		}
+
+		req = so.retryRequest(req, resp, err)
+		next, retryErr := rc.roundTrip(ctx, req)
+		if retryErr != nil {
+			return nil, retryErr
+		}
+		next.Usage = sumUsage(resp.Usage, next.Usage)
+		resp = next
+	}
+}
+
+// finish 记录流式响应的校验结果
+func (so *structuredOutput) finish(resp *model.InternalLLMResponse) {
+	if resp == nil {
+		return
+	}
+	for _, choice := range resp.Choices {
+		if choice.Message == nil || choice.Message.Refusal != "" {
+			continue
+		}
+		if err := schema.ValidateJSON(so.schema, messageText(choice.Message.Content)); err != nil {
+			so.record("invalid: " + err.Error())
+			return
+		}
+	}
+	so.record("valid")
+}
+
+// check 校验所有 choice，校验失败的内容尝试本地修复，返回是否发生修复
+func (so *structuredOutput) check(resp *model.InternalLLMResponse) (bool, error) {
+	repaired := false
+	for i := range resp.Choices {
+		msg := resp.Choices[i].Message
+		// 拒答不属于格式错误
+		if msg == nil || msg.Refusal != "" {
+			continue
+		}
+		text := messageText(msg.Content)
+		err := schema.ValidateJSON(so.schema, text)
+		if err == nil {
+			continue
+		}
+		if fixed, ok := repairJSON(text); ok && schema.ValidateJSON(so.schema, fixed) == nil {
+			msg.Content = model.MessageContent{Content: lo.ToPtr(fixed)}
+			repaired = true
+			continue
+		}
+		if len(resp.Choices) > 1 {
+			return false, fmt.Errorf("choice %d: %w", resp.Choices[i].Index, err)
+		}
+		return false, err
+	}
+	return repaired, nil
+}
+
+// retryRequest 在上一轮请求后追加模型输出和校验错误
+func (so *structuredOutput) retryRequest(req *model.InternalLLMRequest, resp *model.InternalLLMResponse, validationErr error) *model.InternalLLMRequest {
+	clone := *req
+	clone.Messages = append([]model.Message(nil), req.Messages...)
+	if len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
+		clone.Messages = append(clone.Messages, model.Message{
+			Role:    "assistant",
+			Content: model.MessageContent{Content: lo.ToPtr(messageText(resp.Choices[0].Message.Content))},
+		})
+	}
+	clone.Messages = append(clone.Messages, model.Message{
+		Role: "user",
+		Content: model.MessageContent{Content: lo.ToPtr(fmt.Sprintf(
+			"Your previous response does not match the required JSON schema: %v. "+
+				"Respond again with only a JSON value that matches the schema.", validationErr))},
+	})
+	return &clone
+}
+
+func (so *structuredOutput) record(outcome string) {
+	so.metrics.SetStructuredOutput(string(so.mode) + ": " + outcome)
+}
+
+// repairJSON 去除 markdown 代码块及前后多余文本，提取第一个完整的 JSON 值
+func repairJSON(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```")
+		if nl := strings.IndexByte(text, '\n'); nl >= 0 {
+			text = text[nl+1:]
+		}
+		if end := strings.LastIndex(text, "```"); end >= 0 {
+			text = text[:end]
+		}
+		text = strings.TrimSpace(text)
+	}
+	if json.Valid([]byte(text)) {
+		return text, true
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return "", false
+	}
+	dec := json.NewDecoder(strings.NewReader(text[start:]))
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return "", false
+	}
+	return string(raw), true
+}
+
+// insertSystemMessage 在原有系统消息之后插入一条系统消息，返回新的消息列表
+func insertSystemMessage(messages []model.Message, text string) []model.Message {
+	idx := 0
+	for idx < len(messages) && (messages[idx].Role == "system" || messages[idx].Role == "developer") {
+		idx++
+	}
+	result := make([]model.Message, 0, len(messages)+1)
+	result = append(result, messages[:idx]...)
+	result = append(result, model.Message{
+		Role:    "system",
+		Content: model.MessageContent{Content: lo.ToPtr(text)},
+	})
+	return append(result, messages[idx:]...)
+}
+
+func messageText(content model.MessageContent) string {
+	if content.Content != nil {
+		return *content.Content
+	}
+	var sb strings.Builder
+	for _, part := range content.MultipleContent {
+		if part.Type == "text" && part.Text != nil {
+			sb.WriteString(*part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// structuredOutbound 包装出站适配器，发送前替换 response_format，响应中解包工具调用
+type structuredOutbound struct {
+	wrappedOutbound
+	so *structuredOutput
+}
+
+func (o *structuredOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	return o.Outbound.TransformRequest(ctx, o.so.prepareRequest(request), baseUrl, key)
+}
+
+func (o *structuredOutbound) TransformResponse(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
+	resp, err := o.Outbound.TransformResponse(ctx, response)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	if o.so.mode == dbmodel.StructuredOutputTool {
+		o.so.unwrapToolCall(resp)
+	}
+	return resp, nil
+}
+
+func (o *structuredOutbound) TransformStream(ctx context.Context, eventData []byte) (*model.InternalLLMResponse, error) {
+	stream, err := o.Outbound.TransformStream(ctx, eventData)
+	if err != nil || stream == nil {
+		return stream, err
+	}
+	if o.so.mode == dbmodel.StructuredOutputTool {
+		o.so.unwrapToolCallDelta(stream)
+	}
+	return stream, nil
+}
@@ -0,0 +1,244 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/samber/lo"
+)
+
+func TestRepairJSON(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want string
+		ok   bool
+	}{
+		{"valid", ` {"a":1} `, `{"a":1}`, true},
+		{"fence with language", "```json\n{\"a\":1}\n```", `{"a":1}`, true},
+		{"fence without language", "```\n[1,2]\n```", `[1,2]`, true},
+		{"prefix and suffix", `Here it is: {"a":{"b":[1]}} hope this helps`, `{"a":{"b":[1]}}`, true},
+		{"array after text", `Result: [1, 2] done`, `[1, 2]`, true},
+		{"first of two values", `{"a":1} {"b":2}`, `{"a":1}`, true},
+		{"scalar", `"plain"`, `"plain"`, true},
+		{"no JSON", `sorry, I cannot`, "", false},
+		{"truncated", `{"a":`, "", false},
+	}
+	for _, c := range cases {
+		got, ok := repairJSON(c.text)
+		if ok != c.ok || got != c.want {
+			t.Errorf("%s: repairJSON = %q, %t, want %q, %t", c.name, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestInsertSystemMessage(t *testing.T) {
+	messages := []model.Message{
+		{Role: "system", Content: model.MessageContent{Content: lo.ToPtr("a")}},
+		{Role: "developer", Content: model.MessageContent{Content: lo.ToPtr("b")}},
+		{Role: "user", Content: model.MessageContent{Content: lo.ToPtr("c")}},
+	}
+	got := insertSystemMessage(messages, "schema")
+	roles := lo.Map(got, func(m model.Message, _ int) string { return m.Role + ":" + messageText(m.Content) })
+	if want := []string{"system:a", "developer:b", "system:schema", "user:c"}; strings.Join(roles, ",") != strings.Join(want, ",") {
+		t.Fatalf("messages = %v, want %v", roles, want)
+	}
+	if len(messages) != 3 {
+		t.Fatal("original messages modified")
+	}
+}
+
+func newTestStructuredOutput(t *testing.T, mode dbmodel.StructuredOutputMode, schema string, stream bool) *structuredOutput {
+	t.Helper()
+	req := &model.InternalLLMRequest{
+		Model:          "m",
+		Messages:       []model.Message{{Role: "user", Content: model.MessageContent{Content: lo.ToPtr("hi")}}},
+		Stream:         lo.ToPtr(stream),
+		ResponseFormat: &model.ResponseFormat{Type: "json_schema", JSONSchema: json.RawMessage(`{"name":"answer","schema":` + schema + `}`)},
+	}
+	so := newStructuredOutput(dbmodel.Group{StructuredOutput: mode}, req, NewRelayMetrics("m"))
+	if so == nil {
+		t.Fatal("structured output not enabled")
+	}
+	return so
+}
+
+// 根节点不是对象时，工具参数包装为 {"value": ...}，响应中再解包
+func TestStructuredToolModeWrapsNonObject(t *testing.T) {
+	so := newTestStructuredOutput(t, dbmodel.StructuredOutputTool, `{"type":"array","items":{"type":"integer"}}`, false)
+	if so.mode != dbmodel.StructuredOutputTool || !so.wrapped || so.toolName != "answer" {
+		t.Fatalf("mode %s, wrapped %t, tool %s", so.mode, so.wrapped, so.toolName)
+	}
+
+	req := so.prepareRequest(&model.InternalLLMRequest{Model: "m", ResponseFormat: &model.ResponseFormat{Type: "json_schema"}})
+	if req.ResponseFormat != nil || len(req.Tools) != 1 || req.ToolChoice == nil || req.ToolChoice.NamedToolChoice.Function.Name != "answer" {
+		t.Fatalf("unexpected tool request: %+v", req)
+	}
+	var parameters map[string]any
+	json.Unmarshal(req.Tools[0].Function.Parameters, &parameters)
+	value, _ := parameters["properties"].(map[string]any)["value"].(map[string]any)
+	if parameters["type"] != "object" || value["type"] != "array" {
+		t.Fatalf("parameters = %s", req.Tools[0].Function.Parameters)
+	}
+
+	resp := &model.InternalLLMResponse{Choices: []model.Choice{{
+		Message: &model.Message{Role: "assistant", ToolCalls: []model.ToolCall{{
+			Function: model.FunctionCall{Name: "answer", Arguments: `{"value":[1,2]}`},
+		}}},
+		FinishReason: lo.ToPtr("tool_calls"),
+	}}}
+	so.unwrapToolCall(resp)
+	choice := resp.Choices[0]
+	if got := messageText(choice.Message.Content); got != "[1,2]" || len(choice.Message.ToolCalls) != 0 || *choice.FinishReason != "stop" {
+		t.Fatalf("unwrapped = %q, tool calls %d, finish %s", got, len(choice.Message.ToolCalls), *choice.FinishReason)
+	}
+	if _, err := so.check(resp); err != nil {
+		t.Fatalf("unwrapped value invalid: %v", err)
+	}
+}
+
+// 流式响应无法解包 value，非对象 schema 退回提示词模拟；请求自带工具时同样退回
+func TestStructuredToolModeFallsBackToPrompt(t *testing.T) {
+	if so := newTestStructuredOutput(t, dbmodel.StructuredOutputTool, `{"type":"string"}`, true); so.mode != dbmodel.StructuredOutputPrompt {
+		t.Fatalf("stream wrapped mode = %s, want prompt", so.mode)
+	}
+	if so := newTestStructuredOutput(t, dbmodel.StructuredOutputTool, `{"type":"object"}`, true); so.mode != dbmodel.StructuredOutputTool {
+		t.Fatalf("stream object mode = %s, want tool", so.mode)
+	}
+
+	so := newTestStructuredOutput(t, dbmodel.StructuredOutputPrompt, `{"type":"object"}`, false)
+	req := so.prepareRequest(&model.InternalLLMRequest{Messages: []model.Message{{Role: "user", Content: model.MessageContent{Content: lo.ToPtr("hi")}}}})
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || !strings.Contains(messageText(req.Messages[0].Content), `{"type":"object"}`) {
+		t.Fatalf("prompt messages = %+v", req.Messages)
+	}
+}
+
+func TestUnwrapToolCallDelta(t *testing.T) {
+	so := newTestStructuredOutput(t, dbmodel.StructuredOutputTool, `{"type":"object"}`, true)
+	chunk := &model.InternalLLMResponse{Choices: []model.Choice{{
+		Delta: &model.Message{ToolCalls: []model.ToolCall{{Function: model.FunctionCall{Arguments: `{"a":`}}}},
+	}}}
+	so.unwrapToolCallDelta(chunk)
+	if got := messageText(chunk.Choices[0].Delta.Content); got != `{"a":` || len(chunk.Choices[0].Delta.ToolCalls) != 0 {
+		t.Fatalf("delta content = %q", got)
+	}
+	end := &model.InternalLLMResponse{Choices: []model.Choice{{Delta: &model.Message{}, FinishReason: lo.ToPtr("tool_calls")}}}
+	so.unwrapToolCallDelta(end)
+	if *end.Choices[0].FinishReason != "stop" {
+		t.Fatalf("finish reason = %s, want stop", *end.Choices[0].FinishReason)
+	}
+}
+
+// newStructuredGroup 在上游渠道上创建启用结构化输出的分组
+func newStructuredGroup(t *testing.T, upstream *testUpstream, mode dbmodel.StructuredOutputMode, retries int) string {
+	t.Helper()
+	group := &dbmodel.Group{
+		Name:                    upstream.model + "-structured",
+		Mode:                    dbmodel.GroupModeRoundRobin,
+		StructuredOutput:        mode,
+		StructuredOutputRetries: retries,
+		Items:                   []dbmodel.GroupItem{{ChannelID: upstream.channelID, ModelName: upstream.model, Priority: 1, Weight: 1}},
+	}
+	if err := op.GroupCreate(group, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return group.Name
+}
+
+func structuredBody(model string) string {
+	return fmt.Sprintf(`{"model":%q,"messages":[{"role":"user","content":"hi"}],`+
+		`"response_format":{"type":"json_schema","json_schema":{"name":"answer","schema":{"type":"object","required":["n"],"properties":{"n":{"type":"integer"}}}}}}`, model)
+}
+
+// completionWithContent 返回固定用量 (10 输入、5 输出) 的 chat completion
+func completionWithContent(w http.ResponseWriter, model, content string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": 1,
+		"model":   model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": content},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]any{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15, "prompt_tokens_details": map[string]any{"cached_tokens": 4}},
+	})
+}
+
+// 校验失败后带上错误信息重试，重试的用量与首次请求累加
+func TestStructuredOutputRetry(t *testing.T) {
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if upstream.hits.Load() == 1 {
+			completionWithContent(w, upstream.model, `{"n":"one"}`)
+			return
+		}
+		var req model.InternalLLMRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if last := req.Messages[len(req.Messages)-1]; !strings.Contains(messageText(last.Content), "does not match the required JSON schema") {
+			http.Error(w, "retry without validation error", http.StatusBadRequest)
+			return
+		}
+		completionWithContent(w, upstream.model, "```json\n{\"n\":1}\n```")
+	})
+	group := newStructuredGroup(t, upstream, dbmodel.StructuredOutputPrompt, 1)
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	logs := subscribeRelayLogs(t)
+
+	status, body := postChat(t, server, structuredBody(group))
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage model.Usage `json:"usage"`
+	}
+	json.Unmarshal([]byte(body), &resp)
+	if resp.Choices[0].Message.Content != `{"n":1}` {
+		t.Fatalf("content = %q, want the repaired JSON", resp.Choices[0].Message.Content)
+	}
+	if resp.Usage.PromptTokens != 20 || resp.Usage.CompletionTokens != 10 || resp.Usage.PromptTokensDetails == nil || resp.Usage.PromptTokensDetails.CachedTokens != 8 {
+		t.Fatalf("usage = %+v, want both requests summed", resp.Usage)
+	}
+	relayLog := nextRelayLog(t, logs, key.ID)
+	if relayLog.StructuredOutput != "prompt: repaired (retries: 1)" || relayLog.CacheReadTokens != 8 {
+		t.Fatalf("structured output %q, cache read %d", relayLog.StructuredOutput, relayLog.CacheReadTokens)
+	}
+}
+
+// 重试次数用完仍未通过校验时返回 invalid_structured_output，不切换渠道
+func TestStructuredOutputInvalidAfterRetries(t *testing.T) {
+	var upstream *testUpstream
+	upstream = newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		completionWithContent(w, upstream.model, "sorry, no JSON here")
+	})
+	group := newStructuredGroup(t, upstream, dbmodel.StructuredOutputPrompt, 2)
+	key := newTestAPIKey(t)
+	server := newIdempotencyServer(t, key)
+	logs := subscribeRelayLogs(t)
+
+	status, body := postChat(t, server, structuredBody(group))
+	if status != http.StatusBadGateway || !strings.Contains(body, "invalid_structured_output") {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	if hits := upstream.hits.Load(); hits != 3 {
+		t.Fatalf("upstream hits = %d, want 3", hits)
+	}
+	if relayLog := nextRelayLog(t, logs, key.ID); !strings.HasPrefix(relayLog.StructuredOutput, "prompt: invalid: invalid JSON") {
+		t.Fatalf("structured output = %q", relayLog.StructuredOutput)
+	}
+}
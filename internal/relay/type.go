@@ -1,12 +1,14 @@
 package relay
 
 import (
+	"context"
 	"os"
 	"strconv"
 	"strings"
 
 	"github.com/bestruirui/octopus/internal/conf"
 	dbmodel "github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/transformer/inbound"
 	"github.com/bestruirui/octopus/internal/transformer/model"
 	"github.com/gin-gonic/gin"
 )
@@ -29,6 +31,8 @@ func init() {
 var hopByHopHeaders = map[string]bool{
 	"authorization":       true,
 	"x-api-key":           true,
+	"api-key":             true, // Azure OpenAI 的认证头
+	"x-goog-api-key":      true,
 	"connection":          true,
 	"keep-alive":          true,
 	"proxy-authenticate":  true,
@@ -40,20 +44,42 @@ var hopByHopHeaders = map[string]bool{
 	"content-length":      true,
 	"host":                true,
 	"accept-encoding":     true,
+	"idempotency-key":     true, // 由网关处理，不同 API Key 可能使用相同的值
 }
 
 // relayContext 保存请求转发过程中的上下文信息
 type relayContext struct {
-	c               *gin.Context
-	inAdapter       model.Inbound
-	outAdapter      model.Outbound
+	c           *gin.Context
+	inboundType inbound.InboundType
+	inAdapter   model.Inbound
+	outAdapter  model.Outbound
+	// newOutbound 创建独立的出站适配器实例，用于拆分 n 的并发请求
+	newOutbound     func() model.Outbound
 	internalRequest *model.InternalLLMRequest
 	channel         *dbmodel.Channel
 	metrics         *RelayMetrics
 
 	usedKey dbmodel.ChannelKey
 
-	// firstTokenTimeOutSec: streaming-only "time to first token" timeout for the selected group/channel.
+	// firstTokenTimeOutSec: "time to first token" timeout for the selected group/channel, applies whenever the upstream streams.
 	// When >0 and stream doesn't produce any transformed output within this duration, we abort and retry next channel.
 	firstTokenTimeOutSec int
+
+	// structured 分组启用结构化输出处理时非 nil
+	structured *structuredOutput
+	// fanOut 非流式拆分 n 时跨渠道保留的结果，其他请求为 nil
+	fanOut *fanOutResults
+}
+
+// wrappedOutbound 为包装其他出站适配器的基础类型，未覆盖的方法转发给被包装的适配器
+type wrappedOutbound struct {
+	model.Outbound
+}
+
+// TransformError 保留被包装适配器的错误处理
+func (o wrappedOutbound) TransformError(ctx context.Context, statusCode int, body []byte) error {
+	if handler, ok := o.Outbound.(model.OutboundErrorHandler); ok {
+		return handler.TransformError(ctx, statusCode, body)
+	}
+	return nil
 }
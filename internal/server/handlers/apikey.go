@@ -34,6 +34,14 @@ func init() {
 		AddRoute(
 			router.NewRoute("/delete/:id", http.MethodDelete).
 				Handle(deleteAPIKey),
+		).
+		AddRoute(
+			router.NewRoute("/transaction/create", http.MethodPost).
+				Handle(createAPIKeyTransaction),
+		).
+		AddRoute(
+			router.NewRoute("/transaction/list", http.MethodGet).
+				Handle(listAPIKeyTransaction),
 		)
 	router.NewGroupRouter("/api/v1/apikey").
 		Use(middleware.APIKeyAuth()).
@@ -53,6 +61,14 @@ func createAPIKey(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if !req.ReasoningPolicy.IsValid() {
+		resp.Error(c, http.StatusBadRequest, "invalid reasoning_policy: "+string(req.ReasoningPolicy))
+		return
+	}
+	if err := model.ValidateAPIKeyLimits(req.Limits); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	req.APIKey = auth.GenerateAPIKey()
 	if err := op.APIKeyCreate(&req, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
@@ -76,6 +92,14 @@ func updateAPIKey(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if !req.ReasoningPolicy.IsValid() {
+		resp.Error(c, http.StatusBadRequest, "invalid reasoning_policy: "+string(req.ReasoningPolicy))
+		return
+	}
+	if err := model.ValidateAPIKeyLimits(req.Limits); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err := op.APIKeyUpdate(&req, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -97,9 +121,84 @@ func deleteAPIKey(c *gin.Context) {
 	resp.Success(c, nil)
 }
 
+// createAPIKeyTransaction 充值、退款或人工调整余额，type 为空时视为充值
+func createAPIKeyTransaction(c *gin.Context) {
+	var req struct {
+		APIKeyID int                   `json:"api_key_id" binding:"required"`
+		Type     model.TransactionType `json:"type"`
+		Amount   float64               `json:"amount"`
+		Note     string                `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if req.Type == "" {
+		req.Type = model.TransactionTypeTopUp
+	}
+	switch req.Type {
+	case model.TransactionTypeTopUp, model.TransactionTypeRefund:
+		if req.Amount <= 0 {
+			resp.Error(c, http.StatusBadRequest, "amount must be positive")
+			return
+		}
+	case model.TransactionTypeAdjustment:
+		if req.Amount == 0 {
+			resp.Error(c, http.StatusBadRequest, "amount must not be zero")
+			return
+		}
+	default:
+		resp.Error(c, http.StatusBadRequest, "invalid transaction type: "+string(req.Type))
+		return
+	}
+	if _, err := op.APIKeyGet(req.APIKeyID, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	tx := model.APIKeyTransaction{
+		APIKeyID: req.APIKeyID,
+		Type:     req.Type,
+		Amount:   req.Amount,
+		Note:     req.Note,
+	}
+	if err := op.APIKeyTransactionCreate(c.Request.Context(), &tx); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, tx)
+}
+
+func listAPIKeyTransaction(c *gin.Context) {
+	apiKeyID, err := strconv.Atoi(c.Query("api_key_id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	transactions, total, err := op.APIKeyTransactionList(c.Request.Context(), apiKeyID, page, pageSize)
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, map[string]any{
+		"balance":      op.APIKeyBalance(apiKeyID),
+		"total":        total,
+		"transactions": transactions,
+	})
+}
+
 func getStatsAPIKeyById(c *gin.Context) {
 	id := c.GetInt("api_key_id")
 	stats := op.StatsAPIKeyGet(id)
+	// 上游实际费用只对管理员可见
+	stats.UpstreamCost = 0
 	info, err := op.APIKeyGet(id, c.Request.Context())
 	if err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
@@ -123,10 +222,16 @@ func getStatsAPIKeyById(c *gin.Context) {
 		modelsString = strings.Join(models, ", ")
 	}
 	info.SupportedModels = modelsString
-	resp.Success(c, map[string]any{
-		"stats": stats,
-		"info":  info,
-	})
+	limits, _ := op.APIKeyLimitCheck(info)
+	data := map[string]any{
+		"stats":  stats,
+		"info":   info,
+		"limits": limits,
+	}
+	if info.Prepaid {
+		data["balance"] = op.APIKeyBalance(id)
+	}
+	resp.Success(c, data)
 }
 
 func loginAPIKey(c *gin.Context) {
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/relay"
+	"github.com/bestruirui/octopus/internal/server/middleware"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/server/router"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+// maxFileSize 上传文件的最大大小，文件内容存储在数据库中
+const maxFileSize = 100 << 20
+
+// batchCompletionWindow 目前仅支持 24h
+const batchCompletionWindow = "24h"
+
+func init() {
+	// 文件上传使用 multipart/form-data，不经过 RequireJSON
+	router.NewGroupRouter("/v1/files").
+		Use(middleware.APIKeyAuth()).
+		AddRoute(
+			router.NewRoute("", http.MethodPost).
+				Handle(fileUpload),
+		).
+		AddRoute(
+			router.NewRoute("", http.MethodGet).
+				Handle(fileList),
+		).
+		AddRoute(
+			router.NewRoute("/:id", http.MethodGet).
+				Handle(fileRetrieve),
+		).
+		AddRoute(
+			router.NewRoute("/:id", http.MethodDelete).
+				Handle(fileDelete),
+		).
+		AddRoute(
+			router.NewRoute("/:id/content", http.MethodGet).
+				Handle(fileContent),
+		)
+
+	router.NewGroupRouter("/v1/batches").
+		Use(middleware.APIKeyAuth()).
+		Use(middleware.RequireJSON()).
+		AddRoute(
+			router.NewRoute("", http.MethodPost).
+				Handle(batchCreate),
+		).
+		AddRoute(
+			router.NewRoute("", http.MethodGet).
+				Handle(batchList),
+		).
+		AddRoute(
+			router.NewRoute("/:id", http.MethodGet).
+				Handle(batchRetrieve),
+		).
+		AddRoute(
+			router.NewRoute("/:id/cancel", http.MethodPost).
+				Handle(batchCancel),
+		)
+}
+
+func fileUpload(c *gin.Context) {
+	purpose := c.PostForm("purpose")
+	if purpose != model.FilePurposeBatch {
+		resp.Error(c, http.StatusBadRequest, "purpose must be 'batch'")
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	if header.Size > maxFileSize {
+		resp.Error(c, http.StatusRequestEntityTooLarge, "file exceeds the maximum size of 100MB")
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, maxFileSize+1))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(content) > maxFileSize {
+		resp.Error(c, http.StatusRequestEntityTooLarge, "file exceeds the maximum size of 100MB")
+		return
+	}
+
+	file := &model.File{
+		ID:        relay.NewFileID(),
+		APIKeyID:  c.GetInt("api_key_id"),
+		Filename:  header.Filename,
+		Purpose:   purpose,
+		Bytes:     int64(len(content)),
+		Content:   content,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := op.FileCreate(file, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+func fileList(c *gin.Context) {
+	limit, ok := parseListLimit(c, 10000, 10000)
+	if !ok {
+		return
+	}
+	files, err := op.FileList(c.GetInt("api_key_id"), c.Query("purpose"), limit, c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     files,
+		"has_more": false,
+	})
+}
+
+func fileRetrieve(c *gin.Context) {
+	file, ok := getFile(c, false)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+func fileContent(c *gin.Context) {
+	file, ok := getFile(c, true)
+	if !ok {
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=\""+file.Filename+"\"")
+	c.Data(http.StatusOK, "application/jsonl", file.Content)
+}
+
+func fileDelete(c *gin.Context) {
+	id := c.Param("id")
+	if err := op.FileDelete(id, c.GetInt("api_key_id"), c.Request.Context()); err != nil {
+		if errors.Is(err, op.ErrFileNotFound) {
+			resp.Error(c, http.StatusNotFound, "file with id '"+id+"' not found")
+			return
+		}
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      id,
+		"object":  "file",
+		"deleted": true,
+	})
+}
+
+func batchCreate(c *gin.Context) {
+	var req struct {
+		InputFileID      string            `json:"input_file_id"`
+		Endpoint         string            `json:"endpoint"`
+		CompletionWindow string            `json:"completion_window"`
+		Metadata         map[string]string `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if _, ok := relay.BatchEndpoints[req.Endpoint]; !ok {
+		resp.Error(c, http.StatusBadRequest, "unsupported endpoint: "+req.Endpoint)
+		return
+	}
+	if req.CompletionWindow != batchCompletionWindow {
+		resp.Error(c, http.StatusBadRequest, "completion_window must be '24h'")
+		return
+	}
+
+	apiKeyID := c.GetInt("api_key_id")
+	input, err := op.FileGet(req.InputFileID, apiKeyID, false, c.Request.Context())
+	if err != nil {
+		if errors.Is(err, op.ErrFileNotFound) {
+			resp.Error(c, http.StatusNotFound, "file with id '"+req.InputFileID+"' not found")
+			return
+		}
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if input.Purpose != model.FilePurposeBatch {
+		resp.Error(c, http.StatusBadRequest, "input file must have purpose 'batch'")
+		return
+	}
+
+	now := time.Now()
+	batch := &model.Batch{
+		ID:               relay.NewBatchID(),
+		APIKeyID:         apiKeyID,
+		Endpoint:         req.Endpoint,
+		InputFileID:      req.InputFileID,
+		CompletionWindow: req.CompletionWindow,
+		Status:           model.BatchStatusValidating,
+		Metadata:         req.Metadata,
+		CreatedAt:        now.Unix(),
+		ExpiresAt:        lo.ToPtr(now.Add(24 * time.Hour).Unix()),
+	}
+	if err := op.BatchCreate(batch, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	relay.BatchStart(batch.ID)
+	c.JSON(http.StatusOK, batch)
+}
+
+func batchList(c *gin.Context) {
+	limit, ok := parseListLimit(c, 20, 100)
+	if !ok {
+		return
+	}
+	// 多取一条用于判断 has_more
+	batches, err := op.BatchList(c.GetInt("api_key_id"), c.Query("after"), limit+1, c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	hasMore := len(batches) > limit
+	if hasMore {
+		batches = batches[:limit]
+	}
+	var firstID, lastID *string
+	if len(batches) > 0 {
+		firstID = &batches[0].ID
+		lastID = &batches[len(batches)-1].ID
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     batches,
+		"first_id": firstID,
+		"last_id":  lastID,
+		"has_more": hasMore,
+	})
+}
+
+func batchRetrieve(c *gin.Context) {
+	id := c.Param("id")
+	batch, err := op.BatchGet(id, c.GetInt("api_key_id"), c.Request.Context())
+	if err != nil {
+		if errors.Is(err, op.ErrBatchNotFound) {
+			resp.Error(c, http.StatusNotFound, "batch with id '"+id+"' not found")
+			return
+		}
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+func batchCancel(c *gin.Context) {
+	id := c.Param("id")
+	batch, err := op.BatchCancel(id, c.GetInt("api_key_id"), time.Now().Unix(), c.Request.Context())
+	if err != nil {
+		if errors.Is(err, op.ErrBatchNotFound) {
+			resp.Error(c, http.StatusNotFound, "batch with id '"+id+"' not found")
+			return
+		}
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if batch.Status != model.BatchStatusCancelling {
+		resp.Error(c, http.StatusConflict, "batch with status '"+string(batch.Status)+"' cannot be cancelled")
+		return
+	}
+	// 停止执行后由调度生成已完成部分的结果文件
+	relay.BatchStop(id)
+	relay.BatchStart(id)
+	c.JSON(http.StatusOK, batch)
+}
+
+func getFile(c *gin.Context, withContent bool) (*model.File, bool) {
+	id := c.Param("id")
+	file, err := op.FileGet(id, c.GetInt("api_key_id"), withContent, c.Request.Context())
+	if err != nil {
+		if errors.Is(err, op.ErrFileNotFound) {
+			resp.Error(c, http.StatusNotFound, "file with id '"+id+"' not found")
+		} else {
+			resp.Error(c, http.StatusInternalServerError, err.Error())
+		}
+		return nil, false
+	}
+	return file, true
+}
+
+func parseListLimit(c *gin.Context, defaultLimit, maxLimit int) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return defaultLimit, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxLimit {
+		resp.Error(c, http.StatusBadRequest, "limit must be an integer between 1 and "+strconv.Itoa(maxLimit))
+		return 0, false
+	}
+	return limit, true
+}
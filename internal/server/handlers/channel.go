@@ -15,6 +15,7 @@ import (
 	"github.com/bestruirui/octopus/internal/server/router"
 	"github.com/bestruirui/octopus/internal/task"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 func init() {
@@ -76,6 +77,30 @@ func createChannel(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if _, err := model.ParseModelMapping(channel.ModelMapping); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !channel.MediaMode.IsValid() {
+		resp.Error(c, http.StatusBadRequest, "invalid media mode")
+		return
+	}
+	if !channel.StreamMode.IsValid() {
+		resp.Error(c, http.StatusBadRequest, "invalid stream mode")
+		return
+	}
+	if !channel.PromptCache.IsValid() {
+		resp.Error(c, http.StatusBadRequest, "invalid prompt cache mode")
+		return
+	}
+	if channel.PromptCacheMinTokens < 0 {
+		resp.Error(c, http.StatusBadRequest, "prompt cache min tokens must not be negative")
+		return
+	}
+	if err := model.ValidateChannelPricing(channel.PriceMultiplier, channel.PriceOverrides); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err := op.ChannelCreate(&channel, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -100,6 +125,30 @@ func updateChannel(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if _, err := model.ParseModelMapping(req.ModelMapping); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.MediaMode != nil && !req.MediaMode.IsValid() {
+		resp.Error(c, http.StatusBadRequest, "invalid media mode")
+		return
+	}
+	if req.StreamMode != nil && !req.StreamMode.IsValid() {
+		resp.Error(c, http.StatusBadRequest, "invalid stream mode")
+		return
+	}
+	if req.PromptCache != nil && !req.PromptCache.IsValid() {
+		resp.Error(c, http.StatusBadRequest, "invalid prompt cache mode")
+		return
+	}
+	if req.PromptCacheMinTokens != nil && *req.PromptCacheMinTokens < 0 {
+		resp.Error(c, http.StatusBadRequest, "prompt cache min tokens must not be negative")
+		return
+	}
+	if err := model.ValidateChannelPricing(req.PriceMultiplier, lo.FromPtr(req.PriceOverrides)); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	channel, err := op.ChannelUpdate(&req, c.Request.Context())
 	if err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/bestruirui/octopus/internal/server/router"
 	"github.com/dlclark/regexp2"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 func init() {
@@ -61,6 +63,14 @@ func createGroup(c *gin.Context) {
 			return
 		}
 	}
+	if err := validateStructuredOutput(group.StructuredOutput, group.StructuredOutputRetries); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !group.ReasoningPolicy.IsValid() {
+		resp.Error(c, http.StatusBadRequest, fmt.Sprintf("invalid reasoning_policy: %s", group.ReasoningPolicy))
+		return
+	}
 	if err := op.GroupCreate(&group, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -81,6 +91,14 @@ func updateGroup(c *gin.Context) {
 			return
 		}
 	}
+	if err := validateStructuredOutput(lo.FromPtr(req.StructuredOutput), lo.FromPtr(req.StructuredOutputRetries)); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if policy := lo.FromPtr(req.ReasoningPolicy); !policy.IsValid() {
+		resp.Error(c, http.StatusBadRequest, fmt.Sprintf("invalid reasoning_policy: %s", policy))
+		return
+	}
 	group, err := op.GroupUpdate(&req, c.Request.Context())
 	if err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
@@ -89,6 +107,16 @@ func updateGroup(c *gin.Context) {
 	resp.Success(c, group)
 }
 
+func validateStructuredOutput(mode model.StructuredOutputMode, retries int) error {
+	if !mode.IsValid() {
+		return fmt.Errorf("invalid structured_output: %s", mode)
+	}
+	if retries < 0 || retries > model.MaxStructuredOutputRetries {
+		return fmt.Errorf("structured_output_retries must be between 0 and %d", model.MaxStructuredOutputRetries)
+	}
+	return nil
+}
+
 func deleteGroup(c *gin.Context) {
 	id := c.Param("id")
 	idNum, err := strconv.Atoi(id)
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/bestruirui/octopus/internal/model"
@@ -45,6 +46,18 @@ func init() {
 		AddRoute(
 			router.NewRoute("/last-update-time", http.MethodGet).
 				Handle(getLastUpdateTime),
+		).
+		AddRoute(
+			router.NewRoute("/price-source", http.MethodGet).
+				Handle(getPriceSource),
+		).
+		AddRoute(
+			router.NewRoute("/price-source/upload", http.MethodPost).
+				Handle(uploadPriceSource),
+		).
+		AddRoute(
+			router.NewRoute("/price-source/delete", http.MethodPost).
+				Handle(deletePriceSource),
 		)
 	router.NewGroupRouter("/v1").
 		Use(middleware.APIKeyAuth()).
@@ -136,6 +149,10 @@ func createLLM(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := model.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err := op.LLMCreate(model, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -149,6 +166,10 @@ func updateLLM(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := model.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err := op.LLMUpdate(model, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -184,3 +205,43 @@ func getLastUpdateTime(c *gin.Context) {
 	time := price.GetLastUpdateTime()
 	resp.Success(c, time)
 }
+
+func getPriceSource(c *gin.Context) {
+	info, err := os.Stat(price.UploadedSourcePath)
+	if err != nil {
+		resp.Success(c, map[string]any{"uploaded": false})
+		return
+	}
+	resp.Success(c, map[string]any{
+		"uploaded":    true,
+		"uploaded_at": info.ModTime(),
+	})
+}
+
+// uploadPriceSource 保存请求体中 models.dev 或 LiteLLM 格式的价格文件并立即更新价格
+func uploadPriceSource(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	count, err := price.SaveUploadedSource(data)
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	result := map[string]any{"models": count}
+	// 其他来源不可用时上传的价格仍会生效，失败原因一并返回
+	if err := price.UpdateLLMPrice(c.Request.Context()); err != nil {
+		result["warning"] = err.Error()
+	}
+	resp.Success(c, result)
+}
+
+func deletePriceSource(c *gin.Context) {
+	if err := price.DeleteUploadedSource(); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
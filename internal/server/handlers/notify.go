@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/notify"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/bestruirui/octopus/internal/server/middleware"
+	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/server/router"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/notify").
+		Use(middleware.Auth()).
+		Use(middleware.RequireJSON()).
+		AddRoute(
+			router.NewRoute("/target/list", http.MethodGet).
+				Handle(listNotifyTarget),
+		).
+		AddRoute(
+			router.NewRoute("/target/create", http.MethodPost).
+				Handle(createNotifyTarget),
+		).
+		AddRoute(
+			router.NewRoute("/target/update", http.MethodPost).
+				Handle(updateNotifyTarget),
+		).
+		AddRoute(
+			router.NewRoute("/target/delete/:id", http.MethodDelete).
+				Handle(deleteNotifyTarget),
+		).
+		AddRoute(
+			router.NewRoute("/target/test", http.MethodPost).
+				Handle(testNotifyTarget),
+		).
+		AddRoute(
+			router.NewRoute("/rule/list", http.MethodGet).
+				Handle(listNotifyRule),
+		).
+		AddRoute(
+			router.NewRoute("/rule/create", http.MethodPost).
+				Handle(createNotifyRule),
+		).
+		AddRoute(
+			router.NewRoute("/rule/update", http.MethodPost).
+				Handle(updateNotifyRule),
+		).
+		AddRoute(
+			router.NewRoute("/rule/delete/:id", http.MethodDelete).
+				Handle(deleteNotifyRule),
+		)
+}
+
+func listNotifyTarget(c *gin.Context) {
+	targets, err := op.NotifyTargetList(c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, targets)
+}
+
+func createNotifyTarget(c *gin.Context) {
+	var req model.NotifyTarget
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.ID = 0
+	if err := op.NotifyTargetCreate(&req, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, req)
+}
+
+func updateNotifyTarget(c *gin.Context) {
+	var req model.NotifyTarget
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := op.NotifyTargetUpdate(&req, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, req)
+}
+
+func deleteNotifyTarget(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	if err := op.NotifyTargetDelete(id, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
+
+// testNotifyTarget 按请求中的配置发送测试通知，未保存的配置也可以测试
+func testNotifyTarget(c *gin.Context) {
+	var req model.NotifyTarget
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := notify.Test(c.Request.Context(), req); err != nil {
+		resp.Error(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
+
+func listNotifyRule(c *gin.Context) {
+	rules, err := op.NotifyRuleList(c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, rules)
+}
+
+func createNotifyRule(c *gin.Context) {
+	var req model.NotifyRule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if !validateNotifyRule(c, &req) {
+		return
+	}
+	req.ID = 0
+	if err := op.NotifyRuleCreate(&req, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, req)
+}
+
+func updateNotifyRule(c *gin.Context) {
+	var req model.NotifyRule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if !validateNotifyRule(c, &req) {
+		return
+	}
+	if err := op.NotifyRuleUpdate(&req, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, req)
+}
+
+func deleteNotifyRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	if err := op.NotifyRuleDelete(id, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
+
+// validateNotifyRule 校验规则配置及其引用的通知渠道，失败时写入 400 响应
+func validateNotifyRule(c *gin.Context, rule *model.NotifyRule) bool {
+	if err := rule.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return false
+	}
+	for _, targetID := range rule.TargetIDs {
+		if _, err := op.NotifyTargetGet(targetID, c.Request.Context()); err != nil {
+			resp.Error(c, http.StatusBadRequest, "notify target not found: "+strconv.Itoa(targetID))
+			return false
+		}
+	}
+	return true
+}
@@ -1,12 +1,20 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
 
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/relay"
 	"github.com/bestruirui/octopus/internal/server/middleware"
+	"github.com/bestruirui/octopus/internal/server/resp"
 	"github.com/bestruirui/octopus/internal/server/router"
 	"github.com/bestruirui/octopus/internal/transformer/inbound"
+	"github.com/bestruirui/octopus/internal/transformer/inbound/openai"
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,6 +30,18 @@ func init() {
 			router.NewRoute("/responses", http.MethodPost).
 				Handle(response),
 		).
+		AddRoute(
+			router.NewRoute("/responses/:id", http.MethodGet).
+				Handle(responseRetrieve),
+		).
+		AddRoute(
+			router.NewRoute("/responses/:id", http.MethodDelete).
+				Handle(responseDelete),
+		).
+		AddRoute(
+			router.NewRoute("/responses/:id/input_items", http.MethodGet).
+				Handle(responseInputItems),
+		).
 		AddRoute(
 			router.NewRoute("/messages", http.MethodPost).
 				Handle(message),
@@ -29,6 +49,17 @@ func init() {
 		AddRoute(
 			router.NewRoute("/embeddings", http.MethodPost).
 				Handle(embedding),
+		).
+		AddRoute(
+			router.NewRoute("/aisdk/chat", http.MethodPost).
+				Handle(aisdkChat),
+		)
+	// Realtime 为 WebSocket 升级请求，不经过 RequireJSON
+	router.NewGroupRouter("/v1").
+		Use(middleware.APIKeyAuth()).
+		AddRoute(
+			router.NewRoute("/realtime", http.MethodGet).
+				Handle(relay.RealtimeHandler),
 		)
 }
 
@@ -44,3 +75,102 @@ func message(c *gin.Context) {
 func embedding(c *gin.Context) {
 	relay.Handler(inbound.InboundTypeOpenAIEmbedding, c)
 }
+
+// aisdkChat 对应 useChat 的 streamProtocol，默认 data，?protocol=text 时仅输出文本
+func aisdkChat(c *gin.Context) {
+	if c.Query("protocol") == "text" {
+		relay.Handler(inbound.InboundTypeAiSDKText, c)
+		return
+	}
+	relay.Handler(inbound.InboundTypeAiSDKDataStream, c)
+}
+
+func responseRetrieve(c *gin.Context) {
+	obj, ok := getStoredResponse(c)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", []byte(obj.Response))
+}
+
+func responseDelete(c *gin.Context) {
+	id := c.Param("id")
+	if err := op.ResponseDelete(id, c.GetInt("api_key_id"), c.Request.Context()); err != nil {
+		if errors.Is(err, op.ErrResponseNotFound) {
+			resp.Error(c, http.StatusNotFound, "response with id '"+id+"' not found")
+			return
+		}
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      id,
+		"object":  "response.deleted",
+		"deleted": true,
+	})
+}
+
+// responseInputItems 支持 limit (1-100, 默认 20)、order (asc/desc, 默认 desc)、after 分页参数
+func responseInputItems(c *gin.Context) {
+	obj, ok := getStoredResponse(c)
+	if !ok {
+		return
+	}
+
+	var items []openai.ResponsesItem
+	if err := json.Unmarshal([]byte(obj.InputItems), &items); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > 100 {
+			resp.Error(c, http.StatusBadRequest, "limit must be an integer between 1 and 100")
+			return
+		}
+		limit = v
+	}
+	if c.DefaultQuery("order", "desc") == "desc" {
+		slices.Reverse(items)
+	}
+	if after := c.Query("after"); after != "" {
+		idx := slices.IndexFunc(items, func(item openai.ResponsesItem) bool { return item.ID == after })
+		if idx >= 0 {
+			items = items[idx+1:]
+		}
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	var firstID, lastID *string
+	if len(items) > 0 {
+		firstID = &items[0].ID
+		lastID = &items[len(items)-1].ID
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     items,
+		"first_id": firstID,
+		"last_id":  lastID,
+		"has_more": hasMore,
+	})
+}
+
+// getStoredResponse 获取当前 API Key 下存储的响应，不存在时直接返回 404
+func getStoredResponse(c *gin.Context) (*model.ResponseObject, bool) {
+	id := c.Param("id")
+	obj, err := op.ResponseGet(id, c.GetInt("api_key_id"), c.Request.Context())
+	if err != nil {
+		if errors.Is(err, op.ErrResponseNotFound) {
+			resp.Error(c, http.StatusNotFound, "response with id '"+id+"' not found")
+		} else {
+			resp.Error(c, http.StatusInternalServerError, err.Error())
+		}
+		return nil, false
+	}
+	return obj, true
+}
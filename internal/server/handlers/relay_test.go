@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/gin-gonic/gin"
+)
+
+func TestResponseInputItems(t *testing.T) {
+	items := make([]map[string]string, 5)
+	for i := range items {
+		items[i] = map[string]string{"id": fmt.Sprintf("msg_%d", i+1), "type": "message", "role": "user"}
+	}
+	inputItems, _ := json.Marshal(items)
+	if err := op.ResponseCreate(&model.ResponseObject{ID: "resp_input_items", APIKeyID: 1, InputItems: string(inputItems)}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.GET("/v1/responses/:id/input_items", func(c *gin.Context) {
+		c.Set("api_key_id", 1)
+		responseInputItems(c)
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		ids     string
+		hasMore bool
+	}{
+		{"default order is desc", "resp_input_items/input_items", http.StatusOK, "msg_5,msg_4,msg_3,msg_2,msg_1", false},
+		{"limit", "resp_input_items/input_items?limit=2", http.StatusOK, "msg_5,msg_4", true},
+		{"after in desc order", "resp_input_items/input_items?limit=2&after=msg_4", http.StatusOK, "msg_3,msg_2", true},
+		{"asc", "resp_input_items/input_items?order=asc&after=msg_3", http.StatusOK, "msg_4,msg_5", false},
+		{"last page", "resp_input_items/input_items?order=asc&after=msg_5", http.StatusOK, "", false},
+		{"limit too large", "resp_input_items/input_items?limit=101", http.StatusBadRequest, "", false},
+		{"invalid limit", "resp_input_items/input_items?limit=x", http.StatusBadRequest, "", false},
+		{"unknown response", "resp_missing/input_items", http.StatusNotFound, "", false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/responses/"+tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var page struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+			FirstID *string `json:"first_id"`
+			LastID  *string `json:"last_id"`
+			HasMore bool    `json:"has_more"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		ids := make([]string, len(page.Data))
+		for i, item := range page.Data {
+			ids[i] = item.ID
+		}
+		if strings.Join(ids, ",") != tt.ids || page.HasMore != tt.hasMore {
+			t.Errorf("%s: ids %v, has_more %t, want %s, %t", tt.name, ids, page.HasMore, tt.ids, tt.hasMore)
+		}
+		if len(ids) > 0 && (*page.FirstID != ids[0] || *page.LastID != ids[len(ids)-1]) {
+			t.Errorf("%s: first_id %s, last_id %s", tt.name, *page.FirstID, *page.LastID)
+		}
+		if len(ids) == 0 && (page.FirstID != nil || page.LastID != nil) {
+			t.Errorf("%s: empty page should have null first_id and last_id", tt.name)
+		}
+	}
+
+	// 其他 API Key 无法读取
+	other := gin.New()
+	other.GET("/v1/responses/:id/input_items", func(c *gin.Context) {
+		c.Set("api_key_id", 2)
+		responseInputItems(c)
+	})
+	w := httptest.NewRecorder()
+	other.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/responses/resp_input_items/input_items", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("other API key: status %d, want 404", w.Code)
+	}
+}
@@ -8,12 +8,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bestruirui/octopus/internal/helper"
 	"github.com/bestruirui/octopus/internal/model"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/server/middleware"
 	"github.com/bestruirui/octopus/internal/server/resp"
 	"github.com/bestruirui/octopus/internal/server/router"
 	"github.com/bestruirui/octopus/internal/task"
+	"github.com/bestruirui/octopus/internal/utils/log"
 	"github.com/gin-gonic/gin"
 )
 
@@ -77,6 +79,11 @@ func setSetting(c *gin.Context) {
 			return
 		}
 		task.Update(string(setting.Key), time.Duration(hours)*time.Hour)
+	case model.SettingKeyTokenizerRules:
+		if err := helper.ApplyTokenizerRules(); err != nil {
+			resp.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 	}
 	resp.Success(c, setting)
 }
@@ -140,6 +147,9 @@ func importDB(c *gin.Context) {
 	}
 
 	_ = op.InitCache()
+	if err := helper.ApplyTokenizerRules(); err != nil {
+		log.Warnf("invalid tokenizer rules: %v", err)
+	}
 
 	resp.Success(c, result)
 }
@@ -159,6 +169,7 @@ func decodeDBDump(body []byte, dump *model.DBDump) error {
 		len(dump.GroupItems) == 0 &&
 		len(dump.Settings) == 0 &&
 		len(dump.APIKeys) == 0 &&
+		len(dump.APIKeyTransactions) == 0 &&
 		len(dump.LLMInfos) == 0 &&
 		len(dump.RelayLogs) == 0 &&
 		len(dump.StatsDaily) == 0 &&
@@ -166,6 +177,8 @@ func decodeDBDump(body []byte, dump *model.DBDump) error {
 		len(dump.StatsTotal) == 0 &&
 		len(dump.StatsChannel) == 0 &&
 		len(dump.StatsModel) == 0 &&
+		len(dump.StatsModelHourly) == 0 &&
+		len(dump.StatsAPIKeyModel) == 0 &&
 		len(dump.StatsAPIKey) == 0 {
 		var wrapper struct {
 			Code    int             `json:"code"`
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/bestruirui/octopus/internal/helper"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/server/middleware"
 	"github.com/bestruirui/octopus/internal/server/resp"
@@ -32,6 +37,18 @@ func init() {
 		AddRoute(
 			router.NewRoute("/apikey", http.MethodGet).
 				Handle(getStatsAPIKey),
+		).
+		AddRoute(
+			router.NewRoute("/model", http.MethodGet).
+				Handle(getStatsModel),
+		).
+		AddRoute(
+			router.NewRoute("/model/series", http.MethodGet).
+				Handle(getStatsModelSeries),
+		).
+		AddRoute(
+			router.NewRoute("/usage/export", http.MethodGet).
+				Handle(exportUsage),
 		)
 }
 
@@ -59,3 +76,116 @@ func getStatsTotal(c *gin.Context) {
 func getStatsAPIKey(c *gin.Context) {
 	resp.Success(c, op.StatsAPIKeyList())
 }
+
+// getStatsModel 按模型汇总时间范围内的用量，group_by=channel 时按 模型 × 渠道 拆分
+func getStatsModel(c *gin.Context) {
+	query, err := parseStatsModelQuery(c)
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	groupBy := c.DefaultQuery("group_by", "model")
+	if groupBy != "model" && groupBy != "channel" {
+		resp.Error(c, http.StatusBadRequest, "group_by must be model or channel")
+		return
+	}
+	summary, err := op.StatsModelSummaryList(c.Request.Context(), query, groupBy == "channel")
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, summary)
+}
+
+// getStatsModelSeries 时间范围内按天或按小时的用量序列，可按模型和渠道过滤
+func getStatsModelSeries(c *gin.Context) {
+	query, err := parseStatsModelQuery(c)
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	interval := c.DefaultQuery("interval", "day")
+	if interval != "day" && interval != "hour" {
+		resp.Error(c, http.StatusBadRequest, "interval must be day or hour")
+		return
+	}
+	series, err := op.StatsModelSeries(c.Request.Context(), query, interval == "hour")
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, series)
+}
+
+// exportUsage 按 API Key × 模型 × 天 导出 start 到 end (YYYY-MM-DD，含当天) 的用量，format 为 csv 或 json
+func exportUsage(c *gin.Context) {
+	start, end, err := helper.ParseUsageRange(c.Query("start"), c.Query("end"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	apiKeyID := 0
+	if v := c.Query("api_key_id"); v != "" {
+		if apiKeyID, err = strconv.Atoi(v); err != nil {
+			resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+			return
+		}
+	}
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		resp.Error(c, http.StatusBadRequest, "format must be csv or json")
+		return
+	}
+	rows, err := op.UsageExport(c.Request.Context(), start, end, apiKeyID)
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if format == "json" {
+		resp.Success(c, rows)
+		return
+	}
+	var buf bytes.Buffer
+	if err := helper.WriteUsageCSV(&buf, rows); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	filename := fmt.Sprintf("octopus-usage-%s-%s.csv", start.Format("20060102"), end.AddDate(0, 0, -1).Format("20060102"))
+	c.Header("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// parseStatsModelQuery 解析 start_time、end_time (秒级时间戳，默认最近 7 天)、model 和 channel_id
+func parseStatsModelQuery(c *gin.Context) (op.StatsModelQuery, error) {
+	now := time.Now()
+	query := op.StatsModelQuery{
+		Start: now.AddDate(0, 0, -6),
+		End:   now,
+		Name:  c.Query("model"),
+	}
+	if v := c.Query("start_time"); v != "" {
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid start_time: %w", err)
+		}
+		query.Start = time.Unix(ts, 0)
+	}
+	if v := c.Query("end_time"); v != "" {
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid end_time: %w", err)
+		}
+		query.End = time.Unix(ts, 0)
+	}
+	if query.End.Before(query.Start) {
+		return query, fmt.Errorf("end_time must not be before start_time")
+	}
+	if v := c.Query("channel_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return query, fmt.Errorf("invalid channel_id: %w", err)
+		}
+		query.ChannelID = id
+	}
+	return query, nil
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/op"
+	"github.com/gin-gonic/gin"
+)
+
+func TestStatsModelHandlers(t *testing.T) {
+	name := "handler-" + t.Name()
+	op.StatsModelUpdate(name, 1, model.StatsMetrics{InputCost: 1, RequestSuccess: 1})
+	op.StatsModelUpdate(name, 2, model.StatsMetrics{InputCost: 2, RequestFailed: 1})
+
+	engine := gin.New()
+	engine.GET("/stats/model", getStatsModel)
+	engine.GET("/stats/model/series", getStatsModelSeries)
+
+	now := time.Now().Unix()
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		// 返回的记录数
+		count int
+	}{
+		{"default range", "/stats/model?model=" + name, http.StatusOK, 1},
+		{"group by channel", "/stats/model?group_by=channel&model=" + name, http.StatusOK, 2},
+		{"filter by channel", "/stats/model?group_by=channel&channel_id=2&model=" + name, http.StatusOK, 1},
+		{"range without data", fmt.Sprintf("/stats/model?model=%s&start_time=%d&end_time=%d", name, now-30*86400, now-20*86400), http.StatusOK, 0},
+		{"invalid group_by", "/stats/model?group_by=key", http.StatusBadRequest, 0},
+		{"invalid start_time", "/stats/model?start_time=x", http.StatusBadRequest, 0},
+		{"end before start", fmt.Sprintf("/stats/model?start_time=%d&end_time=%d", now, now-1), http.StatusBadRequest, 0},
+		{"invalid channel_id", "/stats/model?channel_id=x", http.StatusBadRequest, 0},
+		{"daily series", "/stats/model/series?model=" + name, http.StatusOK, 1},
+		{"hourly series", "/stats/model/series?interval=hour&channel_id=1&model=" + name, http.StatusOK, 1},
+		{"invalid interval", "/stats/model/series?interval=week", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var body struct {
+			Data []json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(body.Data) != tt.count {
+			t.Errorf("%s: %d records, want %d: %s", tt.name, len(body.Data), tt.count, w.Body)
+		}
+	}
+}
@@ -3,13 +3,13 @@ package middleware
 import (
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/bestruirui/octopus/internal/conf"
 	"github.com/bestruirui/octopus/internal/helper"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/server/auth"
 	"github.com/bestruirui/octopus/internal/server/resp"
+	"github.com/bestruirui/octopus/internal/utils/websocket"
 	"github.com/gin-gonic/gin"
 )
 
@@ -30,6 +30,9 @@ func Auth() gin.HandlerFunc {
 	}
 }
 
+// APIKeyAuth 校验 /v1 接口的 API Key，写入 x-ratelimit-* 响应头但不拦截超出限额的请求
+// 限额只在中继 (relay.checkAPIKeyLimits) 中检查并计数：幂等重放需在计数之前返回，
+// /v1/models、/v1/files 等非中继接口不计入请求数，拒绝日志需要请求的模型，批处理也不经过本中间件
 func APIKeyAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var apiKey string
@@ -41,6 +44,9 @@ func APIKeyAuth() gin.HandlerFunc {
 		} else if auth := c.Request.Header.Get("Authorization"); auth != "" {
 			apiKey = strings.TrimPrefix(auth, "Bearer ")
 			requestType = "openai"
+		} else if key := websocketProtocolKey(c.Request); key != "" {
+			apiKey = key
+			requestType = "openai"
 		}
 
 		if apiKey == "" {
@@ -60,40 +66,26 @@ func APIKeyAuth() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		if !apiKeyObj.Enabled {
-			resp.Error(c, http.StatusUnauthorized, "API key is disabled")
-			c.Abort()
-			return
-		}
-		if apiKeyObj.ExpireAt > 0 && apiKeyObj.ExpireAt < time.Now().Unix() {
-			resp.Error(c, http.StatusUnauthorized, "API key has expired")
+		quotaExceeded, err := helper.APIKeyAuthorize(&apiKeyObj, c.Request.Context())
+		if err != nil {
+			resp.Error(c, http.StatusUnauthorized, err.Error())
 			c.Abort()
 			return
 		}
+		helper.SetAPIKeyContext(c, apiKeyObj, requestType, quotaExceeded)
+		c.Next()
+	}
+}
 
-		// Auto reset quota logic
-		if apiKeyObj.AutoResetQuota && apiKeyObj.ResetDuration > 0 {
-			now := time.Now()
-			nowUnix := now.Unix()
-			forceReset := apiKeyObj.ResetUnit == "day" && apiKeyObj.NextResetTime > 0 && !helper.IsAlignedToMidnight(apiKeyObj.NextResetTime)
-			if apiKeyObj.NextResetTime == 0 {
-				apiKeyObj.NextResetTime = helper.CalculateNextResetTime(now, apiKeyObj.ResetDuration, apiKeyObj.ResetUnit)
-				op.APIKeyUpdate(&apiKeyObj, c.Request.Context())
-			} else if nowUnix >= apiKeyObj.NextResetTime || forceReset {
-				if err := op.StatsAPIKeyReset(apiKeyObj.ID); err == nil {
-					apiKeyObj.NextResetTime = helper.CalculateNextResetTime(now, apiKeyObj.ResetDuration, apiKeyObj.ResetUnit)
-					op.APIKeyUpdate(&apiKeyObj, c.Request.Context())
-				}
-			}
-		}
-
-		statsAPIKey := op.StatsAPIKeyGet(apiKeyObj.ID)
-		if apiKeyObj.MaxCost > 0 && apiKeyObj.MaxCost < statsAPIKey.StatsMetrics.OutputCost+statsAPIKey.StatsMetrics.InputCost {
-			c.Set("quota_exceeded", true)
+// websocketProtocolKey 浏览器无法为 WebSocket 设置请求头，Realtime 客户端通过子协议传递 API Key
+func websocketProtocolKey(r *http.Request) string {
+	if !websocket.IsUpgradeRequest(r) {
+		return ""
+	}
+	for _, protocol := range websocket.Subprotocols(r) {
+		if key, ok := strings.CutPrefix(protocol, "openai-insecure-api-key."); ok {
+			return key
 		}
-		c.Set("request_type", requestType)
-		c.Set("supported_models", apiKeyObj.SupportedModels)
-		c.Set("api_key_id", apiKeyObj.ID)
-		c.Next()
 	}
+	return ""
 }
@@ -5,18 +5,24 @@ import (
 	"time"
 
 	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/notify"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/price"
+	"github.com/bestruirui/octopus/internal/relay"
 	"github.com/bestruirui/octopus/internal/utils/log"
 )
 
 const (
-	TaskPriceUpdate  = "price_update"
-	TaskStatsSave    = "stats_save"
-	TaskRelayLogSave = "relay_log_save"
-	TaskSyncLLM      = "sync_llm"
-	TaskCleanLLM     = "clean_llm"
-	TaskBaseUrlDelay = "base_url_delay"
+	TaskPriceUpdate     = "price_update"
+	TaskStatsSave       = "stats_save"
+	TaskRelayLogSave    = "relay_log_save"
+	TaskSyncLLM         = "sync_llm"
+	TaskCleanLLM        = "clean_llm"
+	TaskBaseUrlDelay    = "base_url_delay"
+	TaskResponseClean   = "response_clean"
+	TaskBatchDispatch   = "batch_dispatch"
+	TaskStatsModelClean = "stats_model_clean"
+	TaskNotifyCheck     = "notify_check"
 )
 
 func Init() {
@@ -60,6 +66,26 @@ func Init() {
 		}
 	})
 
+	// 注册响应清理任务
+	Register(TaskResponseClean, 1*time.Hour, true, func() {
+		if err := op.ResponseCleanup(context.Background()); err != nil {
+			log.Warnf("response cleanup task failed: %v", err)
+		}
+	})
+
+	// 注册按模型统计的小时数据清理任务
+	Register(TaskStatsModelClean, 1*time.Hour, true, func() {
+		if err := op.StatsModelHourlyCleanup(context.Background()); err != nil {
+			log.Warnf("stats model hourly cleanup task failed: %v", err)
+		}
+	})
+
+	// 注册批处理调度任务，启动时恢复未完成的批处理
+	Register(TaskBatchDispatch, 1*time.Minute, true, relay.BatchDispatchTask)
+
 	// 注册配额重置任务
 	Register("quota_reset", 1*time.Minute, true, CheckAndResetQuotas)
+
+	// 注册通知检查任务，检查额度、当日费用和渠道成功率
+	Register(TaskNotifyCheck, 1*time.Minute, true, notify.CheckTask)
 }
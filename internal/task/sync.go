@@ -7,6 +7,7 @@ import (
 
 	"github.com/bestruirui/octopus/internal/helper"
 	"github.com/bestruirui/octopus/internal/model"
+	"github.com/bestruirui/octopus/internal/notify"
 	"github.com/bestruirui/octopus/internal/op"
 	"github.com/bestruirui/octopus/internal/utils/diff"
 	"github.com/bestruirui/octopus/internal/utils/log"
@@ -68,6 +69,7 @@ func SyncModelsTask() {
 		// 批量删除消失的模型对应的 GroupItem
 		if len(deletedModels) > 0 {
 			log.Infof("deleted channel %s models: %v", channel.Name, deletedModels)
+			notify.ModelsRemoved(channel, deletedModels)
 			keys := make([]model.GroupIDAndLLMName, len(deletedModels))
 			for i, m := range deletedModels {
 				keys[i] = model.GroupIDAndLLMName{ChannelID: channel.ID, ModelName: m}
@@ -0,0 +1,389 @@
+package aisdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/transformer/inbound/openai"
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/samber/lo"
+)
+
+// DataStreamInbound 接收 AI SDK useChat 请求，按 data stream protocol 输出
+// TextOnly 时使用 text stream protocol，仅输出文本内容
+type DataStreamInbound struct {
+	TextOnly bool
+
+	// streamChunks stores stream chunks for aggregation
+	streamChunks []*model.InternalLLMResponse
+	// storedResponse stores the non-stream response
+	storedResponse *model.InternalLLMResponse
+
+	messageID    string
+	hasStarted   bool
+	hasFinished  bool
+	finishReason string
+	finishSent   bool
+
+	// toolCalls accumulates tool call deltas by index, emitted as complete tool call parts on finish
+	toolCalls []model.ToolCall
+}
+
+func (i *DataStreamInbound) TransformRequest(ctx context.Context, body []byte) (*model.InternalLLMRequest, error) {
+	var req ChatRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	if req.Model == "" {
+		return nil, errors.New("model is required, pass it through the useChat body option")
+	}
+
+	format := model.APIFormatAiSDKDataStream
+	if i.TextOnly {
+		format = model.APIFormatAiSDKText
+	}
+	chatReq := &model.InternalLLMRequest{
+		Model:            req.Model,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		MaxTokens:        req.MaxTokens,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		Seed:             req.Seed,
+		ReasoningEffort:  req.ReasoningEffort,
+		Tools:            req.Tools,
+		ToolChoice:       req.ToolChoice,
+		// useChat 始终以流式读取响应
+		Stream:       lo.ToPtr(true),
+		RawAPIFormat: format,
+	}
+	if len(req.StopSequences) > 0 {
+		chatReq.Stop = &model.Stop{MultipleStop: req.StopSequences}
+	}
+
+	if req.System != "" {
+		chatReq.Messages = append(chatReq.Messages, model.Message{
+			Role:    "system",
+			Content: model.MessageContent{Content: lo.ToPtr(req.System)},
+		})
+	}
+	for _, msg := range req.Messages {
+		messages, err := convertUIMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		chatReq.Messages = append(chatReq.Messages, messages...)
+	}
+	return chatReq, nil
+}
+
+func (i *DataStreamInbound) TransformResponse(ctx context.Context, response *model.InternalLLMResponse) ([]byte, error) {
+	i.storedResponse = response
+
+	// 将完整响应视为单个流式块输出，客户端始终按流式协议解析
+	chunk := &model.InternalLLMResponse{
+		ID:      response.ID,
+		Object:  "chat.completion.chunk",
+		Created: response.Created,
+		Model:   response.Model,
+		Usage:   response.Usage,
+	}
+	for _, choice := range response.Choices {
+		chunk.Choices = append(chunk.Choices, model.Choice{
+			Index:        choice.Index,
+			Delta:        choice.Message,
+			FinishReason: choice.FinishReason,
+		})
+	}
+	// 非流式响应的工具调用可能缺少 index，按顺序补齐
+	for _, choice := range chunk.Choices {
+		if choice.Delta == nil {
+			continue
+		}
+		for idx := range choice.Delta.ToolCalls {
+			choice.Delta.ToolCalls[idx].Index = idx
+		}
+	}
+
+	body, err := i.encode(chunk)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := i.finish(nil)
+	if err != nil {
+		return nil, err
+	}
+	return append(body, rest...), nil
+}
+
+func (i *DataStreamInbound) TransformStream(ctx context.Context, stream *model.InternalLLMResponse) ([]byte, error) {
+	// 上游未返回 usage 时在流结束时补发 finish
+	if stream.Object == "[DONE]" {
+		return i.finish(nil)
+	}
+
+	// Store the chunk for aggregation
+	i.streamChunks = append(i.streamChunks, stream)
+
+	return i.encode(stream)
+}
+
+// ResponseHeaders 实现 model.InboundResponseHeader
+func (i *DataStreamInbound) ResponseHeaders(stream bool) map[string]string {
+	headers := map[string]string{
+		"Content-Type": "text/plain; charset=utf-8",
+	}
+	if !i.TextOnly {
+		headers["X-Vercel-AI-Data-Stream"] = "v1"
+	}
+	return headers
+}
+
+// GetInternalResponse returns the complete internal response for logging, statistics, etc.
+// For streaming: aggregates all stored stream chunks into a complete response
+// For non-streaming: returns the stored response
+func (i *DataStreamInbound) GetInternalResponse(ctx context.Context) (*model.InternalLLMResponse, error) {
+	if i.storedResponse != nil {
+		return i.storedResponse, nil
+	}
+
+	result := openai.AggregateStreamChunks(i.streamChunks)
+
+	// Clear stored chunks after aggregation
+	i.streamChunks = nil
+
+	return result, nil
+}
+
+// encode 将单个内部流式块转为 data stream parts
+func (i *DataStreamInbound) encode(stream *model.InternalLLMResponse) ([]byte, error) {
+	var out []byte
+
+	if i.messageID == "" {
+		i.messageID = stream.ID
+	}
+
+	// useChat 仅使用第一个 choice
+	var choice *model.Choice
+	for idx := range stream.Choices {
+		if stream.Choices[idx].Index == 0 {
+			choice = &stream.Choices[idx]
+			break
+		}
+	}
+
+	if i.TextOnly {
+		if choice != nil && choice.Delta != nil && choice.Delta.Content.Content != nil {
+			out = append(out, *choice.Delta.Content.Content...)
+		}
+		return out, nil
+	}
+
+	if !i.hasStarted && (choice != nil || stream.Usage != nil) {
+		i.hasStarted = true
+		if i.messageID == "" {
+			i.messageID = fmt.Sprintf("msg-%s", lo.RandomString(24, lo.AlphanumericCharset))
+		}
+		part, err := encodePart("f", StartStepPart{MessageID: i.messageID})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, part...)
+	}
+
+	if choice != nil && choice.Delta != nil {
+		delta := choice.Delta
+
+		if reasoning := delta.GetReasoningContent(); reasoning != "" {
+			part, err := encodePart("g", reasoning)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, part...)
+		}
+
+		if text := messageText(delta.Content); text != "" {
+			part, err := encodePart("0", text)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, part...)
+		}
+
+		for _, toolCall := range delta.ToolCalls {
+			parts, err := i.encodeToolCallDelta(toolCall)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, parts...)
+		}
+	}
+
+	if choice != nil && choice.FinishReason != nil && !i.hasFinished {
+		i.hasFinished = true
+		i.finishReason = convertFinishReason(*choice.FinishReason)
+
+		// 工具调用参数完整后输出 tool call part
+		for _, toolCall := range i.toolCalls {
+			args := json.RawMessage(toolCall.Function.Arguments)
+			if !json.Valid(args) {
+				args = json.RawMessage("{}")
+			}
+			part, err := encodePart("9", ToolCallPart{
+				ToolCallID: toolCall.ID,
+				ToolName:   toolCall.Function.Name,
+				Args:       args,
+			})
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, part...)
+		}
+	}
+
+	// usage 通常在 finish_reason 之后的块中返回，等待 usage 后再输出 finish parts
+	if stream.Usage != nil && i.hasFinished {
+		parts, err := i.finish(stream.Usage)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, parts...)
+	}
+
+	return out, nil
+}
+
+// encodeToolCallDelta 输出工具调用的 streaming start 和参数增量
+func (i *DataStreamInbound) encodeToolCallDelta(delta model.ToolCall) ([]byte, error) {
+	var out []byte
+
+	idx := -1
+	for n := range i.toolCalls {
+		if i.toolCalls[n].Index == delta.Index {
+			idx = n
+			break
+		}
+	}
+	if idx == -1 {
+		if delta.ID == "" {
+			delta.ID = fmt.Sprintf("call_%s", lo.RandomString(24, lo.AlphanumericCharset))
+		}
+		i.toolCalls = append(i.toolCalls, model.ToolCall{
+			ID:       delta.ID,
+			Type:     "function",
+			Index:    delta.Index,
+			Function: model.FunctionCall{Name: delta.Function.Name},
+		})
+		idx = len(i.toolCalls) - 1
+
+		part, err := encodePart("b", ToolCallStartPart{
+			ToolCallID: delta.ID,
+			ToolName:   delta.Function.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, part...)
+	} else if delta.Function.Name != "" {
+		i.toolCalls[idx].Function.Name += delta.Function.Name
+	}
+
+	if delta.Function.Arguments != "" {
+		i.toolCalls[idx].Function.Arguments += delta.Function.Arguments
+		part, err := encodePart("c", ToolCallDeltaPart{
+			ToolCallID:    i.toolCalls[idx].ID,
+			ArgsTextDelta: delta.Function.Arguments,
+		})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, part...)
+	}
+
+	return out, nil
+}
+
+// finish 输出 finish step 与 finish message parts，只输出一次
+func (i *DataStreamInbound) finish(usage *model.Usage) ([]byte, error) {
+	if i.TextOnly || i.finishSent || !i.hasStarted {
+		return nil, nil
+	}
+	i.finishSent = true
+
+	finishReason := i.finishReason
+	if finishReason == "" {
+		finishReason = "unknown"
+	}
+	var u *Usage
+	if usage != nil {
+		u = &Usage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+		}
+	} else {
+		u = &Usage{}
+	}
+
+	stepPart, err := encodePart("e", FinishPart{
+		FinishReason: finishReason,
+		Usage:        u,
+		IsContinued:  lo.ToPtr(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+	messagePart, err := encodePart("d", FinishPart{
+		FinishReason: finishReason,
+		Usage:        u,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(stepPart, messagePart...), nil
+}
+
+// encodePart 编码单个 data stream part: `<type>:<json>\n`
+func encodePart(typ string, value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data stream part %s: %w", typ, err)
+	}
+	out := make([]byte, 0, len(typ)+len(data)+2)
+	out = append(out, typ...)
+	out = append(out, ':')
+	out = append(out, data...)
+	return append(out, '\n'), nil
+}
+
+func convertFinishReason(reason string) string {
+	switch reason {
+	case "stop":
+		return "stop"
+	case "length":
+		return "length"
+	case "tool_calls", "function_call":
+		return "tool-calls"
+	case "content_filter":
+		return "content-filter"
+	case "error":
+		return "error"
+	default:
+		return "other"
+	}
+}
+
+func messageText(content model.MessageContent) string {
+	if content.Content != nil {
+		return *content.Content
+	}
+	var sb strings.Builder
+	for _, part := range content.MultipleContent {
+		if part.Type == "text" && part.Text != nil {
+			sb.WriteString(*part.Text)
+		}
+	}
+	return sb.String()
+}
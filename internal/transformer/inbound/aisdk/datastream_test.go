@@ -0,0 +1,104 @@
+package aisdk
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/samber/lo"
+)
+
+func TestDataStreamInboundTransformStream(t *testing.T) {
+	ctx := context.Background()
+	in := &DataStreamInbound{}
+	chunks := []*model.InternalLLMResponse{
+		{ID: "chatcmpl-1", Object: "chat.completion.chunk", Choices: []model.Choice{{Delta: &model.Message{
+			Role:             "assistant",
+			ReasoningContent: lo.ToPtr("think"),
+		}}}},
+		{Object: "chat.completion.chunk", Choices: []model.Choice{{Delta: &model.Message{
+			Content: model.MessageContent{Content: lo.ToPtr("hi")},
+		}}}},
+		{Object: "chat.completion.chunk", Choices: []model.Choice{{Delta: &model.Message{
+			ToolCalls: []model.ToolCall{{ID: "call_1", Index: 0, Function: model.FunctionCall{Name: "weather", Arguments: `{"city":`}}},
+		}}}},
+		{Object: "chat.completion.chunk", Choices: []model.Choice{{Delta: &model.Message{
+			ToolCalls: []model.ToolCall{{Index: 0, Function: model.FunctionCall{Arguments: `"sf"}`}}},
+		}}}},
+		{Object: "chat.completion.chunk", Choices: []model.Choice{{Delta: &model.Message{}, FinishReason: lo.ToPtr("tool_calls")}}},
+		{Object: "chat.completion.chunk", Usage: &model.Usage{PromptTokens: 7, CompletionTokens: 3}},
+		{Object: "[DONE]"},
+	}
+
+	var out strings.Builder
+	for _, chunk := range chunks {
+		data, err := in.TransformStream(ctx, chunk)
+		if err != nil {
+			t.Fatalf("TransformStream: %v", err)
+		}
+		out.Write(data)
+	}
+
+	want := strings.Join([]string{
+		`f:{"messageId":"chatcmpl-1"}`,
+		`g:"think"`,
+		`0:"hi"`,
+		`b:{"toolCallId":"call_1","toolName":"weather"}`,
+		`c:{"toolCallId":"call_1","argsTextDelta":"{\"city\":"}`,
+		`c:{"toolCallId":"call_1","argsTextDelta":"\"sf\"}"}`,
+		`9:{"toolCallId":"call_1","toolName":"weather","args":{"city":"sf"}}`,
+		`e:{"finishReason":"tool-calls","usage":{"promptTokens":7,"completionTokens":3},"isContinued":false}`,
+		`d:{"finishReason":"tool-calls","usage":{"promptTokens":7,"completionTokens":3}}`,
+	}, "\n") + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected stream:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	resp, err := in.GetInternalResponse(ctx)
+	if err != nil || resp == nil {
+		t.Fatalf("GetInternalResponse: %v", err)
+	}
+	msg := resp.Choices[0].Message
+	if *msg.Content.Content != "hi" || len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Arguments != `{"city":"sf"}` {
+		t.Fatalf("unexpected aggregated message: %+v", msg)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 7 {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestDataStreamInboundTransformRequest(t *testing.T) {
+	body := `{"id":"chat-1","model":"gpt-4o","system":"be brief","messages":[
+		{"id":"1","role":"user","content":"weather?","parts":[{"type":"text","text":"weather?"}]},
+		{"id":"2","role":"assistant","content":"sunny","parts":[
+			{"type":"step-start"},
+			{"type":"tool-invocation","toolInvocation":{"state":"result","toolCallId":"call_1","toolName":"weather","args":{"city":"sf"},"result":"sunny"}},
+			{"type":"step-start"},
+			{"type":"text","text":"It is sunny."}
+		]},
+		{"id":"3","role":"user","content":"see","experimental_attachments":[{"name":"a.png","contentType":"image/png","url":"data:image/png;base64,AAAA"}]}
+	]}`
+
+	req, err := (&DataStreamInbound{}).TransformRequest(context.Background(), []byte(body))
+	if err != nil {
+		t.Fatalf("TransformRequest: %v", err)
+	}
+	if req.Stream == nil || !*req.Stream || req.RawAPIFormat != model.APIFormatAiSDKDataStream {
+		t.Fatalf("unexpected request: stream=%v format=%s", req.Stream, req.RawAPIFormat)
+	}
+
+	roles := lo.Map(req.Messages, func(m model.Message, _ int) string { return m.Role })
+	if strings.Join(roles, ",") != "system,user,assistant,tool,assistant,user" {
+		t.Fatalf("unexpected roles: %v", roles)
+	}
+	if calls := req.Messages[2].ToolCalls; len(calls) != 1 || calls[0].Function.Arguments != `{"city":"sf"}` {
+		t.Fatalf("unexpected tool calls: %+v", calls)
+	}
+	if *req.Messages[3].ToolCallID != "call_1" || *req.Messages[3].Content.Content != "sunny" {
+		t.Fatalf("unexpected tool message: %+v", req.Messages[3])
+	}
+	if parts := req.Messages[5].Content.MultipleContent; len(parts) != 2 || parts[1].ImageURL == nil {
+		t.Fatalf("unexpected attachment parts: %+v", parts)
+	}
+}
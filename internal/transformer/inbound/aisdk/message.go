@@ -0,0 +1,167 @@
+package aisdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/samber/lo"
+)
+
+// convertUIMessage 将 UIMessage 转为内部消息
+// 助手消息按 step-start 拆分为多轮，每轮的工具结果转为 tool 消息
+func convertUIMessage(msg UIMessage) ([]model.Message, error) {
+	switch msg.Role {
+	case "system":
+		return []model.Message{{
+			Role:    "system",
+			Content: model.MessageContent{Content: lo.ToPtr(uiMessageText(msg))},
+		}}, nil
+	case "user":
+		return []model.Message{convertUserMessage(msg)}, nil
+	case "assistant":
+		return convertAssistantMessage(msg)
+	case "data":
+		// data 消息仅用于前端展示
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
+	}
+}
+
+func uiMessageText(msg UIMessage) string {
+	if len(msg.Parts) == 0 {
+		return msg.Content
+	}
+	var sb strings.Builder
+	for _, part := range msg.Parts {
+		if part.Type == "text" {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+func convertUserMessage(msg UIMessage) model.Message {
+	var parts []model.MessageContentPart
+	if len(msg.Parts) > 0 {
+		for _, part := range msg.Parts {
+			switch part.Type {
+			case "text":
+				parts = append(parts, model.MessageContentPart{Type: "text", Text: lo.ToPtr(part.Text)})
+			case "file":
+				parts = append(parts, filePart("", part.MimeType, "data:"+part.MimeType+";base64,"+part.Data))
+			}
+		}
+	} else if msg.Content != "" {
+		parts = append(parts, model.MessageContentPart{Type: "text", Text: lo.ToPtr(msg.Content)})
+	}
+	for _, attachment := range msg.ExperimentalAttachments {
+		parts = append(parts, filePart(attachment.Name, attachment.ContentType, attachment.URL))
+	}
+
+	// 纯文本消息使用字符串内容
+	if len(parts) == 1 && parts[0].Type == "text" {
+		return model.Message{Role: "user", Content: model.MessageContent{Content: parts[0].Text}}
+	}
+	return model.Message{Role: "user", Content: model.MessageContent{MultipleContent: parts}}
+}
+
+func filePart(name, mimeType, url string) model.MessageContentPart {
+	if strings.HasPrefix(mimeType, "image/") || (mimeType == "" && strings.HasPrefix(url, "data:image/")) {
+		return model.MessageContentPart{Type: "image_url", ImageURL: &model.ImageURL{URL: url}}
+	}
+	return model.MessageContentPart{Type: "file", File: &model.File{Filename: name, FileData: url}}
+}
+
+func convertAssistantMessage(msg UIMessage) ([]model.Message, error) {
+	var messages []model.Message
+
+	current := model.Message{Role: "assistant"}
+	var invocations []ToolInvocation
+	var text, reasoning strings.Builder
+
+	flush := func() {
+		if text.Len() == 0 && reasoning.Len() == 0 && len(invocations) == 0 {
+			return
+		}
+		if text.Len() > 0 {
+			current.Content = model.MessageContent{Content: lo.ToPtr(text.String())}
+		}
+		if reasoning.Len() > 0 {
+			current.ReasoningContent = lo.ToPtr(reasoning.String())
+		}
+		for idx, invocation := range invocations {
+			args := "{}"
+			if len(invocation.Args) > 0 {
+				args = string(invocation.Args)
+			}
+			current.ToolCalls = append(current.ToolCalls, model.ToolCall{
+				ID:    invocation.ToolCallID,
+				Type:  "function",
+				Index: idx,
+				Function: model.FunctionCall{
+					Name:      invocation.ToolName,
+					Arguments: args,
+				},
+			})
+		}
+		messages = append(messages, current)
+
+		// 仅有结果的工具调用才会回传给模型
+		for _, invocation := range invocations {
+			if invocation.State != "result" {
+				continue
+			}
+			messages = append(messages, model.Message{
+				Role:         "tool",
+				ToolCallID:   lo.ToPtr(invocation.ToolCallID),
+				ToolCallName: lo.ToPtr(invocation.ToolName),
+				Content:      model.MessageContent{Content: lo.ToPtr(toolResultText(invocation.Result))},
+			})
+		}
+
+		current = model.Message{Role: "assistant"}
+		invocations = nil
+		text.Reset()
+		reasoning.Reset()
+	}
+
+	if len(msg.Parts) == 0 {
+		text.WriteString(msg.Content)
+		invocations = msg.ToolInvocations
+		flush()
+		return messages, nil
+	}
+
+	for _, part := range msg.Parts {
+		switch part.Type {
+		case "step-start":
+			flush()
+		case "text":
+			text.WriteString(part.Text)
+		case "reasoning":
+			reasoning.WriteString(part.Reasoning)
+		case "tool-invocation":
+			if part.ToolInvocation != nil {
+				invocations = append(invocations, *part.ToolInvocation)
+			}
+		}
+	}
+	flush()
+
+	return messages, nil
+}
+
+// toolResultText 字符串结果直接使用，其他结果使用 JSON 文本
+func toolResultText(result json.RawMessage) string {
+	if len(result) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(result, &s); err == nil {
+		return s
+	}
+	return string(result)
+}
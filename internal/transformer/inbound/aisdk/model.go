@@ -0,0 +1,106 @@
+package aisdk
+
+import (
+	"encoding/json"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+)
+
+// ChatRequest represents the request body sent by the AI SDK `useChat` hook.
+// Fields other than id and messages are passed through the hook's `body` option.
+type ChatRequest struct {
+	ID       string      `json:"id,omitempty"`
+	Messages []UIMessage `json:"messages"`
+	Model    string      `json:"model"`
+
+	// System is prepended to the conversation as a system message.
+	System string `json:"system,omitempty"`
+
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	MaxTokens        *int64   `json:"maxTokens,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	Seed             *int64   `json:"seed,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	ReasoningEffort  string   `json:"reasoningEffort,omitempty"`
+
+	// Tools uses the OpenAI function tool format.
+	Tools      []model.Tool      `json:"tools,omitempty"`
+	ToolChoice *model.ToolChoice `json:"toolChoice,omitempty"`
+}
+
+// UIMessage is a message of the AI SDK chat history.
+// Newer clients send parts; older clients only send content, attachments and toolInvocations.
+type UIMessage struct {
+	ID                      string           `json:"id,omitempty"`
+	Role                    string           `json:"role"`
+	Content                 string           `json:"content"`
+	Parts                   []UIPart         `json:"parts,omitempty"`
+	ExperimentalAttachments []Attachment     `json:"experimental_attachments,omitempty"`
+	ToolInvocations         []ToolInvocation `json:"toolInvocations,omitempty"`
+}
+
+// UIPart is a part of a UIMessage.
+// Any of "text", "reasoning", "tool-invocation", "file", "source", "step-start".
+type UIPart struct {
+	Type           string          `json:"type"`
+	Text           string          `json:"text,omitempty"`
+	Reasoning      string          `json:"reasoning,omitempty"`
+	ToolInvocation *ToolInvocation `json:"toolInvocation,omitempty"`
+	MimeType       string          `json:"mimeType,omitempty"`
+	Data           string          `json:"data,omitempty"`
+}
+
+// ToolInvocation is a tool call made by the assistant, with its result when state is "result".
+type ToolInvocation struct {
+	State      string          `json:"state"`
+	ToolCallID string          `json:"toolCallId"`
+	ToolName   string          `json:"toolName"`
+	Args       json.RawMessage `json:"args,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+}
+
+// Attachment is a file attached to a user message, url may be a data URL.
+type Attachment struct {
+	Name        string `json:"name,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	URL         string `json:"url"`
+}
+
+// Usage is the token usage reported in finish parts.
+type Usage struct {
+	PromptTokens     int64 `json:"promptTokens"`
+	CompletionTokens int64 `json:"completionTokens"`
+}
+
+// FinishPart is the payload of the finish step (e) and finish message (d) parts.
+type FinishPart struct {
+	FinishReason string `json:"finishReason"`
+	Usage        *Usage `json:"usage,omitempty"`
+	IsContinued  *bool  `json:"isContinued,omitempty"`
+}
+
+// StartStepPart is the payload of the start step (f) part.
+type StartStepPart struct {
+	MessageID string `json:"messageId"`
+}
+
+// ToolCallStartPart is the payload of the tool call streaming start (b) part.
+type ToolCallStartPart struct {
+	ToolCallID string `json:"toolCallId"`
+	ToolName   string `json:"toolName"`
+}
+
+// ToolCallDeltaPart is the payload of the tool call delta (c) part.
+type ToolCallDeltaPart struct {
+	ToolCallID    string `json:"toolCallId"`
+	ArgsTextDelta string `json:"argsTextDelta"`
+}
+
+// ToolCallPart is the payload of the tool call (9) part.
+type ToolCallPart struct {
+	ToolCallID string          `json:"toolCallId"`
+	ToolName   string          `json:"toolName"`
+	Args       json.RawMessage `json:"args"`
+}
@@ -151,6 +151,7 @@ func (i *MessagesInbound) TransformRequest(ctx context.Context, body []byte) (*m
 						}
 
 						contentParts = append(contentParts, part)
+						i.inputToken += int64(tokenizer.CountImageTokens(chatReq.Model, part.ImageURL.URL, ""))
 						hasContent = true
 					}
 				case "tool_result":
@@ -200,6 +201,7 @@ func (i *MessagesInbound) TransformRequest(ctx context.Context, body []byte) (*m
 						},
 						CacheControl: convertToLLMCacheControl(block.CacheControl),
 					})
+					i.inputToken += int64(tokenizer.CountTokens(lo.FromPtr(block.Name)+string(block.Input), chatReq.Model))
 					hasContent = true
 				}
 			}
@@ -261,11 +263,9 @@ func (i *MessagesInbound) TransformRequest(ctx context.Context, body []byte) (*m
 				CacheControl: convertToLLMCacheControl(tool.CacheControl),
 			}
 			tools = append(tools, llmTool)
-			i.inputToken += int64(tokenizer.CountTokens(tool.Name, chatReq.Model))
-			i.inputToken += int64(tokenizer.CountTokens(tool.Description, chatReq.Model))
-			i.inputToken += int64(tokenizer.CountTokens(string(tool.InputSchema), chatReq.Model))
+			i.inputToken += int64(tokenizer.CountToolTokens(chatReq.Model, tool.Name, tool.Description, string(tool.InputSchema)))
 		}
-		i.inputToken += int64(len(tools) * 3)
+		i.inputToken += int64(tokenizer.ToolsOverhead(chatReq.Model, len(tools)))
 
 		chatReq.Tools = tools
 	}
@@ -405,6 +405,15 @@ type Usage struct {
 	// The number of input tokens read from the cache.
 	CacheReadInputTokens int64 `json:"cache_read_input_tokens,omitempty"`
 
+	// Breakdown of cached tokens by TTL.
+	CacheCreation *CacheCreation `json:"cache_creation,omitempty"`
+
 	// Available options: standard, priority, batch
 	ServiceTier string `json:"service_tier,omitempty"`
 }
+
+// CacheCreation breaks down cache creation tokens by cache TTL.
+type CacheCreation struct {
+	Ephemeral5mInputTokens int64 `json:"ephemeral_5m_input_tokens"`
+	Ephemeral1hInputTokens int64 `json:"ephemeral_1h_input_tokens"`
+}
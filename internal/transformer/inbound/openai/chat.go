@@ -76,12 +76,23 @@ func (i *ChatInbound) GetInternalResponse(ctx context.Context) (*model.InternalL
 	}
 
 	// Aggregate stream chunks for stream scenario
-	if len(i.streamChunks) == 0 {
-		return nil, nil
+	result := AggregateStreamChunks(i.streamChunks)
+
+	// Clear stored chunks after aggregation
+	i.streamChunks = nil
+
+	return result, nil
+}
+
+// AggregateStreamChunks aggregates chat completion stream chunks into a complete response.
+// Returns nil if there are no chunks.
+func AggregateStreamChunks(chunks []*model.InternalLLMResponse) *model.InternalLLMResponse {
+	if len(chunks) == 0 {
+		return nil
 	}
 
 	// Use the first chunk as the base
-	firstChunk := i.streamChunks[0]
+	firstChunk := chunks[0]
 	result := &model.InternalLLMResponse{
 		ID:                firstChunk.ID,
 		Object:            "chat.completion",
@@ -94,7 +105,7 @@ func (i *ChatInbound) GetInternalResponse(ctx context.Context) (*model.InternalL
 	// Aggregate choices by index
 	choicesMap := make(map[int]*model.Choice)
 
-	for _, chunk := range i.streamChunks {
+	for _, chunk := range chunks {
 		// Update ID and Model if they appear in later chunks (some providers send these later)
 		if chunk.ID != "" {
 			result.ID = chunk.ID
@@ -193,10 +204,7 @@ func (i *ChatInbound) GetInternalResponse(ctx context.Context) (*model.InternalL
 		}
 	}
 
-	// Clear stored chunks after aggregation
-	i.streamChunks = nil
-
-	return result, nil
+	return result
 }
 
 // mergeToolCall merges a tool call delta into the existing tool calls slice
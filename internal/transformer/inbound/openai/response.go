@@ -49,6 +49,12 @@ type ResponseInbound struct {
 	streamChunks []*model.InternalLLMResponse
 	// storedResponse stores the non-stream response
 	storedResponse *model.InternalLLMResponse
+
+	// Stateful responses (previous_response_id / store)
+	request            *ResponsesRequest
+	previousResponseID string
+	// builtResponse is the non-stream response sent to the client, reused when storing
+	builtResponse *ResponsesResponse
 }
 
 func (i *ResponseInbound) TransformRequest(ctx context.Context, body []byte) (*model.InternalLLMRequest, error) {
@@ -61,6 +67,12 @@ func (i *ResponseInbound) TransformRequest(ctx context.Context, body []byte) (*m
 		return nil, fmt.Errorf("model is required")
 	}
 
+	i.request = &req
+	i.responseID = generateResponseID()
+	if req.PreviousResponseID != nil {
+		i.previousResponseID = *req.PreviousResponseID
+	}
+
 	return convertToInternalRequest(&req)
 }
 
@@ -73,7 +85,8 @@ func (i *ResponseInbound) TransformResponse(ctx context.Context, response *model
 	i.storedResponse = response
 
 	// Convert to Responses API format
-	resp := convertToResponsesAPIResponse(response)
+	resp := i.buildResponse(response)
+	i.builtResponse = resp
 
 	body, err := json.Marshal(resp)
 	if err != nil {
@@ -120,12 +133,13 @@ func (i *ResponseInbound) TransformStream(ctx context.Context, stream *model.Int
 		i.hasResponseCreated = true
 
 		response := &ResponsesResponse{
-			Object:    "response",
-			ID:        i.responseID,
-			Model:     i.model,
-			CreatedAt: i.createdAt,
-			Status:    lo.ToPtr("in_progress"),
-			Output:    []ResponsesItem{},
+			Object:             "response",
+			ID:                 i.responseID,
+			Model:              i.model,
+			CreatedAt:          i.createdAt,
+			Status:             lo.ToPtr("in_progress"),
+			Output:             []ResponsesItem{},
+			PreviousResponseID: i.previousResponseIDPtr(),
 		}
 
 		events = append(events, i.enqueueEvent(&ResponsesStreamEvent{
@@ -175,13 +189,14 @@ func (i *ResponseInbound) TransformStream(ctx context.Context, stream *model.Int
 
 		status := "completed"
 		response := &ResponsesResponse{
-			Object:    "response",
-			ID:        i.responseID,
-			Model:     i.model,
-			CreatedAt: i.createdAt,
-			Status:    &status,
-			Output:    []ResponsesItem{},
-			Usage:     convertUsageToResponses(i.usage),
+			Object:             "response",
+			ID:                 i.responseID,
+			Model:              i.model,
+			CreatedAt:          i.createdAt,
+			Status:             &status,
+			Output:             []ResponsesItem{},
+			Usage:              convertUsageToResponses(i.usage),
+			PreviousResponseID: i.previousResponseIDPtr(),
 		}
 
 		events = append(events, i.enqueueEvent(&ResponsesStreamEvent{
@@ -693,6 +708,112 @@ func (i *ResponseInbound) GetInternalResponse(ctx context.Context) (*model.Inter
 	return result, nil
 }
 
+// PreviousResponseID returns the previous_response_id of the request, empty if not set
+func (i *ResponseInbound) PreviousResponseID() string {
+	return i.previousResponseID
+}
+
+// ResponseID returns the id assigned to this response
+func (i *ResponseInbound) ResponseID() string {
+	return i.responseID
+}
+
+// ShouldStore reports whether the response should be persisted, store defaults to true
+func (i *ResponseInbound) ShouldStore() bool {
+	return i.request != nil && (i.request.Store == nil || *i.request.Store)
+}
+
+// ExpandHistory inserts the conversation of the previous response before the new input.
+// Instructions of the previous response are not carried over.
+func (i *ResponseInbound) ExpandHistory(req *model.InternalLLMRequest, history []model.Message) {
+	if len(history) == 0 {
+		return
+	}
+	offset := i.instructionsCount()
+	messages := make([]model.Message, 0, len(req.Messages)+len(history))
+	messages = append(messages, req.Messages[:offset]...)
+	messages = append(messages, history...)
+	messages = append(messages, req.Messages[offset:]...)
+	req.Messages = messages
+}
+
+// Conversation returns the full conversation after this response (excluding instructions),
+// used to expand previous_response_id in later requests.
+func (i *ResponseInbound) Conversation(req *model.InternalLLMRequest, resp *model.InternalLLMResponse) []model.Message {
+	offset := min(i.instructionsCount(), len(req.Messages))
+	messages := make([]model.Message, 0, len(req.Messages)-offset+1)
+	messages = append(messages, req.Messages[offset:]...)
+	if resp != nil && len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
+		output := *resp.Choices[0].Message
+		if output.Role == "" {
+			output.Role = "assistant"
+		}
+		messages = append(messages, output)
+	}
+	return messages
+}
+
+// InputItems returns the input items of this request with ids assigned
+func (i *ResponseInbound) InputItems() []ResponsesItem {
+	if i.request == nil {
+		return nil
+	}
+	input := i.request.Input
+	if input.Text != nil {
+		return []ResponsesItem{{
+			ID:     generateMessageID(),
+			Type:   "message",
+			Role:   "user",
+			Status: lo.ToPtr("completed"),
+			Content: &ResponsesInput{Items: []ResponsesItem{
+				{Type: "input_text", Text: input.Text},
+			}},
+		}}
+	}
+	items := make([]ResponsesItem, 0, len(input.Items))
+	for _, item := range input.Items {
+		if item.Type == "" {
+			item.Type = "message"
+		}
+		if item.ID == "" {
+			item.ID = generateMessageID()
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// BuildResponse converts the aggregated internal response into the Responses API object
+func (i *ResponseInbound) BuildResponse(resp *model.InternalLLMResponse) *ResponsesResponse {
+	if i.builtResponse != nil {
+		return i.builtResponse
+	}
+	return i.buildResponse(resp)
+}
+
+func (i *ResponseInbound) buildResponse(resp *model.InternalLLMResponse) *ResponsesResponse {
+	result := convertToResponsesAPIResponse(resp)
+	if i.responseID != "" {
+		result.ID = i.responseID
+	}
+	result.PreviousResponseID = i.previousResponseIDPtr()
+	return result
+}
+
+func (i *ResponseInbound) previousResponseIDPtr() *string {
+	if i.previousResponseID == "" {
+		return nil
+	}
+	return lo.ToPtr(i.previousResponseID)
+}
+
+func (i *ResponseInbound) instructionsCount() int {
+	if i.request != nil && i.request.Instructions != "" {
+		return 1
+	}
+	return 0
+}
+
 // formatSSEData formats data as SSE data line
 func formatSSEData(data []byte) []byte {
 	return []byte(fmt.Sprintf("data: %s\n\n", string(data)))
@@ -701,24 +822,25 @@ func formatSSEData(data []byte) []byte {
 // Request types
 
 type ResponsesRequest struct {
-	Model             string                `json:"model"`
-	Instructions      string                `json:"instructions,omitempty"`
-	Input             ResponsesInput        `json:"input"`
-	Tools             []ResponsesTool       `json:"tools,omitempty"`
-	ToolChoice        *ResponsesToolChoice  `json:"tool_choice,omitempty"`
-	ParallelToolCalls *bool                 `json:"parallel_tool_calls,omitempty"`
-	Stream            *bool                 `json:"stream,omitempty"`
-	Text              *ResponsesTextOptions `json:"text,omitempty"`
-	Store             *bool                 `json:"store,omitempty"`
-	ServiceTier       *string               `json:"service_tier,omitempty"`
-	User              *string               `json:"user,omitempty"`
-	Metadata          map[string]string     `json:"metadata,omitempty"`
-	MaxOutputTokens   *int64                `json:"max_output_tokens,omitempty"`
-	Temperature       *float64              `json:"temperature,omitempty"`
-	TopP              *float64              `json:"top_p,omitempty"`
-	Reasoning         *ResponsesReasoning   `json:"reasoning,omitempty"`
-	Include           []string              `json:"include,omitempty"`
-	TopLogprobs       *int64                `json:"top_logprobs,omitempty"`
+	Model              string                `json:"model"`
+	Instructions       string                `json:"instructions,omitempty"`
+	PreviousResponseID *string               `json:"previous_response_id,omitempty"`
+	Input              ResponsesInput        `json:"input"`
+	Tools              []ResponsesTool       `json:"tools,omitempty"`
+	ToolChoice         *ResponsesToolChoice  `json:"tool_choice,omitempty"`
+	ParallelToolCalls  *bool                 `json:"parallel_tool_calls,omitempty"`
+	Stream             *bool                 `json:"stream,omitempty"`
+	Text               *ResponsesTextOptions `json:"text,omitempty"`
+	Store              *bool                 `json:"store,omitempty"`
+	ServiceTier        *string               `json:"service_tier,omitempty"`
+	User               *string               `json:"user,omitempty"`
+	Metadata           map[string]string     `json:"metadata,omitempty"`
+	MaxOutputTokens    *int64                `json:"max_output_tokens,omitempty"`
+	Temperature        *float64              `json:"temperature,omitempty"`
+	TopP               *float64              `json:"top_p,omitempty"`
+	Reasoning          *ResponsesReasoning   `json:"reasoning,omitempty"`
+	Include            []string              `json:"include,omitempty"`
+	TopLogprobs        *int64                `json:"top_logprobs,omitempty"`
 }
 
 type ResponsesInput struct {
@@ -893,6 +1015,8 @@ type ResponsesResponse struct {
 	Status    *string         `json:"status,omitempty"`
 	Usage     *ResponsesUsage `json:"usage,omitempty"`
 	Error     *ResponsesError `json:"error,omitempty"`
+
+	PreviousResponseID *string `json:"previous_response_id,omitempty"`
 }
 
 type ResponsesUsage struct {
@@ -1411,3 +1535,11 @@ func convertUsageToResponses(usage *model.Usage) *ResponsesUsage {
 func generateItemID() string {
 	return fmt.Sprintf("item_%s", lo.RandomString(16, lo.AlphanumericCharset))
 }
+
+func generateMessageID() string {
+	return fmt.Sprintf("msg_%s", lo.RandomString(24, lo.AlphanumericCharset))
+}
+
+func generateResponseID() string {
+	return fmt.Sprintf("resp_%s", lo.RandomString(32, lo.AlphanumericCharset))
+}
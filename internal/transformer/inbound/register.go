@@ -1,6 +1,7 @@
 package inbound
 
 import (
+	"github.com/bestruirui/octopus/internal/transformer/inbound/aisdk"
 	"github.com/bestruirui/octopus/internal/transformer/inbound/anthropic"
 	"github.com/bestruirui/octopus/internal/transformer/inbound/openai"
 	"github.com/bestruirui/octopus/internal/transformer/model"
@@ -14,6 +15,8 @@ const (
 	InboundTypeAnthropic
 	InboundTypeGemini
 	InboundTypeOpenAIEmbedding
+	InboundTypeAiSDKDataStream
+	InboundTypeAiSDKText
 
 	// Compatibility alias for legacy naming
 	InboundTypeOpenAI = InboundTypeOpenAIChat
@@ -24,6 +27,8 @@ var inboundFactories = map[InboundType]func() model.Inbound{
 	InboundTypeOpenAIResponse:  func() model.Inbound { return &openai.ResponseInbound{} },
 	InboundTypeOpenAIEmbedding: func() model.Inbound { return &openai.EmbeddingInbound{} },
 	InboundTypeAnthropic:       func() model.Inbound { return &anthropic.MessagesInbound{} },
+	InboundTypeAiSDKDataStream: func() model.Inbound { return &aisdk.DataStreamInbound{} },
+	InboundTypeAiSDKText:       func() model.Inbound { return &aisdk.DataStreamInbound{TextOnly: true} },
 }
 
 func Get(inboundType InboundType) model.Inbound {
@@ -0,0 +1,191 @@
+// Package media 抓取远程图片和文件并转为内联数据，供只接受内联数据的上游使用
+package media
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxRedirects = 5
+	fetchTimeout = 30 * time.Second
+
+	// DefaultMaxBytes 默认单个媒体文件大小上限
+	DefaultMaxBytes = 20 << 20
+
+	cacheTTL      = 10 * time.Minute
+	cacheMaxBytes = 128 << 20
+)
+
+// Options 抓取选项
+type Options struct {
+	// MaxBytes 单个媒体文件大小上限，<=0 使用 DefaultMaxBytes
+	MaxBytes int64
+	// MaxDimension 图片最长边超过该值时等比缩小，0 表示不缩放
+	MaxDimension int
+}
+
+// Media 抓取到的媒体数据
+type Media struct {
+	MimeType string
+	Data     []byte
+}
+
+// Base64 返回 base64 编码的数据
+func (m *Media) Base64() string {
+	return base64.StdEncoding.EncodeToString(m.Data)
+}
+
+// DataURL 返回 data:<mime>;base64,<data> 形式的地址
+func (m *Media) DataURL() string {
+	return "data:" + m.MimeType + ";base64," + m.Base64()
+}
+
+// Fetch 抓取远程媒体，结果按 URL 哈希缓存
+// client 应为渠道的 http 客户端，以使用渠道的代理设置；内网地址会被拒绝
+func Fetch(ctx context.Context, client *http.Client, rawURL string, opts Options) (*Media, error) {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	key := cacheKey(rawURL, opts)
+	if m, ok := defaultCache.get(key); ok {
+		return m, nil
+	}
+
+	u, err := ValidateURL(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create media request: %w", err)
+	}
+	resp, err := safeClient(client).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return nil, fmt.Errorf("%w: %s returned status %d", ErrInvalidMedia, rawURL, resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to fetch media: status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > opts.MaxBytes {
+		return nil, fmt.Errorf("%w: size %d exceeds limit %d bytes", ErrInvalidMedia, resp.ContentLength, opts.MaxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, opts.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media: %w", err)
+	}
+	if int64(len(data)) > opts.MaxBytes {
+		return nil, fmt.Errorf("%w: size exceeds limit %d bytes", ErrInvalidMedia, opts.MaxBytes)
+	}
+
+	m := &Media{MimeType: detectMimeType(resp.Header.Get("Content-Type"), u.Path, data), Data: data}
+	if opts.MaxDimension > 0 && strings.HasPrefix(m.MimeType, "image/") {
+		if resized, ok := downscale(m, opts.MaxDimension); ok {
+			m = resized
+		}
+	}
+
+	defaultCache.set(key, m)
+	return m, nil
+}
+
+// detectMimeType 优先使用响应头，缺失或为通用类型时根据内容嗅探
+func detectMimeType(header, path string, data []byte) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err == nil && mediaType != "" && mediaType != "application/octet-stream" && mediaType != "binary/octet-stream" {
+		return mediaType
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if sniffed != "application/octet-stream" {
+		return sniffed
+	}
+	if idx := strings.LastIndexByte(path, '.'); idx >= 0 {
+		if byExt, _, err := mime.ParseMediaType(mime.TypeByExtension(path[idx:])); err == nil && byExt != "" {
+			return byExt
+		}
+	}
+	return "application/octet-stream"
+}
+
+func cacheKey(rawURL string, opts Options) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:]) + ":" + strconv.FormatInt(opts.MaxBytes, 10) + ":" + strconv.Itoa(opts.MaxDimension)
+}
+
+// mediaCache 按总大小淘汰最久未使用的条目，条目超过 TTL 后失效
+type mediaCache struct {
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	order    *list.List
+	size     int
+	maxBytes int
+}
+
+type cacheEntry struct {
+	key     string
+	media   *Media
+	expires time.Time
+}
+
+var defaultCache = newMediaCache(cacheMaxBytes)
+
+func newMediaCache(maxBytes int) *mediaCache {
+	return &mediaCache{items: make(map[string]*list.Element), order: list.New(), maxBytes: maxBytes}
+}
+
+func (c *mediaCache) get(key string) (*Media, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.media, true
+}
+
+func (c *mediaCache) set(key string, m *Media) {
+	if len(m.Data) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, media: m, expires: time.Now().Add(cacheTTL)})
+	c.size += len(m.Data)
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *mediaCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*cacheEntry)
+	delete(c.items, entry.key)
+	c.size -= len(entry.media.Data)
+}
@@ -0,0 +1,78 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	_ "image/gif"
+)
+
+// downscale 将最长边超过 maxDimension 的图片等比缩小 (区域平均采样)
+// 不支持解码的格式 (如 webp) 或无需缩放时返回 false
+func downscale(m *Media, maxDimension int) (*Media, bool) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(m.Data))
+	if err != nil || max(cfg.Width, cfg.Height) <= maxDimension {
+		return nil, false
+	}
+	src, _, err := image.Decode(bytes.NewReader(m.Data))
+	if err != nil {
+		return nil, false
+	}
+
+	width, height := cfg.Width, cfg.Height
+	if width >= height {
+		height = max(1, height*maxDimension/width)
+		width = maxDimension
+	} else {
+		width = max(1, width*maxDimension/height)
+		height = maxDimension
+	}
+	dst := resizeArea(src, width, height)
+
+	var buf bytes.Buffer
+	mimeType := "image/jpeg"
+	if format == "png" || format == "gif" {
+		// 保留透明通道
+		mimeType = "image/png"
+		err = png.Encode(&buf, dst)
+	} else {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, false
+	}
+	return &Media{MimeType: mimeType, Data: buf.Bytes()}, true
+}
+
+// resizeArea 每个目标像素取其覆盖的源像素区域的平均值
+func resizeArea(src image.Image, width, height int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcH/height
+		y1 := max(bounds.Min.Y+(y+1)*srcH/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcW/width
+			x1 := max(bounds.Min.X+(x+1)*srcW/width, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,80 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+)
+
+// IsRemoteURL 判断是否为需要抓取的 http/https 地址
+func IsRemoteURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// HasRemoteMedia 请求中是否包含远程图片或文件
+func HasRemoteMedia(req *model.InternalLLMRequest) bool {
+	for _, msg := range req.Messages {
+		for _, part := range msg.Content.MultipleContent {
+			if remotePartURL(part) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InlineRequest 返回将远程图片和文件替换为 data URL 的请求副本，不修改原请求
+func InlineRequest(ctx context.Context, client *http.Client, req *model.InternalLLMRequest, opts Options) (*model.InternalLLMRequest, error) {
+	if !HasRemoteMedia(req) {
+		return req, nil
+	}
+
+	clone := *req
+	clone.Messages = make([]model.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		clone.Messages[i] = msg
+		if len(msg.Content.MultipleContent) == 0 {
+			continue
+		}
+		parts := make([]model.MessageContentPart, len(msg.Content.MultipleContent))
+		for j, part := range msg.Content.MultipleContent {
+			parts[j] = part
+			rawURL := remotePartURL(part)
+			if rawURL == "" {
+				continue
+			}
+			m, err := Fetch(ctx, client, rawURL, opts)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case part.ImageURL != nil:
+				if !strings.HasPrefix(m.MimeType, "image/") {
+					return nil, fmt.Errorf("%w: %s is not an image (%s)", ErrInvalidMedia, rawURL, m.MimeType)
+				}
+				imageURL := *part.ImageURL
+				imageURL.URL = m.DataURL()
+				parts[j].ImageURL = &imageURL
+			case part.File != nil:
+				file := *part.File
+				file.FileData = m.DataURL()
+				parts[j].File = &file
+			}
+		}
+		clone.Messages[i].Content.MultipleContent = parts
+	}
+	return &clone, nil
+}
+
+func remotePartURL(part model.MessageContentPart) string {
+	switch {
+	case part.Type == "image_url" && part.ImageURL != nil && IsRemoteURL(part.ImageURL.URL):
+		return part.ImageURL.URL
+	case part.Type == "file" && part.File != nil && IsRemoteURL(part.File.FileData):
+		return part.File.FileData
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+)
+
+var (
+	// ErrInvalidMedia 媒体地址或内容不合法，换渠道重试也无法成功
+	ErrInvalidMedia = errors.New("invalid media")
+	// ErrBlockedAddress 目标地址位于内网或保留网段
+	ErrBlockedAddress = fmt.Errorf("%w: url resolves to a blocked address", ErrInvalidMedia)
+)
+
+// blockedPrefixes 标准库 IsPrivate/IsLoopback 等未覆盖的保留网段
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"),   // 运营商级 NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF 协议分配
+	netip.MustParsePrefix("198.18.0.0/15"),   // 基准测试
+	netip.MustParsePrefix("240.0.0.0/4"),     // 保留
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // 本地 NAT64
+	netip.MustParsePrefix("2002::/16"),       // 6to4，可映射到任意 IPv4
+	netip.MustParsePrefix("fec0::/10"),       // 已废弃的站点本地地址
+	netip.MustParsePrefix("100::/64"),        // 丢弃前缀
+	netip.MustParsePrefix("2001:db8::/32"),   // 文档
+	netip.MustParsePrefix("2001::/32"),       // Teredo
+	netip.MustParsePrefix("::/128"),          // 未指定
+	netip.MustParsePrefix("ff00::/8"),        // 组播
+	netip.MustParsePrefix("169.254.0.0/16"),  // 链路本地 (含云厂商元数据地址)
+	netip.MustParsePrefix("fe80::/10"),       // 链路本地
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 中继
+	netip.MustParsePrefix("203.0.113.0/24"),  // 文档
+	netip.MustParsePrefix("198.51.100.0/24"), // 文档
+	netip.MustParsePrefix("192.0.2.0/24"),    // 文档
+}
+
+// IsBlockedIP 判断地址是否禁止访问：回环、内网、链路本地、组播及其他保留网段
+func IsBlockedIP(ip netip.Addr) bool {
+	if !ip.IsValid() {
+		return true
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateURL 校验媒体地址：仅允许 http/https，且主机解析出的所有地址都不在禁止网段
+func ValidateURL(ctx context.Context, rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMedia, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: unsupported url scheme %s", ErrInvalidMedia, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: url has no host", ErrInvalidMedia)
+	}
+	if err := checkHost(ctx, u.Hostname()); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func checkHost(ctx context.Context, host string) error {
+	_, err := resolveHost(ctx, host)
+	return err
+}
+
+// resolveHost 解析主机并校验所有地址，返回第一个地址
+func resolveHost(ctx context.Context, host string) (netip.Addr, error) {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if IsBlockedIP(ip) {
+			return netip.Addr{}, fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+		return ip, nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to resolve media host: %w", err)
+	}
+	if len(addrs) == 0 {
+		return netip.Addr{}, fmt.Errorf("failed to resolve media host: %s", host)
+	}
+	for _, addr := range addrs {
+		if IsBlockedIP(addr) {
+			return netip.Addr{}, fmt.Errorf("%w: %s (%s)", ErrBlockedAddress, host, addr)
+		}
+	}
+	return addrs[0], nil
+}
+
+// safeClient 基于渠道客户端构造抓取用客户端：
+// 直连或 socks 代理时连接校验后的 IP，防止 DNS 重绑定；
+// http 代理由代理解析目标，只能依赖请求前及重定向时的地址校验
+func safeClient(base *http.Client) *http.Client {
+	if base == nil {
+		base = http.DefaultClient
+	}
+	client := *base
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		_, err := ValidateURL(req.Context(), req.URL.String())
+		return err
+	}
+
+	transport, ok := base.Transport.(*http.Transport)
+	if base.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok || transport.Proxy != nil {
+		return &client
+	}
+	cloned := transport.Clone()
+	// 每次抓取使用独立的 Transport，不保留空闲连接
+	cloned.DisableKeepAlives = true
+	dial := cloned.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	cloned.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ip, err := resolveHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		return dial(ctx, network, net.JoinHostPort(ip.String(), port))
+	}
+	client.Transport = cloned
+	return &client
+}
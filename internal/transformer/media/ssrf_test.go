@@ -0,0 +1,39 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestIsBlockedIP(t *testing.T) {
+	blocked := []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "::ffff:169.254.169.254"}
+	for _, s := range blocked {
+		if !IsBlockedIP(netip.MustParseAddr(s)) {
+			t.Errorf("expected %s to be blocked", s)
+		}
+	}
+	allowed := []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"}
+	for _, s := range allowed {
+		if IsBlockedIP(netip.MustParseAddr(s)) {
+			t.Errorf("expected %s to be allowed", s)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	for _, rawURL := range []string{
+		"http://127.0.0.1/a.png",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]:8080/a.png",
+		"file:///etc/passwd",
+		"ftp://example.com/a.png",
+		"http:///a.png",
+	} {
+		_, err := ValidateURL(context.Background(), rawURL)
+		if !errors.Is(err, ErrInvalidMedia) {
+			t.Errorf("ValidateURL(%q) = %v, want ErrInvalidMedia", rawURL, err)
+		}
+	}
+}
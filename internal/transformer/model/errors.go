@@ -0,0 +1,30 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NonRetryableError 表示不应切换渠道重试的上游错误（如内容审核拦截），
+// 换一个渠道大概率得到同样的结果，直接返回给客户端
+type NonRetryableError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *NonRetryableError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Message)
+	}
+	return e.Message
+}
+
+// AsNonRetryable 判断错误链中是否包含 NonRetryableError
+func AsNonRetryable(err error) (*NonRetryableError, bool) {
+	var nre *NonRetryableError
+	if errors.As(err, &nre) {
+		return nre, true
+	}
+	return nil, false
+}
@@ -111,11 +111,12 @@ type GeminiGenerationConfig struct {
 
 // GeminiSchema for structured output
 type GeminiSchema struct {
-	Type       string                   `json:"type"`
-	Properties map[string]*GeminiSchema `json:"properties,omitempty"`
-	Items      *GeminiSchema            `json:"items,omitempty"`
-	Required   []string                 `json:"required,omitempty"`
-	Enum       []string                 `json:"enum,omitempty"`
+	Type        string                   `json:"type"`
+	Description string                   `json:"description,omitempty"`
+	Properties  map[string]*GeminiSchema `json:"properties,omitempty"`
+	Items       *GeminiSchema            `json:"items,omitempty"`
+	Required    []string                 `json:"required,omitempty"`
+	Enum        []string                 `json:"enum,omitempty"`
 }
 
 // GeminiThinkingConfig is the thinking features configuration
@@ -176,4 +177,16 @@ type GeminiUsageMetadata struct {
 
 	// ThoughtsTokenCount is the number of tokens in the model's thoughts
 	ThoughtsTokenCount int `json:"thoughtsTokenCount,omitempty"`
+
+	// PromptTokensDetails breaks down the prompt tokens by modality
+	PromptTokensDetails []GeminiModalityTokenCount `json:"promptTokensDetails,omitempty"`
+
+	// CandidatesTokensDetails breaks down the candidates tokens by modality
+	CandidatesTokensDetails []GeminiModalityTokenCount `json:"candidatesTokensDetails,omitempty"`
+}
+
+// GeminiModalityTokenCount is the token count of a single modality (TEXT, IMAGE, AUDIO, VIDEO, DOCUMENT)
+type GeminiModalityTokenCount struct {
+	Modality   string `json:"modality"`
+	TokenCount int    `json:"tokenCount"`
 }
@@ -21,6 +21,11 @@ type Inbound interface {
 	GetInternalResponse(ctx context.Context) (*InternalLLMResponse, error)
 }
 
+// InboundResponseHeader 为可选接口，入站适配器可覆盖写回客户端的响应头 (如 Content-Type)
+type InboundResponseHeader interface {
+	ResponseHeaders(stream bool) map[string]string
+}
+
 type Outbound interface {
 	// 将入站内部通用请求转为出站对应的请求格式
 	TransformRequest(ctx context.Context, request *InternalLLMRequest, baseUrl, key string) (*http.Request, error)
@@ -32,6 +37,18 @@ type Outbound interface {
 	TransformStream(ctx context.Context, eventData []byte) (*InternalLLMResponse, error)
 }
 
+// OutboundErrorHandler 为可选接口，出站适配器可将上游非 2xx 响应转为特定的错误类型，
+// 返回 nil 时使用默认的错误处理
+type OutboundErrorHandler interface {
+	TransformError(ctx context.Context, statusCode int, body []byte) error
+}
+
+// OutboundHTTPClientSetter 为可选接口，出站适配器转换请求时需要自行访问网络 (如换取 access token) 时实现，
+// 中继传入渠道的 http 客户端，使这些请求与上游请求使用相同的代理
+type OutboundHTTPClientSetter interface {
+	SetHTTPClient(client *http.Client)
+}
+
 /*
 请求流程
 非流式
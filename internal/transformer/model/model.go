@@ -77,8 +77,8 @@ type InternalLLMRequest struct {
 	// How many chat completion choices to generate for each input message. Note that
 	// you will be charged based on the number of generated tokens across all of the
 	// choices. Keep `n` as `1` to minimize costs.
-	// NOTE: Not supported, always 1.
-	// N *int64 `json:"n,omitempty"`
+	// 上游不支持 n 时由 relay 拆分为 n 个并发请求后合并
+	N *int64 `json:"n,omitempty"`
 
 	// Number between -2.0 and 2.0. Positive values penalize new tokens based on
 	// whether they appear in the text so far, increasing the model's likelihood to
@@ -235,6 +235,9 @@ type InternalLLMRequest struct {
 	Query url.Values `json:"-"`
 }
 
+// MaxChoices 与 OpenAI 一致，n 最大为 128
+const MaxChoices = 128
+
 func (r *InternalLLMRequest) Validate() error {
 	if r.Model == "" {
 		return errors.New("model is required")
@@ -263,10 +266,21 @@ func (r *InternalLLMRequest) Validate() error {
 	if isChatRequest && len(r.Messages) == 0 {
 		return errors.New("messages are required")
 	}
+	if r.N != nil && (*r.N < 1 || *r.N > MaxChoices) {
+		return fmt.Errorf("n must be between 1 and %d", MaxChoices)
+	}
 
 	return nil
 }
 
+// ChoiceCount returns the number of choices requested via `n`, at least 1.
+func (r *InternalLLMRequest) ChoiceCount() int {
+	if r.N == nil || *r.N < 1 {
+		return 1
+	}
+	return int(*r.N)
+}
+
 // IsEmbeddingRequest returns true if this is an embedding request.
 func (r *InternalLLMRequest) IsEmbeddingRequest() bool {
 	return r.EmbeddingInput != nil
@@ -633,6 +647,8 @@ type Usage struct {
 	// Anthropic specific fields
 	AnthropicUsage           bool  `json:"-"`
 	CacheCreationInputTokens int64 `json:"-"`
+	// CacheCreation1hInputTokens 1 小时缓存写入的 token 数，包含在 CacheCreationInputTokens 中
+	CacheCreation1hInputTokens int64 `json:"-"`
 }
 
 func (u *Usage) GetCompletionTokens() *int64 {
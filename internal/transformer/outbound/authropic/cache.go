@@ -0,0 +1,183 @@
+package authropic
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/utils/tokenizer"
+)
+
+const (
+	// MaxCacheBreakpoints Anthropic 单个请求最多允许 4 个缓存断点
+	MaxCacheBreakpoints = 4
+	// DefaultCacheMinTokens 低于该长度的前缀无法被缓存
+	DefaultCacheMinTokens = 1024
+)
+
+// ApplyCacheBreakpoints 返回自动插入缓存断点后的请求副本，不修改原请求
+// 缓存前缀按 tools → system → messages 的顺序累计，断点依次尝试放在工具定义末尾、系统提示词末尾、
+// 最后一条消息和上一轮用户消息上，断点之前的前缀不少于 minTokens 时才放置
+// 客户端已设置的断点保留不变，并计入 4 个的上限
+func ApplyCacheBreakpoints(request *model.InternalLLMRequest, minTokens int, ttl string) *model.InternalLLMRequest {
+	if minTokens <= 0 {
+		minTokens = DefaultCacheMinTokens
+	}
+	budget := MaxCacheBreakpoints - countCacheBreakpoints(request)
+	if budget <= 0 || len(request.Messages) == 0 {
+		return request
+	}
+
+	clone := *request
+	clone.Tools = slices.Clone(request.Tools)
+	clone.Messages = slices.Clone(request.Messages)
+	cacheControl := func() *model.CacheControl {
+		return &model.CacheControl{Type: "ephemeral", TTL: ttl}
+	}
+
+	// 工具定义
+	prefix := 0
+	lastTool := -1
+	toolMarked := false
+	for i, tool := range clone.Tools {
+		if tool.Type != "function" {
+			continue
+		}
+		lastTool = i
+		toolMarked = toolMarked || tool.CacheControl != nil
+		if data, err := json.Marshal(tool.Function); err == nil {
+			prefix += tokenizer.CountTokens(string(data), request.Model)
+		}
+	}
+	if lastTool >= 0 && !toolMarked && prefix >= minTokens {
+		clone.Tools[lastTool].CacheControl = cacheControl()
+		budget--
+	}
+
+	// 系统提示词
+	lastSystem := -1
+	systemMarked := false
+	for i, msg := range clone.Messages {
+		if msg.Role != "system" {
+			continue
+		}
+		lastSystem = i
+		systemMarked = systemMarked || msg.CacheControl != nil
+		prefix += messageTokens(msg, request.Model)
+	}
+	if budget > 0 && lastSystem >= 0 && !systemMarked && prefix >= minTokens {
+		clone.Messages[lastSystem].CacheControl = cacheControl()
+		budget--
+	}
+
+	// 对话轮次：最后一条消息供下一轮命中，上一轮用户消息用于命中本轮之前写入的缓存
+	prefixAt := make([]int, len(clone.Messages))
+	for i, msg := range clone.Messages {
+		if msg.Role != "system" {
+			prefix += messageTokens(msg, request.Model)
+		}
+		prefixAt[i] = prefix
+	}
+	candidates := []int{len(clone.Messages) - 1}
+	for i := len(clone.Messages) - 2; i >= 0; i-- {
+		if clone.Messages[i].Role == "user" {
+			candidates = append(candidates, i)
+			break
+		}
+	}
+	for _, i := range candidates {
+		if budget <= 0 {
+			break
+		}
+		msg := &clone.Messages[i]
+		if msg.Role == "system" || hasMessageBreakpoint(*msg) || prefixAt[i] < minTokens {
+			continue
+		}
+		if setMessageBreakpoint(msg, cacheControl()) {
+			budget--
+		}
+	}
+	return &clone
+}
+
+// countCacheBreakpoints 统计客户端已设置的断点数
+func countCacheBreakpoints(request *model.InternalLLMRequest) int {
+	count := 0
+	for _, tool := range request.Tools {
+		if tool.CacheControl != nil {
+			count++
+		}
+	}
+	for _, msg := range request.Messages {
+		if msg.CacheControl != nil {
+			count++
+		}
+		for _, part := range msg.Content.MultipleContent {
+			if part.CacheControl != nil {
+				count++
+			}
+		}
+		for _, toolCall := range msg.ToolCalls {
+			if toolCall.CacheControl != nil {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func hasMessageBreakpoint(msg model.Message) bool {
+	if msg.CacheControl != nil {
+		return true
+	}
+	for _, part := range msg.Content.MultipleContent {
+		if part.CacheControl != nil {
+			return true
+		}
+	}
+	for _, toolCall := range msg.ToolCalls {
+		if toolCall.CacheControl != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// setMessageBreakpoint 将断点放在消息转换后的最后一个内容块上，与 convertMessages 的转换方式对应
+func setMessageBreakpoint(msg *model.Message, cacheControl *model.CacheControl) bool {
+	switch {
+	case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+		msg.ToolCalls = slices.Clone(msg.ToolCalls)
+		msg.ToolCalls[len(msg.ToolCalls)-1].CacheControl = cacheControl
+		return true
+	case msg.Role == "tool" || msg.Content.Content != nil:
+		msg.CacheControl = cacheControl
+		return true
+	}
+	for i := len(msg.Content.MultipleContent) - 1; i >= 0; i-- {
+		part := msg.Content.MultipleContent[i]
+		if (part.Type == "text" && part.Text != nil) || (part.Type == "image_url" && part.ImageURL != nil) {
+			msg.Content.MultipleContent = slices.Clone(msg.Content.MultipleContent)
+			msg.Content.MultipleContent[i].CacheControl = cacheControl
+			return true
+		}
+	}
+	return false
+}
+
+// messageTokens 估算消息的 token 数，图片等非文本内容不计入
+func messageTokens(msg model.Message, modelName string) int {
+	text := ""
+	if msg.Content.Content != nil {
+		text += *msg.Content.Content
+	}
+	for _, part := range msg.Content.MultipleContent {
+		if part.Text != nil {
+			text += *part.Text
+		}
+	}
+	for _, toolCall := range msg.ToolCalls {
+		text += toolCall.Function.Name + toolCall.Function.Arguments
+	}
+	return tokenizer.CountTokens(text, modelName)
+}
@@ -0,0 +1,85 @@
+package authropic
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/samber/lo"
+)
+
+func cacheTestRequest() *model.InternalLLMRequest {
+	long := strings.Repeat("octopus relays requests to many upstream providers. ", 200)
+	return &model.InternalLLMRequest{
+		Model: "claude-sonnet-4",
+		Tools: []model.Tool{
+			{Type: "function", Function: model.Function{Name: "search", Description: long}},
+		},
+		Messages: []model.Message{
+			{Role: "system", Content: model.MessageContent{Content: lo.ToPtr("You are helpful.")}},
+			{Role: "user", Content: model.MessageContent{Content: lo.ToPtr("first question")}},
+			{Role: "assistant", Content: model.MessageContent{Content: lo.ToPtr("first answer")}},
+			{Role: "user", Content: model.MessageContent{MultipleContent: []model.MessageContentPart{
+				{Type: "text", Text: lo.ToPtr("second question")},
+			}}},
+		},
+	}
+}
+
+func TestApplyCacheBreakpoints(t *testing.T) {
+	req := cacheTestRequest()
+	got := ApplyCacheBreakpoints(req, 1024, "")
+
+	if got.Tools[0].CacheControl == nil {
+		t.Error("tools breakpoint missing")
+	}
+	if got.Messages[0].CacheControl == nil {
+		t.Error("system breakpoint missing")
+	}
+	if got.Messages[1].CacheControl == nil {
+		t.Error("previous user turn breakpoint missing")
+	}
+	if got.Messages[3].Content.MultipleContent[0].CacheControl == nil {
+		t.Error("last message breakpoint missing")
+	}
+	if n := countCacheBreakpoints(got); n != MaxCacheBreakpoints {
+		t.Errorf("breakpoints = %d, want %d", n, MaxCacheBreakpoints)
+	}
+	// 原请求不应被修改，切换到其他渠道时仍是客户端的原始内容
+	if n := countCacheBreakpoints(req); n != 0 {
+		t.Errorf("original request modified, breakpoints = %d", n)
+	}
+
+	// 断点最终出现在发往 Anthropic 的请求体中
+	body, _ := json.Marshal(ConvertToAnthropicRequest(got))
+	if c := strings.Count(string(body), `"cache_control"`); c != MaxCacheBreakpoints {
+		t.Errorf("cache_control in body = %d, want %d", c, MaxCacheBreakpoints)
+	}
+}
+
+func TestApplyCacheBreakpointsRespectsClient(t *testing.T) {
+	req := cacheTestRequest()
+	req.Messages[2].CacheControl = &model.CacheControl{Type: "ephemeral", TTL: "1h"}
+	got := ApplyCacheBreakpoints(req, 1024, "")
+
+	if n := countCacheBreakpoints(got); n != MaxCacheBreakpoints {
+		t.Errorf("breakpoints = %d, want %d", n, MaxCacheBreakpoints)
+	}
+	if got.Messages[2].CacheControl.TTL != "1h" {
+		t.Error("client breakpoint changed")
+	}
+	// 预算用尽后不再为上一轮用户消息放置断点
+	if got.Messages[1].CacheControl != nil {
+		t.Error("breakpoint placed beyond the limit")
+	}
+}
+
+func TestApplyCacheBreakpointsBelowThreshold(t *testing.T) {
+	req := cacheTestRequest()
+	req.Tools = nil
+	got := ApplyCacheBreakpoints(req, 1024, "")
+	if n := countCacheBreakpoints(got); n != 0 {
+		t.Errorf("breakpoints = %d, want 0 for short prompt", n)
+	}
+}
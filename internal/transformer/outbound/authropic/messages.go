@@ -33,7 +33,7 @@ func (o *MessageOutbound) TransformRequest(ctx context.Context, request *model.I
 	}
 
 	// Convert to Anthropic request format
-	anthropicReq := convertToAnthropicRequest(request)
+	anthropicReq := ConvertToAnthropicRequest(request)
 
 	body, err := json.Marshal(anthropicReq)
 	if err != nil {
@@ -247,6 +247,14 @@ func (o *MessageOutbound) TransformStream(ctx context.Context, eventData []byte)
 			usage := convertAnthropicUsage(streamEvent.Usage)
 			if o.streamUsage != nil {
 				usage.PromptTokens = o.streamUsage.PromptTokens
+				// message_delta 不一定带缓存用量，沿用 message_start 中的值
+				if usage.CacheCreationInputTokens == 0 {
+					usage.CacheCreationInputTokens = o.streamUsage.CacheCreationInputTokens
+					usage.CacheCreation1hInputTokens = o.streamUsage.CacheCreation1hInputTokens
+				}
+				if usage.PromptTokensDetails == nil {
+					usage.PromptTokensDetails = o.streamUsage.PromptTokensDetails
+				}
 				usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 			}
 			o.streamUsage = usage
@@ -278,8 +286,8 @@ func (o *MessageOutbound) TransformStream(ctx context.Context, eventData []byte)
 	return resp, nil
 }
 
-// convertToAnthropicRequest converts internal LLM request to Anthropic format
-func convertToAnthropicRequest(req *model.InternalLLMRequest) *anthropicModel.MessageRequest {
+// ConvertToAnthropicRequest converts internal LLM request to Anthropic format
+func ConvertToAnthropicRequest(req *model.InternalLLMRequest) *anthropicModel.MessageRequest {
 	result := &anthropicModel.MessageRequest{
 		Model:       req.Model,
 		Temperature: req.Temperature,
@@ -507,7 +515,7 @@ func convertAssistantWithToolCalls(msg model.Message) []anthropicModel.MessagePa
 	var blocks []anthropicModel.MessageContentBlock
 
 	// Add thinking block if present
-	if msg.ReasoningContent != nil && *msg.ReasoningContent != "" {
+	if hasThinkingContent(msg) {
 		blocks = append(blocks, anthropicModel.MessageContentBlock{
 			Type:      "thinking",
 			Thinking:  msg.ReasoningContent,
@@ -578,14 +586,17 @@ func buildMessageContent(msg model.Message) anthropicModel.MessageContent {
 	return anthropicModel.MessageContent{}
 }
 
+// hasThinkingContent 仅带签名的推理内容可以作为 thinking 块回传，
+// 其他渠道 (如 DeepSeek reasoning_content) 产生的推理内容没有签名，会被 Anthropic 拒绝，故障转移时直接丢弃
 func hasThinkingContent(msg model.Message) bool {
-	return msg.ReasoningContent != nil && *msg.ReasoningContent != ""
+	return msg.ReasoningContent != nil && *msg.ReasoningContent != "" &&
+		msg.ReasoningSignature != nil && *msg.ReasoningSignature != ""
 }
 
 func buildMultipleContentWithThinking(msg model.Message) anthropicModel.MessageContent {
 	var blocks []anthropicModel.MessageContentBlock
 
-	if msg.ReasoningContent != nil && *msg.ReasoningContent != "" {
+	if hasThinkingContent(msg) {
 		blocks = append(blocks, anthropicModel.MessageContentBlock{
 			Type:      "thinking",
 			Thinking:  msg.ReasoningContent,
@@ -851,6 +862,9 @@ func convertAnthropicUsage(usage *anthropicModel.Usage) *model.Usage {
 		AnthropicUsage:           true,
 	}
 
+	if usage.CacheCreation != nil {
+		result.CacheCreation1hInputTokens = usage.CacheCreation.Ephemeral1hInputTokens
+	}
 	if usage.CacheReadInputTokens > 0 {
 		result.PromptTokensDetails = &model.PromptTokensDetails{
 			CachedTokens: usage.CacheReadInputTokens,
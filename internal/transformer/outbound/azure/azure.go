@@ -0,0 +1,95 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+)
+
+const (
+	// defaultAPIVersion 为 Chat / Embeddings 使用的 GA 版本
+	defaultAPIVersion = "2024-10-21"
+	// defaultResponsesAPIVersion 为 Responses API 使用的版本
+	defaultResponsesAPIVersion = "2025-04-01-preview"
+)
+
+// buildURL 构建 Azure OpenAI 请求地址
+// base url 形如 https://{resource}.openai.azure.com，可通过 api-version 查询参数覆盖默认版本
+// deployment 为空时构建非部署路径（如 /openai/responses）
+func buildURL(baseUrl, deployment, endpoint, defaultVersion string) (*url.URL, error) {
+	parsedUrl, err := url.Parse(strings.TrimSuffix(baseUrl, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base url: %w", err)
+	}
+
+	apiVersion := parsedUrl.Query().Get("api-version")
+	if apiVersion == "" {
+		apiVersion = defaultVersion
+	}
+
+	path := strings.TrimSuffix(parsedUrl.Path, "/")
+	if !strings.HasSuffix(path, "/openai") {
+		path += "/openai"
+	}
+	rawPath := (&url.URL{Path: path}).EscapedPath()
+	if deployment != "" {
+		path += "/deployments/" + deployment
+		rawPath += "/deployments/" + url.PathEscape(deployment)
+	}
+	// RawPath 保留部署名中转义的 "/"
+	parsedUrl.Path = path + endpoint
+	parsedUrl.RawPath = rawPath + endpoint
+
+	q := url.Values{}
+	q.Set("api-version", apiVersion)
+	parsedUrl.RawQuery = q.Encode()
+	return parsedUrl, nil
+}
+
+// setAuthHeader Azure 使用 api-key 头而不是 Bearer 认证
+func setAuthHeader(req *http.Request, key string) {
+	req.Header.Del("Authorization")
+	req.Header.Set("api-key", key)
+}
+
+// errorResponse Azure 错误响应格式
+type errorResponse struct {
+	Error struct {
+		Code       string `json:"code"`
+		Message    string `json:"message"`
+		Type       string `json:"type"`
+		InnerError *struct {
+			Code                string          `json:"code"`
+			ContentFilterResult json.RawMessage `json:"content_filter_result,omitempty"`
+		} `json:"innererror,omitempty"`
+	} `json:"error"`
+}
+
+// transformError 将内容审核拦截转为不可重试错误，其他错误交给默认处理
+func transformError(ctx context.Context, statusCode int, body []byte) error {
+	var errResp errorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return nil
+	}
+	isContentFilter := errResp.Error.Code == "content_filter"
+	if errResp.Error.InnerError != nil && errResp.Error.InnerError.Code == "ResponsibleAIPolicyViolation" {
+		isContentFilter = true
+	}
+	if !isContentFilter {
+		return nil
+	}
+	message := errResp.Error.Message
+	if message == "" {
+		message = "the request was filtered by azure content management policy"
+	}
+	return &model.NonRetryableError{
+		StatusCode: statusCode,
+		Code:       "content_filter",
+		Message:    message,
+	}
+}
@@ -0,0 +1,141 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/samber/lo"
+)
+
+func TestBuildURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseUrl    string
+		deployment string
+		endpoint   string
+		version    string
+		want       string
+	}{
+		{
+			name:       "deployment",
+			baseUrl:    "https://res.openai.azure.com",
+			deployment: "my-gpt4o",
+			endpoint:   "/chat/completions",
+			version:    defaultAPIVersion,
+			want:       "https://res.openai.azure.com/openai/deployments/my-gpt4o/chat/completions?api-version=" + defaultAPIVersion,
+		},
+		{
+			name:       "trailing openai path",
+			baseUrl:    "https://res.openai.azure.com/openai/",
+			deployment: "emb",
+			endpoint:   "/embeddings",
+			version:    defaultAPIVersion,
+			want:       "https://res.openai.azure.com/openai/deployments/emb/embeddings?api-version=" + defaultAPIVersion,
+		},
+		{
+			name:       "api-version override",
+			baseUrl:    "https://res.openai.azure.com?api-version=2025-01-01-preview",
+			deployment: "my-gpt4o",
+			endpoint:   "/chat/completions",
+			version:    defaultAPIVersion,
+			want:       "https://res.openai.azure.com/openai/deployments/my-gpt4o/chat/completions?api-version=2025-01-01-preview",
+		},
+		{
+			name:       "deployment is escaped",
+			baseUrl:    "https://res.openai.azure.com",
+			deployment: "a/b c",
+			endpoint:   "/chat/completions",
+			version:    defaultAPIVersion,
+			want:       "https://res.openai.azure.com/openai/deployments/a%2Fb%20c/chat/completions?api-version=" + defaultAPIVersion,
+		},
+		{
+			name:     "responses without deployment",
+			baseUrl:  "https://res.openai.azure.com",
+			endpoint: "/responses",
+			version:  defaultResponsesAPIVersion,
+			want:     "https://res.openai.azure.com/openai/responses?api-version=" + defaultResponsesAPIVersion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildURL(tt.baseUrl, tt.deployment, tt.endpoint, tt.version)
+			if err != nil {
+				t.Fatalf("buildURL: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Fatalf("url = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformError(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		nonRetry   bool
+		wantSubstr string
+	}{
+		{
+			name:       "content filter code",
+			body:       `{"error":{"code":"content_filter","message":"The response was filtered"}}`,
+			nonRetry:   true,
+			wantSubstr: "The response was filtered",
+		},
+		{
+			name:       "responsible ai inner error",
+			body:       `{"error":{"code":"BadRequest","message":"","innererror":{"code":"ResponsibleAIPolicyViolation","content_filter_result":{"hate":{"filtered":true}}}}}`,
+			nonRetry:   true,
+			wantSubstr: "content management policy",
+		},
+		{
+			name: "rate limit",
+			body: `{"error":{"code":"429","message":"Requests exceed the rate limit"}}`,
+		},
+		{
+			name: "not json",
+			body: `upstream connect error`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := transformError(context.Background(), http.StatusBadRequest, []byte(tt.body))
+			nre, ok := model.AsNonRetryable(err)
+			if ok != tt.nonRetry {
+				t.Fatalf("non-retryable = %v, want %v (err: %v)", ok, tt.nonRetry, err)
+			}
+			if !ok {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if nre.StatusCode != http.StatusBadRequest || nre.Code != "content_filter" {
+				t.Fatalf("error = %+v", nre)
+			}
+			if !strings.Contains(nre.Message, tt.wantSubstr) {
+				t.Fatalf("message = %q, want to contain %q", nre.Message, tt.wantSubstr)
+			}
+		})
+	}
+}
+
+func TestChatOutboundTransformRequest(t *testing.T) {
+	o := &ChatOutbound{}
+	req, err := o.TransformRequest(context.Background(), &model.InternalLLMRequest{
+		Model:    "my-gpt4o",
+		Messages: []model.Message{{Role: "user", Content: model.MessageContent{Content: lo.ToPtr("hi")}}},
+	}, "https://res.openai.azure.com", "azure-key")
+	if err != nil {
+		t.Fatalf("TransformRequest: %v", err)
+	}
+	if req.URL.Path != "/openai/deployments/my-gpt4o/chat/completions" {
+		t.Fatalf("path = %s", req.URL.Path)
+	}
+	if req.Header.Get("api-key") != "azure-key" || req.Header.Get("Authorization") != "" {
+		t.Fatalf("auth headers = %v", req.Header)
+	}
+}
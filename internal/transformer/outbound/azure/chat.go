@@ -0,0 +1,40 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/outbound/openai"
+)
+
+// ChatOutbound Azure OpenAI Chat Completions，请求体与 OpenAI 一致
+type ChatOutbound struct {
+	inner openai.ChatOutbound
+}
+
+func (o *ChatOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	req, err := o.inner.TransformRequest(ctx, request, baseUrl, key)
+	if err != nil {
+		return nil, err
+	}
+	parsedUrl, err := buildURL(baseUrl, request.Model, "/chat/completions", defaultAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	req.URL = parsedUrl
+	setAuthHeader(req, key)
+	return req, nil
+}
+
+func (o *ChatOutbound) TransformResponse(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
+	return o.inner.TransformResponse(ctx, response)
+}
+
+func (o *ChatOutbound) TransformStream(ctx context.Context, eventData []byte) (*model.InternalLLMResponse, error) {
+	return o.inner.TransformStream(ctx, eventData)
+}
+
+func (o *ChatOutbound) TransformError(ctx context.Context, statusCode int, body []byte) error {
+	return transformError(ctx, statusCode, body)
+}
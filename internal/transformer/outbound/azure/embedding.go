@@ -0,0 +1,40 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/outbound/openai"
+)
+
+// EmbeddingOutbound Azure OpenAI Embeddings
+type EmbeddingOutbound struct {
+	inner openai.EmbeddingOutbound
+}
+
+func (o *EmbeddingOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	req, err := o.inner.TransformRequest(ctx, request, baseUrl, key)
+	if err != nil {
+		return nil, err
+	}
+	parsedUrl, err := buildURL(baseUrl, request.Model, "/embeddings", defaultAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	req.URL = parsedUrl
+	setAuthHeader(req, key)
+	return req, nil
+}
+
+func (o *EmbeddingOutbound) TransformResponse(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
+	return o.inner.TransformResponse(ctx, response)
+}
+
+func (o *EmbeddingOutbound) TransformStream(ctx context.Context, eventData []byte) (*model.InternalLLMResponse, error) {
+	return o.inner.TransformStream(ctx, eventData)
+}
+
+func (o *EmbeddingOutbound) TransformError(ctx context.Context, statusCode int, body []byte) error {
+	return transformError(ctx, statusCode, body)
+}
@@ -0,0 +1,40 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/outbound/openai"
+)
+
+// ResponseOutbound Azure OpenAI Responses API，部署名通过请求体中的 model 指定
+type ResponseOutbound struct {
+	inner openai.ResponseOutbound
+}
+
+func (o *ResponseOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	req, err := o.inner.TransformRequest(ctx, request, baseUrl, key)
+	if err != nil {
+		return nil, err
+	}
+	parsedUrl, err := buildURL(baseUrl, "", "/responses", defaultResponsesAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	req.URL = parsedUrl
+	setAuthHeader(req, key)
+	return req, nil
+}
+
+func (o *ResponseOutbound) TransformResponse(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
+	return o.inner.TransformResponse(ctx, response)
+}
+
+func (o *ResponseOutbound) TransformStream(ctx context.Context, eventData []byte) (*model.InternalLLMResponse, error) {
+	return o.inner.TransformStream(ctx, eventData)
+}
+
+func (o *ResponseOutbound) TransformError(ctx context.Context, statusCode int, body []byte) error {
+	return transformError(ctx, statusCode, body)
+}
@@ -8,10 +8,10 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"reflect"
 	"strings"
 
 	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/schema"
 	"github.com/bestruirui/octopus/internal/utils/xurl"
 	"github.com/samber/lo"
 )
@@ -20,7 +20,7 @@ type MessagesOutbound struct{}
 
 func (o *MessagesOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
 	// Convert internal request to Gemini format
-	geminiReq := convertLLMToGeminiRequest(request)
+	geminiReq := ConvertLLMToGeminiRequest(request)
 
 	body, err := json.Marshal(geminiReq)
 	if err != nil {
@@ -140,7 +140,8 @@ func audioTypeToMimeType(format string) string {
 	}
 }
 
-func convertLLMToGeminiRequest(request *model.InternalLLMRequest) *model.GeminiGenerateContentRequest {
+// ConvertLLMToGeminiRequest converts internal LLM request to Gemini format
+func ConvertLLMToGeminiRequest(request *model.InternalLLMRequest) *model.GeminiGenerateContentRequest {
 	geminiReq := &model.GeminiGenerateContentRequest{
 		Contents: []*model.GeminiContent{},
 	}
@@ -306,7 +307,7 @@ func convertLLMToGeminiRequest(request *model.InternalLLMRequest) *model.GeminiG
 			hasConfig = true
 		case "json_schema":
 			config.ResponseMimeType = "application/json"
-			// TODO: Convert JSON schema to Gemini schema format if schema is provided
+			config.ResponseSchema = convertResponseSchema(request.ResponseFormat)
 			hasConfig = true
 		case "text":
 			config.ResponseMimeType = "text/plain"
@@ -354,7 +355,7 @@ func convertLLMToGeminiRequest(request *model.InternalLLMRequest) *model.GeminiG
 				// Best-effort: if schema can't be parsed, we still send the declaration without parameters.
 				_ = json.Unmarshal(tool.Function.Parameters, &params)
 			}
-			cleanGeminiSchema(params)
+			schema.CleanGemini(params)
 
 			functionDeclarations = append(functionDeclarations, &model.GeminiFunctionDeclaration{
 				Name:        tool.Function.Name,
@@ -401,6 +402,25 @@ func convertLLMToGeminiRequest(request *model.InternalLLMRequest) *model.GeminiG
 
 }
 
+// convertResponseSchema 将 response_format 中的 JSON Schema 转为 Gemini responseSchema
+// 无法转换时返回 nil，仅保留 responseMimeType
+func convertResponseSchema(format *model.ResponseFormat) *model.GeminiSchema {
+	_, s, err := schema.FromResponseFormat(format)
+	if err != nil || s == nil {
+		return nil
+	}
+	schema.CleanGemini(s)
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil
+	}
+	var geminiSchema model.GeminiSchema
+	if err := json.Unmarshal(data, &geminiSchema); err != nil || geminiSchema.Type == "" {
+		return nil
+	}
+	return &geminiSchema
+}
+
 func convertLLMToolResultToGeminiContent(msg *model.Message) *model.GeminiContent {
 	content := &model.GeminiContent{
 		Role: "user", // Function responses come from user role in Gemini
@@ -558,19 +578,38 @@ func convertGeminiToLLMResponse(geminiResp *model.GeminiGenerateContentResponse,
 		}
 
 		// Add thoughts tokens to completion tokens details if present
+		// Gemini 的 candidatesTokenCount 不含思考部分，与 OpenAI 一致计入 completion_tokens
 		if geminiResp.UsageMetadata.ThoughtsTokenCount > 0 {
 			if usage.CompletionTokensDetails == nil {
 				usage.CompletionTokensDetails = &model.CompletionTokensDetails{}
 			}
 			usage.CompletionTokensDetails.ReasoningTokens = int64(geminiResp.UsageMetadata.ThoughtsTokenCount)
+			usage.CompletionTokens += int64(geminiResp.UsageMetadata.ThoughtsTokenCount)
 		}
 
+		usage.PromptModalityTokenDetails = convertGeminiModalityTokens(geminiResp.UsageMetadata.PromptTokensDetails)
+		usage.CompletionModalityTokenDetails = convertGeminiModalityTokens(geminiResp.UsageMetadata.CandidatesTokensDetails)
+
 		resp.Usage = usage
 	}
 
 	return resp
 }
 
+func convertGeminiModalityTokens(details []model.GeminiModalityTokenCount) []model.ModalityTokenCount {
+	if len(details) == 0 {
+		return nil
+	}
+	result := make([]model.ModalityTokenCount, 0, len(details))
+	for _, detail := range details {
+		result = append(result, model.ModalityTokenCount{
+			Modality:   strings.ToLower(detail.Modality),
+			TokenCount: int64(detail.TokenCount),
+		})
+	}
+	return result
+}
+
 func convertGeminiFinishReason(reason string) string {
 	switch reason {
 	case "STOP":
@@ -585,340 +624,3 @@ func convertGeminiFinishReason(reason string) string {
 		return "stop"
 	}
 }
-
-func cleanGeminiSchema(schema map[string]any) {
-	if schema == nil {
-		return
-	}
-	t := &geminiSchemaTransformer{
-		root:    schema,
-		visited: map[uintptr]struct{}{},
-	}
-	t.transform(schema)
-}
-
-type geminiSchemaTransformer struct {
-	root    map[string]any
-	visited map[uintptr]struct{}
-}
-
-func (t *geminiSchemaTransformer) transform(schemaNode any) {
-	if schemaNode == nil {
-		return
-	}
-
-	// Cycle guard: schema graphs can contain shared sub-objects (or be cyclic after merges).
-	// We only track reference-like kinds to avoid false positives.
-	rv := reflect.ValueOf(schemaNode)
-	switch rv.Kind() {
-	case reflect.Map, reflect.Slice, reflect.Pointer:
-		if rv.IsNil() {
-			return
-		}
-		id := rv.Pointer()
-		if _, seen := t.visited[id]; seen {
-			return
-		}
-		t.visited[id] = struct{}{}
-	}
-
-	switch node := schemaNode.(type) {
-	case []any:
-		for _, item := range node {
-			t.transform(item)
-		}
-		return
-
-	case map[string]any:
-		// 1) Resolve $ref (local-only: #/...)
-		if ref, ok := node["$ref"].(string); ok && strings.HasPrefix(ref, "#/") {
-			path := strings.Split(ref[2:], "/")
-			var cur any = t.root
-			for _, seg := range path {
-				seg = strings.ReplaceAll(seg, "~1", "/")
-				seg = strings.ReplaceAll(seg, "~0", "~")
-				m, ok := cur.(map[string]any)
-				if !ok {
-					cur = nil
-					break
-				}
-				cur = m[seg]
-				if cur == nil {
-					break
-				}
-			}
-
-			if resolved, ok := cur.(map[string]any); ok && resolved != nil {
-				// Merge resolved schema into node, but keep local overrides in node.
-				overlay := make(map[string]any, len(node))
-				for k, v := range node {
-					if k != "$ref" {
-						overlay[k] = v
-					}
-				}
-
-				var copied map[string]any
-				if b, err := json.Marshal(resolved); err == nil {
-					_ = json.Unmarshal(b, &copied)
-				}
-				if copied == nil {
-					copied = make(map[string]any, len(resolved))
-					for k, v := range resolved {
-						copied[k] = v
-					}
-				}
-
-				for k := range node {
-					delete(node, k)
-				}
-				for k, v := range copied {
-					node[k] = v
-				}
-				for k, v := range overlay {
-					node[k] = v
-				}
-				delete(node, "$ref")
-			}
-		}
-
-		// 2) Merge allOf into current node
-		if allOf, ok := node["allOf"].([]any); ok {
-			for _, item := range allOf {
-				t.transform(item)
-				itemMap, ok := item.(map[string]any)
-				if !ok {
-					continue
-				}
-
-				// Merge properties (existing props win)
-				if itemProps, ok := itemMap["properties"].(map[string]any); ok {
-					props, _ := node["properties"].(map[string]any)
-					if props == nil {
-						props = map[string]any{}
-					}
-					for k, v := range itemProps {
-						if _, exists := props[k]; !exists {
-							props[k] = v
-						}
-					}
-					node["properties"] = props
-				}
-
-				// Merge required
-				itemReq := t.asStringSlice(itemMap["required"])
-				if len(itemReq) > 0 {
-					curReq := t.asStringSlice(node["required"])
-					curReq = append(curReq, itemReq...)
-					node["required"] = t.dedupeStrings(curReq)
-				}
-			}
-			delete(node, "allOf")
-		}
-
-		// 3) Type mapping (and nullable union handling)
-		if typ, ok := node["type"]; ok {
-			primary := ""
-			switch v := typ.(type) {
-			case string:
-				primary = v
-			case []any:
-				for _, it := range v {
-					if s, ok := it.(string); ok && strings.ToLower(s) != "null" {
-						primary = s
-						break
-					}
-				}
-			case []string:
-				for _, s := range v {
-					if strings.ToLower(s) != "null" {
-						primary = s
-						break
-					}
-				}
-			}
-
-			switch strings.ToLower(primary) {
-			case "string":
-				node["type"] = "STRING"
-			case "number":
-				node["type"] = "NUMBER"
-			case "integer":
-				node["type"] = "INTEGER"
-			case "boolean":
-				node["type"] = "BOOLEAN"
-			case "array":
-				node["type"] = "ARRAY"
-			case "object":
-				node["type"] = "OBJECT"
-			}
-		}
-
-		// 4) ARRAY items fixes + tuple handling
-		if node["type"] == "ARRAY" {
-			if node["items"] == nil {
-				node["items"] = map[string]any{}
-			} else if tuple, ok := node["items"].([]any); ok {
-				for _, it := range tuple {
-					t.transform(it)
-				}
-
-				// Add tuple hint to description
-				tupleTypes := make([]string, 0, len(tuple))
-				for _, it := range tuple {
-					if itMap, ok := it.(map[string]any); ok {
-						if tt, ok := itMap["type"].(string); ok && tt != "" {
-							tupleTypes = append(tupleTypes, tt)
-						} else {
-							tupleTypes = append(tupleTypes, "any")
-						}
-					} else {
-						tupleTypes = append(tupleTypes, "any")
-					}
-				}
-				hint := fmt.Sprintf("(Tuple: [%s])", strings.Join(tupleTypes, ", "))
-				if origDesc, _ := node["description"].(string); origDesc == "" {
-					node["description"] = hint
-				} else {
-					node["description"] = strings.TrimSpace(origDesc + " " + hint)
-				}
-
-				// Homogeneous tuple => collapse to list schema; otherwise loosen.
-				firstType := ""
-				if len(tuple) > 0 {
-					if itMap, ok := tuple[0].(map[string]any); ok {
-						firstType, _ = itMap["type"].(string)
-					}
-				}
-				isHomogeneous := firstType != ""
-				for _, it := range tuple {
-					itMap, ok := it.(map[string]any)
-					if !ok {
-						isHomogeneous = false
-						break
-					}
-					tt, _ := itMap["type"].(string)
-					if tt != firstType {
-						isHomogeneous = false
-						break
-					}
-				}
-
-				if isHomogeneous {
-					node["items"] = tuple[0]
-				} else {
-					node["items"] = map[string]any{}
-				}
-			}
-		}
-
-		// 5) anyOf: try const->enum; otherwise take first usable schema if no type set
-		if anyOf, ok := node["anyOf"].([]any); ok {
-			for _, item := range anyOf {
-				t.transform(item)
-			}
-
-			allConst := true
-			enumVals := make([]string, 0, len(anyOf))
-			for _, item := range anyOf {
-				itemMap, ok := item.(map[string]any)
-				if !ok {
-					allConst = false
-					break
-				}
-				c, ok := itemMap["const"]
-				if !ok {
-					allConst = false
-					break
-				}
-				if c == nil || c == "" {
-					continue
-				}
-				enumVals = append(enumVals, fmt.Sprint(c))
-			}
-
-			if allConst && len(enumVals) > 0 {
-				node["type"] = "STRING"
-				node["enum"] = enumVals
-			} else if _, hasType := node["type"]; !hasType {
-				for _, item := range anyOf {
-					if itemMap, ok := item.(map[string]any); ok {
-						if itemMap["type"] != nil || itemMap["enum"] != nil {
-							for k, v := range itemMap {
-								node[k] = v
-							}
-							break
-						}
-					}
-				}
-			}
-			delete(node, "anyOf")
-		}
-
-		// 6) Default value -> description hint (then delete default)
-		if def, ok := node["default"]; ok {
-			if desc, ok := node["description"].(string); ok && desc != "" {
-				if b, err := json.Marshal(def); err == nil {
-					node["description"] = desc + " (Default: " + string(b) + ")"
-				}
-			}
-		}
-
-		// 7) Remove unsupported fields
-		for _, k := range []string{
-			"title", "$schema", "$ref", "strict",
-			"exclusiveMaximum", "exclusiveMinimum",
-			"additionalProperties", "oneOf", "default",
-			"$defs",
-		} {
-			delete(node, k)
-		}
-
-		// 8) Recurse into properties/items
-		if props, ok := node["properties"].(map[string]any); ok {
-			for _, prop := range props {
-				t.transform(prop)
-			}
-		}
-		if items := node["items"]; items != nil {
-			t.transform(items)
-		}
-
-		// 9) Ensure required is de-duped (allOf merge can introduce duplicates)
-		if req := t.asStringSlice(node["required"]); len(req) > 0 {
-			node["required"] = t.dedupeStrings(req)
-		}
-	}
-}
-
-func (t *geminiSchemaTransformer) asStringSlice(v any) []string {
-	switch s := v.(type) {
-	case []string:
-		return append([]string(nil), s...)
-	case []any:
-		out := make([]string, 0, len(s))
-		for _, it := range s {
-			if str, ok := it.(string); ok {
-				out = append(out, str)
-			}
-		}
-		return out
-	default:
-		return nil
-	}
-}
-
-func (t *geminiSchemaTransformer) dedupeStrings(in []string) []string {
-	seen := map[string]struct{}{}
-	out := make([]string, 0, len(in))
-	for _, s := range in {
-		if s == "" {
-			continue
-		}
-		if _, ok := seen[s]; ok {
-			continue
-		}
-		seen[s] = struct{}{}
-		out = append(out, s)
-	}
-	return out
-}
@@ -0,0 +1,375 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/utils/xurl"
+)
+
+// ChatOutbound Ollama 原生 /api/chat，流式响应为 NDJSON
+type ChatOutbound struct {
+	// Stream state tracking
+	streamID      string
+	toolCallIndex int
+	hasToolCalls  bool
+}
+
+func (o *ChatOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	if request == nil {
+		return nil, fmt.Errorf("request is nil")
+	}
+
+	ollamaReq, err := convertToOllamaRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ollama request: %w", err)
+	}
+
+	parsedUrl, err := url.Parse(strings.TrimSuffix(baseUrl, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base url: %w", err)
+	}
+	parsedUrl.Path = parsedUrl.Path + "/api/chat"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, parsedUrl.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if ollamaReq.Stream {
+		req.Header.Set("Accept", "application/x-ndjson")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	// Ollama 本身不需要认证，但常见的反向代理会校验 Bearer token
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	return req, nil
+}
+
+func (o *ChatOutbound) TransformResponse(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if len(body) == 0 {
+		return nil, fmt.Errorf("response body is empty")
+	}
+
+	var ollamaResp ChatResponse
+	if err := json.Unmarshal(body, &ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ollama response: %w", err)
+	}
+	if ollamaResp.Error != "" {
+		return nil, &model.ResponseError{
+			StatusCode: response.StatusCode,
+			Detail:     model.ErrorDetail{Message: ollamaResp.Error},
+		}
+	}
+
+	return o.convertToLLMResponse(&ollamaResp, false), nil
+}
+
+func (o *ChatOutbound) TransformStream(ctx context.Context, eventData []byte) (*model.InternalLLMResponse, error) {
+	eventData = bytes.TrimSpace(eventData)
+	if len(eventData) == 0 {
+		return nil, nil
+	}
+
+	var ollamaResp ChatResponse
+	if err := json.Unmarshal(eventData, &ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ollama stream chunk: %w", err)
+	}
+	if ollamaResp.Error != "" {
+		return nil, &model.ResponseError{
+			Detail: model.ErrorDetail{Message: ollamaResp.Error},
+		}
+	}
+
+	return o.convertToLLMResponse(&ollamaResp, true), nil
+}
+
+func (o *ChatOutbound) convertToLLMResponse(resp *ChatResponse, isStream bool) *model.InternalLLMResponse {
+	if o.streamID == "" {
+		o.streamID = "chatcmpl-" + lo.RandomString(24, lo.AlphanumericCharset)
+	}
+
+	result := &model.InternalLLMResponse{
+		ID:      o.streamID,
+		Model:   resp.Model,
+		Created: parseCreatedAt(resp.CreatedAt),
+		Object:  lo.Ternary(isStream, "chat.completion.chunk", "chat.completion"),
+	}
+
+	msg := &model.Message{Role: "assistant"}
+	if resp.Message.Content != "" || !isStream {
+		msg.Content = model.MessageContent{Content: lo.ToPtr(resp.Message.Content)}
+	}
+	if resp.Message.Thinking != "" {
+		msg.ReasoningContent = lo.ToPtr(resp.Message.Thinking)
+	}
+	for _, tc := range resp.Message.ToolCalls {
+		args := string(tc.Function.Arguments)
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		msg.ToolCalls = append(msg.ToolCalls, model.ToolCall{
+			ID:    "call_" + lo.RandomString(24, lo.AlphanumericCharset),
+			Type:  "function",
+			Index: o.toolCallIndex,
+			Function: model.FunctionCall{
+				Name:      tc.Function.Name,
+				Arguments: args,
+			},
+		})
+		o.toolCallIndex++
+		o.hasToolCalls = true
+	}
+
+	choice := model.Choice{Index: 0}
+	if isStream {
+		choice.Delta = msg
+	} else {
+		choice.Message = msg
+	}
+
+	if resp.Done {
+		choice.FinishReason = lo.ToPtr(o.convertDoneReason(resp.DoneReason))
+		result.Usage = &model.Usage{
+			PromptTokens:     resp.PromptEvalCount,
+			CompletionTokens: resp.EvalCount,
+			TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+		}
+	}
+
+	result.Choices = []model.Choice{choice}
+	return result
+}
+
+func (o *ChatOutbound) convertDoneReason(reason string) string {
+	if o.hasToolCalls {
+		return "tool_calls"
+	}
+	switch reason {
+	case "length":
+		return "length"
+	default:
+		return "stop"
+	}
+}
+
+func parseCreatedAt(createdAt string) int64 {
+	if t, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
+		return t.Unix()
+	}
+	return time.Now().Unix()
+}
+
+// convertToOllamaRequest converts internal LLM request to Ollama native format
+func convertToOllamaRequest(req *model.InternalLLMRequest) (*ChatRequest, error) {
+	messages, err := convertMessages(req.Messages)
+	if err != nil {
+		return nil, err
+	}
+	result := &ChatRequest{
+		Model:    req.Model,
+		Stream:   req.Stream != nil && *req.Stream,
+		Messages: messages,
+		Options:  map[string]any{},
+	}
+
+	// Convert sampling options
+	if req.Temperature != nil {
+		result.Options["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		result.Options["top_p"] = *req.TopP
+	}
+	if req.Seed != nil {
+		result.Options["seed"] = *req.Seed
+	}
+	if req.FrequencyPenalty != nil {
+		result.Options["frequency_penalty"] = *req.FrequencyPenalty
+	}
+	if req.PresencePenalty != nil {
+		result.Options["presence_penalty"] = *req.PresencePenalty
+	}
+	if req.MaxCompletionTokens != nil {
+		result.Options["num_predict"] = *req.MaxCompletionTokens
+	} else if req.MaxTokens != nil {
+		result.Options["num_predict"] = *req.MaxTokens
+	}
+	if req.Stop != nil {
+		if req.Stop.Stop != nil {
+			result.Options["stop"] = []string{*req.Stop.Stop}
+		} else if len(req.Stop.MultipleStop) > 0 {
+			result.Options["stop"] = req.Stop.MultipleStop
+		}
+	}
+
+	// Convert tools
+	for _, tool := range req.Tools {
+		if tool.Type != "function" {
+			continue
+		}
+		result.Tools = append(result.Tools, Tool{
+			Type: "function",
+			Function: ToolFunction{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			},
+		})
+	}
+
+	// Convert response format
+	if req.ResponseFormat != nil {
+		switch req.ResponseFormat.Type {
+		case "json_object":
+			result.Format = json.RawMessage(`"json"`)
+		case "json_schema":
+			var jsonSchema struct {
+				Schema json.RawMessage `json:"schema"`
+			}
+			if err := json.Unmarshal(req.ResponseFormat.JSONSchema, &jsonSchema); err == nil && len(jsonSchema.Schema) > 0 {
+				result.Format = jsonSchema.Schema
+			} else {
+				result.Format = json.RawMessage(`"json"`)
+			}
+		}
+	}
+
+	// Convert thinking
+	if req.EnableThinking != nil {
+		result.Think = json.RawMessage(lo.Ternary(*req.EnableThinking, "true", "false"))
+	} else if req.ReasoningEffort != "" {
+		result.Think = json.RawMessage(lo.Ternary(req.ReasoningEffort == "none", "false", "true"))
+	}
+
+	// Ollama 原生参数（keep_alive / options / think）从原始请求或 extra_body 中透传
+	for _, raw := range [][]byte{req.RawRequest, req.ExtraBody} {
+		if err := applyNativeParams(result, raw); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(result.Options) == 0 {
+		result.Options = nil
+	}
+	return result, nil
+}
+
+func applyNativeParams(result *ChatRequest, raw []byte) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	var native struct {
+		KeepAlive json.RawMessage `json:"keep_alive"`
+		Options   map[string]any  `json:"options"`
+		Think     json.RawMessage `json:"think"`
+	}
+	if err := json.Unmarshal(raw, &native); err != nil {
+		// 原始请求可能不是对象（如其他入站格式），忽略即可
+		return nil
+	}
+	if len(native.KeepAlive) > 0 {
+		result.KeepAlive = native.KeepAlive
+	}
+	if len(native.Think) > 0 {
+		result.Think = native.Think
+	}
+	for k, v := range native.Options {
+		result.Options[k] = v
+	}
+	return nil
+}
+
+func convertMessages(msgs []model.Message) ([]Message, error) {
+	// tool_call_id -> function name，用于填充 tool_name
+	toolNames := make(map[string]string)
+	for _, msg := range msgs {
+		for _, tc := range msg.ToolCalls {
+			toolNames[tc.ID] = tc.Function.Name
+		}
+	}
+
+	result := make([]Message, 0, len(msgs))
+	for _, msg := range msgs {
+		role := msg.Role
+		if role == "developer" {
+			role = "system"
+		}
+		out := Message{Role: role}
+
+		if msg.Content.Content != nil {
+			out.Content = *msg.Content.Content
+		} else {
+			var text strings.Builder
+			for _, part := range msg.Content.MultipleContent {
+				switch part.Type {
+				case "text":
+					if part.Text != nil {
+						text.WriteString(*part.Text)
+					}
+				case "image_url":
+					// Ollama 只接受 base64 图片，远程地址由中继按渠道的媒体处理方式预先内联
+					if part.ImageURL != nil {
+						parsed := xurl.ParseDataURL(part.ImageURL.URL)
+						if parsed == nil || !parsed.IsBase64 {
+							return nil, fmt.Errorf("ollama only accepts base64 data URL images, set the channel media mode to inline to fetch remote images")
+						}
+						out.Images = append(out.Images, parsed.Data)
+					}
+				}
+			}
+			out.Content = text.String()
+		}
+
+		if reasoning := msg.GetReasoningContent(); reasoning != "" && role == "assistant" {
+			out.Thinking = reasoning
+		}
+
+		for _, tc := range msg.ToolCalls {
+			args := json.RawMessage(tc.Function.Arguments)
+			if !json.Valid(args) {
+				args = json.RawMessage("{}")
+			}
+			out.ToolCalls = append(out.ToolCalls, ToolCall{
+				Function: ToolCallFunction{
+					Name:      tc.Function.Name,
+					Arguments: args,
+				},
+			})
+		}
+
+		if role == "tool" {
+			if msg.ToolCallName != nil {
+				out.ToolName = *msg.ToolCallName
+			} else if msg.ToolCallID != nil {
+				out.ToolName = toolNames[*msg.ToolCallID]
+			}
+		}
+
+		result = append(result, out)
+	}
+	return result, nil
+}
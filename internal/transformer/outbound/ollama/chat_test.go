@@ -0,0 +1,153 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+)
+
+func TestChatOutboundTransformRequest(t *testing.T) {
+	o := &ChatOutbound{}
+	req, err := o.TransformRequest(context.Background(), &model.InternalLLMRequest{
+		Model:       "llama3.2",
+		Stream:      lo.ToPtr(true),
+		Temperature: lo.ToPtr(0.5),
+		MaxTokens:   lo.ToPtr(int64(128)),
+		Messages: []model.Message{
+			{Role: "developer", Content: model.MessageContent{Content: lo.ToPtr("be brief")}},
+			{Role: "user", Content: model.MessageContent{MultipleContent: []model.MessageContentPart{
+				{Type: "text", Text: lo.ToPtr("what is this?")},
+				{Type: "image_url", ImageURL: &model.ImageURL{URL: "data:image/png;base64,aGVsbG8="}},
+			}}},
+			{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "call_1", Type: "function", Function: model.FunctionCall{Name: "lookup", Arguments: `{"q":"x"}`}}}},
+			{Role: "tool", ToolCallID: lo.ToPtr("call_1"), Content: model.MessageContent{Content: lo.ToPtr("result")}},
+		},
+		RawRequest: []byte(`{"keep_alive":"5m","options":{"num_ctx":8192}}`),
+	}, "http://localhost:11434/", "")
+	if err != nil {
+		t.Fatalf("TransformRequest: %v", err)
+	}
+	if req.URL.String() != "http://localhost:11434/api/chat" {
+		t.Fatalf("url = %s", req.URL)
+	}
+	if req.Header.Get("Accept") != "application/x-ndjson" || req.Header.Get("Authorization") != "" {
+		t.Fatalf("headers = %v", req.Header)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	var got ChatRequest
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Stream || string(got.KeepAlive) != `"5m"` {
+		t.Fatalf("stream = %v, keep_alive = %s", got.Stream, got.KeepAlive)
+	}
+	if got.Options["temperature"] != 0.5 || got.Options["num_predict"] != float64(128) || got.Options["num_ctx"] != float64(8192) {
+		t.Fatalf("options = %v", got.Options)
+	}
+	if len(got.Messages) != 4 {
+		t.Fatalf("messages = %+v", got.Messages)
+	}
+	if got.Messages[0].Role != "system" {
+		t.Fatalf("developer role = %q, want system", got.Messages[0].Role)
+	}
+	if got.Messages[1].Content != "what is this?" || len(got.Messages[1].Images) != 1 || got.Messages[1].Images[0] != "aGVsbG8=" {
+		t.Fatalf("user message = %+v", got.Messages[1])
+	}
+	if calls := got.Messages[2].ToolCalls; len(calls) != 1 || calls[0].Function.Name != "lookup" || string(calls[0].Function.Arguments) != `{"q":"x"}` {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+	if got.Messages[3].ToolName != "lookup" {
+		t.Fatalf("tool_name = %q, want lookup", got.Messages[3].ToolName)
+	}
+}
+
+func TestChatOutboundRejectsRemoteImage(t *testing.T) {
+	o := &ChatOutbound{}
+	_, err := o.TransformRequest(context.Background(), &model.InternalLLMRequest{
+		Model: "llava",
+		Messages: []model.Message{{Role: "user", Content: model.MessageContent{MultipleContent: []model.MessageContentPart{
+			{Type: "image_url", ImageURL: &model.ImageURL{URL: "https://example.com/cat.png"}},
+		}}}},
+	}, "http://localhost:11434", "")
+	if err == nil || !strings.Contains(err.Error(), "base64") {
+		t.Fatalf("err = %v, want remote image rejected", err)
+	}
+}
+
+func TestChatOutboundTransformStream(t *testing.T) {
+	o := &ChatOutbound{}
+	lines := []string{
+		`{"model":"llama3.2","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":"","thinking":"hmm"},"done":false}`,
+		`{"model":"llama3.2","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":"Hel"},"done":false}`,
+		`{"model":"llama3.2","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"lookup","arguments":{"q":"x"}}}]},"done":false}`,
+		`{"model":"llama3.2","created_at":"2025-01-01T00:00:01Z","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":7}`,
+	}
+	var chunks []*model.InternalLLMResponse
+	for _, line := range lines {
+		chunk, err := o.TransformStream(context.Background(), []byte(line))
+		if err != nil {
+			t.Fatalf("TransformStream: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	for _, chunk := range chunks {
+		if chunk.ID != chunks[0].ID || chunk.Object != "chat.completion.chunk" {
+			t.Fatalf("chunk id/object = %s/%s", chunk.ID, chunk.Object)
+		}
+	}
+	if r := chunks[0].Choices[0].Delta.ReasoningContent; r == nil || *r != "hmm" {
+		t.Fatalf("reasoning = %v", r)
+	}
+	if c := chunks[1].Choices[0].Delta.Content.Content; c == nil || *c != "Hel" {
+		t.Fatalf("content = %v", c)
+	}
+	if calls := chunks[2].Choices[0].Delta.ToolCalls; len(calls) != 1 || calls[0].Function.Arguments != `{"q":"x"}` || calls[0].Index != 0 {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+	last := chunks[3]
+	if fr := last.Choices[0].FinishReason; fr == nil || *fr != "tool_calls" {
+		t.Fatalf("finish reason = %v, want tool_calls", fr)
+	}
+	if last.Usage == nil || last.Usage.PromptTokens != 12 || last.Usage.CompletionTokens != 7 || last.Usage.TotalTokens != 19 {
+		t.Fatalf("usage = %+v", last.Usage)
+	}
+}
+
+func TestChatOutboundStreamError(t *testing.T) {
+	o := &ChatOutbound{}
+	_, err := o.TransformStream(context.Background(), []byte(`{"error":"model \"x\" not found"}`))
+	var respErr *model.ResponseError
+	if !errors.As(err, &respErr) || !strings.Contains(respErr.Detail.Message, "not found") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestChatOutboundTransformResponse(t *testing.T) {
+	o := &ChatOutbound{}
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Body: io.NopCloser(strings.NewReader(`{"model":"llama3.2","created_at":"2025-01-01T00:00:00Z",` +
+			`"message":{"role":"assistant","content":"Hello"},"done":true,"done_reason":"length","prompt_eval_count":3,"eval_count":2}`)),
+	}
+	got, err := o.TransformResponse(context.Background(), resp)
+	if err != nil {
+		t.Fatalf("TransformResponse: %v", err)
+	}
+	choice := got.Choices[0]
+	if got.Object != "chat.completion" || choice.Message == nil || *choice.Message.Content.Content != "Hello" {
+		t.Fatalf("response = %+v", got)
+	}
+	if choice.FinishReason == nil || *choice.FinishReason != "length" {
+		t.Fatalf("finish reason = %v, want length", choice.FinishReason)
+	}
+}
@@ -3,8 +3,11 @@ package outbound
 import (
 	"github.com/bestruirui/octopus/internal/transformer/model"
 	"github.com/bestruirui/octopus/internal/transformer/outbound/authropic"
+	"github.com/bestruirui/octopus/internal/transformer/outbound/azure"
 	"github.com/bestruirui/octopus/internal/transformer/outbound/gemini"
+	"github.com/bestruirui/octopus/internal/transformer/outbound/ollama"
 	"github.com/bestruirui/octopus/internal/transformer/outbound/openai"
+	"github.com/bestruirui/octopus/internal/transformer/outbound/vertex"
 	"github.com/bestruirui/octopus/internal/transformer/outbound/volcengine"
 )
 
@@ -17,11 +20,17 @@ const (
 	OutboundTypeGemini
 	OutboundTypeVolcengine
 	OutboundTypeOpenAIEmbedding
+	OutboundTypeVertex
+	OutboundTypeAzureChat
+	OutboundTypeAzureResponse
+	OutboundTypeAzureEmbedding
+	OutboundTypeOllama
 )
 
 // EmbeddingChannelTypes 定义支持 embedding 请求的 channel 类型集合
 var EmbeddingChannelTypes = map[OutboundType]bool{
 	OutboundTypeOpenAIEmbedding: true,
+	OutboundTypeAzureEmbedding:  true,
 }
 
 // ChatChannelTypes 定义支持 chat 请求的 channel 类型集合
@@ -31,6 +40,29 @@ var ChatChannelTypes = map[OutboundType]bool{
 	OutboundTypeAnthropic:      true,
 	OutboundTypeGemini:         true,
 	OutboundTypeVolcengine:     true,
+	OutboundTypeVertex:         true,
+	OutboundTypeAzureChat:      true,
+	OutboundTypeAzureResponse:  true,
+	OutboundTypeOllama:         true,
+}
+
+// InlineMediaChannelTypes 定义只接受内联图片/文件数据的 channel 类型集合
+var InlineMediaChannelTypes = map[OutboundType]bool{
+	OutboundTypeGemini: true,
+	OutboundTypeVertex: true,
+	OutboundTypeOllama: true,
+}
+
+// MultiChoiceChannelTypes 定义原生支持 n 参数的 channel 类型集合
+var MultiChoiceChannelTypes = map[OutboundType]bool{
+	OutboundTypeOpenAIChat: true,
+	OutboundTypeAzureChat:  true,
+}
+
+// RealtimeChannelTypes 定义支持 Realtime WebSocket 会话的 channel 类型集合
+var RealtimeChannelTypes = map[OutboundType]bool{
+	OutboundTypeOpenAIChat:     true,
+	OutboundTypeOpenAIResponse: true,
 }
 
 // IsEmbeddingChannelType 判断 channel 类型是否支持 embedding 请求
@@ -43,6 +75,21 @@ func IsChatChannelType(channelType OutboundType) bool {
 	return ChatChannelTypes[channelType]
 }
 
+// IsRealtimeChannelType 判断 channel 类型是否支持 Realtime 会话
+func IsRealtimeChannelType(channelType OutboundType) bool {
+	return RealtimeChannelTypes[channelType]
+}
+
+// SupportsMultipleChoices 判断 channel 类型是否原生支持 n 参数
+func SupportsMultipleChoices(channelType OutboundType) bool {
+	return MultiChoiceChannelTypes[channelType]
+}
+
+// NeedsInlineMedia 判断 channel 类型是否需要将远程图片/文件转为内联数据
+func NeedsInlineMedia(channelType OutboundType) bool {
+	return InlineMediaChannelTypes[channelType]
+}
+
 var outboundFactories = map[OutboundType]func() model.Outbound{
 	OutboundTypeOpenAIChat:      func() model.Outbound { return &openai.ChatOutbound{} },
 	OutboundTypeOpenAIResponse:  func() model.Outbound { return &openai.ResponseOutbound{} },
@@ -50,6 +97,11 @@ var outboundFactories = map[OutboundType]func() model.Outbound{
 	OutboundTypeAnthropic:       func() model.Outbound { return &authropic.MessageOutbound{} },
 	OutboundTypeGemini:          func() model.Outbound { return &gemini.MessagesOutbound{} },
 	OutboundTypeVolcengine:      func() model.Outbound { return &volcengine.ResponseOutbound{} },
+	OutboundTypeVertex:          func() model.Outbound { return &vertex.MessagesOutbound{} },
+	OutboundTypeAzureChat:       func() model.Outbound { return &azure.ChatOutbound{} },
+	OutboundTypeAzureResponse:   func() model.Outbound { return &azure.ResponseOutbound{} },
+	OutboundTypeAzureEmbedding:  func() model.Outbound { return &azure.EmbeddingOutbound{} },
+	OutboundTypeOllama:          func() model.Outbound { return &ollama.ChatOutbound{} },
 }
 
 func Get(outboundType OutboundType) model.Outbound {
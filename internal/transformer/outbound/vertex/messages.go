@@ -0,0 +1,162 @@
+package vertex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/bestruirui/octopus/internal/transformer/outbound/authropic"
+	"github.com/bestruirui/octopus/internal/transformer/outbound/gemini"
+)
+
+const (
+	publisherGoogle    = "google"
+	publisherAnthropic = "anthropic"
+
+	// anthropicVertexVersion 是 Vertex 上 Claude 模型要求的 anthropic_version
+	anthropicVertexVersion = "vertex-2023-10-16"
+)
+
+// MessagesOutbound 通过 Vertex AI 访问 Gemini 与 Claude 模型。
+// 渠道 key 为 service account JSON，base url 形如 https://{location}-aiplatform.googleapis.com/v1，
+// 非官方域名（如代理或本地测试）可通过 base url 上的 location 查询参数指定区域。
+type MessagesOutbound struct {
+	publisher string
+	gemini    gemini.MessagesOutbound
+	anthropic authropic.MessageOutbound
+
+	// httpClient 换取 access token 使用的渠道客户端
+	httpClient *http.Client
+}
+
+// SetHTTPClient 设置换取 access token 使用的 http 客户端，与上游请求使用相同的代理
+func (o *MessagesOutbound) SetHTTPClient(client *http.Client) {
+	o.httpClient = client
+}
+
+func (o *MessagesOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	if request == nil {
+		return nil, fmt.Errorf("request is nil")
+	}
+
+	sa, err := parseServiceAccount(key)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedUrl, err := url.Parse(strings.TrimSuffix(baseUrl, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base url: %w", err)
+	}
+	location := resolveLocation(parsedUrl)
+	parsedUrl.RawQuery = ""
+
+	publisher, modelName := resolvePublisher(request.Model)
+	o.publisher = publisher
+
+	isStream := request.Stream != nil && *request.Stream
+
+	var body []byte
+	var method string
+	switch publisher {
+	case publisherAnthropic:
+		anthropicReq := authropic.ConvertToAnthropicRequest(request)
+		// Vertex 通过 URL 指定模型，请求体中不能携带 model
+		anthropicReq.Model = ""
+		anthropicReq.AnthropicVersion = anthropicVertexVersion
+		body, err = json.Marshal(anthropicReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal anthropic request: %w", err)
+		}
+		method = "rawPredict"
+		if isStream {
+			method = "streamRawPredict"
+		}
+	default:
+		geminiReq := gemini.ConvertLLMToGeminiRequest(request)
+		body, err = json.Marshal(geminiReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal gemini request: %w", err)
+		}
+		method = "generateContent"
+		if isStream {
+			method = "streamGenerateContent"
+		}
+	}
+
+	// Build path: /projects/{project}/locations/{location}/publishers/{publisher}/models/{model}:{method}
+	parsedUrl.Path = fmt.Sprintf("%s/projects/%s/locations/%s/publishers/%s/models/%s:%s",
+		parsedUrl.Path, sa.ProjectID, location, publisher, modelName, method)
+	if isStream {
+		q := parsedUrl.Query()
+		q.Set("alt", "sse")
+		parsedUrl.RawQuery = q.Encode()
+	}
+
+	token, err := getAccessToken(ctx, o.httpClient, sa)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vertex access token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, parsedUrl.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if isStream {
+		req.Header.Set("Accept", "text/event-stream")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return req, nil
+}
+
+func (o *MessagesOutbound) TransformResponse(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
+	if o.publisher == publisherAnthropic {
+		return o.anthropic.TransformResponse(ctx, response)
+	}
+	return o.gemini.TransformResponse(ctx, response)
+}
+
+func (o *MessagesOutbound) TransformStream(ctx context.Context, eventData []byte) (*model.InternalLLMResponse, error) {
+	if o.publisher == publisherAnthropic {
+		return o.anthropic.TransformStream(ctx, eventData)
+	}
+	return o.gemini.TransformStream(ctx, eventData)
+}
+
+// resolvePublisher 根据模型名判断发布方，支持 "anthropic/claude-xxx" 形式显式指定
+func resolvePublisher(modelName string) (string, string) {
+	modelName = strings.TrimPrefix(modelName, "models/")
+	if publisher, name, ok := strings.Cut(modelName, "/"); ok {
+		switch publisher {
+		case publisherGoogle, publisherAnthropic:
+			return publisher, name
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(modelName), "claude") {
+		return publisherAnthropic, modelName
+	}
+	return publisherGoogle, modelName
+}
+
+// resolveLocation 从 base url 中解析区域
+// 优先使用 location 查询参数，其次从 {location}-aiplatform.googleapis.com 域名中提取，默认 global
+func resolveLocation(u *url.URL) string {
+	if location := strings.TrimSpace(u.Query().Get("location")); location != "" {
+		return location
+	}
+	host := u.Hostname()
+	if location, ok := strings.CutSuffix(host, "-aiplatform.googleapis.com"); ok && location != "" {
+		return location
+	}
+	return "global"
+}
@@ -0,0 +1,94 @@
+package vertex
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+)
+
+func newTestServiceAccount(t *testing.T, tokenURL string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	sa, _ := json.Marshal(ServiceAccount{
+		Type:         "service_account",
+		ProjectID:    "test-project",
+		PrivateKeyID: "kid-1",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail:  "octopus@test-project.iam.gserviceaccount.com",
+		TokenURI:     tokenURL,
+	})
+	return string(sa)
+}
+
+func TestMessagesOutboundTransformRequest(t *testing.T) {
+	var tokenCalls atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenCalls.Add(1)
+		if err := r.ParseForm(); err != nil || r.Form.Get("assertion") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"test-token","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer tokenServer.Close()
+
+	key := newTestServiceAccount(t, tokenServer.URL)
+	stream := true
+
+	tests := []struct {
+		model   string
+		stream  *bool
+		wantURL string
+	}{
+		{
+			model:   "gemini-2.5-pro",
+			wantURL: "https://us-east5-aiplatform.googleapis.com/v1/projects/test-project/locations/us-east5/publishers/google/models/gemini-2.5-pro:generateContent",
+		},
+		{
+			model:   "claude-sonnet-4@20250514",
+			stream:  &stream,
+			wantURL: "https://us-east5-aiplatform.googleapis.com/v1/projects/test-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict?alt=sse",
+		},
+	}
+
+	for _, tt := range tests {
+		o := &MessagesOutbound{}
+		o.SetHTTPClient(tokenServer.Client())
+		content := "hi"
+		req, err := o.TransformRequest(context.Background(), &model.InternalLLMRequest{
+			Model:    tt.model,
+			Stream:   tt.stream,
+			Messages: []model.Message{{Role: "user", Content: model.MessageContent{Content: &content}}},
+		}, "https://us-east5-aiplatform.googleapis.com/v1", key)
+		if err != nil {
+			t.Fatalf("%s: TransformRequest error: %v", tt.model, err)
+		}
+		if got := req.URL.String(); got != tt.wantURL {
+			t.Errorf("%s: url = %s, want %s", tt.model, got, tt.wantURL)
+		}
+		if got := req.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("%s: authorization = %q", tt.model, got)
+		}
+	}
+
+	if got := tokenCalls.Load(); got != 1 {
+		t.Errorf("token endpoint called %d times, want 1 (token should be cached)", got)
+	}
+}
@@ -0,0 +1,228 @@
+package vertex
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/octopus/internal/conf"
+)
+
+const (
+	defaultTokenURL = "https://oauth2.googleapis.com/token"
+	tokenScope      = "https://www.googleapis.com/auth/cloud-platform"
+	// tokenRefreshMargin 在过期前提前刷新 token
+	tokenRefreshMargin = 5 * time.Minute
+	// tokenRequestTimeout 换取 token 的请求超时
+	tokenRequestTimeout = 30 * time.Second
+)
+
+// tokenURLOverride 覆盖 service account 中的 token_uri，主要用于本地测试。
+// 可通过环境变量 OCTOPUS_VERTEX_TOKEN_URL 设置。
+var tokenURLOverride string
+
+func init() {
+	tokenURLOverride = strings.TrimSpace(os.Getenv(strings.ToUpper(conf.APP_NAME) + "_VERTEX_TOKEN_URL"))
+}
+
+// ServiceAccount 是 GCP service account JSON 中用到的字段
+type ServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+func parseServiceAccount(key string) (*ServiceAccount, error) {
+	var sa ServiceAccount
+	if err := json.Unmarshal([]byte(key), &sa); err != nil {
+		return nil, fmt.Errorf("failed to parse service account json: %w", err)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, fmt.Errorf("service account json missing client_email or private_key")
+	}
+	if sa.ProjectID == "" {
+		return nil, fmt.Errorf("service account json missing project_id")
+	}
+	return &sa, nil
+}
+
+func (sa *ServiceAccount) tokenURL() string {
+	if tokenURLOverride != "" {
+		return tokenURLOverride
+	}
+	if sa.TokenURI != "" {
+		return sa.TokenURI
+	}
+	return defaultTokenURL
+}
+
+type cachedToken struct {
+	lock        sync.Mutex
+	accessToken string
+	expireAt    time.Time
+}
+
+var (
+	tokenCache     = make(map[string]*cachedToken)
+	tokenCacheLock sync.Mutex
+)
+
+// getAccessToken 返回缓存的 access token，临近过期时通过 httpClient 重新签发
+func getAccessToken(ctx context.Context, httpClient *http.Client, sa *ServiceAccount) (string, error) {
+	cacheKey := sa.ClientEmail + "|" + sa.PrivateKeyID + "|" + sa.tokenURL()
+
+	tokenCacheLock.Lock()
+	entry, ok := tokenCache[cacheKey]
+	if !ok {
+		entry = &cachedToken{}
+		tokenCache[cacheKey] = entry
+	}
+	tokenCacheLock.Unlock()
+
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+
+	if entry.accessToken != "" && time.Until(entry.expireAt) > tokenRefreshMargin {
+		return entry.accessToken, nil
+	}
+
+	token, expiresIn, err := fetchAccessToken(ctx, httpClient, sa)
+	if err != nil {
+		return "", err
+	}
+	entry.accessToken = token
+	entry.expireAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	return token, nil
+}
+
+// fetchAccessToken 使用 JWT bearer 断言换取 OAuth access token
+// refer: https://developers.google.com/identity/protocols/oauth2/service-account#httprest
+func fetchAccessToken(ctx context.Context, httpClient *http.Client, sa *ServiceAccount) (string, int64, error) {
+	if httpClient == nil {
+		return "", 0, fmt.Errorf("http client is not set")
+	}
+	ctx, cancel := context.WithTimeout(ctx, tokenRequestTimeout)
+	defer cancel()
+
+	tokenURL := sa.tokenURL()
+	assertion, err := signJWT(sa, tokenURL, time.Now())
+	if err != nil {
+		return "", 0, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token endpoint error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", 0, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", 0, fmt.Errorf("token response missing access_token")
+	}
+	if tokenResp.ExpiresIn <= 0 {
+		tokenResp.ExpiresIn = 3600
+	}
+	return tokenResp.AccessToken, tokenResp.ExpiresIn, nil
+}
+
+// signJWT 生成 RS256 签名的 JWT 断言
+func signJWT(sa *ServiceAccount, audience string, now time.Time) (string, error) {
+	privateKey, err := parsePrivateKey(sa.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	}
+	if sa.PrivateKeyID != "" {
+		header["kid"] = sa.PrivateKeyID
+	}
+	claims := map[string]any{
+		"iss":   sa.ClientEmail,
+		"scope": tokenScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign jwt: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func parsePrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid service account private key")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("service account private key is not RSA")
+		}
+		return rsaKey, nil
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account private key: %w", err)
+	}
+	return key, nil
+}
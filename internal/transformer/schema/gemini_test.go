@@ -0,0 +1,61 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCleanGemini(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{
+			"types and unsupported fields",
+			`{"$schema":"x","title":"t","type":"object","additionalProperties":false,"properties":{"a":{"type":["string","null"],"default":"x","description":"d"}}}`,
+			`{"properties":{"a":{"description":"d (Default: \"x\")","type":"STRING"}},"type":"OBJECT"}`,
+		},
+		{
+			"ref",
+			`{"$defs":{"id":{"type":"integer","minimum":1}},"type":"object","properties":{"id":{"$ref":"#/$defs/id","description":"d"}}}`,
+			`{"properties":{"id":{"description":"d","minimum":1,"type":"INTEGER"}},"type":"OBJECT"}`,
+		},
+		{
+			"allOf",
+			`{"type":"object","required":["a"],"allOf":[{"properties":{"b":{"type":"string"}},"required":["a","b"]}]}`,
+			`{"properties":{"b":{"type":"STRING"}},"required":["a","b"],"type":"OBJECT"}`,
+		},
+		{
+			"homogeneous tuple",
+			`{"type":"array","items":[{"type":"string"},{"type":"string"}]}`,
+			`{"description":"(Tuple: [STRING, STRING])","items":{"type":"STRING"},"type":"ARRAY"}`,
+		},
+		{
+			"array without items",
+			`{"type":"array"}`,
+			`{"items":{},"type":"ARRAY"}`,
+		},
+		{
+			"anyOf const",
+			`{"anyOf":[{"const":"a"},{"const":"b"}]}`,
+			`{"enum":["a","b"],"type":"STRING"}`,
+		},
+		{
+			"anyOf first typed schema",
+			`{"anyOf":[{"description":"x"},{"type":"number"}]}`,
+			`{"type":"NUMBER"}`,
+		},
+	}
+	for _, c := range cases {
+		var s map[string]any
+		if err := json.Unmarshal([]byte(c.schema), &s); err != nil {
+			t.Fatalf("%s: invalid schema: %v", c.name, err)
+		}
+		CleanGemini(s)
+		got, _ := json.Marshal(s)
+		if string(got) != c.want {
+			t.Errorf("%s:\n got %s\nwant %s", c.name, got, c.want)
+		}
+	}
+}
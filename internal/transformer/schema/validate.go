@@ -0,0 +1,429 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+	"github.com/dlclark/regexp2"
+)
+
+const (
+	// maxDepth 限制校验递归深度，防止循环 $ref
+	maxDepth = 64
+	// patternTimeout 单次正则匹配的超时时间
+	patternTimeout = 100 * time.Millisecond
+)
+
+// FromResponseFormat 解析 response_format 中的 JSON Schema，每次调用返回新的 map
+// json_schema 返回其 name 与 schema；json_object 返回仅要求对象的 schema；其他情况返回 nil
+func FromResponseFormat(format *model.ResponseFormat) (string, map[string]any, error) {
+	if format == nil {
+		return "", nil, nil
+	}
+	switch format.Type {
+	case "json_object":
+		return "", map[string]any{"type": "object"}, nil
+	case "json_schema":
+		var jsonSchema struct {
+			Name   string         `json:"name"`
+			Schema map[string]any `json:"schema"`
+		}
+		if len(format.JSONSchema) == 0 {
+			return "", nil, errors.New("json_schema is required")
+		}
+		if err := json.Unmarshal(format.JSONSchema, &jsonSchema); err != nil {
+			return "", nil, fmt.Errorf("invalid json_schema: %w", err)
+		}
+		if jsonSchema.Schema == nil {
+			jsonSchema.Schema = map[string]any{}
+		}
+		return jsonSchema.Name, jsonSchema.Schema, nil
+	default:
+		return "", nil, nil
+	}
+}
+
+// ValidateJSON 解析 JSON 文本并按 schema 校验
+func ValidateJSON(s map[string]any, text string) error {
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return Validate(s, value)
+}
+
+// Validate 校验 value 是否符合 JSON Schema，value 为 json.Unmarshal 得到的值
+// 支持 type/enum/const、对象与数组约束、数值与字符串约束、组合关键字以及本地 $ref，format 仅作注解不校验
+func Validate(s map[string]any, value any) error {
+	v := &validator{root: s}
+	return v.validate(s, value, "$", 0)
+}
+
+type validator struct {
+	root map[string]any
+}
+
+func (v *validator) validate(node any, value any, path string, depth int) error {
+	if depth > maxDepth {
+		return nil
+	}
+	switch n := node.(type) {
+	case nil:
+		return nil
+	case bool:
+		if !n {
+			return fmt.Errorf("%s: value is not allowed", path)
+		}
+		return nil
+	case map[string]any:
+		return v.validateObject(n, value, path, depth)
+	default:
+		return nil
+	}
+}
+
+func (v *validator) validateObject(node map[string]any, value any, path string, depth int) error {
+	if ref, ok := node["$ref"].(string); ok {
+		resolved, ok := v.resolveRef(ref)
+		if !ok {
+			return fmt.Errorf("%s: unresolvable $ref %q", path, ref)
+		}
+		if err := v.validate(resolved, value, path, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if err := v.validateType(node, value, path); err != nil {
+		return err
+	}
+
+	if enum, ok := node["enum"].([]any); ok {
+		found := false
+		for _, item := range enum {
+			if reflect.DeepEqual(item, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value must be one of %s", path, compactJSON(enum))
+		}
+	}
+	if c, ok := node["const"]; ok && !reflect.DeepEqual(c, value) {
+		return fmt.Errorf("%s: value must be %s", path, compactJSON(c))
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		if err := v.validateProperties(node, val, path, depth); err != nil {
+			return err
+		}
+	case []any:
+		if err := v.validateItems(node, val, path, depth); err != nil {
+			return err
+		}
+	case string:
+		if err := validateString(node, val, path); err != nil {
+			return err
+		}
+	case float64:
+		if err := validateNumber(node, val, path); err != nil {
+			return err
+		}
+	}
+
+	if allOf, ok := node["allOf"].([]any); ok {
+		for _, sub := range allOf {
+			if err := v.validate(sub, value, path, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	if anyOf, ok := node["anyOf"].([]any); ok {
+		var firstErr error
+		matched := false
+		for _, sub := range anyOf {
+			err := v.validate(sub, value, path, depth+1)
+			if err == nil {
+				matched = true
+				break
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: value does not match any schema in anyOf (%v)", path, firstErr)
+		}
+	}
+	if oneOf, ok := node["oneOf"].([]any); ok {
+		matched := 0
+		for _, sub := range oneOf {
+			if v.validate(sub, value, path, depth+1) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: value must match exactly one schema in oneOf, matched %d", path, matched)
+		}
+	}
+	if not, ok := node["not"]; ok {
+		if v.validate(not, value, path, depth+1) == nil {
+			return fmt.Errorf("%s: value must not match the schema in not", path)
+		}
+	}
+	return nil
+}
+
+func (v *validator) validateType(node map[string]any, value any, path string) error {
+	var types []string
+	switch t := node["type"].(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+	default:
+		return nil
+	}
+	// OpenAPI 风格的 nullable
+	if nullable, _ := node["nullable"].(bool); nullable {
+		types = append(types, "null")
+	}
+
+	actual := typeOf(value)
+	for _, t := range types {
+		t = strings.ToLower(t)
+		if t == actual || (t == "number" && actual == "integer") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), actual)
+}
+
+func (v *validator) validateProperties(node map[string]any, value map[string]any, path string, depth int) error {
+	for _, name := range asStrings(node["required"]) {
+		if _, ok := value[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+	if min, ok := asNumber(node["minProperties"]); ok && float64(len(value)) < min {
+		return fmt.Errorf("%s: must have at least %v properties", path, min)
+	}
+	if max, ok := asNumber(node["maxProperties"]); ok && float64(len(value)) > max {
+		return fmt.Errorf("%s: must have at most %v properties", path, max)
+	}
+
+	properties, _ := node["properties"].(map[string]any)
+	patternProperties, _ := node["patternProperties"].(map[string]any)
+	additional, hasAdditional := node["additionalProperties"]
+
+	for name, item := range value {
+		itemPath := path + "." + name
+		matched := false
+		if sub, ok := properties[name]; ok {
+			matched = true
+			if err := v.validate(sub, item, itemPath, depth+1); err != nil {
+				return err
+			}
+		}
+		for pattern, sub := range patternProperties {
+			if ok, err := matchPattern(pattern, name); err == nil && ok {
+				matched = true
+				if err := v.validate(sub, item, itemPath, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		if matched || !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			return fmt.Errorf("%s: additional property %q is not allowed", path, name)
+		}
+		if err := v.validate(additional, item, itemPath, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *validator) validateItems(node map[string]any, value []any, path string, depth int) error {
+	if min, ok := asNumber(node["minItems"]); ok && float64(len(value)) < min {
+		return fmt.Errorf("%s: must have at least %v items", path, min)
+	}
+	if max, ok := asNumber(node["maxItems"]); ok && float64(len(value)) > max {
+		return fmt.Errorf("%s: must have at most %v items", path, max)
+	}
+	if unique, _ := node["uniqueItems"].(bool); unique {
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if reflect.DeepEqual(value[i], value[j]) {
+					return fmt.Errorf("%s: items must be unique", path)
+				}
+			}
+		}
+	}
+
+	// prefixItems 或旧版数组形式的 items 为元组校验
+	prefix, _ := node["prefixItems"].([]any)
+	if tuple, ok := node["items"].([]any); ok && prefix == nil {
+		prefix = tuple
+	}
+	for i, item := range value {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		if i < len(prefix) {
+			if err := v.validate(prefix[i], item, itemPath, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+		if items, ok := node["items"]; ok {
+			if _, isTuple := items.([]any); isTuple {
+				continue
+			}
+			if err := v.validate(items, item, itemPath, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateString(node map[string]any, value string, path string) error {
+	length := float64(utf8.RuneCountInString(value))
+	if min, ok := asNumber(node["minLength"]); ok && length < min {
+		return fmt.Errorf("%s: string must be at least %v characters", path, min)
+	}
+	if max, ok := asNumber(node["maxLength"]); ok && length > max {
+		return fmt.Errorf("%s: string must be at most %v characters", path, max)
+	}
+	if pattern, ok := node["pattern"].(string); ok {
+		// 无法编译的正则不参与校验
+		if matched, err := matchPattern(pattern, value); err == nil && !matched {
+			return fmt.Errorf("%s: string does not match pattern %q", path, pattern)
+		}
+	}
+	return nil
+}
+
+func validateNumber(node map[string]any, value float64, path string) error {
+	if min, ok := asNumber(node["minimum"]); ok {
+		if exclusive, _ := node["exclusiveMinimum"].(bool); exclusive && value <= min {
+			return fmt.Errorf("%s: must be greater than %v", path, min)
+		}
+		if value < min {
+			return fmt.Errorf("%s: must be greater than or equal to %v", path, min)
+		}
+	}
+	if max, ok := asNumber(node["maximum"]); ok {
+		if exclusive, _ := node["exclusiveMaximum"].(bool); exclusive && value >= max {
+			return fmt.Errorf("%s: must be less than %v", path, max)
+		}
+		if value > max {
+			return fmt.Errorf("%s: must be less than or equal to %v", path, max)
+		}
+	}
+	if min, ok := asNumber(node["exclusiveMinimum"]); ok && value <= min {
+		return fmt.Errorf("%s: must be greater than %v", path, min)
+	}
+	if max, ok := asNumber(node["exclusiveMaximum"]); ok && value >= max {
+		return fmt.Errorf("%s: must be less than %v", path, max)
+	}
+	if multipleOf, ok := asNumber(node["multipleOf"]); ok && multipleOf > 0 {
+		if q := value / multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			return fmt.Errorf("%s: must be a multiple of %v", path, multipleOf)
+		}
+	}
+	return nil
+}
+
+// resolveRef 解析本地 $ref，如 #/$defs/Item
+func (v *validator) resolveRef(ref string) (any, bool) {
+	if ref == "#" {
+		return v.root, true
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	var cur any = v.root
+	for _, seg := range strings.Split(ref[2:], "/") {
+		seg = strings.ReplaceAll(seg, "~1", "/")
+		seg = strings.ReplaceAll(seg, "~0", "~")
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[seg]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func typeOf(value any) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func matchPattern(pattern, value string) (bool, error) {
+	re, err := regexp2.Compile(pattern, regexp2.ECMAScript)
+	if err != nil {
+		return false, err
+	}
+	// 正则来自客户端，限制回溯耗时
+	re.MatchTimeout = patternTimeout
+	return re.MatchString(value)
+}
+
+func asNumber(v any) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
+
+func asStrings(v any) []string {
+	items, _ := v.([]any)
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func compactJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,119 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/octopus/internal/transformer/model"
+)
+
+func TestFromResponseFormat(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   *model.ResponseFormat
+		wantName string
+		want     string
+		wantErr  bool
+	}{
+		{"nil", nil, "", "null", false},
+		{"text", &model.ResponseFormat{Type: "text"}, "", "null", false},
+		{"json_object", &model.ResponseFormat{Type: "json_object"}, "", `{"type":"object"}`, false},
+		{"json_schema", &model.ResponseFormat{Type: "json_schema", JSONSchema: json.RawMessage(`{"name":"answer","schema":{"type":"string"}}`)}, "answer", `{"type":"string"}`, false},
+		{"json_schema without schema", &model.ResponseFormat{Type: "json_schema", JSONSchema: json.RawMessage(`{"name":"any"}`)}, "any", `{}`, false},
+		{"json_schema missing", &model.ResponseFormat{Type: "json_schema"}, "", "null", true},
+		{"json_schema invalid", &model.ResponseFormat{Type: "json_schema", JSONSchema: json.RawMessage(`[]`)}, "", "null", true},
+	}
+	for _, c := range cases {
+		name, s, err := FromResponseFormat(c.format)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: err = %v, want error %t", c.name, err, c.wantErr)
+			continue
+		}
+		got, _ := json.Marshal(s)
+		if name != c.wantName || string(got) != c.want {
+			t.Errorf("%s: got %q %s, want %q %s", c.name, name, got, c.wantName, c.want)
+		}
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		value  string
+		// wantErr 为空表示校验通过，否则为错误信息中应包含的内容
+		wantErr string
+	}{
+		{"invalid JSON", `{}`, `{"a":`, "invalid JSON"},
+		{"empty schema", `{}`, `[1,"a",null]`, ""},
+
+		{"type", `{"type":"string"}`, `"a"`, ""},
+		{"type mismatch", `{"type":"string"}`, `1`, "$: expected string, got integer"},
+		{"integer is number", `{"type":"number"}`, `1`, ""},
+		{"number is not integer", `{"type":"integer"}`, `1.5`, "expected integer, got number"},
+		{"type union", `{"type":["string","null"]}`, `null`, ""},
+		{"nullable", `{"type":"string","nullable":true}`, `null`, ""},
+		{"enum", `{"enum":["a","b"]}`, `"c"`, `value must be one of ["a","b"]`},
+		{"const", `{"const":{"a":1}}`, `{"a":1}`, ""},
+		{"const mismatch", `{"const":1}`, `2`, "value must be 1"},
+
+		{"required", `{"type":"object","required":["a"]}`, `{}`, `missing required property "a"`},
+		{"properties", `{"properties":{"a":{"type":"integer"}}}`, `{"a":"x"}`, "$.a: expected integer"},
+		{"additionalProperties false", `{"properties":{"a":{}},"additionalProperties":false}`, `{"a":1,"b":2}`, `additional property "b" is not allowed`},
+		{"additionalProperties schema", `{"additionalProperties":{"type":"string"}}`, `{"b":2}`, "$.b: expected string"},
+		{"patternProperties", `{"patternProperties":{"^x-":{"type":"string"}},"additionalProperties":false}`, `{"x-a":"1"}`, ""},
+		{"minProperties", `{"minProperties":2}`, `{"a":1}`, "at least 2 properties"},
+		{"maxProperties", `{"maxProperties":1}`, `{"a":1,"b":2}`, "at most 1 properties"},
+
+		{"items", `{"items":{"type":"string"}}`, `["a",1]`, "$[1]: expected string"},
+		{"prefixItems", `{"prefixItems":[{"type":"string"},{"type":"integer"}],"items":false}`, `["a",1,true]`, "$[2]: value is not allowed"},
+		{"tuple items", `{"items":[{"type":"string"}]}`, `["a",1]`, ""},
+		{"minItems", `{"minItems":1}`, `[]`, "at least 1 items"},
+		{"maxItems", `{"maxItems":1}`, `[1,2]`, "at most 1 items"},
+		{"uniqueItems", `{"uniqueItems":true}`, `[{"a":1},{"a":1}]`, "items must be unique"},
+
+		{"minLength counts runes", `{"minLength":2}`, `"你好"`, ""},
+		{"maxLength", `{"maxLength":1}`, `"ab"`, "at most 1 characters"},
+		{"pattern", `{"pattern":"^\\d+$"}`, `"12a"`, "does not match pattern"},
+		{"invalid pattern ignored", `{"pattern":"("}`, `"a"`, ""},
+		{"minimum", `{"minimum":1}`, `0`, "greater than or equal to 1"},
+		{"maximum", `{"maximum":1}`, `2`, "less than or equal to 1"},
+		{"exclusiveMinimum number", `{"exclusiveMinimum":1}`, `1`, "must be greater than 1"},
+		{"exclusiveMaximum draft 4", `{"maximum":1,"exclusiveMaximum":true}`, `1`, "must be less than 1"},
+		{"multipleOf", `{"multipleOf":0.1}`, `0.3`, ""},
+		{"multipleOf mismatch", `{"multipleOf":2}`, `3`, "multiple of 2"},
+
+		{"allOf", `{"allOf":[{"type":"integer"},{"minimum":5}]}`, `3`, "greater than or equal to 5"},
+		{"anyOf", `{"anyOf":[{"type":"string"},{"type":"integer"}]}`, `true`, "does not match any schema in anyOf"},
+		{"oneOf", `{"oneOf":[{"type":"integer"},{"type":"number"}]}`, `1`, "matched 2"},
+		{"not", `{"not":{"type":"null"}}`, `null`, "must not match"},
+
+		{"ref", `{"$defs":{"id":{"type":"integer"}},"properties":{"id":{"$ref":"#/$defs/id"}}}`, `{"id":"x"}`, "$.id: expected integer"},
+		{"recursive ref", `{"type":"object","properties":{"child":{"$ref":"#"}},"additionalProperties":false}`, `{"child":{"child":{"x":1}}}`, `additional property "x"`},
+		{"unresolvable ref", `{"$ref":"#/$defs/missing"}`, `1`, `unresolvable $ref`},
+	}
+	for _, c := range cases {
+		var s map[string]any
+		if err := json.Unmarshal([]byte(c.schema), &s); err != nil {
+			t.Fatalf("%s: invalid schema: %v", c.name, err)
+		}
+		err := ValidateJSON(s, c.value)
+		switch {
+		case c.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		case c.wantErr != "" && err == nil:
+			t.Errorf("%s: expected error containing %q", c.name, c.wantErr)
+		case c.wantErr != "" && !strings.Contains(err.Error(), c.wantErr):
+			t.Errorf("%s: error %q does not contain %q", c.name, err, c.wantErr)
+		}
+	}
+}
+
+// 循环引用在深度上限处停止，不会无限递归
+func TestValidateRefCycle(t *testing.T) {
+	s := map[string]any{"$ref": "#"}
+	if err := Validate(s, map[string]any{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
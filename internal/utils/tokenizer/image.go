@@ -0,0 +1,88 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"image"
+	"math"
+	"strings"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/bestruirui/octopus/internal/utils/xurl"
+)
+
+// CountImageTokens 估算一张图片的输入 token 数，按模型所属厂商的计费规则计算
+// 只能从 data URL 中读取尺寸，远程 URL 或无法解码的格式按该厂商单图的上限估算
+func CountImageTokens(model, url, detail string) int {
+	width, height, ok := imageSize(url)
+	name := strings.ToLower(model)
+	switch {
+	case strings.Contains(name, "claude"):
+		return anthropicImageTokens(width, height, ok)
+	case strings.Contains(name, "gemini") || strings.Contains(name, "gemma"):
+		return geminiImageTokens(width, height, ok)
+	default:
+		return openAIImageTokens(width, height, ok, detail)
+	}
+}
+
+// openAIImageTokens 低精度固定 85；高精度先缩放到 2048 以内、短边缩放到 768，每个 512 切片 170，另加 85
+func openAIImageTokens(width, height int, ok bool, detail string) int {
+	if detail == "low" {
+		return 85
+	}
+	if !ok {
+		return 765
+	}
+	w, h := float64(width), float64(height)
+	if longest := math.Max(w, h); longest > 2048 {
+		w, h = w*2048/longest, h*2048/longest
+	}
+	if shortest := math.Min(w, h); shortest > 768 {
+		w, h = w*768/shortest, h*768/shortest
+	}
+	tiles := int(math.Ceil(w/512) * math.Ceil(h/512))
+	return tiles*170 + 85
+}
+
+// anthropicImageTokens 长边超过 1568 或超过约 1.15MP 时先等比缩小，再按 宽×高/750 计算
+func anthropicImageTokens(width, height int, ok bool) int {
+	const maxTokens = 1600
+	if !ok {
+		return maxTokens
+	}
+	w, h := float64(width), float64(height)
+	if longest := math.Max(w, h); longest > 1568 {
+		w, h = w*1568/longest, h*1568/longest
+	}
+	if pixels := w * h; pixels > 1_150_000 {
+		scale := math.Sqrt(1_150_000 / pixels)
+		w, h = w*scale, h*scale
+	}
+	return min(int(math.Ceil(w*h/750)), maxTokens)
+}
+
+// geminiImageTokens 两边都不超过 384 时固定 258，否则按 768 切片，每片 258
+func geminiImageTokens(width, height int, ok bool) int {
+	const perTile = 258
+	if !ok || (width <= 384 && height <= 384) {
+		return perTile
+	}
+	tiles := int(math.Ceil(float64(width)/768) * math.Ceil(float64(height)/768))
+	return tiles * perTile
+}
+
+// imageSize 从 base64 data URL 中读取图片尺寸，只解码文件头
+func imageSize(url string) (int, int, bool) {
+	dataURL := xurl.ParseDataURL(url)
+	if dataURL == nil || !dataURL.IsBase64 {
+		return 0, 0, false
+	}
+	cfg, _, err := image.DecodeConfig(base64.NewDecoder(base64.StdEncoding, strings.NewReader(dataURL.Data)))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return 0, 0, false
+	}
+	return cfg.Width, cfg.Height, true
+}
@@ -0,0 +1,119 @@
+package tokenizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 未公开分词器的厂商，按官方计数接口校准
+var (
+	// Claude 相同文本约为 o200k_base 的 1.2 倍
+	Claude = Approximate("claude", O200kBase, 1.2)
+	// Gemini 256K SentencePiece 词表，Gemma 与其共用
+	Gemini = Approximate("gemini", O200kBase, 1.05)
+)
+
+// builtinEncodings 自定义规则可以引用的分词器，按名称查找
+// 未内置 Llama、Qwen、DeepSeek、Mistral 等开源模型的词表，这些模型按默认的 o200k_base 计数，可通过 SetRules 指定
+var builtinEncodings = []Encoding{O200kBase, Cl100kBase, Claude, Gemini}
+
+type rule struct {
+	pattern  *regexp.Regexp
+	encoding Encoding
+}
+
+// defaultRules 按顺序匹配模型名，更具体的规则在前
+var defaultRules = []rule{
+	{regexp.MustCompile(`gpt-4o|gpt-4\.1|gpt-4\.5|gpt-5|gpt-oss|chatgpt|codex|(^|[/:])o\d`), O200kBase},
+	{regexp.MustCompile(`gpt-4|gpt-3\.5|gpt-35|text-embedding|ada-002`), Cl100kBase},
+	{regexp.MustCompile(`claude`), Claude},
+	{regexp.MustCompile(`gemini|gemma`), Gemini},
+}
+
+// Rule 自定义的模型名匹配规则，Encoding 为内置分词器名称 (如 cl100k_base)，Factor 大于 0 时按系数缩放计数
+type Rule struct {
+	Match    string  `json:"match"`
+	Encoding string  `json:"encoding"`
+	Factor   float64 `json:"factor,omitempty"`
+}
+
+var (
+	customRules   []rule
+	customRulesMu sync.RWMutex
+)
+
+// Lookup 按名称查找内置分词器
+func Lookup(name string) (Encoding, bool) {
+	for _, encoding := range builtinEncodings {
+		if encoding.Name() == name {
+			return encoding, true
+		}
+	}
+	return nil, false
+}
+
+func compileRules(rules []Rule) ([]rule, error) {
+	compiled := make([]rule, 0, len(rules))
+	for _, r := range rules {
+		if r.Match == "" {
+			return nil, fmt.Errorf("tokenizer rule pattern must not be empty")
+		}
+		re, err := regexp.Compile("(?i)" + r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tokenizer rule pattern %q: %w", r.Match, err)
+		}
+		encoding, ok := Lookup(r.Encoding)
+		if !ok {
+			return nil, fmt.Errorf("unknown tokenizer encoding %q", r.Encoding)
+		}
+		if r.Factor < 0 {
+			return nil, fmt.Errorf("tokenizer rule factor must not be negative")
+		}
+		if r.Factor > 0 && r.Factor != 1 {
+			encoding = Approximate(fmt.Sprintf("%s*%g", encoding.Name(), r.Factor), encoding, r.Factor)
+		}
+		compiled = append(compiled, rule{pattern: re, encoding: encoding})
+	}
+	return compiled, nil
+}
+
+// ValidateRules 检查规则能否编译，不修改当前生效的规则
+func ValidateRules(rules []Rule) error {
+	_, err := compileRules(rules)
+	return err
+}
+
+// SetRules 替换自定义规则，按顺序匹配 (忽略大小写)，优先于内置规则
+// 规则有误时返回错误并保留原有规则
+func SetRules(rules []Rule) error {
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return err
+	}
+	customRulesMu.Lock()
+	customRules = compiled
+	customRulesMu.Unlock()
+	return nil
+}
+
+// ForModel 返回模型对应的分词器，未匹配时使用 o200k_base
+func ForModel(model string) Encoding {
+	customRulesMu.RLock()
+	for _, r := range customRules {
+		if r.pattern.MatchString(model) {
+			customRulesMu.RUnlock()
+			return r.encoding
+		}
+	}
+	customRulesMu.RUnlock()
+
+	model = strings.ToLower(model)
+	for _, r := range defaultRules {
+		if r.pattern.MatchString(model) {
+			return r.encoding
+		}
+	}
+	return O200kBase
+}
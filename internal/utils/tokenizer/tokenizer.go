@@ -1,15 +1,73 @@
 package tokenizer
 
 import (
+	"math"
+	"sync"
+
 	"github.com/tiktoken-go/tokenizer/codec"
 )
 
-func CountTokens(content, model string) int {
-	// TODO 更多模型
-	enc := codec.NewO200kBase()
-	tc, err := enc.Count(content)
+// Encoding 统计一段文本的 token 数
+type Encoding interface {
+	Name() string
+	Count(text string) int
+}
+
+// bpeEncoding 内置的 tiktoken BPE 词表，首次使用时才加载
+type bpeEncoding struct {
+	name  string
+	codec func() *codec.Codec
+}
+
+// newBPE 词表加载开销较大，同一词表只构建一次
+func newBPE(name string, build func() *codec.Codec) *bpeEncoding {
+	return &bpeEncoding{name: name, codec: sync.OnceValue(build)}
+}
+
+func (e *bpeEncoding) Name() string { return e.name }
+
+func (e *bpeEncoding) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	n, err := e.codec().Count(text)
 	if err != nil {
 		return 0
 	}
-	return tc
+	return n
+}
+
+// scaledEncoding 以相近词表的计数乘以校准系数近似，用于没有公开分词器的模型与自定义规则
+// 系数为英文、中文与代码混合语料上与官方计数的比值
+type scaledEncoding struct {
+	name   string
+	base   Encoding
+	factor float64
+}
+
+// Approximate 基于已有词表按系数近似
+func Approximate(name string, base Encoding, factor float64) Encoding {
+	return &scaledEncoding{name: name, base: base, factor: factor}
+}
+
+func (e *scaledEncoding) Name() string { return e.name }
+
+func (e *scaledEncoding) Count(text string) int {
+	n := e.base.Count(text)
+	if n == 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(n) * e.factor))
+}
+
+var (
+	// O200kBase GPT-4o、o 系列、GPT-4.1/5 使用的词表
+	O200kBase Encoding = newBPE("o200k_base", codec.NewO200kBase)
+	// Cl100kBase GPT-4、GPT-3.5 与 embedding 模型使用的词表
+	Cl100kBase Encoding = newBPE("cl100k_base", codec.NewCl100kBase)
+)
+
+// CountTokens 使用模型对应的分词器统计文本 token 数
+func CountTokens(content, model string) int {
+	return ForModel(model).Count(content)
 }
@@ -0,0 +1,91 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestForModel(t *testing.T) {
+	cases := map[string]Encoding{
+		"gpt-4o-mini":             O200kBase,
+		"o3-mini":                 O200kBase,
+		"openai/o4-mini":          O200kBase,
+		"gpt-4-turbo":             Cl100kBase,
+		"text-embedding-3-small":  Cl100kBase,
+		"claude-sonnet-4-5":       Claude,
+		"gemini-2.5-pro":          Gemini,
+		"meta-llama/Llama-3.1-8B": O200kBase,
+		"Qwen/Qwen3-235B-A22B":    O200kBase,
+		"some-unknown-model":      O200kBase,
+	}
+	for name, want := range cases {
+		if got := ForModel(name); got != want {
+			t.Errorf("ForModel(%q) = %s, want %s", name, got.Name(), want.Name())
+		}
+	}
+}
+
+func TestSetRules(t *testing.T) {
+	t.Cleanup(func() { SetRules(nil) })
+	if err := SetRules([]Rule{
+		{Match: `^my-org/`, Encoding: "cl100k_base", Factor: 2},
+		{Match: `^my-org/`, Encoding: "o200k_base"},
+		{Match: `^local-qwen`, Encoding: "claude"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got := ForModel("My-Org/gpt-4o")
+	if a, b := got.Count("hello world"), Cl100kBase.Count("hello world"); a != b*2 {
+		t.Errorf("scaled count = %d, want %d", a, b*2)
+	}
+	if got := ForModel("local-qwen-7b"); got != Claude {
+		t.Errorf("ForModel(local-qwen-7b) = %s, want %s", got.Name(), Claude.Name())
+	}
+
+	// 规则有误时保留原有规则
+	for _, rules := range [][]Rule{
+		{{Match: `(`, Encoding: "cl100k_base"}},
+		{{Match: `x`, Encoding: "llama3"}},
+		{{Match: `x`, Encoding: "cl100k_base", Factor: -1}},
+	} {
+		if err := SetRules(rules); err == nil {
+			t.Errorf("invalid rules accepted: %+v", rules)
+		}
+	}
+	if got := ForModel("local-qwen-7b"); got != Claude {
+		t.Errorf("rules replaced by invalid update, got %s", got.Name())
+	}
+
+	SetRules(nil)
+	if got := ForModel("my-org/gpt-4o"); got != O200kBase {
+		t.Errorf("rules not cleared, got %s", got.Name())
+	}
+}
+
+func TestCountImageTokens(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1024, 1024))); err != nil {
+		t.Fatal(err)
+	}
+	url := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	cases := []struct {
+		model, url, detail string
+		want               int
+	}{
+		{"gpt-4o", url, "", 765},
+		{"gpt-4o", url, "low", 85},
+		{"gpt-4o", "https://example.com/a.png", "", 765},
+		{"claude-sonnet-4", url, "", 1399},
+		{"claude-sonnet-4", "https://example.com/a.png", "", 1600},
+		{"gemini-2.5-flash", url, "", 4 * 258},
+	}
+	for _, c := range cases {
+		if got := CountImageTokens(c.model, c.url, c.detail); got != c.want {
+			t.Errorf("CountImageTokens(%s, %.20s, %q) = %d, want %d", c.model, c.url, c.detail, got, c.want)
+		}
+	}
+}
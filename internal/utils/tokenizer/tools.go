@@ -0,0 +1,38 @@
+package tokenizer
+
+import "strings"
+
+// CountToolTokens 估算一个工具定义的 token 数：名称、描述、参数 JSON Schema，另加格式化开销
+func CountToolTokens(model, name, description, parameters string) int {
+	enc := ForModel(model)
+	return enc.Count(name) + enc.Count(description) + enc.Count(parameters) + 8
+}
+
+// ToolsOverhead 请求携带工具时上游额外注入的 token 数
+// Anthropic 会插入工具调用说明的系统提示词 (约 346)，OpenAI 将工具包装为命名空间声明 (约 12)
+func ToolsOverhead(model string, n int) int {
+	if n == 0 {
+		return 0
+	}
+	name := strings.ToLower(model)
+	switch {
+	case strings.Contains(name, "claude"):
+		return 346
+	case strings.Contains(name, "gemini") || strings.Contains(name, "gemma"):
+		return 0
+	default:
+		return 12
+	}
+}
+
+// MessagesOverhead chat 格式中每条消息的角色与分隔符开销，以及回复前缀的 3 个 token
+// 仅 OpenAI 系列词表按此格式计数
+func MessagesOverhead(model string, n int) int {
+	if n == 0 {
+		return 0
+	}
+	if enc := ForModel(model); enc != O200kBase && enc != Cl100kBase {
+		return 0
+	}
+	return n*3 + 3
+}
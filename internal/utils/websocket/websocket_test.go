@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoServer 原样返回收到的消息，收到关闭帧后结束
+func echoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Accept(w, r, "realtime")
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestDialEcho(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, resp, err := Dial(ctx, server.Client(), strings.Replace(server.URL, "http", "ws", 1), http.Header{"Sec-WebSocket-Protocol": {"realtime"}})
+	// 握手完成后取消上下文不应影响已建立的连接
+	cancel()
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != "realtime" {
+		t.Errorf("subprotocol = %q, want realtime", got)
+	}
+
+	messages := []struct {
+		messageType int
+		data        string
+	}{
+		{TextMessage, `{"type":"session.update"}`},
+		{BinaryMessage, "\x00\x01\x02"},
+		{TextMessage, strings.Repeat("a", 70000)},
+	}
+	for _, m := range messages {
+		if err := conn.WriteMessage(m.messageType, []byte(m.data)); err != nil {
+			t.Fatalf("WriteMessage() error = %v", err)
+		}
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		if messageType != m.messageType || string(data) != m.data {
+			t.Errorf("echo = (%d, %d bytes), want (%d, %d bytes)", messageType, len(data), m.messageType, len(m.data))
+		}
+	}
+
+	if err := conn.WriteClose(CloseNormalClosure, "bye"); err != nil {
+		t.Fatalf("WriteClose() error = %v", err)
+	}
+	_, _, err = conn.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseNormalClosure {
+		t.Errorf("ReadMessage() after close = %v, want close 1000", err)
+	}
+}
+
+func TestAcceptRejectsPlainRequest(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUpgradeRequired)
+	}
+}
@@ -132,14 +132,37 @@ def generate_claude_aliases(model_id: str) -> list[str]:
     return aliases
 
 
+# 可选的细分价格: models.dev 字段 -> model.LLMPrice 字段
+OPTIONAL_PRICES = {
+    "reasoning": "Reasoning",
+    "input_audio": "InputAudio",
+    "output_audio": "OutputAudio",
+}
+
+# models.dev 中 context_over_200k 价格的生效阈值
+CONTEXT_OVER_200K_THRESHOLD = 200000
+
+
 def generate_entry(model_id: str, cost: dict) -> str:
     """生成单个模型的 Go map entry"""
     input_price = format_price(cost.get("input"))
     output_price = format_price(cost.get("output"))
     cache_read = format_price(cost.get("cache_read"))
     cache_write = format_price(cost.get("cache_write"))
-    
-    return f'\t"{model_id}": {{Input: {input_price}, Output: {output_price}, CacheRead: {cache_read}, CacheWrite: {cache_write}}},'
+
+    extra = ""
+    for key, field in OPTIONAL_PRICES.items():
+        if cost.get(key):
+            extra += f", {field}: {format_price(cost[key])}"
+    tier = cost.get("context_over_200k")
+    if tier:
+        extra += (
+            f", ContextTiers: []model.LLMPriceTier{{{{Threshold: {CONTEXT_OVER_200K_THRESHOLD}, "
+            f"Input: {format_price(tier.get('input'))}, Output: {format_price(tier.get('output'))}, "
+            f"CacheRead: {format_price(tier.get('cache_read'))}, CacheWrite: {format_price(tier.get('cache_write'))}}}}}"
+        )
+
+    return f'\t"{model_id}": {{Input: {input_price}, Output: {output_price}, CacheRead: {cache_read}, CacheWrite: {cache_write}{extra}}},'
 
 
 def main():
@@ -3,8 +3,29 @@ import { apiClient } from '../client';
 import { logger } from '@/lib/logger';
 import { useAuthStore } from './user';
 import { StatsAPIKey, StatsAPIKeyFormatted } from './stats';
+import type { ReasoningPolicy } from './group';
 import { formatCount, formatMoney, formatTime } from '@/lib/utils';
 
+/**
+ * API Key 限额
+ */
+export type APIKeyLimitMetric = 'cost' | 'tokens' | 'requests';
+export type APIKeyLimitWindow = 'minute' | 'hour' | 'day' | 'week' | 'month';
+
+export interface APIKeyLimit {
+    metric: APIKeyLimitMetric;
+    window: APIKeyLimitWindow;
+    max: number;
+}
+
+/**
+ * 限额在当前窗口的用量
+ */
+export interface APIKeyLimitState extends APIKeyLimit {
+    used: number;
+    reset_at: number;
+}
+
 /**
  * API Key 数据
  */
@@ -21,6 +42,38 @@ export interface APIKey {
     reset_duration?: number;
     reset_unit?: string;
     next_reset_time?: number;
+    reasoning_policy?: ReasoningPolicy;
+    prepaid?: boolean;
+    limits?: APIKeyLimit[];
+}
+
+/**
+ * 余额流水
+ */
+export type TransactionType = 'topup' | 'debit' | 'adjustment' | 'refund';
+
+export interface APIKeyTransaction {
+    id: number;
+    api_key_id: number;
+    time: number;
+    type: TransactionType;
+    amount: number;
+    balance: number;
+    model?: string;
+    note?: string;
+}
+
+export interface APIKeyTransactionList {
+    balance: number;
+    total: number;
+    transactions: APIKeyTransaction[];
+}
+
+export interface CreateAPIKeyTransactionRequest {
+    api_key_id: number;
+    type: Exclude<TransactionType, 'debit'>;
+    amount: number;
+    note?: string;
 }
 
 /**
@@ -29,11 +82,16 @@ export interface APIKey {
 export interface APIKeyStatsResponse {
     stats: StatsAPIKey;
     info: APIKey;
+    limits?: APIKeyLimitState[];
+    balance?: number;
 }
 
 export interface APIKeyStatsResponseFormatted {
     stats: StatsAPIKeyFormatted;
+    quota_cost: number;
     info: APIKey;
+    limits?: APIKeyLimitState[];
+    balance?: number;
 }
 
 /**
@@ -74,12 +132,16 @@ export function useAPIKeyDashboardStats() {
                 input_cost: formatMoney(data.stats.input_cost),
                 output_cost: formatMoney(data.stats.output_cost),
                 total_cost: formatMoney(data.stats.input_cost + data.stats.output_cost),
+                upstream_cost: formatMoney(data.stats.upstream_cost ?? 0),
                 wait_time: formatTime(data.stats.wait_time),
                 request_success: formatCount(data.stats.request_success),
                 request_failed: formatCount(data.stats.request_failed),
                 request_count: formatCount(data.stats.request_success + data.stats.request_failed),
             },
+            quota_cost: data.stats.quota_cost ?? data.stats.input_cost + data.stats.output_cost,
             info: data.info,
+            limits: data.limits,
+            balance: data.balance,
         }),
         enabled: isAPIKeyAuth && isAuthenticated,
         refetchInterval: 30000,
@@ -220,6 +282,7 @@ export function useAPIKeyStats() {
             input_cost: formatMoney(data.input_cost),
             output_cost: formatMoney(data.output_cost),
             total_cost: formatMoney(data.input_cost + data.output_cost),
+            upstream_cost: formatMoney(data.upstream_cost ?? 0),
             wait_time: formatTime(data.wait_time),
             request_success: formatCount(data.request_success),
             request_failed: formatCount(data.request_failed),
@@ -229,3 +292,37 @@ export function useAPIKeyStats() {
         refetchOnMount: 'always',
     });
 }
+
+/**
+ * 获取 API Key 余额流水 Hook
+ */
+export function useAPIKeyTransactions(apiKeyId: number, page = 1, pageSize = 20) {
+    return useQuery({
+        queryKey: ['apikeys', 'transactions', apiKeyId, page, pageSize],
+        queryFn: async () => {
+            return apiClient.get<APIKeyTransactionList>(
+                `/api/v1/apikey/transaction/list?api_key_id=${apiKeyId}&page=${page}&page_size=${pageSize}`
+            );
+        },
+    });
+}
+
+/**
+ * 充值、退款或调整 API Key 余额 Hook
+ */
+export function useCreateAPIKeyTransaction() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (data: CreateAPIKeyTransactionRequest) => {
+            return apiClient.post<APIKeyTransaction>('/api/v1/apikey/transaction/create', data);
+        },
+        onSuccess: (data) => {
+            logger.log('API Key 余额变更成功:', data);
+            queryClient.invalidateQueries({ queryKey: ['apikeys', 'transactions', data.api_key_id] });
+        },
+        onError: (error) => {
+            logger.error('API Key 余额变更失败:', error);
+        },
+    });
+}
@@ -3,6 +3,7 @@ import { apiClient } from '../client';
 import { logger } from '@/lib/logger';
 import { formatCount, formatMoney, formatTime } from '@/lib/utils';
 import { StatsChannel, type StatsMetricsFormatted } from './stats';
+import { type LLMPrice } from './model';
 /**
  * 渠道类型枚举
  */
@@ -13,11 +14,31 @@ export enum ChannelType {
     Gemini = 3,
     Volcengine = 4,
     OpenAIEmbedding = 5,
+    Vertex = 6,
+    AzureChat = 7,
+    AzureResponse = 8,
+    AzureEmbedding = 9,
+    Ollama = 10,
 }
 
 /**
  * 自动分组类型枚举
  */
+/**
+ * 远程图片/文件处理方式：'' 自动 / inline 始终内联 / url 始终透传地址
+ */
+export type MediaMode = '' | 'inline' | 'url';
+
+/**
+ * 上游请求的流式模式：'' 跟随客户端 / stream 始终流式 / non_stream 始终非流式
+ */
+export type StreamMode = '' | 'stream' | 'non_stream';
+
+/**
+ * Anthropic 自动缓存断点策略：'' 关闭 / auto 缓存 5 分钟 / auto_1h 缓存 1 小时
+ */
+export type PromptCacheMode = '' | 'auto' | 'auto_1h';
+
 export enum AutoGroupType {
     None = 0,   // 不自动分组
     Fuzzy = 1,  // 模糊匹配
@@ -35,6 +56,13 @@ export type CustomHeader = {
     header_value: string;
 };
 
+/**
+ * 渠道上某个模型的实际价格，优先于价格倍率
+ */
+export type ChannelPriceOverride = LLMPrice & {
+    model: string;
+};
+
 export type ChannelKey = {
     id: number;
     channel_id: number;
@@ -60,17 +88,26 @@ export type Channel = {
     custom_model: string;
     proxy: boolean;
     auto_sync: boolean;
+    prompt_tool_call: boolean;
+    media_mode: MediaMode;
+    stream_mode: StreamMode;
+    prompt_cache: PromptCacheMode;
+    prompt_cache_min_tokens: number;
+    price_multiplier?: number | null;
+    price_overrides: ChannelPriceOverride[];
     auto_group: AutoGroupType;
     custom_header: CustomHeader[];
     param_override?: string | null;
+    model_mapping?: string | null;
     channel_proxy?: string | null;
     match_regex?: string | null;
     stats: StatsChannel;
 };
 
 // Internal type: backend may return null for slice fields; normalize to [] in select()
-type ChannelServer = Omit<Channel, 'base_urls' | 'custom_header' | 'keys'> & {
+type ChannelServer = Omit<Channel, 'base_urls' | 'custom_header' | 'keys' | 'price_overrides'> & {
     base_urls: BaseUrl[] | null;
+    price_overrides: ChannelPriceOverride[] | null;
     custom_header: CustomHeader[] | null;
     keys: ChannelKey[] | null;
 };
@@ -88,10 +125,18 @@ export type CreateChannelRequest = {
     custom_model?: string;
     proxy?: boolean;
     auto_sync?: boolean;
+    prompt_tool_call?: boolean;
+    media_mode?: MediaMode;
+    stream_mode?: StreamMode;
+    prompt_cache?: PromptCacheMode;
+    prompt_cache_min_tokens?: number;
+    price_multiplier?: number;
+    price_overrides?: ChannelPriceOverride[];
     auto_group?: AutoGroupType;
     custom_header?: CustomHeader[];
     channel_proxy?: string | null;
     param_override?: string | null;
+    model_mapping?: string | null;
     match_regex?: string | null;
 };
 
@@ -108,10 +153,18 @@ export type UpdateChannelRequest = {
     custom_model?: string;
     proxy?: boolean;
     auto_sync?: boolean;
+    prompt_tool_call?: boolean;
+    media_mode?: MediaMode;
+    stream_mode?: StreamMode;
+    prompt_cache?: PromptCacheMode;
+    prompt_cache_min_tokens?: number;
+    price_multiplier?: number;
+    price_overrides?: ChannelPriceOverride[];
     auto_group?: AutoGroupType;
     custom_header?: CustomHeader[];
     channel_proxy?: string | null;
     param_override?: string | null;
+    model_mapping?: string | null;
     match_regex?: string | null;
     // keys diff
     keys_to_add?: Array<Pick<ChannelKey, 'enabled' | 'channel_key' | 'remark'>>;
@@ -150,6 +203,7 @@ export function useChannelList() {
                 base_urls: item.base_urls ?? [],
                 custom_header: item.custom_header ?? [],
                 keys: item.keys ?? [],
+                price_overrides: item.price_overrides ?? [],
             }) satisfies Channel,
             formatted: {
                 input_token: formatCount(item.stats.input_token),
@@ -158,6 +212,7 @@ export function useChannelList() {
                 input_cost: formatMoney(item.stats.input_cost),
                 output_cost: formatMoney(item.stats.output_cost),
                 total_cost: formatMoney(item.stats.input_cost + item.stats.output_cost),
+                upstream_cost: formatMoney(item.stats.upstream_cost ?? 0),
                 request_success: formatCount(item.stats.request_success),
                 request_failed: formatCount(item.stats.request_failed),
                 request_count: formatCount(item.stats.request_success + item.stats.request_failed),
@@ -24,6 +24,13 @@ export enum GroupMode {
     Weighted = 4,
 }
 
+/**
+ * 结构化输出处理方式
+ */
+export type StructuredOutputMode = '' | 'native' | 'prompt' | 'tool';
+
+export type ReasoningPolicy = '' | 'passthrough' | 'strip' | 'think_tags' | 'text';
+
 /**
  * 分组信息
  */
@@ -33,6 +40,9 @@ export interface Group {
     mode: GroupMode;
     match_regex: string;
     first_token_time_out?: number;
+    structured_output?: StructuredOutputMode;
+    structured_output_retries?: number;
+    reasoning_policy?: ReasoningPolicy;
     items?: GroupItem[];
 }
 
@@ -64,6 +74,9 @@ export interface GroupUpdateRequest {
     mode?: GroupMode;                     // 仅在模式变更时发送
     match_regex?: string;                 // 仅在匹配正则变更时发送
     first_token_time_out?: number;        // 仅在超时变更时发送
+    structured_output?: StructuredOutputMode; // 仅在结构化输出方式变更时发送
+    structured_output_retries?: number;   // 仅在重试次数变更时发送
+    reasoning_policy?: ReasoningPolicy;   // 仅在推理处理方式变更时发送
     items_to_add?: GroupItemAddRequest[];    // 新增的 items
     items_to_update?: GroupItemUpdateRequest[]; // 更新的 items (priority 变更)
     items_to_delete?: number[];              // 删除的 item IDs
@@ -30,17 +30,27 @@ export interface RelayLog {
     actual_model_name: string;   // 实际使用模型名称
     input_tokens: number;        // 输入Token
     output_tokens: number;       // 输出Token
+    cache_read_tokens?: number;  // 缓存命中Token
+    cache_write_tokens?: number; // 缓存写入Token
     ftut: number;                // 首字时间(毫秒)
     use_time: number;            // 总用时(毫秒)
     cost: number;                // 消耗费用
+    upstream_cost?: number;      // 按渠道实际价格计算的上游费用
     request_content: string;     // 请求内容
     response_content: string;    // 响应内容
     error: string;               // 错误信息
     attempts?: ChannelAttempt[]; // 所有尝试记录
     total_attempts?: number;     // 总尝试次数
     successful_round?: number;   // 成功的轮次
+    structured_output?: string;  // 结构化输出校验结果
+    usage_source?: UsageSource;  // 用量的计算方式
 }
 
+/**
+ * 用量的计算方式：上游返回、客户端断开后继续读取上游取得、按已传输内容估算
+ */
+export type UsageSource = 'upstream' | 'drained' | 'estimated';
+
 /**
  * 日志列表查询参数
  */
@@ -11,7 +11,25 @@ export interface LLMPrice {
     output: number;
     cache_read: number;
     cache_write: number;
+    cache_write_1h?: number;  // 1 小时缓存写入，为 0 时按 cache_write 的 1.6 倍
+    reasoning?: number;       // 以下细分价格为 0 时按输入或输出价格计算
+    input_audio?: number;
+    output_audio?: number;
+    input_image?: number;
+    output_image?: number;
     request?: number;
+    context_tiers?: LLMPriceTier[];
+}
+
+/**
+ * 上下文超过 threshold 个 token 时使用的价格
+ */
+export interface LLMPriceTier {
+    threshold: number;
+    input: number;
+    output: number;
+    cache_read: number;
+    cache_write: number;
 }
 
 /**
@@ -204,4 +222,77 @@ export function useLastUpdateTime() {
         },
         refetchInterval: 30000,
     });
-}
\ No newline at end of file
+}
+/**
+ * 上传的价格文件状态
+ */
+export interface PriceSourceInfo {
+    uploaded: boolean;
+    uploaded_at?: string;
+}
+
+/**
+ * 上传价格文件结果
+ */
+export interface PriceSourceUploadResult {
+    models: number;
+    warning?: string;
+}
+
+/**
+ * 获取上传的价格文件状态 Hook
+ */
+export function usePriceSource() {
+    return useQuery({
+        queryKey: ['models', 'price-source'],
+        queryFn: async () => {
+            return apiClient.get<PriceSourceInfo>('/api/v1/model/price-source');
+        },
+    });
+}
+
+/**
+ * 上传 models.dev 或 LiteLLM 格式的价格文件 Hook
+ *
+ * @example
+ * const uploadPriceSource = useUploadPriceSource();
+ *
+ * uploadPriceSource.mutate(file);
+ */
+export function useUploadPriceSource() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (file: File) => {
+            const data: unknown = JSON.parse(await file.text());
+            return apiClient.post<PriceSourceUploadResult>('/api/v1/model/price-source/upload', data);
+        },
+        onSuccess: (data) => {
+            logger.log('价格文件上传成功:', data);
+            queryClient.invalidateQueries({ queryKey: ['models'] });
+        },
+        onError: (error) => {
+            logger.error('价格文件上传失败:', error);
+        },
+    });
+}
+
+/**
+ * 删除上传的价格文件 Hook
+ */
+export function useDeletePriceSource() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async () => {
+            return apiClient.post<null>('/api/v1/model/price-source/delete', {});
+        },
+        onSuccess: () => {
+            logger.log('价格文件删除成功');
+            queryClient.invalidateQueries({ queryKey: ['models', 'price-source'] });
+        },
+        onError: (error) => {
+            logger.error('价格文件删除失败:', error);
+        },
+    });
+}
@@ -0,0 +1,179 @@
+import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
+import { apiClient } from '../client';
+import { logger } from '@/lib/logger';
+
+/**
+ * 通知渠道类型
+ */
+export type NotifyTargetType = 'webhook' | 'json' | 'smtp';
+
+/**
+ * 通知事件类型
+ */
+export type NotifyEvent =
+    | 'apikey_budget'
+    | 'daily_spend'
+    | 'channel_key_error'
+    | 'channel_success_rate'
+    | 'model_sync_removed';
+
+export interface NotifySMTP {
+    host: string;
+    port: number;
+    username?: string;
+    password?: string;
+    from: string;
+    to: string[];
+}
+
+/**
+ * 通知渠道
+ */
+export interface NotifyTarget {
+    id: number;
+    name: string;
+    type: NotifyTargetType;
+    enabled: boolean;
+    url?: string;
+    headers?: Record<string, string>;
+    template?: string;   // json 类型的请求体模板，支持 {{title}} {{message}} {{event}} {{time}}
+    smtp?: NotifySMTP;
+}
+
+/**
+ * 通知规则
+ */
+export interface NotifyRule {
+    id: number;
+    name: string;
+    event: NotifyEvent;
+    enabled: boolean;
+    threshold: number;       // apikey_budget 为百分比，daily_spend 为美元，channel_success_rate 为成功率百分比
+    window?: number;         // 成功率统计窗口（分钟）
+    min_requests?: number;   // 成功率判断所需的最少请求数
+    cooldown?: number;       // 重复通知的最小间隔（分钟）
+    target_ids: number[];
+}
+
+export type NotifyTargetRequest = Omit<NotifyTarget, 'id'> & { id?: number };
+export type NotifyRuleRequest = Omit<NotifyRule, 'id'> & { id?: number };
+
+/**
+ * 获取通知渠道列表 Hook
+ */
+export function useNotifyTargetList() {
+    return useQuery({
+        queryKey: ['notify', 'targets'],
+        queryFn: async () => {
+            return apiClient.get<NotifyTarget[]>('/api/v1/notify/target/list');
+        },
+    });
+}
+
+/**
+ * 创建或更新通知渠道 Hook，有 id 时更新
+ */
+export function useSaveNotifyTarget() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (data: NotifyTargetRequest) => {
+            const path = data.id ? '/api/v1/notify/target/update' : '/api/v1/notify/target/create';
+            return apiClient.post<NotifyTarget>(path, data);
+        },
+        onSuccess: (data) => {
+            logger.log('通知渠道保存成功:', data);
+            queryClient.invalidateQueries({ queryKey: ['notify', 'targets'] });
+        },
+        onError: (error) => {
+            logger.error('通知渠道保存失败:', error);
+        },
+    });
+}
+
+/**
+ * 删除通知渠道 Hook，同时会从规则中移除
+ */
+export function useDeleteNotifyTarget() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (id: number) => {
+            return apiClient.delete<null>(`/api/v1/notify/target/delete/${id}`);
+        },
+        onSuccess: () => {
+            logger.log('通知渠道删除成功');
+            queryClient.invalidateQueries({ queryKey: ['notify'] });
+        },
+        onError: (error) => {
+            logger.error('通知渠道删除失败:', error);
+        },
+    });
+}
+
+/**
+ * 按当前配置发送测试通知 Hook
+ */
+export function useTestNotifyTarget() {
+    return useMutation({
+        mutationFn: async (data: NotifyTargetRequest) => {
+            return apiClient.post<null>('/api/v1/notify/target/test', data);
+        },
+        onError: (error) => {
+            logger.error('测试通知发送失败:', error);
+        },
+    });
+}
+
+/**
+ * 获取通知规则列表 Hook
+ */
+export function useNotifyRuleList() {
+    return useQuery({
+        queryKey: ['notify', 'rules'],
+        queryFn: async () => {
+            return apiClient.get<NotifyRule[]>('/api/v1/notify/rule/list');
+        },
+    });
+}
+
+/**
+ * 创建或更新通知规则 Hook，有 id 时更新
+ */
+export function useSaveNotifyRule() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (data: NotifyRuleRequest) => {
+            const path = data.id ? '/api/v1/notify/rule/update' : '/api/v1/notify/rule/create';
+            return apiClient.post<NotifyRule>(path, data);
+        },
+        onSuccess: (data) => {
+            logger.log('通知规则保存成功:', data);
+            queryClient.invalidateQueries({ queryKey: ['notify', 'rules'] });
+        },
+        onError: (error) => {
+            logger.error('通知规则保存失败:', error);
+        },
+    });
+}
+
+/**
+ * 删除通知规则 Hook
+ */
+export function useDeleteNotifyRule() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (id: number) => {
+            return apiClient.delete<null>(`/api/v1/notify/rule/delete/${id}`);
+        },
+        onSuccess: () => {
+            logger.log('通知规则删除成功');
+            queryClient.invalidateQueries({ queryKey: ['notify', 'rules'] });
+        },
+        onError: (error) => {
+            logger.error('通知规则删除失败:', error);
+        },
+    });
+}
@@ -19,6 +19,18 @@ export const SettingKey = {
     RelayLogKeepEnabled: 'relay_log_keep_enabled',
     RelayLogKeepPeriod: 'relay_log_keep_period',
     CORSAllowOrigins: 'cors_allow_origins',
+    ResponseKeepPeriod: 'response_keep_period',
+    BatchConcurrency: 'batch_concurrency',
+    MediaMaxSize: 'media_max_size',
+    MediaMaxDimension: 'media_max_dimension',
+    StreamDrainGrace: 'stream_drain_grace',
+    PriceSources: 'price_sources',
+    PriceProviders: 'price_providers',
+    PriceAliases: 'price_aliases',
+    NotifyRateLimit: 'notify_rate_limit',
+    IdempotencyTTL: 'idempotency_ttl',
+    IdempotencyMaxSize: 'idempotency_max_size',
+    TokenizerRules: 'tokenizer_rules',
 } as const;
 
 /**
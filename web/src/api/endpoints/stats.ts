@@ -10,6 +10,7 @@ interface StatsMetrics {
     output_token: number;
     input_cost: number;
     output_cost: number;
+    upstream_cost?: number; // 按渠道实际价格计算的上游费用
     wait_time: number;
     request_success: number;
     request_failed: number;
@@ -27,6 +28,7 @@ export interface StatsMetricsFormatted {
     request_count: ReturnType<typeof formatCount>;
     total_token: ReturnType<typeof formatCount>;
     total_cost: ReturnType<typeof formatMoney>;
+    upstream_cost: ReturnType<typeof formatMoney>;
 }
 
 export interface StatsChannel extends StatsMetrics {
@@ -58,6 +60,7 @@ export interface StatsHourlyFormatted extends StatsMetricsFormatted {
  */
 export interface StatsAPIKey extends StatsMetrics {
     api_key_id: number;
+    quota_cost?: number;
 }
 
 export interface StatsAPIKeyFormatted extends StatsMetricsFormatted {
@@ -93,6 +96,7 @@ export function useStatsDaily() {
             input_cost: formatMoney(item.input_cost),
             output_cost: formatMoney(item.output_cost),
             total_cost: formatMoney(item.input_cost + item.output_cost),
+            upstream_cost: formatMoney(item.upstream_cost ?? 0),
             wait_time: formatTime(item.wait_time),
             request_success: formatCount(item.request_success),
             request_failed: formatCount(item.request_failed),
@@ -121,6 +125,7 @@ export function useStatsHourly() {
             input_cost: formatMoney(item.input_cost),
             output_cost: formatMoney(item.output_cost),
             total_cost: formatMoney(item.input_cost + item.output_cost),
+            upstream_cost: formatMoney(item.upstream_cost ?? 0),
             wait_time: formatTime(item.wait_time),
             request_success: formatCount(item.request_success),
             request_failed: formatCount(item.request_failed),
@@ -144,6 +149,8 @@ export function useStatsTotal() {
             input_cost: formatMoney(data.input_cost),
             output_cost: formatMoney(data.output_cost),
             total_cost: formatMoney(data.input_cost + data.output_cost),
+            upstream_cost: formatMoney(data.upstream_cost ?? 0),
+            margin: formatMoney(data.input_cost + data.output_cost - (data.upstream_cost ?? 0)),
             wait_time: formatTime(data.wait_time),
             request_success: formatCount(data.request_success),
             request_failed: formatCount(data.request_failed),
@@ -173,6 +180,7 @@ export function useStatsAPIKey() {
             input_cost: formatMoney(item.input_cost),
             output_cost: formatMoney(item.output_cost),
             total_cost: formatMoney(item.input_cost + item.output_cost),
+            upstream_cost: formatMoney(item.upstream_cost ?? 0),
             wait_time: formatTime(item.wait_time),
             request_success: formatCount(item.request_success),
             request_failed: formatCount(item.request_failed),
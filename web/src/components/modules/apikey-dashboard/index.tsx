@@ -29,11 +29,19 @@ import {
     Languages,
     Zap,
     Layers,
-    Clock
+    Clock,
+    Gauge
 } from 'lucide-react';
 import { Button } from '@/components/ui/button';
 import { Progress } from '@/components/ui/progress';
 import dayjs from 'dayjs';
+import { cn } from '@/lib/utils';
+import type { APIKeyLimitMetric } from '@/api/endpoints/apikey';
+
+function formatLimit(metric: APIKeyLimitMetric, value: number): string {
+    if (metric === 'cost') return `${value.toFixed(2)} $`;
+    return Math.floor(value).toLocaleString();
+}
 
 export function APIKeyDashboard() {
     const t = useTranslations('apiKeyDashboard');
@@ -71,9 +79,10 @@ export function APIKeyDashboard() {
     }
 
     const { stats, info } = data;
+    const limits = data.limits ?? [];
 
     // Quota calculations
-    const usedCost = stats.total_cost.raw;
+    const usedCost = data.quota_cost;
     const maxCost = info.max_cost || 0;
 
     // Expiry calculations
@@ -177,6 +186,12 @@ export function APIKeyDashboard() {
                                         </div>
                                     </div>
                                 )}
+                                {data.balance !== undefined && (
+                                    <div className="mt-4 flex justify-between text-sm">
+                                        <span className="text-muted-foreground">{t('balance')}</span>
+                                        <span className={cn('font-semibold tabular-nums', data.balance <= 0 && 'text-destructive')}>{data.balance.toFixed(4)} $</span>
+                                    </div>
+                                )}
                             </div>
                         </div>
                     </div>
@@ -268,6 +283,33 @@ export function APIKeyDashboard() {
                         </div>
                     </div>
 
+                    {/* Limits */}
+                    {limits.length > 0 && (
+                        <div className="custom-shadow rounded-2xl border bg-card p-6">
+                            <div className="flex items-center gap-2 mb-4">
+                                <Gauge className="w-5 h-5 text-chart-2" />
+                                <span className="font-semibold">{t('limits')}</span>
+                            </div>
+                            <div className="grid gap-4 md:grid-cols-2">
+                                {limits.map((limit) => (
+                                    <div key={`${limit.metric}-${limit.window}`}>
+                                        <div className="flex justify-between text-sm mb-1">
+                                            <span>{t(`limitMetric.${limit.metric}`)} / {t(`limitWindow.${limit.window}`)}</span>
+                                            <span className="text-muted-foreground">{formatLimit(limit.metric, limit.used)} / {formatLimit(limit.metric, limit.max)}</span>
+                                        </div>
+                                        <Progress
+                                            value={Math.min(100, (limit.used / limit.max) * 100)}
+                                            className={cn('h-2', limit.used >= limit.max && '*:data-[slot=progress-indicator]:bg-destructive')}
+                                        />
+                                        <div className="text-xs text-muted-foreground mt-1">
+                                            {t('resetAt')} {dayjs.unix(limit.reset_at).format('YYYY-MM-DD HH:mm')}
+                                        </div>
+                                    </div>
+                                ))}
+                            </div>
+                        </div>
+                    )}
+
                     {/* Supported Models */}
                     {info.supported_models && info.supported_models.trim().length > 0 && (
                         <div className="custom-shadow rounded-2xl border bg-card p-6">
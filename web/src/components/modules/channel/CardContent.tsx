@@ -41,6 +41,7 @@ export function CardContent({ channel, stats }: { channel: Channel; stats: Stats
         custom_header: channel.custom_header ?? [],
         channel_proxy: channel.channel_proxy ?? '',
         param_override: channel.param_override ?? '',
+        model_mapping: channel.model_mapping ?? '',
         keys: channel.keys.length > 0
             ? channel.keys.map((k) => ({
                 id: k.id,
@@ -56,6 +57,13 @@ export function CardContent({ channel, stats }: { channel: Channel; stats: Stats
         custom_model: channel.custom_model,
         proxy: channel.proxy,
         auto_sync: channel.auto_sync,
+        prompt_tool_call: channel.prompt_tool_call ?? false,
+        media_mode: channel.media_mode ?? '',
+        stream_mode: channel.stream_mode ?? '',
+        prompt_cache: channel.prompt_cache ?? '',
+        prompt_cache_min_tokens: channel.prompt_cache_min_tokens ?? 0,
+        price_multiplier: channel.price_multiplier == null ? '' : String(channel.price_multiplier),
+        price_overrides: channel.price_overrides ?? [],
         auto_group: channel.auto_group,
         match_regex: channel.match_regex ?? '',
     });
@@ -86,7 +94,17 @@ export function CardContent({ channel, stats }: { channel: Channel; stats: Stats
         if (formData.custom_model !== channel.custom_model) req.custom_model = formData.custom_model;
         if (formData.proxy !== channel.proxy) req.proxy = formData.proxy;
         if (formData.auto_sync !== channel.auto_sync) req.auto_sync = formData.auto_sync;
+        if (formData.prompt_tool_call !== (channel.prompt_tool_call ?? false)) req.prompt_tool_call = formData.prompt_tool_call;
+        if (formData.media_mode !== (channel.media_mode ?? '')) req.media_mode = formData.media_mode;
+        if (formData.stream_mode !== (channel.stream_mode ?? '')) req.stream_mode = formData.stream_mode;
+        if (formData.prompt_cache !== (channel.prompt_cache ?? '')) req.prompt_cache = formData.prompt_cache;
+        if (formData.prompt_cache_min_tokens !== (channel.prompt_cache_min_tokens ?? 0)) req.prompt_cache_min_tokens = formData.prompt_cache_min_tokens;
         if (formData.auto_group !== channel.auto_group) req.auto_group = formData.auto_group;
+        const nextPriceMultiplier = formData.price_multiplier.trim() === '' ? 1 : Number(formData.price_multiplier);
+        if (nextPriceMultiplier !== (channel.price_multiplier ?? 1)) req.price_multiplier = nextPriceMultiplier;
+        if (JSON.stringify(formData.price_overrides) !== JSON.stringify(channel.price_overrides ?? [])) {
+            req.price_overrides = formData.price_overrides.filter((o) => o.model.trim()).map((o) => ({ ...o, model: o.model.trim() }));
+        }
 
         if (!headersEqual(formData.custom_header, channel.custom_header)) {
             req.custom_header = (formData.custom_header ?? [])
@@ -106,6 +124,12 @@ export function CardContent({ channel, stats }: { channel: Channel; stats: Stats
             req.param_override = nextParamOverride ? nextParamOverride : null;
         }
 
+        const nextModelMapping = formData.model_mapping.trim();
+        const curModelMapping = channel.model_mapping ?? '';
+        if (nextModelMapping !== curModelMapping) {
+            req.model_mapping = nextModelMapping;
+        }
+
         const nextMatchRegex = formData.match_regex.trim();
         const curMatchRegex = channel.match_regex ?? '';
         if (nextMatchRegex !== curMatchRegex) {
@@ -285,7 +309,7 @@ export function CardContent({ channel, stats }: { channel: Channel; stats: Stats
                                         <DollarSign className="size-3.5" />
                                         {t('sections.costs')}
                                     </h4>
-                                    <dl className="grid gap-3 grid-cols-1 sm:grid-cols-2">
+                                    <dl className="grid gap-3 grid-cols-1 sm:grid-cols-3">
                                         <div className="rounded-2xl border bg-card p-3 sm:p-4 transition-colors hover:bg-accent/5">
                                             <dt className="flex items-center gap-2 mb-2 text-xs text-muted-foreground">
                                                 <div className="size-2 rounded-full bg-chart-2" />
@@ -307,6 +331,17 @@ export function CardContent({ channel, stats }: { channel: Channel; stats: Stats
                                                 <span className="text-sm font-normal ml-1 text-muted-foreground">{stats.output_cost.formatted.unit}</span>
                                             </dd>
                                         </div>
+
+                                        <div className="rounded-2xl border bg-card p-3 sm:p-4 transition-colors hover:bg-accent/5">
+                                            <dt className="flex items-center gap-2 mb-2 text-xs text-muted-foreground">
+                                                <div className="size-2 rounded-full bg-chart-3" />
+                                                {t('metrics.upstreamCost')}
+                                            </dt>
+                                            <dd className="text-2xl font-bold text-card-foreground">
+                                                {stats.upstream_cost.formatted.value}
+                                                <span className="text-sm font-normal ml-1 text-muted-foreground">{stats.upstream_cost.formatted.unit}</span>
+                                            </dd>
+                                        </div>
                                     </dl>
                                 </section>
 
@@ -19,10 +19,18 @@ export function CreateDialogContent() {
         custom_header: [],
         channel_proxy: '',
         param_override: '',
+        model_mapping: '',
         keys: [{ enabled: true, channel_key: '', remark: '' }],
         model: '',
         custom_model: '',
         auto_sync: false,
+        prompt_tool_call: false,
+        media_mode: '',
+        stream_mode: '',
+        prompt_cache: '',
+        prompt_cache_min_tokens: 0,
+        price_multiplier: '',
+        price_overrides: [],
         auto_group: AutoGroupType.None,
         enabled: true,
         proxy: false,
@@ -45,6 +53,7 @@ export function CreateDialogContent() {
 
         const channelProxy = formData.channel_proxy.trim();
         const paramOverride = formData.param_override.trim();
+        const modelMapping = formData.model_mapping.trim();
         createChannel.mutate(
             {
                 name: formData.name,
@@ -56,10 +65,18 @@ export function CreateDialogContent() {
                 custom_model: formData.custom_model,
                 proxy: formData.proxy,
                 auto_sync: formData.auto_sync,
+                prompt_tool_call: formData.prompt_tool_call,
+                media_mode: formData.media_mode,
+                stream_mode: formData.stream_mode,
+                prompt_cache: formData.prompt_cache,
+                prompt_cache_min_tokens: formData.prompt_cache_min_tokens,
+                price_multiplier: formData.price_multiplier.trim() === '' ? undefined : Number(formData.price_multiplier),
+                price_overrides: formData.price_overrides.filter((o) => o.model.trim()).map((o) => ({ ...o, model: o.model.trim() })),
                 auto_group: formData.auto_group,
                 custom_header: normalizedHeaders,
                 channel_proxy: channelProxy ? channelProxy : null,
                 param_override: paramOverride ? paramOverride : null,
+                model_mapping: modelMapping ? modelMapping : null,
                 match_regex: formData.match_regex.trim() ? formData.match_regex.trim() : null,
             },
             {
@@ -71,10 +88,18 @@ export function CreateDialogContent() {
                         custom_header: [],
                         channel_proxy: '',
                         param_override: '',
+                        model_mapping: '',
                         keys: [{ enabled: true, channel_key: '', remark: '' }],
                         model: '',
                         custom_model: '',
                         auto_sync: false,
+                        prompt_tool_call: false,
+                        media_mode: '',
+                        stream_mode: '',
+                        prompt_cache: '',
+                        prompt_cache_min_tokens: 0,
+                        price_multiplier: '',
+                        price_overrides: [],
                         auto_group: AutoGroupType.None,
                         enabled: true,
                         proxy: false,
@@ -1,4 +1,4 @@
-import { AutoGroupType, ChannelType, type Channel, useFetchModel } from '@/api/endpoints/channel';
+import { AutoGroupType, ChannelType, type Channel, type ChannelPriceOverride, type MediaMode, type PromptCacheMode, type StreamMode, useFetchModel } from '@/api/endpoints/channel';
 import {
     Select,
     SelectContent,
@@ -32,12 +32,20 @@ export interface ChannelFormData {
     custom_header: Channel['custom_header'];
     channel_proxy: string;
     param_override: string;
+    model_mapping: string;
     keys: ChannelKeyFormItem[];
     model: string;
     custom_model: string;
     enabled: boolean;
     proxy: boolean;
     auto_sync: boolean;
+    prompt_tool_call: boolean;
+    media_mode: MediaMode;
+    stream_mode: StreamMode;
+    prompt_cache: PromptCacheMode;
+    prompt_cache_min_tokens: number;
+    price_multiplier: string;
+    price_overrides: ChannelPriceOverride[];
     auto_group: AutoGroupType;
     match_regex: string;
 }
@@ -213,6 +221,22 @@ export function ChannelForm({
         onFormDataChange({ ...formData, custom_header: next });
     };
 
+    const handleAddPriceOverride = () => {
+        onFormDataChange({
+            ...formData,
+            price_overrides: [...formData.price_overrides, { model: '', input: 0, output: 0, cache_read: 0, cache_write: 0 }],
+        });
+    };
+
+    const handleUpdatePriceOverride = (idx: number, patch: Partial<ChannelPriceOverride>) => {
+        const next = formData.price_overrides.map((o, i) => (i === idx ? { ...o, ...patch } : o));
+        onFormDataChange({ ...formData, price_overrides: next });
+    };
+
+    const handleRemovePriceOverride = (idx: number) => {
+        onFormDataChange({ ...formData, price_overrides: formData.price_overrides.filter((_, i) => i !== idx) });
+    };
+
     const handleRemoveHeader = (idx: number) => {
         const curr = formData.custom_header ?? [];
         if (curr.length <= 1) return;
@@ -253,6 +277,11 @@ export function ChannelForm({
                             <SelectItem className='rounded-xl' value={String(ChannelType.Anthropic)}>{t('typeAnthropic')}</SelectItem>
                             <SelectItem className='rounded-xl' value={String(ChannelType.Gemini)}>{t('typeGemini')}</SelectItem>
                             <SelectItem className='rounded-xl' value={String(ChannelType.Volcengine)}>{t('typeVolcengine')}</SelectItem>
+                            <SelectItem className='rounded-xl' value={String(ChannelType.Vertex)}>{t('typeVertex')}</SelectItem>
+                            <SelectItem className='rounded-xl' value={String(ChannelType.AzureChat)}>{t('typeAzureChat')}</SelectItem>
+                            <SelectItem className='rounded-xl' value={String(ChannelType.AzureResponse)}>{t('typeAzureResponse')}</SelectItem>
+                            <SelectItem className='rounded-xl' value={String(ChannelType.AzureEmbedding)}>{t('typeAzureEmbedding')}</SelectItem>
+                            <SelectItem className='rounded-xl' value={String(ChannelType.Ollama)}>{t('typeOllama')}</SelectItem>
                             <SelectItem className='rounded-xl' value={String(ChannelType.OpenAIEmbedding)}>{t('typeOpenAIEmbedding')}</SelectItem>
                         </SelectContent>
                     </Select>
@@ -546,6 +575,79 @@ export function ChannelForm({
                             </div>
                         </div>
 
+                        <div className="space-y-2">
+                            <div className="flex items-center justify-between">
+                                <label className="text-sm font-medium text-card-foreground">
+                                    {t('priceOverrides')} {formData.price_overrides.length > 0 ? `(${formData.price_overrides.length})` : ''}
+                                </label>
+                                <Button
+                                    type="button"
+                                    variant="ghost"
+                                    size="sm"
+                                    onClick={handleAddPriceOverride}
+                                    className="h-6 px-2 text-xs text-muted-foreground/70 hover:text-muted-foreground hover:bg-transparent"
+                                >
+                                    <Plus className="h-3 w-3 mr-1" />
+                                    {t('priceOverrideAdd')}
+                                </Button>
+                            </div>
+                            <div className="flex items-center gap-2">
+                                <span className="text-xs text-muted-foreground shrink-0">{t('priceMultiplier')}</span>
+                                <Input
+                                    id={`${idPrefix}-price-multiplier`}
+                                    type="number"
+                                    min={0}
+                                    step="any"
+                                    value={formData.price_multiplier}
+                                    onChange={(e) => onFormDataChange({ ...formData, price_multiplier: e.target.value })}
+                                    placeholder="1"
+                                    className="rounded-xl w-28"
+                                />
+                            </div>
+                            <div className="space-y-2">
+                                {formData.price_overrides.map((o, idx) => (
+                                    <div key={`price-${idx}`} className="flex items-center gap-2">
+                                        <Input
+                                            type="text"
+                                            value={o.model}
+                                            onChange={(e) => handleUpdatePriceOverride(idx, { model: e.target.value })}
+                                            placeholder={t('priceOverrideModel')}
+                                            className="rounded-xl flex-1"
+                                        />
+                                        <Input
+                                            type="number"
+                                            min={0}
+                                            step="any"
+                                            value={o.input}
+                                            onChange={(e) => handleUpdatePriceOverride(idx, { input: Math.max(0, Number(e.target.value) || 0) })}
+                                            title={t('priceOverrideInput')}
+                                            className="rounded-xl w-24"
+                                        />
+                                        <Input
+                                            type="number"
+                                            min={0}
+                                            step="any"
+                                            value={o.output}
+                                            onChange={(e) => handleUpdatePriceOverride(idx, { output: Math.max(0, Number(e.target.value) || 0) })}
+                                            title={t('priceOverrideOutput')}
+                                            className="rounded-xl w-24"
+                                        />
+                                        <Button
+                                            type="button"
+                                            variant="ghost"
+                                            size="sm"
+                                            onClick={() => handleRemovePriceOverride(idx)}
+                                            className="h-8 w-8 p-0 rounded-xl text-muted-foreground hover:text-destructive hover:bg-transparent"
+                                            title="Remove"
+                                        >
+                                            <X className="h-4 w-4" />
+                                        </Button>
+                                    </div>
+                                ))}
+                            </div>
+                            <p className="text-xs text-muted-foreground">{t('priceHint')}</p>
+                        </div>
+
                         <div className="space-y-2">
                             <label htmlFor={`${idPrefix}-match-regex`} className="text-sm font-medium text-card-foreground">
                                 {t('matchRegex')}
@@ -572,6 +674,98 @@ export function ChannelForm({
                                 className="min-h-28 w-full rounded-xl border border-border bg-background px-3 py-2 text-sm text-foreground focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring"
                             />
                         </div>
+
+                        <div className="space-y-2">
+                            <label htmlFor={`${idPrefix}-model-mapping`} className="text-sm font-medium text-card-foreground">
+                                {t('modelMapping')}
+                            </label>
+                            <textarea
+                                id={`${idPrefix}-model-mapping`}
+                                value={formData.model_mapping}
+                                onChange={(e) => onFormDataChange({ ...formData, model_mapping: e.target.value })}
+                                placeholder={t('modelMappingPlaceholder')}
+                                className="min-h-28 w-full rounded-xl border border-border bg-background px-3 py-2 text-sm text-foreground focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring"
+                            />
+                        </div>
+
+                        <div className="space-y-2">
+                            <label htmlFor={`${idPrefix}-media-mode`} className="text-sm font-medium text-card-foreground">
+                                {t('mediaMode')}
+                            </label>
+                            <Select
+                                value={formData.media_mode || 'auto'}
+                                onValueChange={(value) => onFormDataChange({ ...formData, media_mode: (value === 'auto' ? '' : value) as MediaMode })}
+                            >
+                                <SelectTrigger id={`${idPrefix}-media-mode`} className="rounded-xl w-full border border-border px-4 py-2 text-foreground focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring">
+                                    <SelectValue />
+                                </SelectTrigger>
+                                <SelectContent className='rounded-xl'>
+                                    <SelectItem className='rounded-xl' value="auto">{t('mediaModeAuto')}</SelectItem>
+                                    <SelectItem className='rounded-xl' value="inline">{t('mediaModeInline')}</SelectItem>
+                                    <SelectItem className='rounded-xl' value="url">{t('mediaModeUrl')}</SelectItem>
+                                </SelectContent>
+                            </Select>
+                            <p className="text-xs text-muted-foreground">{t('mediaModeHint')}</p>
+                        </div>
+
+                        <div className="space-y-2">
+                            <label htmlFor={`${idPrefix}-stream-mode`} className="text-sm font-medium text-card-foreground">
+                                {t('streamMode')}
+                            </label>
+                            <Select
+                                value={formData.stream_mode || 'auto'}
+                                onValueChange={(value) => onFormDataChange({ ...formData, stream_mode: (value === 'auto' ? '' : value) as StreamMode })}
+                            >
+                                <SelectTrigger id={`${idPrefix}-stream-mode`} className="rounded-xl w-full border border-border px-4 py-2 text-foreground focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring">
+                                    <SelectValue />
+                                </SelectTrigger>
+                                <SelectContent className='rounded-xl'>
+                                    <SelectItem className='rounded-xl' value="auto">{t('streamModeAuto')}</SelectItem>
+                                    <SelectItem className='rounded-xl' value="stream">{t('streamModeStream')}</SelectItem>
+                                    <SelectItem className='rounded-xl' value="non_stream">{t('streamModeNonStream')}</SelectItem>
+                                </SelectContent>
+                            </Select>
+                            <p className="text-xs text-muted-foreground">{t('streamModeHint')}</p>
+                        </div>
+
+                        {formData.type === ChannelType.Anthropic && (
+                            <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+                                <div className="space-y-2">
+                                    <label htmlFor={`${idPrefix}-prompt-cache`} className="text-sm font-medium text-card-foreground">
+                                        {t('promptCache')}
+                                    </label>
+                                    <Select
+                                        value={formData.prompt_cache || 'off'}
+                                        onValueChange={(value) => onFormDataChange({ ...formData, prompt_cache: (value === 'off' ? '' : value) as PromptCacheMode })}
+                                    >
+                                        <SelectTrigger id={`${idPrefix}-prompt-cache`} className="rounded-xl w-full border border-border px-4 py-2 text-foreground focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring">
+                                            <SelectValue />
+                                        </SelectTrigger>
+                                        <SelectContent className='rounded-xl'>
+                                            <SelectItem className='rounded-xl' value="off">{t('promptCacheOff')}</SelectItem>
+                                            <SelectItem className='rounded-xl' value="auto">{t('promptCacheAuto')}</SelectItem>
+                                            <SelectItem className='rounded-xl' value="auto_1h">{t('promptCacheAuto1h')}</SelectItem>
+                                        </SelectContent>
+                                    </Select>
+                                </div>
+                                <div className="space-y-2">
+                                    <label htmlFor={`${idPrefix}-prompt-cache-min-tokens`} className="text-sm font-medium text-card-foreground">
+                                        {t('promptCacheMinTokens')}
+                                    </label>
+                                    <Input
+                                        className='rounded-xl'
+                                        id={`${idPrefix}-prompt-cache-min-tokens`}
+                                        type="number"
+                                        min={0}
+                                        value={formData.prompt_cache_min_tokens || ''}
+                                        placeholder="1024"
+                                        disabled={!formData.prompt_cache}
+                                        onChange={(event) => onFormDataChange({ ...formData, prompt_cache_min_tokens: Math.max(0, Number(event.target.value) || 0) })}
+                                    />
+                                </div>
+                                <p className="text-xs text-muted-foreground md:col-span-2">{t('promptCacheHint')}</p>
+                            </div>
+                        )}
                     </AccordionContent>
                 </AccordionItem>
             </Accordion>
@@ -599,6 +793,13 @@ export function ChannelForm({
                         />
                         <span className="text-sm text-card-foreground">{t('autoSync')}</span>
                     </label>
+                    <label className="flex items-center gap-2 cursor-pointer" title={t('promptToolCallHint')}>
+                        <Switch
+                            checked={formData.prompt_tool_call}
+                            onCheckedChange={(checked) => onFormDataChange({ ...formData, prompt_tool_call: checked })}
+                        />
+                        <span className="text-sm text-card-foreground">{t('promptToolCall')}</span>
+                    </label>
                 </div>
             </div>
 
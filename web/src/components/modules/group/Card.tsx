@@ -54,6 +54,9 @@ function EditDialogContent({ group, displayMembers, isSubmitting, onSubmit }: Ed
                         match_regex: group.match_regex ?? '',
                         mode: group.mode,
                         first_token_time_out: group.first_token_time_out ?? 0,
+                        structured_output: group.structured_output ?? '',
+                        structured_output_retries: group.structured_output_retries ?? 0,
+                        reasoning_policy: group.reasoning_policy ?? '',
                         members: displayMembers,
                     }}
                     submitText={t('detail.actions.save')}
@@ -219,6 +222,9 @@ export function GroupCard({ group }: { group: Group }) {
         if (values.mode !== group.mode) payload.mode = values.mode;
         if (nextRegex !== (group.match_regex ?? '')) payload.match_regex = nextRegex;
         if (nextFirstTokenTimeOut !== (group.first_token_time_out ?? 0)) payload.first_token_time_out = nextFirstTokenTimeOut;
+        if (values.structured_output !== (group.structured_output ?? '')) payload.structured_output = values.structured_output;
+        if (values.structured_output_retries !== (group.structured_output_retries ?? 0)) payload.structured_output_retries = values.structured_output_retries;
+        if (values.reasoning_policy !== (group.reasoning_policy ?? '')) payload.reasoning_policy = values.reasoning_policy;
         if (items_to_add.length) payload.items_to_add = items_to_add;
         if (items_to_update.length) payload.items_to_update = items_to_update;
         if (items_to_delete.length) payload.items_to_delete = items_to_delete;
@@ -235,7 +241,7 @@ export function GroupCard({ group }: { group: Group }) {
             },
             onError,
         });
-    }, [group.first_token_time_out, group.id, group.items, group.match_regex, group.mode, group.name, onSuccess, onError, updateGroup]);
+    }, [group.first_token_time_out, group.structured_output, group.structured_output_retries, group.reasoning_policy, group.id, group.items, group.match_regex, group.mode, group.name, onSuccess, onError, updateGroup]);
 
     return (
         <article className="flex flex-col rounded-3xl border border-border bg-card text-card-foreground p-4 custom-shadow">
@@ -39,7 +39,7 @@ export function CreateDialogContent() {
                     submitText={t('create.submit')}
                     submittingText={t('create.submitting')}
                     isSubmitting={createGroup.isPending}
-                    onSubmit={({ name, match_regex, mode, first_token_time_out, members }) => {
+                    onSubmit={({ name, match_regex, mode, first_token_time_out, structured_output, structured_output_retries, reasoning_policy, members }) => {
                         const items: GroupItem[] = members.map((member, index) => ({
                             channel_id: member.channel_id,
                             model_name: member.name,
@@ -48,7 +48,7 @@ export function CreateDialogContent() {
                         }));
 
                         createGroup.mutate(
-                            { name, mode, match_regex: match_regex ?? '', first_token_time_out: first_token_time_out ?? 0, items },
+                            { name, mode, match_regex: match_regex ?? '', first_token_time_out: first_token_time_out ?? 0, structured_output, structured_output_retries, reasoning_policy, items },
                             {
                                 onSuccess: () => setIsOpen(false),
                                 onError: (error) => toast.error(t('toast.createFailed'), { description: error.message }),
@@ -11,7 +11,7 @@ import { Input } from '@/components/ui/input';
 import { Accordion, AccordionContent, AccordionItem } from '@/components/ui/accordion';
 import { cn } from '@/lib/utils';
 import { getModelIcon } from '@/lib/model-icons';
-import type { GroupMode } from '@/api/endpoints/group';
+import type { GroupMode, ReasoningPolicy, StructuredOutputMode } from '@/api/endpoints/group';
 import type { SelectedMember } from './ItemList';
 import { MemberList } from './ItemList';
 import { matchesGroupName, memberKey, normalizeKey, MODE_LABELS } from './utils';
@@ -20,11 +20,17 @@ import { HelpCircle } from 'lucide-react';
 
 
 
+const STRUCTURED_OUTPUT_MODES: StructuredOutputMode[] = ['', 'native', 'prompt', 'tool'];
+const REASONING_POLICIES: ReasoningPolicy[] = ['', 'strip', 'think_tags', 'text'];
+
 export type GroupEditorValues = {
     name: string;
     match_regex: string;
     mode: GroupMode;
     first_token_time_out: number;
+    structured_output: StructuredOutputMode;
+    structured_output_retries: number;
+    reasoning_policy: ReasoningPolicy;
     members: SelectedMember[];
 };
 
@@ -232,6 +238,9 @@ export function GroupEditor({
     const [matchRegex, setMatchRegex] = useState(initial?.match_regex ?? '');
     const [mode, setMode] = useState<GroupMode>((initial?.mode ?? 1) as GroupMode);
     const [firstTokenTimeOut, setFirstTokenTimeOut] = useState<number>(initial?.first_token_time_out ?? 0);
+    const [structuredOutput, setStructuredOutput] = useState<StructuredOutputMode>(initial?.structured_output ?? '');
+    const [structuredOutputRetries, setStructuredOutputRetries] = useState<number>(initial?.structured_output_retries ?? 0);
+    const [reasoningPolicy, setReasoningPolicy] = useState<ReasoningPolicy>(initial?.reasoning_policy ?? '');
     const [selectedMembers, setSelectedMembers] = useState<SelectedMember[]>(initial?.members ?? []);
     const [removingIds, setRemovingIds] = useState<Set<string>>(new Set());
 
@@ -314,6 +323,9 @@ export function GroupEditor({
             match_regex: regexKey,
             mode,
             first_token_time_out: firstTokenTimeOut,
+            structured_output: structuredOutput,
+            structured_output_retries: structuredOutputRetries,
+            reasoning_policy: reasoningPolicy,
             members: selectedMembers,
         });
     };
@@ -401,6 +413,87 @@ export function GroupEditor({
                         ))}
                     </div>
 
+                    {/* Structured output */}
+                    <div className="flex items-center gap-2">
+                        <span className="flex items-center gap-1 text-xs text-muted-foreground shrink-0">
+                            {t('form.structuredOutput')}
+                            <TooltipProvider>
+                                <Tooltip>
+                                    <TooltipTrigger asChild>
+                                        <HelpCircle className="size-4 cursor-help" />
+                                    </TooltipTrigger>
+                                    <TooltipContent>
+                                        {t('form.structuredOutputHint')}
+                                    </TooltipContent>
+                                </Tooltip>
+                            </TooltipProvider>
+                        </span>
+                        <div className="flex flex-1 gap-1">
+                            {STRUCTURED_OUTPUT_MODES.map((m) => (
+                                <button
+                                    key={m || 'off'}
+                                    type="button"
+                                    onClick={() => setStructuredOutput(m)}
+                                    className={cn(
+                                        'flex-1 py-1 text-xs rounded-lg transition-colors',
+                                        structuredOutput === m ? 'bg-primary text-primary-foreground' : 'bg-muted hover:bg-muted/80'
+                                    )}
+                                >
+                                    {t(`structuredOutput.${m || 'off'}`)}
+                                </button>
+                            ))}
+                        </div>
+                        {structuredOutput && (
+                            <Input
+                                type="number"
+                                inputMode="numeric"
+                                min={0}
+                                max={3}
+                                step={1}
+                                value={String(structuredOutputRetries)}
+                                onChange={(e) => {
+                                    const n = Number.parseInt(e.target.value, 10);
+                                    setStructuredOutputRetries(Number.isFinite(n) ? Math.min(Math.max(n, 0), 3) : 0);
+                                }}
+                                title={t('form.structuredOutputRetries')}
+                                aria-label={t('form.structuredOutputRetries')}
+                                className="w-20 h-7 rounded-lg text-xs"
+                            />
+                        )}
+                    </div>
+
+                    {/* Reasoning policy */}
+                    <div className="flex items-center gap-2">
+                        <span className="flex items-center gap-1 text-xs text-muted-foreground shrink-0">
+                            {t('form.reasoningPolicy')}
+                            <TooltipProvider>
+                                <Tooltip>
+                                    <TooltipTrigger asChild>
+                                        <HelpCircle className="size-4 cursor-help" />
+                                    </TooltipTrigger>
+                                    <TooltipContent>
+                                        {t('form.reasoningPolicyHint')}
+                                    </TooltipContent>
+                                </Tooltip>
+                            </TooltipProvider>
+                        </span>
+                        <div className="flex flex-1 gap-1">
+                            {REASONING_POLICIES.map((p) => (
+                                <button
+                                    key={p || 'passthrough'}
+                                    type="button"
+                                    onClick={() => setReasoningPolicy(p)}
+                                    className={cn(
+                                        'flex-1 py-1 text-xs rounded-lg transition-colors',
+                                        reasoningPolicy === p ? 'bg-primary text-primary-foreground' : 'bg-muted hover:bg-muted/80'
+                                    )}
+                                >
+                                    {t(`reasoningPolicy.${p || 'passthrough'}`)}
+                                </button>
+                            ))}
+                        </div>
+                    </div>
+
                     <div className="flex-1 min-h-0">
                         <div className="grid grid-cols-1 md:grid-cols-2 gap-4 h-full min-h-0">
                             <ModelPickerSection
@@ -11,7 +11,9 @@ import {
     ArrowUpFromLine,
     Rewind,
     DollarSign,
-    FastForward
+    FastForward,
+    TrendingUp,
+    Wallet
 } from 'lucide-react';
 import { useTranslations } from 'next-intl';
 import { useStatsTotal } from '@/api/endpoints/stats';
@@ -111,11 +113,33 @@ export function Total() {
                     unit: statsTotalFormatted?.output_cost.formatted.unit
                 }
             ]
+        },
+        {
+            title: t('spendStats'),
+            headerIcon: Wallet,
+            items: [
+                {
+                    label: t('upstreamCost'),
+                    value: statsTotalFormatted?.upstream_cost.formatted.value,
+                    icon: DollarSign,
+                    color: 'text-primary',
+                    bgColor: 'bg-chart-5/10',
+                    unit: statsTotalFormatted?.upstream_cost.formatted.unit
+                },
+                {
+                    label: t('margin'),
+                    value: statsTotalFormatted?.margin.formatted.value,
+                    icon: TrendingUp,
+                    color: 'text-primary',
+                    bgColor: 'bg-chart-5/10',
+                    unit: statsTotalFormatted?.margin.formatted.unit
+                }
+            ]
         }
     ];
 
     return (
-        <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-4">
+        <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-3 xl:grid-cols-5 gap-4">
             {cards.map((card, index) => (
                 <motion.section
                     key={index}
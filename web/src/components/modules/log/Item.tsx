@@ -247,7 +247,16 @@ export function LogCard({ log }: { log: RelayLog }) {
                                 </div>
                                 <div className="flex items-center gap-1.5">
                                     <ArrowDownToLine className="size-3.5 shrink-0 text-green-500" />
-                                    <span>{t('input')} {log.input_tokens.toLocaleString()}</span>
+                                    <span
+                                        title={log.cache_read_tokens || log.cache_write_tokens
+                                            ? `${t('cacheRead')} ${(log.cache_read_tokens ?? 0).toLocaleString()} / ${t('cacheWrite')} ${(log.cache_write_tokens ?? 0).toLocaleString()}`
+                                            : undefined}
+                                    >
+                                        {t('input')} {log.input_tokens.toLocaleString()}
+                                        {!!log.cache_read_tokens && (
+                                            <span className="text-green-600 dark:text-green-400"> ({t('cacheRead')} {log.cache_read_tokens.toLocaleString()})</span>
+                                        )}
+                                    </span>
                                 </div>
                                 <div className="flex items-center gap-1.5">
                                     <ArrowUpFromLine className="size-3.5 shrink-0 text-purple-500" />
@@ -255,8 +264,11 @@ export function LogCard({ log }: { log: RelayLog }) {
                                 </div>
                                 <div className="flex items-center gap-1.5">
                                     <DollarSign className="size-3.5 shrink-0 text-emerald-500" />
-                                    <span className="font-medium text-emerald-600 dark:text-emerald-400">
-                                        {t('cost')} {Number(log.cost).toFixed(6)}
+                                    <span
+                                        className="font-medium text-emerald-600 dark:text-emerald-400"
+                                        title={log.usage_source ? t(`usageSource.${log.usage_source}`) : undefined}
+                                    >
+                                        {t('cost')} {log.usage_source === 'estimated' && '≈'}{Number(log.cost).toFixed(6)}
                                     </span>
                                 </div>
                             </div>
@@ -292,6 +304,29 @@ export function LogCard({ log }: { log: RelayLog }) {
                                 </Badge>
                             )}
                             <span className="text-muted-foreground">{log.actual_model_name}</span>
+                            {log.structured_output && (
+                                <Badge
+                                    variant="outline"
+                                    className={cn(
+                                        "text-xs px-1.5 py-0 max-w-[40%] truncate",
+                                        log.structured_output.includes(': invalid') && "border-destructive/30 text-destructive"
+                                    )}
+                                    title={log.structured_output}
+                                >
+                                    {log.structured_output}
+                                </Badge>
+                            )}
+                            {log.usage_source && log.usage_source !== 'upstream' && (
+                                <Badge
+                                    variant="outline"
+                                    className={cn(
+                                        "text-xs px-1.5 py-0",
+                                        log.usage_source === 'estimated' && "border-amber-500/30 text-amber-600 dark:text-amber-400"
+                                    )}
+                                >
+                                    {t(`usageSource.${log.usage_source}`)}
+                                </Badge>
+                            )}
                         </MorphingDialogTitle>
 
                         <MorphingDialogDescription className="flex-1 min-h-0">
@@ -457,6 +492,12 @@ export function LogCard({ log }: { log: RelayLog }) {
                                     {t('cost')}: {Number(log.cost).toFixed(6)}
                                 </span>
                             </div>
+                            {log.upstream_cost !== undefined && log.upstream_cost !== log.cost && (
+                                <div className="flex items-center gap-1.5">
+                                    <DollarSign className="size-3.5 text-muted-foreground" />
+                                    <span>{t('upstreamCost')}: {Number(log.upstream_cost).toFixed(6)}</span>
+                                </div>
+                            )}
                         </div>
                     </MorphingDialogContent>
                 </MorphingDialogContainer>
@@ -28,6 +28,10 @@ export const ModelItem = memo(function ModelItem({ model }: ModelItemProps) {
         output: model.output.toString(),
         cache_read: model.cache_read.toString(),
         cache_write: model.cache_write.toString(),
+        cache_write_1h: model.cache_write_1h ? model.cache_write_1h.toString() : '',
+        reasoning: model.reasoning ? model.reasoning.toString() : '',
+        input_audio: model.input_audio ? model.input_audio.toString() : '',
+        output_audio: model.output_audio ? model.output_audio.toString() : '',
         request: (model.request || 0).toString(),
     }));
 
@@ -44,6 +48,10 @@ export const ModelItem = memo(function ModelItem({ model }: ModelItemProps) {
             output: model.output.toString(),
             cache_read: model.cache_read.toString(),
             cache_write: model.cache_write.toString(),
+            cache_write_1h: model.cache_write_1h ? model.cache_write_1h.toString() : '',
+            reasoning: model.reasoning ? model.reasoning.toString() : '',
+            input_audio: model.input_audio ? model.input_audio.toString() : '',
+            output_audio: model.output_audio ? model.output_audio.toString() : '',
             request: (model.request || 0).toString(),
         });
         setIsEditing(true);
@@ -54,13 +62,19 @@ export const ModelItem = memo(function ModelItem({ model }: ModelItemProps) {
     };
 
     const handleSaveEdit = () => {
+        // 保留界面上未展示的价格，如图片价格和上下文分档
         updateModel.mutate({
+            ...model,
             name: model.name,
             type: editValues.type,
             input: parseFloat(editValues.input) || 0,
             output: parseFloat(editValues.output) || 0,
             cache_read: parseFloat(editValues.cache_read) || 0,
             cache_write: parseFloat(editValues.cache_write) || 0,
+            cache_write_1h: parseFloat(editValues.cache_write_1h) || 0,
+            reasoning: parseFloat(editValues.reasoning) || 0,
+            input_audio: parseFloat(editValues.input_audio) || 0,
+            output_audio: parseFloat(editValues.output_audio) || 0,
             request: parseFloat(editValues.request) || 0,
         }, {
             onSuccess: () => {
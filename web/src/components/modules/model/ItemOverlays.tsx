@@ -13,6 +13,10 @@ type EditValues = {
     output: string;
     cache_read: string;
     cache_write: string;
+    cache_write_1h: string;
+    reasoning: string;
+    input_audio: string;
+    output_audio: string;
     request: string;
 };
 
@@ -157,6 +161,50 @@ export function ModelEditOverlay({
                             className="h-9 text-sm rounded-xl"
                         />
                     </label>
+                    <label className="grid gap-1 text-xs text-muted-foreground">
+                        {t('cacheWrite1h')}
+                        <Input
+                            type="number"
+                            step="any"
+                            value={editValues.cache_write_1h}
+                            placeholder="-"
+                            onChange={(e) => onChange({ ...editValues, cache_write_1h: e.target.value })}
+                            className="h-9 text-sm rounded-xl"
+                        />
+                    </label>
+                    <label className="grid gap-1 text-xs text-muted-foreground">
+                        {t('reasoning')}
+                        <Input
+                            type="number"
+                            step="any"
+                            value={editValues.reasoning}
+                            placeholder="-"
+                            onChange={(e) => onChange({ ...editValues, reasoning: e.target.value })}
+                            className="h-9 text-sm rounded-xl"
+                        />
+                    </label>
+                    <label className="grid gap-1 text-xs text-muted-foreground">
+                        {t('inputAudio')}
+                        <Input
+                            type="number"
+                            step="any"
+                            value={editValues.input_audio}
+                            placeholder="-"
+                            onChange={(e) => onChange({ ...editValues, input_audio: e.target.value })}
+                            className="h-9 text-sm rounded-xl"
+                        />
+                    </label>
+                    <label className="grid gap-1 text-xs text-muted-foreground">
+                        {t('outputAudio')}
+                        <Input
+                            type="number"
+                            step="any"
+                            value={editValues.output_audio}
+                            placeholder="-"
+                            onChange={(e) => onChange({ ...editValues, output_audio: e.target.value })}
+                            className="h-9 text-sm rounded-xl"
+                        />
+                    </label>
                 </div>
             )}
 
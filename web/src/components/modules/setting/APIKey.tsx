@@ -29,14 +29,25 @@ import {
     useUpdateAPIKey,
     useDeleteAPIKey,
     type APIKey,
+    type APIKeyLimit,
+    type APIKeyLimitMetric,
+    type APIKeyLimitWindow,
+    useAPIKeyTransactions,
+    useCreateAPIKeyTransaction,
+    type CreateAPIKeyTransactionRequest,
 } from '@/api/endpoints/apikey';
-import { useGroupList } from '@/api/endpoints/group';
+import { useGroupList, type ReasoningPolicy } from '@/api/endpoints/group';
 import { useStatsAPIKey } from '@/api/endpoints/stats';
 import { cn } from '@/lib/utils';
 import { toast } from '@/components/common/Toast';
 import { CopyIconButton } from '@/components/common/CopyButton';
 import type { ApiError } from '@/api/types';
 
+const REASONING_POLICIES: ReasoningPolicy[] = ['', 'passthrough', 'strip', 'think_tags', 'text'];
+const LIMIT_METRICS: APIKeyLimitMetric[] = ['requests', 'tokens', 'cost'];
+const LIMIT_WINDOWS: APIKeyLimitWindow[] = ['minute', 'hour', 'day', 'week', 'month'];
+const TRANSACTION_TYPES: CreateAPIKeyTransactionRequest['type'][] = ['topup', 'refund', 'adjustment'];
+
 function toExpireAt(date: Date, time: string): number {
     const t = /^\d{2}:\d{2}$/.test(time) ? time : '00:00';
     const [hh, mm] = t.split(':').map(Number);
@@ -100,6 +111,9 @@ function APIKeyForm({ apiKey, isPending, submitLabel, onSubmit, onClose }: APIKe
         is_flat_fee: apiKey?.is_flat_fee ?? false,
         reset_duration: apiKey?.reset_duration ?? 0,
         reset_unit: apiKey?.reset_unit ?? 'day',
+        reasoning_policy: apiKey?.reasoning_policy ?? '',
+        prepaid: apiKey?.prepaid ?? false,
+        limits: apiKey?.limits ?? [],
     }));
     const [maxCostInput, setMaxCostInput] = useState(() =>
         apiKey?.max_cost != null ? String(apiKey.max_cost) : ''
@@ -215,6 +229,18 @@ function APIKeyForm({ apiKey, isPending, submitLabel, onSubmit, onClose }: APIKe
         updateForm({ max_cost: undefined });
     }, [updateForm]);
 
+    const handleAddLimit = () => {
+        updateForm({ limits: [...(form.limits ?? []), { metric: 'requests', window: 'minute', max: 60 }] });
+    };
+
+    const handleLimitChange = (index: number, patch: Partial<APIKeyLimit>) => {
+        updateForm({ limits: (form.limits ?? []).map((l, i) => (i === index ? { ...l, ...patch } : l)) });
+    };
+
+    const handleRemoveLimit = (index: number) => {
+        updateForm({ limits: (form.limits ?? []).filter((_, i) => i !== index) });
+    };
+
     const handleSubmit = useCallback((e: React.FormEvent) => {
         e.preventDefault();
         if (!form.name.trim()) return;
@@ -277,6 +303,15 @@ function APIKeyForm({ apiKey, isPending, submitLabel, onSubmit, onClose }: APIKe
                 />
             </div>
 
+            <div className="flex items-center justify-between pt-1">
+                <span className="text-xs text-muted-foreground">{t('apiKey.form.prepaid')}</span>
+                <Switch
+                    checked={form.prepaid ?? false}
+                    onCheckedChange={(checked) => updateForm({ prepaid: checked })}
+                    disabled={isPending}
+                />
+            </div>
+
             <div className="flex items-center justify-between pt-1">
                 <span className="text-xs text-muted-foreground">Auto Reset Quota</span>
                 <Switch
@@ -312,6 +347,72 @@ function APIKeyForm({ apiKey, isPending, submitLabel, onSubmit, onClose }: APIKe
                 </div>
             )}
 
+            <div className="grid gap-1 text-xs text-muted-foreground">
+                <div className="flex items-center justify-between">
+                    {t('apiKey.form.limits')}
+                    <button
+                        type="button"
+                        onClick={handleAddLimit}
+                        disabled={isPending}
+                        className="flex items-center gap-1 text-primary hover:underline disabled:opacity-50"
+                    >
+                        <Plus className="size-3" />
+                        {t('apiKey.form.addLimit')}
+                    </button>
+                </div>
+                {(form.limits ?? []).map((limit, index) => (
+                    <div key={index} className="flex items-center gap-2">
+                        <Input
+                            type="number"
+                            min="0"
+                            step="any"
+                            value={Number.isFinite(limit.max) ? limit.max : ''}
+                            onChange={(e) => handleLimitChange(index, { max: parseFloat(e.target.value) })}
+                            className="h-9 text-sm rounded-xl flex-1"
+                            disabled={isPending}
+                            required
+                        />
+                        <Select
+                            value={limit.metric}
+                            onValueChange={(v) => handleLimitChange(index, { metric: v as APIKeyLimitMetric })}
+                            disabled={isPending}
+                        >
+                            <SelectTrigger className="h-9 w-[100px] rounded-xl">
+                                <SelectValue />
+                            </SelectTrigger>
+                            <SelectContent>
+                                {LIMIT_METRICS.map((m) => (
+                                    <SelectItem key={m} value={m}>{t(`apiKey.limitMetric.${m}`)}</SelectItem>
+                                ))}
+                            </SelectContent>
+                        </Select>
+                        <Select
+                            value={limit.window}
+                            onValueChange={(v) => handleLimitChange(index, { window: v as APIKeyLimitWindow })}
+                            disabled={isPending}
+                        >
+                            <SelectTrigger className="h-9 w-[100px] rounded-xl">
+                                <SelectValue />
+                            </SelectTrigger>
+                            <SelectContent>
+                                {LIMIT_WINDOWS.map((w) => (
+                                    <SelectItem key={w} value={w}>{t(`apiKey.limitWindow.${w}`)}</SelectItem>
+                                ))}
+                            </SelectContent>
+                        </Select>
+                        <button
+                            type="button"
+                            onClick={() => handleRemoveLimit(index)}
+                            disabled={isPending}
+                            className="h-9 w-9 grid place-items-center rounded-xl text-muted-foreground hover:text-destructive shrink-0"
+                        >
+                            <X className="size-4" />
+                        </button>
+                    </div>
+                ))}
+                <div className="text-[11px] text-muted-foreground/80">{t('apiKey.form.limitsHint')}</div>
+            </div>
+
             <div className="grid gap-1 text-xs text-muted-foreground">
                 {t('apiKey.form.expireAt')}
                 <div className="flex items-center gap-2 relative">
@@ -411,6 +512,26 @@ function APIKeyForm({ apiKey, isPending, submitLabel, onSubmit, onClose }: APIKe
                 <div className="text-[11px] text-muted-foreground/80">{t('apiKey.form.modelsHint')}</div>
             </div>
 
+            <div className="flex items-center justify-between gap-2">
+                <span className="text-xs text-muted-foreground">{t('apiKey.form.reasoningPolicy')}</span>
+                <Select
+                    value={form.reasoning_policy || 'inherit'}
+                    onValueChange={(v) => updateForm({ reasoning_policy: (v === 'inherit' ? '' : v) as ReasoningPolicy })}
+                    disabled={isPending}
+                >
+                    <SelectTrigger className="h-9 w-[160px] rounded-xl">
+                        <SelectValue />
+                    </SelectTrigger>
+                    <SelectContent>
+                        {REASONING_POLICIES.map((p) => (
+                            <SelectItem key={p || 'inherit'} value={p || 'inherit'}>
+                                {t(`apiKey.reasoningPolicy.${p || 'inherit'}`)}
+                            </SelectItem>
+                        ))}
+                    </SelectContent>
+                </Select>
+            </div>
+
             <div className="flex items-center justify-between pt-1">
                 <span className="text-xs text-muted-foreground">{t('apiKey.form.enabled')}</span>
                 <Switch
@@ -555,10 +676,120 @@ function APIKeyStatsCard({
                     </div>
                 </div>
             )}
+
+            {apiKey.prepaid && <APIKeyBalancePanel apiKeyId={apiKey.id} />}
         </motion.div>
     );
 }
 
+function APIKeyBalancePanel({ apiKeyId }: { apiKeyId: number }) {
+    const t = useTranslations('setting');
+    const { data } = useAPIKeyTransactions(apiKeyId);
+    const createTransaction = useCreateAPIKeyTransaction();
+    const [type, setType] = useState<CreateAPIKeyTransactionRequest['type']>('topup');
+    const [amountInput, setAmountInput] = useState('');
+    const [note, setNote] = useState('');
+
+    const handleSubmit = (e: React.FormEvent) => {
+        e.preventDefault();
+        const amount = parseFloat(amountInput);
+        if (!Number.isFinite(amount) || amount === 0) return;
+        createTransaction.mutate(
+            { api_key_id: apiKeyId, type, amount, note: note.trim() || undefined },
+            {
+                onSuccess: () => {
+                    setAmountInput('');
+                    setNote('');
+                    toast.success(t('apiKey.toast.transactionSuccess'));
+                },
+                onError: (error) => {
+                    const msg = (error as ApiError)?.message || String(error);
+                    toast.error(t('apiKey.toast.transactionError'), { description: msg });
+                },
+            }
+        );
+    };
+
+    return (
+        <div className="mt-4 grid gap-2 text-sm">
+            <div className="flex items-center justify-between">
+                <span className="text-xs text-muted-foreground">{t('apiKey.balance.title')}</span>
+                <span className="font-semibold tabular-nums">${(data?.balance ?? 0).toFixed(4)}</span>
+            </div>
+
+            <form onSubmit={handleSubmit} className="grid gap-2">
+                <div className="flex items-center gap-2">
+                    <Select
+                        value={type}
+                        onValueChange={(v) => setType(v as CreateAPIKeyTransactionRequest['type'])}
+                        disabled={createTransaction.isPending}
+                    >
+                        <SelectTrigger className="h-9 w-[110px] rounded-xl">
+                            <SelectValue />
+                        </SelectTrigger>
+                        <SelectContent>
+                            {TRANSACTION_TYPES.map((tt) => (
+                                <SelectItem key={tt} value={tt}>{t(`apiKey.balance.type.${tt}`)}</SelectItem>
+                            ))}
+                        </SelectContent>
+                    </Select>
+                    <Input
+                        type="number"
+                        step="any"
+                        placeholder={t('apiKey.balance.amount')}
+                        value={amountInput}
+                        onChange={(e) => setAmountInput(e.target.value)}
+                        className="h-9 text-sm rounded-xl flex-1"
+                        disabled={createTransaction.isPending}
+                        required
+                    />
+                </div>
+                <div className="flex items-center gap-2">
+                    <Input
+                        type="text"
+                        placeholder={t('apiKey.balance.note')}
+                        value={note}
+                        onChange={(e) => setNote(e.target.value)}
+                        className="h-9 text-sm rounded-xl flex-1"
+                        disabled={createTransaction.isPending}
+                    />
+                    <button
+                        type="submit"
+                        disabled={createTransaction.isPending}
+                        className="h-9 px-3 rounded-xl bg-primary text-primary-foreground text-sm shrink-0 disabled:opacity-50"
+                    >
+                        {createTransaction.isPending ? <Loader className="size-4 animate-spin" /> : t('apiKey.balance.submit')}
+                    </button>
+                </div>
+            </form>
+
+            <div className="grid gap-1">
+                {(data?.transactions ?? []).length === 0 ? (
+                    <div className="text-xs text-muted-foreground">{t('apiKey.balance.empty')}</div>
+                ) : (
+                    data?.transactions.map((tx) => (
+                        <div key={tx.id} className="flex items-center justify-between gap-2 rounded-lg bg-muted/40 px-3 py-2 text-xs">
+                            <div className="min-w-0">
+                                <div className="flex items-center gap-1">
+                                    <Badge variant="secondary">{t(`apiKey.balance.type.${tx.type}`)}</Badge>
+                                    <span className="truncate text-muted-foreground">{tx.model || tx.note}</span>
+                                </div>
+                                <div className="text-muted-foreground/80">{new Date(tx.time * 1000).toLocaleString()}</div>
+                            </div>
+                            <div className="text-right tabular-nums shrink-0">
+                                <div className={cn(tx.amount < 0 ? 'text-destructive' : 'text-primary')}>
+                                    {tx.amount < 0 ? '-' : '+'}${Math.abs(tx.amount).toFixed(4)}
+                                </div>
+                                <div className="text-muted-foreground/80">${tx.balance.toFixed(4)}</div>
+                            </div>
+                        </div>
+                    ))
+                )}
+            </div>
+        </div>
+    );
+}
+
 function APIKeyKeyItem({
     apiKey,
     statsLayoutId,
@@ -2,11 +2,11 @@
 
 import { useEffect, useState, useRef } from 'react';
 import { useTranslations } from 'next-intl';
-import { DollarSign, Clock, RefreshCw } from 'lucide-react';
+import { DollarSign, Clock, RefreshCw, Link, Filter, Shuffle, Upload, Hash } from 'lucide-react';
 import { Input } from '@/components/ui/input';
 import { Button } from '@/components/ui/button';
 import { useSettingList, useSetSetting, SettingKey } from '@/api/endpoints/setting';
-import { useUpdateModelPrice, useLastUpdateTime } from '@/api/endpoints/model';
+import { useUpdateModelPrice, useLastUpdateTime, usePriceSource, useUploadPriceSource, useDeletePriceSource } from '@/api/endpoints/model';
 import { toast } from '@/components/common/Toast';
 
 export function SettingLLMPrice() {
@@ -16,30 +16,72 @@ export function SettingLLMPrice() {
     const updatePrice = useUpdateModelPrice();
     const { data: lastUpdateTime } = useLastUpdateTime();
 
+    const { data: priceSource } = usePriceSource();
+    const uploadPriceSource = useUploadPriceSource();
+    const deletePriceSource = useDeletePriceSource();
+    const fileInputRef = useRef<HTMLInputElement | null>(null);
+
     const [updateInterval, setUpdateInterval] = useState('');
-    const initialUpdateInterval = useRef('');
+    const [sources, setSources] = useState('');
+    const [providers, setProviders] = useState('');
+    const [aliases, setAliases] = useState('');
+    const [tokenizerRules, setTokenizerRules] = useState('');
+    const initialValues = useRef<Record<string, string>>({});
 
     useEffect(() => {
         if (settings) {
-            const interval = settings.find(s => s.key === SettingKey.ModelInfoUpdateInterval);
-            if (interval) {
-                queueMicrotask(() => setUpdateInterval(interval.value));
-                initialUpdateInterval.current = interval.value;
+            const values: Record<string, string> = {};
+            for (const key of [SettingKey.ModelInfoUpdateInterval, SettingKey.PriceSources, SettingKey.PriceProviders, SettingKey.PriceAliases, SettingKey.TokenizerRules]) {
+                const setting = settings.find(s => s.key === key);
+                if (setting) values[key] = setting.value;
             }
+            queueMicrotask(() => {
+                setUpdateInterval(values[SettingKey.ModelInfoUpdateInterval] ?? '');
+                setSources(values[SettingKey.PriceSources] ?? '');
+                setProviders(values[SettingKey.PriceProviders] ?? '');
+                setAliases(values[SettingKey.PriceAliases] ?? '');
+                setTokenizerRules(values[SettingKey.TokenizerRules] ?? '');
+            });
+            initialValues.current = values;
         }
     }, [settings]);
 
-    const handleSave = (key: string, value: string, initialValue: string) => {
-        if (value === initialValue) return;
+    const handleSave = (key: string, value: string) => {
+        if (value === (initialValues.current[key] ?? '')) return;
 
         setSetting.mutate({ key, value }, {
             onSuccess: () => {
                 toast.success(t('saved'));
-                initialUpdateInterval.current = value;
+                initialValues.current[key] = value;
+            },
+            onError: (error) => {
+                toast.error(error.message);
             }
         });
     };
 
+    const handleUpload = (file: File | null) => {
+        if (!file) return;
+        uploadPriceSource.mutate(file, {
+            onSuccess: (data) => {
+                toast.success(t('llmPrice.upload.success', { count: data.models }));
+                if (data.warning) toast.warning(data.warning);
+            },
+            onError: (error) => {
+                toast.error(error.message || t('llmPrice.upload.failed'));
+            },
+            onSettled: () => {
+                if (fileInputRef.current) fileInputRef.current.value = '';
+            }
+        });
+    };
+
+    const handleDeleteUpload = () => {
+        deletePriceSource.mutate(undefined, {
+            onSuccess: () => toast.success(t('llmPrice.upload.removed')),
+        });
+    };
+
     const handleManualUpdate = () => {
         updatePrice.mutate(undefined, {
             onSuccess: () => {
@@ -75,12 +117,120 @@ export function SettingLLMPrice() {
                     type="number"
                     value={updateInterval}
                     onChange={(e) => setUpdateInterval(e.target.value)}
-                    onBlur={() => handleSave(SettingKey.ModelInfoUpdateInterval, updateInterval, initialUpdateInterval.current)}
+                    onBlur={() => handleSave(SettingKey.ModelInfoUpdateInterval, updateInterval)}
                     placeholder={t('llmPrice.updateInterval.placeholder')}
                     className="w-48 rounded-xl"
                 />
             </div>
 
+            {/* 价格来源 */}
+            <div className="space-y-2">
+                <div className="flex items-center gap-3">
+                    <Link className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('llmPrice.sources.label')}</span>
+                </div>
+                <textarea
+                    value={sources}
+                    onChange={(e) => setSources(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.PriceSources, sources)}
+                    placeholder={t('llmPrice.sources.placeholder')}
+                    className="min-h-20 w-full rounded-xl border border-border bg-background px-3 py-2 text-sm text-foreground focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring"
+                />
+                <p className="text-xs text-muted-foreground">{t('llmPrice.sources.hint')}</p>
+            </div>
+
+            {/* 供应商过滤 */}
+            <div className="space-y-2">
+                <div className="flex items-center gap-3">
+                    <Filter className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('llmPrice.providers.label')}</span>
+                </div>
+                <Input
+                    value={providers}
+                    onChange={(e) => setProviders(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.PriceProviders, providers)}
+                    placeholder={t('llmPrice.providers.placeholder')}
+                    className="rounded-xl"
+                />
+                <p className="text-xs text-muted-foreground">{t('llmPrice.providers.hint')}</p>
+            </div>
+
+            {/* 模型别名 */}
+            <div className="space-y-2">
+                <div className="flex items-center gap-3">
+                    <Shuffle className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('llmPrice.aliases.label')}</span>
+                </div>
+                <textarea
+                    value={aliases}
+                    onChange={(e) => setAliases(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.PriceAliases, aliases)}
+                    placeholder='[{"match": "^[^/]+/(.+)$", "replace": "$1"}]'
+                    className="min-h-20 w-full rounded-xl border border-border bg-background px-3 py-2 font-mono text-xs text-foreground focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring"
+                />
+                <p className="text-xs text-muted-foreground">{t('llmPrice.aliases.hint')}</p>
+            </div>
+
+            {/* 分词器规则 */}
+            <div className="space-y-2">
+                <div className="flex items-center gap-3">
+                    <Hash className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('llmPrice.tokenizerRules.label')}</span>
+                </div>
+                <textarea
+                    value={tokenizerRules}
+                    onChange={(e) => setTokenizerRules(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.TokenizerRules, tokenizerRules)}
+                    placeholder='[{"match": "^my-org/", "encoding": "cl100k_base", "factor": 1.1}]'
+                    className="min-h-20 w-full rounded-xl border border-border bg-background px-3 py-2 font-mono text-xs text-foreground focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring"
+                />
+                <p className="text-xs text-muted-foreground">{t('llmPrice.tokenizerRules.hint')}</p>
+            </div>
+
+            {/* 上传价格文件 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex flex-col gap-1">
+                    <div className="flex items-center gap-3">
+                        <Upload className="h-5 w-5 text-muted-foreground" />
+                        <span className="text-sm font-medium">{t('llmPrice.upload.label')}</span>
+                    </div>
+                    <span className="text-xs text-muted-foreground ml-8">
+                        {priceSource?.uploaded && priceSource.uploaded_at
+                            ? t('llmPrice.upload.uploadedAt', { time: new Date(priceSource.uploaded_at).toLocaleString() })
+                            : t('llmPrice.upload.none')}
+                    </span>
+                </div>
+                <div className="flex items-center gap-2">
+                    {priceSource?.uploaded && (
+                        <Button
+                            variant="outline"
+                            size="sm"
+                            onClick={handleDeleteUpload}
+                            disabled={deletePriceSource.isPending}
+                            className="rounded-xl"
+                        >
+                            {t('llmPrice.upload.remove')}
+                        </Button>
+                    )}
+                    <Button
+                        variant="outline"
+                        size="sm"
+                        onClick={() => fileInputRef.current?.click()}
+                        disabled={uploadPriceSource.isPending}
+                        className="rounded-xl"
+                    >
+                        {uploadPriceSource.isPending ? t('llmPrice.upload.uploading') : t('llmPrice.upload.button')}
+                    </Button>
+                    <input
+                        ref={fileInputRef}
+                        type="file"
+                        accept="application/json,.json"
+                        onChange={(e) => handleUpload(e.target.files?.[0] ?? null)}
+                        className="hidden"
+                    />
+                </div>
+            </div>
+
             {/* 手动更新 */}
             <div className="flex items-center justify-between gap-4">
                 <div className="flex flex-col gap-1">
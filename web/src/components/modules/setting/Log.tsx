@@ -2,7 +2,7 @@
 
 import { useEffect, useState, useRef } from 'react';
 import { useTranslations } from 'next-intl';
-import { ScrollText, Calendar, Trash2 } from 'lucide-react';
+import { ScrollText, Calendar, Trash2, MessagesSquare } from 'lucide-react';
 import { Input } from '@/components/ui/input';
 import { Switch } from '@/components/ui/switch';
 import { Button } from '@/components/ui/button';
@@ -18,15 +18,18 @@ export function SettingLog() {
 
     const [enabled, setEnabled] = useState(true);
     const [keepPeriod, setKeepPeriod] = useState('7');
+    const [responseKeepPeriod, setResponseKeepPeriod] = useState('30');
     const [isClearing, setIsClearing] = useState(false);
 
     const initialEnabled = useRef(true);
     const initialKeepPeriod = useRef('7');
+    const initialResponseKeepPeriod = useRef('30');
 
     useEffect(() => {
         if (settings) {
             const enabledSetting = settings.find(s => s.key === SettingKey.RelayLogKeepEnabled);
             const periodSetting = settings.find(s => s.key === SettingKey.RelayLogKeepPeriod);
+            const responsePeriodSetting = settings.find(s => s.key === SettingKey.ResponseKeepPeriod);
             if (enabledSetting) {
                 const isEnabled = enabledSetting.value === 'true';
                 queueMicrotask(() => setEnabled(isEnabled));
@@ -36,6 +39,10 @@ export function SettingLog() {
                 queueMicrotask(() => setKeepPeriod(periodSetting.value));
                 initialKeepPeriod.current = periodSetting.value;
             }
+            if (responsePeriodSetting) {
+                queueMicrotask(() => setResponseKeepPeriod(responsePeriodSetting.value));
+                initialResponseKeepPeriod.current = responsePeriodSetting.value;
+            }
         }
     }, [settings]);
 
@@ -66,6 +73,20 @@ export function SettingLog() {
         );
     };
 
+    const handleResponseKeepPeriodSave = () => {
+        if (responseKeepPeriod === initialResponseKeepPeriod.current) return;
+
+        setSetting.mutate(
+            { key: SettingKey.ResponseKeepPeriod, value: responseKeepPeriod },
+            {
+                onSuccess: () => {
+                    toast.success(t('saved'));
+                    initialResponseKeepPeriod.current = responseKeepPeriod;
+                }
+            }
+        );
+    };
+
     const handleClearLogs = () => {
         setIsClearing(true);
         clearLogs.mutate(undefined, {
@@ -116,6 +137,22 @@ export function SettingLog() {
                 />
             </div>
 
+            {/* Responses API 存储保存范围 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <MessagesSquare className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('log.responseKeepPeriod.label')}</span>
+                </div>
+                <Input
+                    type="number"
+                    value={responseKeepPeriod}
+                    onChange={(e) => setResponseKeepPeriod(e.target.value)}
+                    onBlur={handleResponseKeepPeriodSave}
+                    placeholder={t('log.responseKeepPeriod.placeholder')}
+                    className="w-48 rounded-xl"
+                />
+            </div>
+
             {/* 清空历史日志 */}
             <div className="flex items-center justify-between gap-4">
                 <div className="flex items-center gap-3">
@@ -0,0 +1,494 @@
+'use client';
+
+import { useEffect, useRef, useState } from 'react';
+import { useTranslations } from 'next-intl';
+import { Bell, Plus, Pencil, Trash2, Send, Loader, Gauge } from 'lucide-react';
+import { Input } from '@/components/ui/input';
+import { Button } from '@/components/ui/button';
+import { Switch } from '@/components/ui/switch';
+import { Badge } from '@/components/ui/badge';
+import {
+    Select,
+    SelectContent,
+    SelectItem,
+    SelectTrigger,
+    SelectValue,
+} from '@/components/ui/select';
+import {
+    useNotifyTargetList,
+    useSaveNotifyTarget,
+    useDeleteNotifyTarget,
+    useTestNotifyTarget,
+    useNotifyRuleList,
+    useSaveNotifyRule,
+    useDeleteNotifyRule,
+    type NotifyTarget,
+    type NotifyTargetRequest,
+    type NotifyTargetType,
+    type NotifyRule,
+    type NotifyRuleRequest,
+    type NotifyEvent,
+} from '@/api/endpoints/notify';
+import { useSettingList, useSetSetting, SettingKey } from '@/api/endpoints/setting';
+import { toast } from '@/components/common/Toast';
+import { cn } from '@/lib/utils';
+
+const TARGET_TYPES: NotifyTargetType[] = ['webhook', 'json', 'smtp'];
+const EVENTS: NotifyEvent[] = ['apikey_budget', 'daily_spend', 'channel_key_error', 'channel_success_rate', 'model_sync_removed'];
+const THRESHOLD_EVENTS: NotifyEvent[] = ['apikey_budget', 'daily_spend', 'channel_success_rate'];
+
+// 常见机器人的请求体模板
+const TEMPLATE_PRESETS: Record<string, string> = {
+    slack: '{"text": "*{{title}}*\\n{{message}}"}',
+    feishu: '{"msg_type": "text", "content": {"text": "{{title}}\\n{{message}}"}}',
+    dingtalk: '{"msgtype": "text", "text": {"content": "{{title}}\\n{{message}}"}}',
+};
+
+const textareaClassName = 'min-h-20 w-full rounded-xl border border-border bg-background px-3 py-2 font-mono text-xs text-foreground focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring';
+
+function parseHeaders(text: string): Record<string, string> | undefined {
+    const headers: Record<string, string> = {};
+    for (const line of text.split('\n')) {
+        const index = line.indexOf(':');
+        if (index <= 0) continue;
+        headers[line.slice(0, index).trim()] = line.slice(index + 1).trim();
+    }
+    return Object.keys(headers).length ? headers : undefined;
+}
+
+function formatHeaders(headers?: Record<string, string>): string {
+    return Object.entries(headers ?? {}).map(([k, v]) => `${k}: ${v}`).join('\n');
+}
+
+function ListItem({ title, badge, enabled, onToggle, onEdit, onDelete }: {
+    title: string;
+    badge: string;
+    enabled: boolean;
+    onToggle: (enabled: boolean) => void;
+    onEdit: () => void;
+    onDelete: () => void;
+}) {
+    const [confirmDelete, setConfirmDelete] = useState(false);
+
+    return (
+        <div className="flex items-center justify-between gap-3 p-3 rounded-xl bg-muted/50">
+            <div className="flex items-center gap-2 min-w-0">
+                <span className={cn('text-sm font-medium truncate', !enabled && 'text-muted-foreground')}>{title}</span>
+                <Badge variant="secondary" className="shrink-0">{badge}</Badge>
+            </div>
+            <div className="flex items-center gap-1.5">
+                <Switch checked={enabled} onCheckedChange={onToggle} />
+                <button
+                    type="button"
+                    onClick={onEdit}
+                    className="flex size-8 items-center justify-center rounded-lg bg-muted/60 text-muted-foreground transition-colors hover:bg-muted hover:text-foreground active:scale-95"
+                >
+                    <Pencil className="size-4" />
+                </button>
+                <button
+                    type="button"
+                    onClick={() => (confirmDelete ? onDelete() : setConfirmDelete(true))}
+                    onBlur={() => setConfirmDelete(false)}
+                    className={cn(
+                        'flex size-8 items-center justify-center rounded-lg transition-colors',
+                        confirmDelete
+                            ? 'bg-destructive text-destructive-foreground'
+                            : 'bg-destructive/10 text-destructive hover:bg-destructive hover:text-destructive-foreground'
+                    )}
+                >
+                    <Trash2 className="size-4" />
+                </button>
+            </div>
+        </div>
+    );
+}
+
+function TargetForm({ target, onClose }: { target?: NotifyTarget; onClose: () => void }) {
+    const t = useTranslations('setting.notify');
+    const saveTarget = useSaveNotifyTarget();
+    const testTarget = useTestNotifyTarget();
+
+    const [form, setForm] = useState<NotifyTargetRequest>(() => ({
+        id: target?.id,
+        name: target?.name ?? '',
+        type: target?.type ?? 'webhook',
+        enabled: target?.enabled ?? true,
+        url: target?.url ?? '',
+        template: target?.template ?? TEMPLATE_PRESETS.slack,
+        smtp: target?.smtp,
+    }));
+    const [headers, setHeaders] = useState(() => formatHeaders(target?.headers));
+    const [smtpTo, setSmtpTo] = useState(() => target?.smtp?.to.join(', ') ?? '');
+
+    const smtp = form.smtp ?? { host: '', port: 587, from: '', to: [] };
+    const updateSMTP = (patch: Partial<typeof smtp>) => setForm({ ...form, smtp: { ...smtp, ...patch } });
+
+    const buildRequest = (): NotifyTargetRequest => {
+        if (form.type === 'smtp') {
+            return {
+                id: form.id,
+                name: form.name,
+                type: form.type,
+                enabled: form.enabled,
+                smtp: { ...smtp, to: smtpTo.split(/[,\s]+/).filter(Boolean) },
+            };
+        }
+        return {
+            id: form.id,
+            name: form.name,
+            type: form.type,
+            enabled: form.enabled,
+            url: form.url,
+            headers: parseHeaders(headers),
+            template: form.type === 'json' ? form.template : undefined,
+        };
+    };
+
+    const handleTest = () => {
+        testTarget.mutate(buildRequest(), {
+            onSuccess: () => toast.success(t('toast.testSuccess')),
+            onError: (error) => toast.error(t('toast.testFailed'), { description: error.message }),
+        });
+    };
+
+    const handleSave = () => {
+        saveTarget.mutate(buildRequest(), {
+            onSuccess: () => {
+                toast.success(t('toast.saved'));
+                onClose();
+            },
+            onError: (error) => toast.error(t('toast.saveFailed'), { description: error.message }),
+        });
+    };
+
+    return (
+        <div className="space-y-3 p-3 rounded-xl border border-border">
+            <div className="flex items-center gap-2">
+                <Input
+                    value={form.name}
+                    onChange={(e) => setForm({ ...form, name: e.target.value })}
+                    placeholder={t('target.name')}
+                    className="rounded-xl"
+                />
+                <Select value={form.type} onValueChange={(v) => setForm({ ...form, type: v as NotifyTargetType })}>
+                    <SelectTrigger className="h-9 w-[140px] rounded-xl">
+                        <SelectValue />
+                    </SelectTrigger>
+                    <SelectContent>
+                        {TARGET_TYPES.map((type) => (
+                            <SelectItem key={type} value={type}>{t(`target.types.${type}`)}</SelectItem>
+                        ))}
+                    </SelectContent>
+                </Select>
+            </div>
+
+            {form.type === 'smtp' ? (
+                <div className="grid grid-cols-2 gap-2">
+                    <Input value={smtp.host} onChange={(e) => updateSMTP({ host: e.target.value })} placeholder={t('target.smtpHost')} className="rounded-xl" />
+                    <Input type="number" value={smtp.port} onChange={(e) => updateSMTP({ port: Number(e.target.value) })} placeholder={t('target.smtpPort')} className="rounded-xl" />
+                    <Input value={smtp.username ?? ''} onChange={(e) => updateSMTP({ username: e.target.value })} placeholder={t('target.smtpUsername')} className="rounded-xl" />
+                    <Input type="password" value={smtp.password ?? ''} onChange={(e) => updateSMTP({ password: e.target.value })} placeholder={t('target.smtpPassword')} className="rounded-xl" />
+                    <Input value={smtp.from} onChange={(e) => updateSMTP({ from: e.target.value })} placeholder={t('target.smtpFrom')} className="rounded-xl" />
+                    <Input value={smtpTo} onChange={(e) => setSmtpTo(e.target.value)} placeholder={t('target.smtpTo')} className="rounded-xl" />
+                </div>
+            ) : (
+                <>
+                    <Input
+                        value={form.url ?? ''}
+                        onChange={(e) => setForm({ ...form, url: e.target.value })}
+                        placeholder="https://"
+                        className="rounded-xl"
+                    />
+                    <textarea
+                        value={headers}
+                        onChange={(e) => setHeaders(e.target.value)}
+                        placeholder={t('target.headers')}
+                        className={textareaClassName}
+                    />
+                    {form.type === 'json' && (
+                        <div className="space-y-2">
+                            <div className="flex items-center gap-1.5">
+                                {Object.keys(TEMPLATE_PRESETS).map((preset) => (
+                                    <Badge
+                                        key={preset}
+                                        variant="outline"
+                                        className="cursor-pointer"
+                                        onClick={() => setForm({ ...form, template: TEMPLATE_PRESETS[preset] })}
+                                    >
+                                        {t(`target.presets.${preset}`)}
+                                    </Badge>
+                                ))}
+                            </div>
+                            <textarea
+                                value={form.template ?? ''}
+                                onChange={(e) => setForm({ ...form, template: e.target.value })}
+                                className={textareaClassName}
+                            />
+                            <p className="text-xs text-muted-foreground">{t('target.templateHint')}</p>
+                        </div>
+                    )}
+                </>
+            )}
+
+            <div className="flex items-center justify-end gap-2">
+                <Button variant="outline" size="sm" onClick={handleTest} disabled={testTarget.isPending} className="rounded-xl">
+                    {testTarget.isPending ? <Loader className="size-4 animate-spin" /> : <Send className="size-4" />}
+                    {t('target.test')}
+                </Button>
+                <Button variant="outline" size="sm" onClick={onClose} className="rounded-xl">
+                    {t('cancel')}
+                </Button>
+                <Button size="sm" onClick={handleSave} disabled={saveTarget.isPending} className="rounded-xl">
+                    {t('save')}
+                </Button>
+            </div>
+        </div>
+    );
+}
+
+function RuleForm({ rule, targets, onClose }: { rule?: NotifyRule; targets: NotifyTarget[]; onClose: () => void }) {
+    const t = useTranslations('setting.notify');
+    const saveRule = useSaveNotifyRule();
+
+    const [form, setForm] = useState<NotifyRuleRequest>(() => ({
+        id: rule?.id,
+        name: rule?.name ?? '',
+        event: rule?.event ?? 'apikey_budget',
+        enabled: rule?.enabled ?? true,
+        threshold: rule?.threshold ?? 80,
+        window: rule?.window,
+        min_requests: rule?.min_requests,
+        cooldown: rule?.cooldown,
+        target_ids: rule?.target_ids ?? [],
+    }));
+
+    const toggleTarget = (id: number) => {
+        const ids = form.target_ids.includes(id)
+            ? form.target_ids.filter((v) => v !== id)
+            : [...form.target_ids, id];
+        setForm({ ...form, target_ids: ids });
+    };
+
+    const optionalNumber = (value: string) => (value === '' ? undefined : Number(value));
+
+    const handleSave = () => {
+        saveRule.mutate(form, {
+            onSuccess: () => {
+                toast.success(t('toast.saved'));
+                onClose();
+            },
+            onError: (error) => toast.error(t('toast.saveFailed'), { description: error.message }),
+        });
+    };
+
+    return (
+        <div className="space-y-3 p-3 rounded-xl border border-border">
+            <div className="flex items-center gap-2">
+                <Input
+                    value={form.name}
+                    onChange={(e) => setForm({ ...form, name: e.target.value })}
+                    placeholder={t('rule.name')}
+                    className="rounded-xl"
+                />
+                <Select value={form.event} onValueChange={(v) => setForm({ ...form, event: v as NotifyEvent })}>
+                    <SelectTrigger className="h-9 w-[200px] rounded-xl">
+                        <SelectValue />
+                    </SelectTrigger>
+                    <SelectContent>
+                        {EVENTS.map((event) => (
+                            <SelectItem key={event} value={event}>{t(`rule.events.${event}`)}</SelectItem>
+                        ))}
+                    </SelectContent>
+                </Select>
+            </div>
+
+            <div className="grid grid-cols-2 gap-2">
+                {THRESHOLD_EVENTS.includes(form.event) && (
+                    <div className="grid gap-1 text-xs text-muted-foreground">
+                        {t(`rule.thresholds.${form.event}`)}
+                        <Input
+                            type="number"
+                            value={form.threshold}
+                            onChange={(e) => setForm({ ...form, threshold: Number(e.target.value) })}
+                            className="rounded-xl"
+                        />
+                    </div>
+                )}
+                {form.event === 'channel_success_rate' && (
+                    <>
+                        <div className="grid gap-1 text-xs text-muted-foreground">
+                            {t('rule.window')}
+                            <Input type="number" value={form.window ?? ''} onChange={(e) => setForm({ ...form, window: optionalNumber(e.target.value) })} placeholder="15" className="rounded-xl" />
+                        </div>
+                        <div className="grid gap-1 text-xs text-muted-foreground">
+                            {t('rule.minRequests')}
+                            <Input type="number" value={form.min_requests ?? ''} onChange={(e) => setForm({ ...form, min_requests: optionalNumber(e.target.value) })} placeholder="10" className="rounded-xl" />
+                        </div>
+                    </>
+                )}
+                <div className="grid gap-1 text-xs text-muted-foreground">
+                    {t('rule.cooldown')}
+                    <Input type="number" value={form.cooldown ?? ''} onChange={(e) => setForm({ ...form, cooldown: optionalNumber(e.target.value) })} placeholder="60" className="rounded-xl" />
+                </div>
+            </div>
+
+            <div className="grid gap-1 text-xs text-muted-foreground">
+                {t('rule.targets')}
+                <div className="flex flex-wrap gap-1.5">
+                    {targets.length === 0 ? (
+                        <span>{t('target.empty')}</span>
+                    ) : targets.map((target) => (
+                        <Badge
+                            key={target.id}
+                            variant={form.target_ids.includes(target.id) ? 'default' : 'outline'}
+                            className="cursor-pointer"
+                            onClick={() => toggleTarget(target.id)}
+                        >
+                            {target.name}
+                        </Badge>
+                    ))}
+                </div>
+            </div>
+
+            <div className="flex items-center justify-end gap-2">
+                <Button variant="outline" size="sm" onClick={onClose} className="rounded-xl">
+                    {t('cancel')}
+                </Button>
+                <Button size="sm" onClick={handleSave} disabled={saveRule.isPending} className="rounded-xl">
+                    {t('save')}
+                </Button>
+            </div>
+        </div>
+    );
+}
+
+export function SettingNotify() {
+    const t = useTranslations('setting.notify');
+    const { data: targets = [] } = useNotifyTargetList();
+    const { data: rules = [] } = useNotifyRuleList();
+    const saveTarget = useSaveNotifyTarget();
+    const deleteTarget = useDeleteNotifyTarget();
+    const saveRule = useSaveNotifyRule();
+    const deleteRule = useDeleteNotifyRule();
+    const onError = (error: Error) => toast.error(error.message);
+    const { data: settings } = useSettingList();
+    const setSetting = useSetSetting();
+
+    const [rateLimit, setRateLimit] = useState('');
+    const initialRateLimit = useRef('');
+
+    useEffect(() => {
+        const setting = settings?.find(s => s.key === SettingKey.NotifyRateLimit);
+        if (setting) {
+            queueMicrotask(() => setRateLimit(setting.value));
+            initialRateLimit.current = setting.value;
+        }
+    }, [settings]);
+
+    const handleSaveRateLimit = () => {
+        if (rateLimit === initialRateLimit.current) return;
+        setSetting.mutate({ key: SettingKey.NotifyRateLimit, value: rateLimit }, {
+            onSuccess: () => {
+                toast.success(t('toast.saved'));
+                initialRateLimit.current = rateLimit;
+            },
+            onError,
+        });
+    };
+
+    // null 表示未在编辑，'new' 表示新建
+    const [editingTarget, setEditingTarget] = useState<NotifyTarget | 'new' | null>(null);
+    const [editingRule, setEditingRule] = useState<NotifyRule | 'new' | null>(null);
+
+    return (
+        <div className="rounded-3xl border border-border bg-card p-6 custom-shadow space-y-5">
+            <h2 className="text-lg font-bold text-card-foreground flex items-center gap-2">
+                <Bell className="h-5 w-5" />
+                {t('title')}
+            </h2>
+
+            {/* 每个渠道每分钟的发送上限 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <Gauge className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('rateLimit')}</span>
+                </div>
+                <Input
+                    type="number"
+                    value={rateLimit}
+                    onChange={(e) => setRateLimit(e.target.value)}
+                    onBlur={handleSaveRateLimit}
+                    className="w-48 rounded-xl"
+                />
+            </div>
+
+            {/* 通知渠道 */}
+            <div className="space-y-2">
+                <div className="flex items-center justify-between">
+                    <span className="text-sm font-medium">{t('target.title')}</span>
+                    <button
+                        type="button"
+                        onClick={() => setEditingTarget('new')}
+                        className="h-8 w-8 flex items-center justify-center rounded-lg bg-muted/60 text-muted-foreground transition-colors hover:bg-muted"
+                        title={t('target.add')}
+                    >
+                        <Plus className="size-4" />
+                    </button>
+                </div>
+                {editingTarget === 'new' && <TargetForm onClose={() => setEditingTarget(null)} />}
+                {targets.length === 0 && editingTarget !== 'new' && (
+                    <p className="text-xs text-muted-foreground">{t('target.empty')}</p>
+                )}
+                {targets.map((target) =>
+                    editingTarget !== 'new' && editingTarget?.id === target.id ? (
+                        <TargetForm key={target.id} target={target} onClose={() => setEditingTarget(null)} />
+                    ) : (
+                        <ListItem
+                            key={target.id}
+                            title={target.name}
+                            badge={t(`target.types.${target.type}`)}
+                            enabled={target.enabled}
+                            onToggle={(enabled) => saveTarget.mutate({ ...target, enabled }, { onError })}
+                            onEdit={() => setEditingTarget(target)}
+                            onDelete={() => deleteTarget.mutate(target.id, { onError })}
+                        />
+                    )
+                )}
+            </div>
+
+            {/* 通知规则 */}
+            <div className="space-y-2">
+                <div className="flex items-center justify-between">
+                    <span className="text-sm font-medium">{t('rule.title')}</span>
+                    <button
+                        type="button"
+                        onClick={() => setEditingRule('new')}
+                        className="h-8 w-8 flex items-center justify-center rounded-lg bg-muted/60 text-muted-foreground transition-colors hover:bg-muted"
+                        title={t('rule.add')}
+                    >
+                        <Plus className="size-4" />
+                    </button>
+                </div>
+                {editingRule === 'new' && <RuleForm targets={targets} onClose={() => setEditingRule(null)} />}
+                {rules.length === 0 && editingRule !== 'new' && (
+                    <p className="text-xs text-muted-foreground">{t('rule.empty')}</p>
+                )}
+                {rules.map((rule) =>
+                    editingRule !== 'new' && editingRule?.id === rule.id ? (
+                        <RuleForm key={rule.id} rule={rule} targets={targets} onClose={() => setEditingRule(null)} />
+                    ) : (
+                        <ListItem
+                            key={rule.id}
+                            title={rule.name}
+                            badge={t(`rule.events.${rule.event}`)}
+                            enabled={rule.enabled}
+                            onToggle={(enabled) => saveRule.mutate({ ...rule, enabled }, { onError })}
+                            onEdit={() => setEditingRule(rule)}
+                            onDelete={() => deleteRule.mutate(rule.id, { onError })}
+                        />
+                    )
+                )}
+            </div>
+        </div>
+    );
+}
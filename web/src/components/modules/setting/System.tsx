@@ -2,7 +2,7 @@
 
 import { useEffect, useState, useRef } from 'react';
 import { useTranslations } from 'next-intl';
-import { Monitor, Globe, Clock, Shield, HelpCircle } from 'lucide-react';
+import { Monitor, Globe, Clock, Shield, HelpCircle, Layers, Image as ImageIcon, Unplug, KeyRound, HardDrive } from 'lucide-react';
 import { Input } from '@/components/ui/input';
 import { useSettingList, useSetSetting, SettingKey } from '@/api/endpoints/setting';
 import { toast } from '@/components/common/Toast';
@@ -16,16 +16,34 @@ export function SettingSystem() {
     const [proxyUrl, setProxyUrl] = useState('');
     const [statsSaveInterval, setStatsSaveInterval] = useState('');
     const [corsAllowOrigins, setCorsAllowOrigins] = useState('');
+    const [batchConcurrency, setBatchConcurrency] = useState('');
+    const [mediaMaxSize, setMediaMaxSize] = useState('');
+    const [mediaMaxDimension, setMediaMaxDimension] = useState('');
+    const [streamDrainGrace, setStreamDrainGrace] = useState('');
+    const [idempotencyTTL, setIdempotencyTTL] = useState('');
+    const [idempotencyMaxSize, setIdempotencyMaxSize] = useState('');
 
     const initialProxyUrl = useRef('');
     const initialStatsSaveInterval = useRef('');
     const initialCorsAllowOrigins = useRef('');
+    const initialBatchConcurrency = useRef('');
+    const initialMediaMaxSize = useRef('');
+    const initialMediaMaxDimension = useRef('');
+    const initialStreamDrainGrace = useRef('');
+    const initialIdempotencyTTL = useRef('');
+    const initialIdempotencyMaxSize = useRef('');
 
     useEffect(() => {
         if (settings) {
             const proxy = settings.find(s => s.key === SettingKey.ProxyURL);
             const interval = settings.find(s => s.key === SettingKey.StatsSaveInterval);
             const cors = settings.find(s => s.key === SettingKey.CORSAllowOrigins);
+            const batch = settings.find(s => s.key === SettingKey.BatchConcurrency);
+            const mediaSize = settings.find(s => s.key === SettingKey.MediaMaxSize);
+            const mediaDimension = settings.find(s => s.key === SettingKey.MediaMaxDimension);
+            const drainGrace = settings.find(s => s.key === SettingKey.StreamDrainGrace);
+            const idemTTL = settings.find(s => s.key === SettingKey.IdempotencyTTL);
+            const idemMaxSize = settings.find(s => s.key === SettingKey.IdempotencyMaxSize);
             if (proxy) {
                 queueMicrotask(() => setProxyUrl(proxy.value));
                 initialProxyUrl.current = proxy.value;
@@ -38,6 +56,30 @@ export function SettingSystem() {
                 queueMicrotask(() => setCorsAllowOrigins(cors.value));
                 initialCorsAllowOrigins.current = cors.value;
             }
+            if (batch) {
+                queueMicrotask(() => setBatchConcurrency(batch.value));
+                initialBatchConcurrency.current = batch.value;
+            }
+            if (mediaSize) {
+                queueMicrotask(() => setMediaMaxSize(mediaSize.value));
+                initialMediaMaxSize.current = mediaSize.value;
+            }
+            if (mediaDimension) {
+                queueMicrotask(() => setMediaMaxDimension(mediaDimension.value));
+                initialMediaMaxDimension.current = mediaDimension.value;
+            }
+            if (drainGrace) {
+                queueMicrotask(() => setStreamDrainGrace(drainGrace.value));
+                initialStreamDrainGrace.current = drainGrace.value;
+            }
+            if (idemTTL) {
+                queueMicrotask(() => setIdempotencyTTL(idemTTL.value));
+                initialIdempotencyTTL.current = idemTTL.value;
+            }
+            if (idemMaxSize) {
+                queueMicrotask(() => setIdempotencyMaxSize(idemMaxSize.value));
+                initialIdempotencyMaxSize.current = idemMaxSize.value;
+            }
         }
     }, [settings]);
 
@@ -53,6 +95,18 @@ export function SettingSystem() {
                     initialStatsSaveInterval.current = value;
                 } else if (key === SettingKey.CORSAllowOrigins) {
                     initialCorsAllowOrigins.current = value;
+                } else if (key === SettingKey.BatchConcurrency) {
+                    initialBatchConcurrency.current = value;
+                } else if (key === SettingKey.MediaMaxSize) {
+                    initialMediaMaxSize.current = value;
+                } else if (key === SettingKey.MediaMaxDimension) {
+                    initialMediaMaxDimension.current = value;
+                } else if (key === SettingKey.StreamDrainGrace) {
+                    initialStreamDrainGrace.current = value;
+                } else if (key === SettingKey.IdempotencyTTL) {
+                    initialIdempotencyTTL.current = value;
+                } else if (key === SettingKey.IdempotencyMaxSize) {
+                    initialIdempotencyMaxSize.current = value;
                 }
             }
         });
@@ -96,6 +150,122 @@ export function SettingSystem() {
                 />
             </div>
 
+            {/* 批处理并发数 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <Layers className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('batchConcurrency.label')}</span>
+                </div>
+                <Input
+                    type="number"
+                    value={batchConcurrency}
+                    onChange={(e) => setBatchConcurrency(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.BatchConcurrency, batchConcurrency, initialBatchConcurrency.current)}
+                    placeholder={t('batchConcurrency.placeholder')}
+                    className="w-48 rounded-xl"
+                />
+            </div>
+
+            {/* 远程媒体大小上限 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <ImageIcon className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('mediaMaxSize.label')}</span>
+                </div>
+                <Input
+                    type="number"
+                    value={mediaMaxSize}
+                    onChange={(e) => setMediaMaxSize(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.MediaMaxSize, mediaMaxSize, initialMediaMaxSize.current)}
+                    placeholder={t('mediaMaxSize.placeholder')}
+                    className="w-48 rounded-xl"
+                />
+            </div>
+
+            {/* 内联图片最长边 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <ImageIcon className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('mediaMaxDimension.label')}</span>
+                </div>
+                <Input
+                    type="number"
+                    value={mediaMaxDimension}
+                    onChange={(e) => setMediaMaxDimension(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.MediaMaxDimension, mediaMaxDimension, initialMediaMaxDimension.current)}
+                    placeholder={t('mediaMaxDimension.placeholder')}
+                    className="w-48 rounded-xl"
+                />
+            </div>
+
+            {/* 客户端中断后读取上游用量的时长 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <Unplug className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('streamDrainGrace.label')}</span>
+                    <TooltipProvider>
+                        <Tooltip>
+                            <TooltipTrigger asChild>
+                                <HelpCircle className="size-4 text-muted-foreground cursor-help" />
+                            </TooltipTrigger>
+                            <TooltipContent>
+                                {t('streamDrainGrace.hint')}
+                            </TooltipContent>
+                        </Tooltip>
+                    </TooltipProvider>
+                </div>
+                <Input
+                    type="number"
+                    value={streamDrainGrace}
+                    onChange={(e) => setStreamDrainGrace(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.StreamDrainGrace, streamDrainGrace, initialStreamDrainGrace.current)}
+                    placeholder={t('streamDrainGrace.placeholder')}
+                    className="w-48 rounded-xl"
+                />
+            </div>
+
+            {/* Idempotency-Key 有效期 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <KeyRound className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('idempotencyTTL.label')}</span>
+                    <TooltipProvider>
+                        <Tooltip>
+                            <TooltipTrigger asChild>
+                                <HelpCircle className="size-4 text-muted-foreground cursor-help" />
+                            </TooltipTrigger>
+                            <TooltipContent>
+                                {t('idempotencyTTL.hint')}
+                            </TooltipContent>
+                        </Tooltip>
+                    </TooltipProvider>
+                </div>
+                <Input
+                    type="number"
+                    value={idempotencyTTL}
+                    onChange={(e) => setIdempotencyTTL(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.IdempotencyTTL, idempotencyTTL, initialIdempotencyTTL.current)}
+                    placeholder={t('idempotencyTTL.placeholder')}
+                    className="w-48 rounded-xl"
+                />
+            </div>
+
+            {/* Idempotency-Key 保存的响应总大小 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <HardDrive className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('idempotencyMaxSize.label')}</span>
+                </div>
+                <Input
+                    type="number"
+                    value={idempotencyMaxSize}
+                    onChange={(e) => setIdempotencyMaxSize(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.IdempotencyMaxSize, idempotencyMaxSize, initialIdempotencyMaxSize.current)}
+                    placeholder={t('idempotencyMaxSize.placeholder')}
+                    className="w-48 rounded-xl"
+                />
+            </div>
+
             {/* CORS 跨域白名单 */}
             <div className="flex items-center justify-between gap-4">
                 <div className="flex items-center gap-3">
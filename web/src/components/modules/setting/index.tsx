@@ -10,6 +10,7 @@ import { SettingInfo } from './Info';
 import { SettingLLMSync } from './LLMSync';
 import { SettingLog } from './Log';
 import { SettingBackup } from './Backup';
+import { SettingNotify } from './Notify';
 
 export function Setting() {
     return (
@@ -38,6 +39,9 @@ export function Setting() {
             <div>
                 <SettingLLMSync key="setting-llmsync" />
             </div>
+            <div>
+                <SettingNotify key="setting-notify" />
+            </div>
             <div>
                 <SettingBackup key="setting-backup" />
             </div>